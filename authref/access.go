@@ -0,0 +1,140 @@
+package authref
+
+import (
+	"sort"
+	"strings"
+)
+
+// ActionSummary breaks down a set of actions by service and access level.
+type ActionSummary struct {
+	// Number of actions at each access level
+	Totals map[AccessLevel]int `json:"totals"`
+
+	Services []*ServiceAccess `json:"services"`
+}
+
+// PolicyAccess summarizes the actions a policy grants, by service and access level.
+type PolicyAccess struct {
+	*ActionSummary
+
+	// Allow statements whose wildcards include permissions management actions
+	WildcardGrants []*WildcardGrant `json:"wildcardGrants"`
+}
+
+// ServiceAccess lists the actions of a summary in one service.
+type ServiceAccess struct {
+	ServicePrefix string `json:"servicePrefix"`
+	Name          string `json:"name"`
+
+	// Action names, keyed by access level
+	Actions map[AccessLevel][]string `json:"actions"`
+}
+
+// WildcardGrant is an Allow statement pattern that matches permissions management actions.
+// For a NotAction statement, the pattern is "NotAction".
+type WildcardGrant struct {
+	Statement             int      `json:"statement"`
+	Sid                   string   `json:"sid,omitempty"`
+	Pattern               string   `json:"pattern"`
+	PermissionsManagement []string `json:"permissionsManagement"`
+}
+
+// isWildcard reports whether an action pattern contains wildcards.
+func isWildcard(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?")
+}
+
+// SummarizeActions groups actions by service and access level. Services are sorted by
+// prefix, and actions keep their order within each service.
+func SummarizeActions(actions []*QualifiedAction) *ActionSummary {
+	result := &ActionSummary{Totals: map[AccessLevel]int{}, Services: make([]*ServiceAccess, 0)}
+	services := map[string]*ServiceAccess{}
+
+	for _, action := range actions {
+		prefix := action.Service.ServicePrefix
+		service := services[prefix]
+
+		if service == nil {
+			service = &ServiceAccess{ServicePrefix: prefix, Name: action.Service.Name, Actions: map[AccessLevel][]string{}}
+			services[prefix] = service
+			result.Services = append(result.Services, service)
+		}
+
+		service.Actions[action.Action.AccessLevel] = append(service.Actions[action.Action.AccessLevel], action.Action.Name)
+		result.Totals[action.Action.AccessLevel]++
+	}
+
+	sort.Slice(result.Services, func(i, j int) bool {
+		return result.Services[i].ServicePrefix < result.Services[j].ServicePrefix
+	})
+
+	return result
+}
+
+// AllowedActions works out which actions a policy allows. Actions from Allow statements
+// are allowed unless an unconditional Deny statement on all resources ("*") takes them
+// away. Other Deny statements, conditions, and resources are not evaluated, so the result
+// is an upper bound on what the policy allows. Actions keep their order.
+func AllowedActions(actions []*QualifiedAction, policy *Policy) []*QualifiedAction {
+	granted := map[*QualifiedAction]bool{}
+	denied := map[*QualifiedAction]bool{}
+
+	for _, statement := range policy.Statement {
+		for _, action := range StatementActions(actions, statement) {
+			if statement.Effect == EffectAllow {
+				granted[action] = true
+			} else if len(statement.Condition) == 0 && statement.Resource.Contains("*") {
+				denied[action] = true
+			}
+		}
+	}
+
+	result := make([]*QualifiedAction, 0, len(granted))
+
+	for _, action := range actions {
+		if granted[action] && !denied[action] {
+			result = append(result, action)
+		}
+	}
+
+	return result
+}
+
+// AnalyzeAccess works out which actions a policy grants, as AllowedActions does, and
+// which of its wildcards grant permissions management actions.
+func AnalyzeAccess(actions []*QualifiedAction, policy *Policy) *PolicyAccess {
+	result := &PolicyAccess{ActionSummary: SummarizeActions(AllowedActions(actions, policy)), WildcardGrants: make([]*WildcardGrant, 0)}
+
+	for i, statement := range policy.Statement {
+		if statement.Effect != EffectAllow {
+			continue
+		}
+
+		addWildcardGrant := func(pattern string, matched []*QualifiedAction) {
+			grant := &WildcardGrant{Statement: i, Sid: statement.Sid, Pattern: pattern, PermissionsManagement: make([]string, 0)}
+
+			for _, action := range matched {
+				if action.Action.AccessLevel == AccessLevelPermissionsManagement {
+					grant.PermissionsManagement = append(grant.PermissionsManagement, action.String())
+				}
+			}
+
+			if len(grant.PermissionsManagement) != 0 {
+				result.WildcardGrants = append(result.WildcardGrants, grant)
+			}
+		}
+
+		if len(statement.NotAction) != 0 {
+			addWildcardGrant("NotAction", StatementActions(actions, statement))
+			continue
+		}
+
+		for _, pattern := range statement.Action {
+			if isWildcard(pattern) {
+				addWildcardGrant(pattern, MatchActions(actions, []string{pattern}))
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,155 @@
+package authref
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ArnNamespace returns the service namespace of an ARN or ARN pattern, which is its third
+// field: "apigateway" in "arn:${Partition}:apigateway:${Region}::/apis". It returns "" if
+// the value doesn't start with "arn:" or has too few fields. The namespace may itself be
+// a placeholder, such as "${Vendor}".
+func ArnNamespace(arn string) string {
+	fields := strings.SplitN(arn, ":", 4)
+
+	if len(fields) < 4 || fields[0] != "arn" {
+		return ""
+	}
+
+	return fields[2]
+}
+
+// ArnNamespaceMismatch is a resource type whose ARNs use a different service namespace
+// than the prefix of the service that defines it. For example, Amazon EBS direct APIs
+// define the "snapshot" resource type under the prefix "ebs", but its ARNs are EC2 ARNs.
+type ArnNamespaceMismatch struct {
+	ArnNamespace  string `json:"arnNamespace"`
+	ServicePrefix string `json:"servicePrefix"`
+	ResourceType  string `json:"resourceType"`
+	ArnPattern    string `json:"arnPattern"`
+}
+
+// ArnNamespaceMismatches lists every resource type whose ARN pattern names a service
+// namespace other than its service prefix, sorted by namespace, then prefix and resource
+// type. Tools that start from an ARN need these to find the actions that can act on it,
+// since the namespace alone would point to the wrong service. Resource types whose ARN
+// pattern can't be parsed are left out.
+func ArnNamespaceMismatches(authRefs []*ServiceAuthorizationReference) []*ArnNamespaceMismatch {
+	result := make([]*ArnNamespaceMismatch, 0)
+	seen := map[ArnNamespaceMismatch]bool{}
+
+	for _, authRef := range authRefs {
+		for _, resourceType := range authRef.ResourceTypes {
+			namespace := ArnNamespace(resourceType.ArnPattern)
+
+			if namespace == "" || strings.EqualFold(namespace, authRef.ServicePrefix) {
+				continue
+			}
+
+			mismatch := ArnNamespaceMismatch{
+				ArnNamespace:  namespace,
+				ServicePrefix: authRef.ServicePrefix,
+				ResourceType:  resourceType.Name,
+				ArnPattern:    resourceType.ArnPattern,
+			}
+
+			// Pages that share a prefix can repeat a resource type
+			if !seen[mismatch] {
+				seen[mismatch] = true
+				result = append(result, &mismatch)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+
+		if a.ArnNamespace != b.ArnNamespace {
+			return a.ArnNamespace < b.ArnNamespace
+		}
+
+		if a.ServicePrefix != b.ServicePrefix {
+			return a.ServicePrefix < b.ServicePrefix
+		}
+
+		return a.ResourceType < b.ResourceType
+	})
+
+	return result
+}
+
+var arnPlaceholder = regexp.MustCompile(`\$\{[^}]*\}`)
+
+// ArnMatch is a resource type whose ARN pattern matches an ARN, along with the actions
+// that can be scoped to resources of that type.
+type ArnMatch struct {
+	Service      *ServiceAuthorizationReference
+	ResourceType *ResourceType
+
+	// Actions that list the resource type, in the order of the service's page
+	Actions []*QualifiedAction
+}
+
+// MatchArn finds the resource types whose ARN patterns match an ARN, such as
+// "arn:aws:s3:::my-bucket/key", and the actions that can act on each. The ARN can contain
+// wildcards, as in a policy; see ArnPatternMatches. Resource types are
+// found by the namespace in their ARN patterns, not by their service prefixes, since some
+// use another service's namespace; see ArnNamespaceMismatches. Patterns with more fixed
+// text are more specific, so they come first.
+//
+// Actions that can only be granted on all resources aren't listed, although they may
+// affect the resource too.
+func MatchArn(authRefs []*ServiceAuthorizationReference, arn string) ([]*ArnMatch, error) {
+	if splitArn(arn) == nil {
+		return nil, fmt.Errorf("%#v doesn't have the six fields of an ARN", arn)
+	}
+
+	result := make([]*ArnMatch, 0)
+
+	// Most resource types can be ruled out by their namespace alone, which may itself be a
+	// wildcard in the ARN or a placeholder in the pattern
+	arnNamespace := resourceGlob(ArnNamespace(arn), false)
+
+	for _, authRef := range authRefs {
+		for _, resourceType := range authRef.ResourceTypes {
+			if namespace := ArnNamespace(resourceType.ArnPattern); namespace == "" || !globsIntersect(patternGlob(namespace, false), arnNamespace) {
+				continue
+			}
+
+			matches, err := ArnPatternMatches(resourceType.ArnPattern, arn)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !matches {
+				continue
+			}
+
+			match := &ArnMatch{Service: authRef, ResourceType: resourceType, Actions: make([]*QualifiedAction, 0)}
+
+			for _, action := range authRef.Actions {
+				for _, actionResourceType := range action.ResourceTypes {
+					if actionResourceType.ResourceType == resourceType.Name {
+						match.Actions = append(match.Actions, &QualifiedAction{Service: authRef, Action: action})
+						break
+					}
+				}
+			}
+
+			result = append(result, match)
+		}
+	}
+
+	fixedText := func(pattern string) int {
+		return len(arnPlaceholder.ReplaceAllString(pattern, ""))
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return fixedText(result[i].ResourceType.ArnPattern) > fixedText(result[j].ResourceType.ArnPattern)
+	})
+
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package authref
+
+import "testing"
+
+func TestMatchArnWildcardService(t *testing.T) {
+	authRefs := []*ServiceAuthorizationReference{
+		{
+			ServicePrefix: "s3",
+			ResourceTypes: []*ResourceType{{Name: "bucket", ArnPattern: "arn:${Partition}:s3:::${BucketName}"}},
+		},
+		{
+			ServicePrefix: "ec2",
+			ResourceTypes: []*ResourceType{{Name: "instance", ArnPattern: "arn:${Partition}:ec2:${Region}:${Account}:instance/${InstanceId}"}},
+		},
+	}
+
+	tests := []struct {
+		arn  string
+		want []string
+	}{
+		{"arn:aws:s3:::my-bucket", []string{"bucket"}},
+		{"arn:aws:s3*:::my-bucket", []string{"bucket"}},
+		{"arn:aws:*:*:*:*", []string{"instance", "bucket"}},
+		{"arn:aws:e?2:*:*:*", []string{"instance"}},
+	}
+
+	for _, test := range tests {
+		matches, err := MatchArn(authRefs, test.arn)
+
+		if err != nil {
+			t.Errorf("MatchArn(%q): %v", test.arn, err)
+			continue
+		}
+
+		got := make([]string, len(matches))
+
+		for i, match := range matches {
+			got[i] = match.ResourceType.Name
+		}
+
+		if len(got) != len(test.want) {
+			t.Errorf("MatchArn(%q) = %v, want %v", test.arn, got, test.want)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("MatchArn(%q) = %v, want %v", test.arn, got, test.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package authref
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ArnPlaceholders returns the names of the placeholders in an ARN pattern, in order and
+// without repeats, such as ["Partition", "BucketName", "ObjectName"] for
+// "arn:${Partition}:s3:::${BucketName}/${ObjectName}".
+func ArnPlaceholders(pattern string) []string {
+	result := make([]string, 0)
+	seen := map[string]bool{}
+
+	for _, placeholder := range arnPlaceholder.FindAllString(pattern, -1) {
+		name := placeholder[2 : len(placeholder)-1]
+
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+
+	return result
+}
+
+// hasBareColon reports whether a value contains a colon outside a policy variable such
+// as "${aws:username}".
+func hasBareColon(value string) bool {
+	depth := 0
+
+	for i := 0; i < len(value); i++ {
+		switch {
+		case strings.HasPrefix(value[i:], "${"):
+			depth++
+		case value[i] == '}' && depth > 0:
+			depth--
+		case value[i] == ':' && depth == 0:
+			return true
+		}
+	}
+
+	return false
+}
+
+// FillArnPattern fills in the placeholders of an ARN pattern from the reference with
+// values keyed by placeholder name, such as {"BucketName": "reports", "ObjectName":
+// "2024/*"}. Names are matched without regard to case. Values are used as they are, so
+// they can be wildcards or policy variables. Partition can be left out, and is then worked
+// out from Region, or is "aws" if there's no region either.
+//
+// The result is only returned if it's a complete ARN. It's an error if a placeholder has
+// no value, if a value before the resource contains a colon, or if a value in the resource
+// is empty. Region and Account can be empty, since some ARNs leave them out.
+//
+// A value that follows a "/" in the pattern has its leading slashes removed, and a value
+// followed by a "/" has its trailing slashes removed, so that a path such as "/division/"
+// in "role/${RoleNameWithPath}" doesn't make an empty path segment.
+func FillArnPattern(pattern string, values map[string]string) (string, error) {
+	fields := splitArn(pattern)
+
+	if fields == nil {
+		return "", fmt.Errorf("ARN pattern %#v doesn't have the six fields of an ARN", pattern)
+	}
+
+	byName := make(map[string]string, len(values))
+
+	for name, value := range values {
+		byName[strings.ToLower(name)] = value
+	}
+
+	if _, ok := byName["partition"]; !ok {
+		byName["partition"] = RegionPartition(byName["region"])
+	}
+
+	missing := map[string]bool{}
+	var b strings.Builder
+
+	for i, field := range fields {
+		if i != 0 {
+			b.WriteByte(':')
+		}
+
+		last := 0
+
+		for _, loc := range arnPlaceholder.FindAllStringIndex(field, -1) {
+			b.WriteString(field[last:loc[0]])
+			last = loc[1]
+			name := field[loc[0]+2 : loc[1]-1]
+			value, ok := byName[strings.ToLower(name)]
+
+			if !ok {
+				missing[name] = true
+				continue
+			}
+
+			if loc[0] > 0 && field[loc[0]-1] == '/' {
+				value = strings.TrimLeft(value, "/")
+			}
+
+			if loc[1] < len(field) && field[loc[1]] == '/' {
+				value = strings.TrimRight(value, "/")
+			}
+
+			switch {
+			case i < 5 && hasBareColon(value):
+				return "", fmt.Errorf("ARN pattern %#v: the value of %s, %#v, can't contain a colon", pattern, name, value)
+			case i == 5 && value == "":
+				return "", fmt.Errorf("ARN pattern %#v: the value of %s is empty", pattern, name)
+			}
+
+			b.WriteString(value)
+		}
+
+		b.WriteString(field[last:])
+	}
+
+	if len(missing) != 0 {
+		names := make([]string, 0, len(missing))
+
+		for name := range missing {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		return "", fmt.Errorf("ARN pattern %#v: no value for %s", pattern, strings.Join(names, ", "))
+	}
+
+	return b.String(), nil
+}
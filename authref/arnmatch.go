@@ -0,0 +1,263 @@
+package authref
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Partitions lists the AWS partitions, which are what "${Partition}" stands for in an ARN
+// pattern.
+var Partitions = []string{"aws", "aws-cn", "aws-us-gov", "aws-iso", "aws-iso-b", "aws-iso-e", "aws-iso-f", "aws-eusc"}
+
+// Kinds of globToken
+const (
+	globLiteral = iota
+	globOne
+	globStar
+)
+
+// globToken is one step of a glob: a literal character, any single character, or any run
+// of characters. The last two exclude the characters in exclude.
+type globToken struct {
+	kind    int
+	char    byte
+	exclude string
+}
+
+// overlaps reports whether some character can satisfy both tokens.
+func (token globToken) overlaps(other globToken) bool {
+	switch {
+	case token.kind == globLiteral && other.kind == globLiteral:
+		return token.char == other.char
+	case token.kind == globLiteral:
+		return strings.IndexByte(other.exclude, token.char) < 0
+	case other.kind == globLiteral:
+		return strings.IndexByte(token.exclude, other.char) < 0
+	default:
+		return true
+	}
+}
+
+// globsIntersect reports whether some text matches both globs, by searching the pairs of
+// positions the two can reach while reading the same text.
+func globsIntersect(a, b []globToken) bool {
+	seen := map[[2]int]bool{}
+	var visit func(i, j int) bool
+
+	visit = func(i, j int) bool {
+		if seen[[2]int{i, j}] {
+			return false
+		}
+
+		seen[[2]int{i, j}] = true
+
+		if i == len(a) && j == len(b) {
+			return true
+		}
+
+		// A run can match nothing
+		if i < len(a) && a[i].kind == globStar && visit(i+1, j) {
+			return true
+		}
+
+		if j < len(b) && b[j].kind == globStar && visit(i, j+1) {
+			return true
+		}
+
+		if i == len(a) || j == len(b) || !a[i].overlaps(b[j]) {
+			return false
+		}
+
+		// Read one character; a run stays where it is to read more
+		next := [2]int{i + 1, j + 1}
+
+		if a[i].kind == globStar {
+			next[0] = i
+		}
+
+		if b[j].kind == globStar {
+			next[1] = j
+		}
+
+		return visit(next[0], next[1])
+	}
+
+	return visit(0, 0)
+}
+
+// splitArn splits an ARN or ARN pattern into its five fixed fields and its resource,
+// ignoring colons inside placeholders such as "${aws:username}". It returns nil if the
+// value doesn't start with "arn:" or has too few fields.
+func splitArn(arn string) []string {
+	fields := make([]string, 0, 6)
+	start, depth := 0, 0
+
+	for i := 0; i < len(arn) && len(fields) < 5; i++ {
+		switch {
+		case strings.HasPrefix(arn[i:], "${"):
+			depth++
+		case arn[i] == '}' && depth > 0:
+			depth--
+		case arn[i] == ':' && depth == 0:
+			fields = append(fields, arn[start:i])
+			start = i + 1
+		}
+	}
+
+	if len(fields) < 5 || fields[0] != "arn" {
+		return nil
+	}
+
+	return append(fields, arn[start:])
+}
+
+// arnGlob converts one field of an ARN or ARN pattern to a glob. Wildcards are "*" and "?",
+// and a placeholder becomes whatever placeholder returns, given whether it ends the text
+// and follows a "/". Neither may match a character in exclude.
+func arnGlob(text, exclude string, placeholder func(trailing bool) []globToken) []globToken {
+	result := make([]globToken, 0, len(text))
+
+	for i := 0; i < len(text); i++ {
+		switch {
+		case strings.HasPrefix(text[i:], "${") && strings.IndexByte(text[i:], '}') > 0:
+			end := i + strings.IndexByte(text[i:], '}')
+			result = append(result, placeholder(end == len(text)-1 && i > 0 && text[i-1] == '/')...)
+			i = end
+		case text[i] == '*':
+			result = append(result, globToken{kind: globStar, exclude: exclude})
+		case text[i] == '?':
+			result = append(result, globToken{kind: globOne, exclude: exclude})
+		default:
+			result = append(result, globToken{kind: globLiteral, char: text[i]})
+		}
+	}
+
+	return result
+}
+
+// patternGlob converts one field of an ARN pattern from the reference to a glob. A
+// placeholder stands for at least one character, without a colon, or in the resource,
+// without a "/" either, unless it ends the resource after a "/", where it matches the rest
+// of a path such as an S3 object key. Only a field that's empty in the pattern, such as the
+// region of an S3 bucket, matches an empty field.
+func patternGlob(field string, resource bool) []globToken {
+	if !resource {
+		return arnGlob(field, ":", func(bool) []globToken {
+			return []globToken{{kind: globOne, exclude: ":"}, {kind: globStar, exclude: ":"}}
+		})
+	}
+
+	return arnGlob(field, "", func(trailing bool) []globToken {
+		if trailing {
+			return []globToken{{kind: globOne}, {kind: globStar}}
+		}
+
+		return []globToken{{kind: globOne, exclude: ":/"}, {kind: globStar, exclude: ":/"}}
+	})
+}
+
+// resourceGlob converts one field of an ARN from a policy's Resource element to a glob.
+// Policy variables such as "${aws:username}" could stand for anything.
+func resourceGlob(field string, resource bool) []globToken {
+	exclude := ":"
+
+	if resource {
+		exclude = ""
+	}
+
+	return arnGlob(field, exclude, func(bool) []globToken {
+		return []globToken{{kind: globStar, exclude: exclude}}
+	})
+}
+
+// ArnPatternMatches reports whether an ARN can name a resource described by an ARN pattern
+// from the reference, such as "arn:${Partition}:s3:::${BucketName}/${ObjectName}". The ARN
+// can be concrete, or come from a policy's Resource element with "*" and "?" wildcards, in
+// which case it matches if any of the ARNs it covers would. Wildcards and placeholders
+// stay within their field, except in the resource, where a wildcard matches anything.
+// "${Partition}" only matches the partitions in Partitions.
+//
+// An error means the ARN or the pattern isn't of the form "arn:partition:service:region:account:resource".
+func ArnPatternMatches(pattern, arn string) (bool, error) {
+	patternFields := splitArn(pattern)
+
+	if patternFields == nil {
+		return false, fmt.Errorf("ARN pattern %#v doesn't have the six fields of an ARN", pattern)
+	}
+
+	arnFields := splitArn(arn)
+
+	if arnFields == nil {
+		return false, fmt.Errorf("%#v doesn't have the six fields of an ARN", arn)
+	}
+
+	for i := 1; i < 6; i++ {
+		resource := i == 5
+		arnField := resourceGlob(arnFields[i], resource)
+
+		if i == 1 && patternFields[i] == "${Partition}" {
+			partition := false
+
+			for _, name := range Partitions {
+				if globsIntersect(arnField, arnGlob(name, "", nil)) {
+					partition = true
+					break
+				}
+			}
+
+			if !partition {
+				return false, nil
+			}
+		} else if !globsIntersect(patternGlob(patternFields[i], resource), arnField) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// ArnResourceTypes returns the resource types of a service whose ARN patterns an ARN can
+// match, as decided by ArnPatternMatches, in the order of the service's page.
+func ArnResourceTypes(authRef *ServiceAuthorizationReference, arn string) ([]*ResourceType, error) {
+	result := make([]*ResourceType, 0)
+
+	for _, resourceType := range authRef.ResourceTypes {
+		matches, err := ArnPatternMatches(resourceType.ArnPattern, arn)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s resource type %s: %w", authRef.ServicePrefix, resourceType.Name, err)
+		}
+
+		if matches {
+			result = append(result, resourceType)
+		}
+	}
+
+	return result, nil
+}
+
+// ActionAcceptsArn reports whether an ARN from a policy's Resource element can apply to
+// an action of a service: "*" applies to every action, and any other ARN must be able to
+// name a resource of one of the resource types the action lists. An action that lists no
+// resource types can only be granted on "*".
+func ActionAcceptsArn(authRef *ServiceAuthorizationReference, action *Action, arn string) (bool, error) {
+	if arn == "*" {
+		return true, nil
+	}
+
+	resourceTypes, err := ArnResourceTypes(authRef, arn)
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, resourceType := range resourceTypes {
+		for _, actionResourceType := range action.ResourceTypes {
+			if actionResourceType.ResourceType == resourceType.Name {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,31 @@
+package authref
+
+import "testing"
+
+func TestArnPatternMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		arn     string
+		want    bool
+	}{
+		{"arn:${Partition}:s3:::${BucketName}", "arn:aws:s3:::my-bucket", true},
+		{"arn:${Partition}:s3:::${BucketName}", "arn:aws:s3:::*", true},
+		{"arn:${Partition}:s3:::${BucketName}/${ObjectName}", "arn:aws:s3:::my-bucket/a/b/c", true},
+
+		// A placeholder in the region or account needs a value, so a bucket ARN, which
+		// leaves both empty, can't name a Storage Lens group
+		{"arn:${Partition}:s3:${Region}:${Account}:storage-lens-group/${Name}", "arn:aws:s3:::*", false},
+		{"arn:${Partition}:s3:${Region}:${Account}:storage-lens-group/${Name}", "arn:aws:s3:*:*:*", true},
+		{"arn:${Partition}:s3:${Region}:${Account}:storage-lens-group/${Name}", "arn:aws:s3:us-east-1:123456789012:storage-lens-group/g", true},
+	}
+
+	for _, test := range tests {
+		got, err := ArnPatternMatches(test.pattern, test.arn)
+
+		if err != nil {
+			t.Errorf("ArnPatternMatches(%q, %q): %v", test.pattern, test.arn, err)
+		} else if got != test.want {
+			t.Errorf("ArnPatternMatches(%q, %q) = %v, want %v", test.pattern, test.arn, got, test.want)
+		}
+	}
+}
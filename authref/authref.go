@@ -0,0 +1,316 @@
+// Package authref describes the data in the AWS Service Authorization Reference, as
+// scraped into service-auth.json, and provides helpers for loading and querying it.
+package authref
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// AccessLevel is the access level classification of an action. See
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/access_policies_understand-policy-summary-access-level-summaries.html
+type AccessLevel string
+
+const (
+	AccessLevelList                  AccessLevel = "List"
+	AccessLevelRead                  AccessLevel = "Read"
+	AccessLevelWrite                 AccessLevel = "Write"
+	AccessLevelPermissionsManagement AccessLevel = "Permissions management"
+	AccessLevelTagging               AccessLevel = "Tagging"
+)
+
+// AccessLevels lists the documented access levels in the order AWS presents them.
+var AccessLevels = []AccessLevel{
+	AccessLevelList,
+	AccessLevelRead,
+	AccessLevelWrite,
+	AccessLevelPermissionsManagement,
+	AccessLevelTagging,
+}
+
+// Known reports whether the access level is one of the documented classifications.
+func (level AccessLevel) Known() bool {
+	for _, known := range AccessLevels {
+		if level == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ServiceAuthorizationReference describes the IAM authorization details for an AWS
+// service, as listed on one page of the reference.
+type ServiceAuthorizationReference struct {
+	Name              string `json:"name"`
+	ServicePrefix     string `json:"servicePrefix"`
+	AuthReferenceHref string `json:"authReferenceHref"`
+	ApiReferenceHref  string `json:"apiReferenceHref,omitempty"`
+
+	// Service principals the service uses in trust policies, such as "lambda.amazonaws.com".
+	// These aren't part of the reference itself; they come from service-principals.json.
+	ServicePrincipals []string `json:"servicePrincipals,omitempty"`
+
+	// AWS SDK clients that call the service, if known. Like ServicePrincipals, these come
+	// from a mapping maintained by hand, sdk-services.json.
+	SdkServices []*SdkService `json:"sdkServices,omitempty"`
+
+	// Date AWS last changed the service's page, as YYYY-MM-DD, if the scraper could tell.
+	// Snapshots made before this was recorded leave it empty.
+	LastUpdated string `json:"lastUpdated,omitempty"`
+
+	// Partitions from ArnPartitions the service isn't offered in, such as "aws-cn". Like
+	// ServicePrincipals, these come from a mapping maintained by hand, service-partitions.json,
+	// so a service with none listed may still be missing from some partition.
+	UnavailablePartitions []string `json:"unavailablePartitions,omitempty"`
+
+	// Regions the service has endpoints in, across every partition, such as "us-east-1"
+	// and "cn-north-1", sorted. These come from the AWS SDK's endpoints data rather than
+	// the reference; the list is empty for services the SDK doesn't know, and for
+	// snapshots made before it was recorded.
+	Regions []string `json:"regions,omitempty"`
+
+	Actions       []*Action       `json:"actions"`
+	ResourceTypes []*ResourceType `json:"resourceTypes"`
+	ConditionKeys []*ConditionKey `json:"conditionKeys"`
+}
+
+// SdkService identifies an AWS SDK client for a service. The names SDKs use often differ
+// from the service prefix: actions with the prefix "states" are called through the SDK
+// service "SFN", and "cloudwatch" actions go to the endpoint "monitoring".
+type SdkService struct {
+	// Service ID from the SDK's service model, such as "SFN" or "Elasticsearch Service"
+	ServiceId string `json:"serviceId"`
+
+	// Endpoint prefix from the service model, such as "states". This is also the first
+	// part of the event source in CloudTrail, as in "states.amazonaws.com".
+	EndpointPrefix string `json:"endpointPrefix"`
+}
+
+// ActionResourceType is a resource type that can be specified on an action.
+type ActionResourceType struct {
+	ResourceType string `json:"resourceType"`
+
+	// Required is true if the reference marks the resource type with an asterisk. That
+	// doesn't mean a resource of this type is always needed: when several resource types
+	// of an action are marked, the action needs at least one of them, not all of them.
+	Required bool `json:"required"`
+
+	// RequiredGroup numbers the group of required resource types this one belongs to. A
+	// request must name a resource from at least one type in each group. It's zero for
+	// types that aren't required. The reference only ever marks one group per action, so
+	// this is 1 for every required type.
+	RequiredGroup int `json:"requiredGroup,omitempty"`
+
+	ConditionKeys    []string `json:"conditionKeys"`
+	DependentActions []string `json:"dependentActions"`
+}
+
+// RequiredResourceGroups returns the groups of resource types the action requires. A
+// request for the action must name a resource of at least one type from each group. The
+// result is empty if no resource type is required. Data written before RequiredGroup
+// existed is treated as having every required type in group 1.
+func (action *Action) RequiredResourceGroups() [][]string {
+	groups := map[int][]string{}
+	numbers := make([]int, 0)
+
+	for _, resourceType := range action.ResourceTypes {
+		if !resourceType.Required {
+			continue
+		}
+
+		group := resourceType.RequiredGroup
+
+		if group == 0 {
+			group = 1
+		}
+
+		if groups[group] == nil {
+			numbers = append(numbers, group)
+		}
+
+		groups[group] = append(groups[group], resourceType.ResourceType)
+	}
+
+	sort.Ints(numbers)
+	result := make([][]string, len(numbers))
+
+	for i, group := range numbers {
+		result[i] = groups[group]
+	}
+
+	return result
+}
+
+// SatisfiesRequiredResources reports whether naming resources of the given types meets
+// every required group of the action, as described under RequiredResourceGroups.
+func (action *Action) SatisfiesRequiredResources(resourceTypes []string) bool {
+	named := map[string]bool{}
+
+	for _, resourceType := range resourceTypes {
+		named[resourceType] = true
+	}
+
+	for _, group := range action.RequiredResourceGroups() {
+		satisfied := false
+
+		for _, resourceType := range group {
+			satisfied = satisfied || named[resourceType]
+		}
+
+		if !satisfied {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Action is an action that can be allowed or denied via IAM policy.
+type Action struct {
+	Name           string               `json:"name"`
+	PermissionOnly bool                 `json:"permissionOnly"`
+	Annotations    []string             `json:"annotations"`
+	ReferenceHref  string               `json:"referenceHref,omitempty"`
+	Description    string               `json:"description"`
+	AccessLevel    AccessLevel          `json:"accessLevel"`
+	ResourceTypes  []ActionResourceType `json:"resourceTypes"`
+	ConditionKeys  []string             `json:"conditionKeys"`
+
+	// Translations of Description, keyed by documentation locale such as "ja_jp". Only
+	// the locales the scraper was asked for are present.
+	LocalizedDescriptions map[string]string `json:"localizedDescriptions,omitempty"`
+
+	// Where the action was scraped from; only present if the scraper was run with --provenance
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// ResourceType is a type of resource that can be specified for a service in an IAM policy.
+type ResourceType struct {
+	Name          string   `json:"name"`
+	ReferenceHref string   `json:"referenceHref,omitempty"`
+	ArnPattern    string   `json:"arnPattern"`
+	ConditionKeys []string `json:"conditionKeys"`
+
+	// ArnPattern with its partition filled in, keyed by partition, for each of ArnPartitions
+	// the service is available in, as in "arn:aws-us-gov:s3:::${BucketName}". Snapshots made
+	// before this was recorded leave it empty; use ArnPatternInPartition for those.
+	PartitionArnPatterns map[string]string `json:"partitionArnPatterns,omitempty"`
+
+	// Where the resource type was scraped from, as for actions
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// ConditionKey is a condition that can be specified for an action in an IAM policy.
+type ConditionKey struct {
+	Name          string `json:"name"`
+	ReferenceHref string `json:"referenceHref,omitempty"`
+	Description   string `json:"description"`
+	Type          string `json:"type"`
+
+	// Translations of Description, keyed by documentation locale, as for actions
+	LocalizedDescriptions map[string]string `json:"localizedDescriptions,omitempty"`
+
+	// Where the condition key was scraped from, as for actions
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// Provenance records where a record in the reference came from, so a disputed value can
+// be traced back to the page and table it was read from.
+type Provenance struct {
+	// Address of the page the record was scraped from
+	SourceHref string `json:"sourceHref"`
+
+	// Id of the heading of the table on the page, which can be appended to SourceHref as a
+	// fragment; empty if the heading had no id
+	Anchor string `json:"anchor,omitempty"`
+
+	// When the page was scraped, in RFC 3339 format
+	ScrapedAt string `json:"scrapedAt"`
+}
+
+var localePattern = regexp.MustCompile(`^[a-z]{2}_[a-z]{2}$`)
+
+// IsLocale reports whether locale is in the form the AWS documentation uses for its
+// translations, such as "ja_jp" or "pt_br".
+func IsLocale(locale string) bool {
+	return localePattern.MatchString(locale)
+}
+
+// Decode reads a dataset in the service-auth.json format.
+func Decode(r io.Reader) ([]*ServiceAuthorizationReference, error) {
+	var result []*ServiceAuthorizationReference
+
+	if err := json.NewDecoder(r).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode service authorization reference: %w", err)
+	}
+
+	return result, nil
+}
+
+// SkipRemaining can be returned by the function given to ForEachService to stop reading
+// without an error.
+var SkipRemaining = errors.New("skip the remaining services")
+
+// ForEachService reads a dataset in the service-auth.json format one service at a time,
+// calling fn with each, so that only one service needs to be in memory at once. It
+// stops at the first error from fn and returns it, unless the error is SkipRemaining.
+// Services are passed in the order they appear; fn may keep them.
+func ForEachService(r io.Reader, fn func(authRef *ServiceAuthorizationReference) error) error {
+	decoder := json.NewDecoder(r)
+
+	if token, err := decoder.Token(); err != nil {
+		return fmt.Errorf("decode service authorization reference: %w", err)
+	} else if token != json.Delim('[') {
+		return fmt.Errorf("decode service authorization reference: expected an array")
+	}
+
+	for i := 0; decoder.More(); i++ {
+		var authRef *ServiceAuthorizationReference
+
+		if err := decoder.Decode(&authRef); err != nil {
+			return fmt.Errorf("decode service authorization reference: service %d: %w", i, err)
+		}
+
+		if authRef == nil {
+			return fmt.Errorf("decode service authorization reference: service %d is null", i)
+		}
+
+		if err := fn(authRef); errors.Is(err, SkipRemaining) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	// Closing bracket
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("decode service authorization reference: %w", err)
+	}
+
+	return nil
+}
+
+// LoadFile reads a dataset in the service-auth.json format from a file.
+func LoadFile(filename string) ([]*ServiceAuthorizationReference, error) {
+	file, err := os.Open(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	result, err := Decode(file)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package authref
+
+// BoundaryAccess describes how a permissions boundary limits an identity policy. A
+// principal can only use actions that both its identity policy and its boundary allow.
+type BoundaryAccess struct {
+	// Actions both policies allow
+	Effective *ActionSummary `json:"effective"`
+
+	// Actions the identity policy allows but the boundary doesn't
+	Blocked *ActionSummary `json:"blocked"`
+
+	// Number of actions the boundary allows that the identity policy doesn't, which the
+	// boundary would let the principal have if its identity policy granted them
+	BoundaryOnly int `json:"boundaryOnly"`
+}
+
+// AnalyzeBoundary works out which actions an identity policy leaves allowed under a
+// permissions boundary. Each policy is evaluated as AllowedActions does, so the result is
+// an upper bound.
+func AnalyzeBoundary(actions []*QualifiedAction, identity, boundary *Policy) *BoundaryAccess {
+	bounded := map[*QualifiedAction]bool{}
+
+	for _, action := range AllowedActions(actions, boundary) {
+		bounded[action] = true
+	}
+
+	effective := make([]*QualifiedAction, 0)
+	blocked := make([]*QualifiedAction, 0)
+
+	for _, action := range AllowedActions(actions, identity) {
+		if bounded[action] {
+			effective = append(effective, action)
+		} else {
+			blocked = append(blocked, action)
+		}
+	}
+
+	return &BoundaryAccess{
+		Effective:    SummarizeActions(effective),
+		Blocked:      SummarizeActions(blocked),
+		BoundaryOnly: len(bounded) - len(effective),
+	}
+}
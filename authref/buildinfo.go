@@ -0,0 +1,68 @@
+package authref
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// BuildInfo identifies the build of a program, so that a bug report or a published
+// snapshot can say exactly which code produced it.
+type BuildInfo struct {
+	// Module version, such as "v1.2.0" when installed with "go install ...@v1.2.0", or
+	// "(devel)" when built from a checkout
+	Version string `json:"version"`
+
+	// Commit the program was built from, and when it was made, in RFC 3339 format. Empty if
+	// the build didn't record them, as when built outside a git checkout or before Go 1.18.
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commitTime,omitempty"`
+
+	// Whether the checkout had uncommitted changes
+	Modified bool `json:"modified,omitempty"`
+
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+// ReadBuildInfo describes the build of the running program from what the Go toolchain
+// embedded in it.
+func ReadBuildInfo() BuildInfo {
+	result := BuildInfo{Version: "(unknown)", GoVersion: runtime.Version(), Platform: runtime.GOOS + "/" + runtime.GOARCH}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Version != "" {
+			result.Version = info.Main.Version
+		}
+
+		readVCSSettings(info, &result)
+	}
+
+	return result
+}
+
+// String describes the build on one line, such as "v1.2.0 (0123abcd, 2026-10-14T19:10:10Z)".
+func (info BuildInfo) String() string {
+	result := info.Version
+
+	if info.Commit != "" {
+		commit := info.Commit
+
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+
+		if info.Modified {
+			commit += "+dirty"
+		}
+
+		result += " (" + commit
+
+		if info.CommitTime != "" {
+			result += ", " + info.CommitTime
+		}
+
+		result += ")"
+	}
+
+	return result
+}
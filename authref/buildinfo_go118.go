@@ -0,0 +1,19 @@
+//go:build go1.18
+// +build go1.18
+
+package authref
+
+import "runtime/debug"
+
+func readVCSSettings(info *debug.BuildInfo, result *BuildInfo) {
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			result.Commit = setting.Value
+		case "vcs.time":
+			result.CommitTime = setting.Value
+		case "vcs.modified":
+			result.Modified = setting.Value == "true"
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !go1.18
+// +build !go1.18
+
+package authref
+
+import "runtime/debug"
+
+// Builds before Go 1.18 don't record version control information.
+func readVCSSettings(info *debug.BuildInfo, result *BuildInfo) {}
@@ -0,0 +1,189 @@
+package authref
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ChecksumsFile is the name of the checksum list published alongside the artifacts, in
+// the format written by sha256sum. SignatureFile is its minisign-compatible signature.
+const (
+	ChecksumsFile = "SHA256SUMS"
+	SignatureFile = "SHA256SUMS.minisig"
+)
+
+// SHA256File returns the hex-encoded SHA-256 digest of a file.
+func SHA256File(filename string) (string, error) {
+	file, err := os.Open(filename)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+	hash := sha256.New()
+
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// FormatChecksums formats a map of file names to digests like sha256sum does, sorted by name.
+func FormatChecksums(checksums map[string]string) []byte {
+	names := make([]string, 0, len(checksums))
+
+	for name := range checksums {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	var buf bytes.Buffer
+
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s  %s\n", checksums[name], name)
+	}
+
+	return buf.Bytes()
+}
+
+// ParseChecksums reads a checksum list in the format written by sha256sum.
+func ParseChecksums(data []byte) (map[string]string, error) {
+	result := map[string]string{}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "  ", 2)
+
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 {
+			return nil, fmt.Errorf("checksums line %d: invalid format", i+1)
+		}
+
+		result[fields[1]] = fields[0]
+	}
+
+	return result, nil
+}
+
+// Signatures use the minisign format with the original ("Ed") algorithm, which signs the
+// message directly with Ed25519. Keys made by Sign get the first eight bytes of the SHA-256
+// digest of the public key as their ID; minisign picks a random one, so a key's ID comes
+// from its public key file.
+var minisignAlgorithm = []byte("Ed")
+
+func minisignKeyID(publicKey ed25519.PublicKey) []byte {
+	digest := sha256.Sum256(publicKey)
+	return digest[:8]
+}
+
+// ParseSigningKey decodes a base64-encoded Ed25519 private key, given either as the 32-byte
+// seed or the 64-byte private key.
+func ParseSigningKey(text string) (ed25519.PrivateKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+
+	if err != nil {
+		return nil, fmt.Errorf("decode signing key: %w", err)
+	}
+
+	switch len(data) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(data), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(data), nil
+	default:
+		return nil, fmt.Errorf("decode signing key: expected %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(data))
+	}
+}
+
+// FormatPublicKey returns the public key for key in the minisign public key file format.
+func FormatPublicKey(key ed25519.PrivateKey) string {
+	publicKey := key.Public().(ed25519.PublicKey)
+	encoded := append(append(append([]byte{}, minisignAlgorithm...), minisignKeyID(publicKey)...), publicKey...)
+
+	return fmt.Sprintf("untrusted comment: aws-service-auth-reference public key\n%s\n", base64.StdEncoding.EncodeToString(encoded))
+}
+
+// Sign produces a minisign signature of message. The trusted comment is covered by the
+// signature as well.
+func Sign(key ed25519.PrivateKey, message []byte, trustedComment string) string {
+	publicKey := key.Public().(ed25519.PublicKey)
+	signature := ed25519.Sign(key, message)
+	encoded := append(append(append([]byte{}, minisignAlgorithm...), minisignKeyID(publicKey)...), signature...)
+	globalSignature := ed25519.Sign(key, append(append([]byte{}, signature...), trustedComment...))
+
+	return fmt.Sprintf("untrusted comment: signature from aws-service-auth-reference\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(encoded), trustedComment, base64.StdEncoding.EncodeToString(globalSignature))
+}
+
+// PublicKey is a minisign public key: an Ed25519 key and the ID signatures made with it
+// carry.
+type PublicKey struct {
+	KeyID []byte
+	Key   ed25519.PublicKey
+}
+
+// ParsePublicKey decodes a public key in the minisign public key file format, or just its
+// base64 line.
+func ParsePublicKey(text string) (*PublicKey, error) {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[len(lines)-1]))
+
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+
+	if len(data) != 2+8+ed25519.PublicKeySize || !bytes.Equal(data[:2], minisignAlgorithm) {
+		return nil, errors.New("decode public key: not an Ed25519 minisign public key")
+	}
+
+	return &PublicKey{KeyID: data[2:10], Key: ed25519.PublicKey(data[10:])}, nil
+}
+
+// Verify checks a minisign signature of message made by Sign, or by minisign with the
+// original algorithm (minisign -S -l). Signatures of prehashed messages ("ED"), which
+// minisign makes by default, aren't supported.
+func Verify(publicKey *PublicKey, message []byte, signatureText string) error {
+	lines := strings.Split(strings.TrimSpace(signatureText), "\n")
+
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return errors.New("verify signature: invalid signature format")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+
+	if err != nil || len(data) != 2+8+ed25519.SignatureSize || !bytes.Equal(data[:2], minisignAlgorithm) {
+		return errors.New("verify signature: invalid signature format")
+	}
+
+	if !bytes.Equal(data[2:10], publicKey.KeyID) {
+		return errors.New("verify signature: signed with a different key")
+	}
+
+	signature := data[10:]
+
+	if !ed25519.Verify(publicKey.Key, message, signature) {
+		return errors.New("verify signature: signature does not match")
+	}
+
+	trustedComment := strings.TrimPrefix(lines[2], "trusted comment: ")
+	globalSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+
+	if err != nil || !ed25519.Verify(publicKey.Key, append(append([]byte{}, signature...), trustedComment...), globalSignature) {
+		return errors.New("verify signature: trusted comment does not match")
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package authref
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// A public key and a signature in minisign's file formats, made apart from this package
+// with the original algorithm, as minisign -S -l signs. The key has a random ID, as
+// minisign gives its keys, rather than one derived from the key.
+const (
+	minisignPublicKey = `untrusted comment: minisign public key A2F4BD17F15C740A
+RWQKdFzxF730otHcxPxojFCco4kOB/Ai22MsLxK1dmMj+xkoBUb7E9Hr
+`
+	minisignMessage   = "3b1f0c2a8d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8  service-auth.json\n"
+	minisignSignature = "untrusted comment: signature from minisign secret key\n" +
+		"RWQKdFzxF730oopUCxDEOC3+6CPJlLTwk77GuZ84Y4EIgzqzJ+EqGWLvlNgugtEN2icVGMAnG6VCJbdEndGVOGUSvXEd2HVN3wI=\n" +
+		"trusted comment: timestamp:1760486400\tfile:SHA256SUMS\n" +
+		"46glBwAYVTZuISKq18/ktXsqN6SnEz2Wa6Q8uWCZQYWL4bEB258PW0yyBsDl23JTQDqOKk+KZLf2yH+rIV4nBA==\n"
+)
+
+func testSigningKey(t *testing.T, seed byte) ed25519.PrivateKey {
+	key, err := ParseSigningKey(base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(seed)), ed25519.SeedSize))))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func TestParseSigningKey(t *testing.T) {
+	key := testSigningKey(t, 1)
+
+	if full, err := ParseSigningKey(base64.StdEncoding.EncodeToString(key)); err != nil || !key.Equal(full) {
+		t.Errorf("ParseSigningKey of the 64-byte key returned %v, %v", full, err)
+	}
+
+	for _, text := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := ParseSigningKey(text); err == nil {
+			t.Errorf("ParseSigningKey(%q) succeeded", text)
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	key := testSigningKey(t, 1)
+	message := []byte("checksums\n")
+	signature := Sign(key, message, "aws-service-auth-reference dataset 1.0.0")
+	publicKey, err := ParsePublicKey(FormatPublicKey(key))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Verify(publicKey, message, signature); err != nil {
+		t.Fatalf("Verify of a fresh signature: %v", err)
+	}
+
+	otherKey, err := ParsePublicKey(FormatPublicKey(testSigningKey(t, 2)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		publicKey *PublicKey
+		message   string
+		signature string
+		want      string
+	}{
+		{"tampered message", publicKey, "checksums!\n", signature, "signature does not match"},
+		{"tampered trusted comment", publicKey, string(message), strings.Replace(signature, "1.0.0", "2.0.0", 1), "trusted comment does not match"},
+		{"wrong key ID", otherKey, string(message), signature, "signed with a different key"},
+		{"wrong key", &PublicKey{KeyID: publicKey.KeyID, Key: otherKey.Key}, string(message), signature, "signature does not match"},
+		{"no trusted comment", publicKey, string(message), strings.Replace(signature, "\ntrusted comment: ", "\n", 1), "invalid signature format"},
+		{"missing line", publicKey, string(message), signature[strings.Index(signature, "\n")+1:], "invalid signature format"},
+		{"empty", publicKey, string(message), "", "invalid signature format"},
+		{"prehashed", publicKey, string(message), strings.Replace(signature, "\nRW", "\nRU", 1), "invalid signature format"},
+		{"short", publicKey, string(message), "untrusted comment: x\nRWQA\ntrusted comment: x\nAAAA\n", "invalid signature format"},
+	}
+
+	for _, test := range tests {
+		if err := Verify(test.publicKey, []byte(test.message), test.signature); err == nil {
+			t.Errorf("%s: Verify succeeded", test.name)
+		} else if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: Verify returned %q, want %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	publicKey, err := ParsePublicKey(FormatPublicKey(testSigningKey(t, 1)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := strings.Split(FormatPublicKey(testSigningKey(t, 1)), "\n")[1]
+
+	if bare, err := ParsePublicKey(line); err != nil || !reflect.DeepEqual(bare, publicKey) {
+		t.Errorf("ParsePublicKey of the base64 line returned %v, %v", bare, err)
+	}
+
+	data, _ := base64.StdEncoding.DecodeString(line)
+
+	for _, text := range []string{
+		"",
+		"untrusted comment: no key\n",
+		"not base64!",
+		base64.StdEncoding.EncodeToString(data[:len(data)-1]),
+		base64.StdEncoding.EncodeToString(append([]byte("ED"), data[2:]...)),
+	} {
+		if _, err := ParsePublicKey(text); err == nil {
+			t.Errorf("ParsePublicKey(%q) succeeded", text)
+		}
+	}
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	publicKey, err := ParsePublicKey(minisignPublicKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Verify(publicKey, []byte(minisignMessage), minisignSignature); err != nil {
+		t.Errorf("Verify of a minisign signature: %v", err)
+	}
+
+	if err := Verify(publicKey, []byte(strings.ToUpper(minisignMessage)), minisignSignature); err == nil {
+		t.Errorf("Verify of a minisign signature of another message succeeded")
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	checksums := map[string]string{
+		"service-auth.json": strings.Repeat("ab", 32),
+		"metadata.json":     strings.Repeat("01", 32),
+		"name with spaces":  strings.Repeat("ff", 32),
+	}
+
+	formatted := FormatChecksums(checksums)
+
+	if !strings.HasPrefix(string(formatted), strings.Repeat("01", 32)+"  metadata.json\n") {
+		t.Errorf("FormatChecksums didn't sort by name:\n%s", formatted)
+	}
+
+	if parsed, err := ParseChecksums(formatted); err != nil || !reflect.DeepEqual(parsed, checksums) {
+		t.Errorf("ParseChecksums(FormatChecksums(%v)) = %v, %v", checksums, parsed, err)
+	}
+
+	for _, text := range []string{
+		"abcd  service-auth.json\n",
+		strings.Repeat("ab", 32) + " service-auth.json\n",
+		strings.Repeat("ab", 32) + "\n",
+	} {
+		if _, err := ParseChecksums([]byte(text)); err == nil {
+			t.Errorf("ParseChecksums(%q) succeeded", text)
+		}
+	}
+}
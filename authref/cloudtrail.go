@@ -0,0 +1,252 @@
+package authref
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// CloudTrailEvent holds the fields of a CloudTrail event record needed to work out which
+// IAM actions it implies.
+type CloudTrailEvent struct {
+	EventSource string `json:"eventSource"`
+	EventName   string `json:"eventName"`
+	EventTime   string `json:"eventTime,omitempty"`
+	ErrorCode   string `json:"errorCode,omitempty"`
+}
+
+// ReadCloudTrailEvents reads CloudTrail event records in any of the forms CloudTrail and
+// its tools produce them: a log file from S3, with the events under "Records"; the output
+// of "aws cloudtrail lookup-events", with each event as a JSON string in "CloudTrailEvent";
+// a JSON array of events; or one event per line, as exported by CloudTrail Lake and
+// CloudWatch Logs.
+func ReadCloudTrailEvents(r io.Reader) ([]*CloudTrailEvent, error) {
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("read CloudTrail events: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+
+	if len(trimmed) != 0 && trimmed[0] == '[' {
+		var events []*CloudTrailEvent
+
+		if err := json.Unmarshal(trimmed, &events); err != nil {
+			return nil, fmt.Errorf("parse CloudTrail events: %w", err)
+		}
+
+		return events, nil
+	}
+
+	var file struct {
+		Records []*CloudTrailEvent `json:"Records"`
+		Events  []struct {
+			CloudTrailEvent string `json:"CloudTrailEvent"`
+		} `json:"Events"`
+	}
+
+	if err := json.Unmarshal(trimmed, &file); err == nil && (file.Records != nil || file.Events != nil) {
+		if file.Records != nil {
+			return file.Records, nil
+		}
+
+		events := make([]*CloudTrailEvent, len(file.Events))
+
+		for i, event := range file.Events {
+			if err := json.Unmarshal([]byte(event.CloudTrailEvent), &events[i]); err != nil {
+				return nil, fmt.Errorf("parse CloudTrail event %d: %w", i, err)
+			}
+		}
+
+		return events, nil
+	}
+
+	events := make([]*CloudTrailEvent, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var event CloudTrailEvent
+
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("parse CloudTrail event on line %d: %w", line, err)
+		}
+
+		events = append(events, &event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read CloudTrail events: %w", err)
+	}
+
+	return events, nil
+}
+
+// Event sources whose first label isn't the service prefix or the endpoint prefix of an
+// SDK service
+var cloudTrailSourcePrefixes = map[string]string{
+	"monitoring": "cloudwatch",
+	"email":      "ses",
+	"tagging":    "tag",
+	"pinpoint":   "mobiletargeting",
+}
+
+// Events whose names aren't the names of the actions they need, keyed by event source
+// prefix, then event name. Several need more than one action. DynamoDB transactions need
+// the action for each kind of item they read or write, which the event name doesn't say,
+// so they list every one they could need.
+var cloudTrailSpecialCases = map[string]map[string][]string{
+	"dynamodb": {
+		"TransactGetItems":   {"GetItem"},
+		"TransactWriteItems": {"ConditionCheckItem", "DeleteItem", "PutItem", "UpdateItem"},
+	},
+	"kms": {
+		"ReEncrypt": {"ReEncryptFrom", "ReEncryptTo"},
+	},
+	"lambda": {
+		"Invoke":                   {"InvokeFunction"},
+		"InvokeWithResponseStream": {"InvokeFunction"},
+	},
+	"s3": {
+		"ListBuckets":                        {"ListAllMyBuckets"},
+		"ListObjects":                        {"ListBucket"},
+		"ListObjectsV2":                      {"ListBucket"},
+		"HeadBucket":                         {"ListBucket"},
+		"ListObjectVersions":                 {"ListBucketVersions"},
+		"HeadObject":                         {"GetObject"},
+		"CopyObject":                         {"GetObject", "PutObject"},
+		"UploadPartCopy":                     {"GetObject", "PutObject"},
+		"CreateMultipartUpload":              {"PutObject"},
+		"UploadPart":                         {"PutObject"},
+		"CompleteMultipartUpload":            {"PutObject"},
+		"DeleteObjects":                      {"DeleteObject"},
+		"ListMultipartUploads":               {"ListBucketMultipartUploads"},
+		"ListParts":                          {"ListMultipartUploadParts"},
+		"GetBucketLifecycle":                 {"GetLifecycleConfiguration"},
+		"GetBucketLifecycleConfiguration":    {"GetLifecycleConfiguration"},
+		"PutBucketLifecycle":                 {"PutLifecycleConfiguration"},
+		"PutBucketLifecycleConfiguration":    {"PutLifecycleConfiguration"},
+		"DeleteBucketLifecycle":              {"PutLifecycleConfiguration"},
+		"GetBucketEncryption":                {"GetEncryptionConfiguration"},
+		"PutBucketEncryption":                {"PutEncryptionConfiguration"},
+		"DeleteBucketEncryption":             {"PutEncryptionConfiguration"},
+		"GetBucketCors":                      {"GetBucketCORS"},
+		"PutBucketCors":                      {"PutBucketCORS"},
+		"DeleteBucketCors":                   {"PutBucketCORS"},
+		"GetBucketReplication":               {"GetReplicationConfiguration"},
+		"PutBucketReplication":               {"PutReplicationConfiguration"},
+		"DeleteBucketReplication":            {"PutReplicationConfiguration"},
+		"DeleteBucketTagging":                {"PutBucketTagging"},
+		"GetBucketNotificationConfiguration": {"GetBucketNotification"},
+		"PutBucketNotificationConfiguration": {"PutBucketNotification"},
+		"DeleteBucketOwnershipControls":      {"PutBucketOwnershipControls"},
+		"GetBucketAccelerateConfiguration":   {"GetAccelerateConfiguration"},
+		"PutBucketAccelerateConfiguration":   {"PutAccelerateConfiguration"},
+		"GetObjectLockConfiguration":         {"GetBucketObjectLockConfiguration"},
+		"PutObjectLockConfiguration":         {"PutBucketObjectLockConfiguration"},
+		"PutBucketAnalyticsConfiguration":    {"PutAnalyticsConfiguration"},
+		"DeleteBucketAnalyticsConfiguration": {"PutAnalyticsConfiguration"},
+		"PutBucketInventoryConfiguration":    {"PutInventoryConfiguration"},
+		"DeleteBucketInventoryConfiguration": {"PutInventoryConfiguration"},
+		"PutBucketMetricsConfiguration":      {"PutMetricsConfiguration"},
+		"DeleteBucketMetricsConfiguration":   {"PutMetricsConfiguration"},
+	},
+}
+
+// Events that don't need any IAM permission, keyed by event source prefix, then event name
+var cloudTrailNoAction = map[string]map[string]bool{
+	"signin": {"ConsoleLogin": true, "CheckMfa": true, "SwitchRole": true, "ExitRole": true, "RenewRole": true},
+	"sts":    {"GetCallerIdentity": true},
+}
+
+// API versions some services append to event names, such as "ListFunctions20150331" and
+// "GetFunctionConfiguration20150331v2" in Lambda and "CreateDistribution2020_05_31" in
+// CloudFront
+var cloudTrailVersionSuffix = regexp.MustCompile(`(\d{8}(v\d+)?|\d{4}_\d{2}_\d{2})$`)
+
+// Outcomes of resolving a CloudTrail event.
+const (
+	// The event name is the action name
+	EventResolved = "resolved"
+
+	// The event needs actions with other names, from a table of known differences
+	EventSpecialCase = "special-case"
+
+	// The event doesn't need any IAM permission, such as sts:GetCallerIdentity
+	EventNoAction = "no-action"
+
+	// No action in the dataset matches the event
+	EventUnknown = "unknown"
+)
+
+// EventResolution is the set of IAM actions a CloudTrail event implies.
+type EventResolution struct {
+	EventSource string   `json:"eventSource"`
+	EventName   string   `json:"eventName"`
+	Status      string   `json:"status"`
+	Actions     []string `json:"actions"`
+}
+
+// ResolveCloudTrailEvent works out which IAM actions the caller needed for a CloudTrail
+// event. The event source, such as "monitoring.amazonaws.com", is mapped to a service
+// prefix through a table of known exceptions, then through the SDK services in the
+// dataset, and otherwise used as is. Event names usually match action names; a table covers
+// known differences, such as S3's ListObjectsV2, which needs s3:ListBucket.
+//
+// An event with an error code still implies the actions it needed, whether or not the
+// caller had them.
+func (index *Index) ResolveCloudTrailEvent(event *CloudTrailEvent) *EventResolution {
+	result := &EventResolution{EventSource: event.EventSource, EventName: event.EventName, Status: EventUnknown, Actions: make([]string, 0)}
+	source := strings.ToLower(strings.TrimSuffix(event.EventSource, ".amazonaws.com"))
+
+	if cloudTrailNoAction[source][event.EventName] {
+		result.Status = EventNoAction
+		return result
+	}
+
+	prefixes := make([]string, 0)
+
+	if prefix, ok := cloudTrailSourcePrefixes[source]; ok {
+		prefixes = append(prefixes, prefix)
+	}
+
+	for _, authRef := range index.ServicesBySdkService(source) {
+		prefixes = append(prefixes, authRef.ServicePrefix)
+	}
+
+	prefixes = append(prefixes, source)
+
+	for _, prefix := range prefixes {
+		if names := cloudTrailSpecialCases[prefix][event.EventName]; names != nil {
+			for _, name := range names {
+				if action := index.Action(prefix + ":" + name); action != nil {
+					result.Actions = append(result.Actions, action.String())
+				}
+			}
+
+			if len(result.Actions) != 0 {
+				result.Status = EventSpecialCase
+				return result
+			}
+		}
+
+		for _, name := range []string{event.EventName, cloudTrailVersionSuffix.ReplaceAllString(event.EventName, "")} {
+			if action := index.Action(prefix + ":" + name); action != nil {
+				result.Status = EventResolved
+				result.Actions = append(result.Actions, action.String())
+				return result
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,63 @@
+package authref
+
+import (
+	"strings"
+	"testing"
+)
+
+// cloudTrailTestIndex has just the actions the tests below resolve events to.
+func cloudTrailTestIndex() *Index {
+	service := func(prefix string, names ...string) *ServiceAuthorizationReference {
+		authRef := &ServiceAuthorizationReference{ServicePrefix: prefix}
+
+		for _, name := range names {
+			authRef.Actions = append(authRef.Actions, &Action{Name: name})
+		}
+
+		return authRef
+	}
+
+	return NewIndex([]*ServiceAuthorizationReference{
+		service("dynamodb", "BatchGetItem", "ConditionCheckItem", "DeleteItem", "GetItem", "PutItem", "UpdateItem"),
+		service("kms", "Decrypt", "GenerateDataKeyWithoutPlaintext", "ReEncryptFrom", "ReEncryptTo"),
+		service("lambda", "GetFunctionConfiguration", "InvokeAsync", "InvokeFunction", "UpdateFunctionConfiguration"),
+	})
+}
+
+func testResolveCloudTrailEvents(t *testing.T, source string, tests map[string]string) {
+	index := cloudTrailTestIndex()
+
+	for name, want := range tests {
+		resolution := index.ResolveCloudTrailEvent(&CloudTrailEvent{EventSource: source, EventName: name})
+
+		if got := strings.Join(resolution.Actions, " "); got != want {
+			t.Errorf("%s %s resolved to %q (%s), want %q", source, name, got, resolution.Status, want)
+		}
+	}
+}
+
+func TestResolveCloudTrailEventLambda(t *testing.T) {
+	testResolveCloudTrailEvents(t, "lambda.amazonaws.com", map[string]string{
+		"Invoke":                                "lambda:InvokeFunction",
+		"InvokeWithResponseStream":              "lambda:InvokeFunction",
+		"InvokeAsync":                           "lambda:InvokeAsync",
+		"GetFunctionConfiguration20150331v2":    "lambda:GetFunctionConfiguration",
+		"UpdateFunctionConfiguration20150331v2": "lambda:UpdateFunctionConfiguration",
+	})
+}
+
+func TestResolveCloudTrailEventKMS(t *testing.T) {
+	testResolveCloudTrailEvents(t, "kms.amazonaws.com", map[string]string{
+		"Decrypt":                         "kms:Decrypt",
+		"GenerateDataKeyWithoutPlaintext": "kms:GenerateDataKeyWithoutPlaintext",
+		"ReEncrypt":                       "kms:ReEncryptFrom kms:ReEncryptTo",
+	})
+}
+
+func TestResolveCloudTrailEventDynamoDB(t *testing.T) {
+	testResolveCloudTrailEvents(t, "dynamodb.amazonaws.com", map[string]string{
+		"BatchGetItem":       "dynamodb:BatchGetItem",
+		"TransactGetItems":   "dynamodb:GetItem",
+		"TransactWriteItems": "dynamodb:ConditionCheckItem dynamodb:DeleteItem dynamodb:PutItem dynamodb:UpdateItem",
+	})
+}
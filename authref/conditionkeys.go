@@ -0,0 +1,196 @@
+package authref
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ConditionKeySummary is a condition key as defined across the whole dataset, for tools
+// that work from condition keys rather than from services.
+type ConditionKeySummary struct {
+	Name string `json:"name"`
+
+	// Whether this is a global condition key, one starting with "aws:", that any service
+	// may support
+	Global bool `json:"global"`
+
+	// The type most of the services give the key, and the description and link of the
+	// first service that gives that type
+	Type          string `json:"type"`
+	Description   string `json:"description"`
+	ReferenceHref string `json:"referenceHref,omitempty"`
+
+	// Prefixes of the services that define the key, sorted
+	Services []string `json:"services"`
+
+	// Only present when services disagree on the type: the prefixes of the services that
+	// give each of the other types
+	OtherTypes map[string][]string `json:"otherTypes,omitempty"`
+}
+
+// IsGlobalConditionKey reports whether a condition key is global, such as
+// "aws:RequestTag/${TagKey}", as opposed to belonging to a single service.
+func IsGlobalConditionKey(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "aws:")
+}
+
+// GlobalConditionKeys are the global condition keys listed in the IAM User Guide, which
+// service pages refer to without defining. The list is kept by hand, since the guide isn't
+// scraped, and needs a new entry when AWS adds a global key.
+var GlobalConditionKeys = []string{
+	"aws:CalledVia",
+	"aws:CalledViaFirst",
+	"aws:CalledViaLast",
+	"aws:CurrentTime",
+	"aws:Ec2InstanceSourcePrivateIPv4",
+	"aws:Ec2InstanceSourceVpc",
+	"aws:EpochTime",
+	"aws:FederatedProvider",
+	"aws:MultiFactorAuthAge",
+	"aws:MultiFactorAuthPresent",
+	"aws:PrincipalAccount",
+	"aws:PrincipalArn",
+	"aws:PrincipalIsAWSService",
+	"aws:PrincipalOrgID",
+	"aws:PrincipalOrgPaths",
+	"aws:PrincipalServiceName",
+	"aws:PrincipalServiceNamesList",
+	"aws:PrincipalTag/${TagKey}",
+	"aws:PrincipalType",
+	"aws:referer",
+	"aws:RequestedRegion",
+	"aws:RequestTag/${TagKey}",
+	"aws:ResourceAccount",
+	"aws:ResourceOrgID",
+	"aws:ResourceOrgPaths",
+	"aws:ResourceTag/${TagKey}",
+	"aws:SecureTransport",
+	"aws:SourceAccount",
+	"aws:SourceArn",
+	"aws:SourceIdentity",
+	"aws:SourceIp",
+	"aws:SourceOrgID",
+	"aws:SourceOrgPaths",
+	"aws:SourceVpc",
+	"aws:SourceVpce",
+	"aws:TagKeys",
+	"aws:TokenIssueTime",
+	"aws:UserAgent",
+	"aws:userid",
+	"aws:username",
+	"aws:ViaAWSService",
+	"aws:VpcSourceIp",
+}
+
+// conditionKeyPlaceholder matches the placeholder in a condition key name, which pages
+// write as either "${TagKey}" or "<key>".
+var conditionKeyPlaceholder = regexp.MustCompile(`\$\{[^}]*\}|<[^>]*>`)
+
+// normalConditionKey returns a condition key name in lowercase with any placeholder made
+// generic, so that "s3:ExistingObjectTag/<key>" and "s3:existingobjecttag/${TagKey}"
+// compare equal.
+func normalConditionKey(name string) string {
+	return conditionKeyPlaceholder.ReplaceAllString(strings.ToLower(name), "${}")
+}
+
+var knownGlobalConditionKeys = func() map[string]bool {
+	result := make(map[string]bool, len(GlobalConditionKeys))
+
+	for _, name := range GlobalConditionKeys {
+		result[normalConditionKey(name)] = true
+	}
+
+	return result
+}()
+
+// IsKnownGlobalConditionKey reports whether a condition key is one of GlobalConditionKeys,
+// ignoring case and how its placeholder is written.
+func IsKnownGlobalConditionKey(name string) bool {
+	return knownGlobalConditionKeys[normalConditionKey(name)]
+}
+
+// SummarizeConditionKeys merges the condition keys of every service into one entry per key,
+// comparing names without regard to case, sorted by name. Global keys that many services
+// define appear once, listing every one of those services.
+func SummarizeConditionKeys(authRefs []*ServiceAuthorizationReference) []*ConditionKeySummary {
+	type definition struct {
+		prefix string
+		key    *ConditionKey
+	}
+
+	byName := map[string][]definition{}
+	names := make([]string, 0)
+
+	for _, authRef := range authRefs {
+		for _, key := range authRef.ConditionKeys {
+			lower := strings.ToLower(key.Name)
+
+			if byName[lower] == nil {
+				names = append(names, lower)
+			}
+
+			byName[lower] = append(byName[lower], definition{prefix: authRef.ServicePrefix, key: key})
+		}
+	}
+
+	sort.Strings(names)
+	result := make([]*ConditionKeySummary, 0, len(names))
+
+	for _, lower := range names {
+		definitions := byName[lower]
+		servicesByType := map[string][]string{}
+		types := make([]string, 0)
+		seen := map[string]bool{}
+
+		for _, definition := range definitions {
+			keyType := definition.key.Type
+
+			if servicesByType[keyType] == nil {
+				types = append(types, keyType)
+			}
+
+			// Pages that share a prefix can both define a key
+			if typed := keyType + " " + definition.prefix; !seen[typed] {
+				seen[typed] = true
+				servicesByType[keyType] = append(servicesByType[keyType], definition.prefix)
+			}
+		}
+
+		// The most common type wins, and the first seen breaks ties
+		sort.SliceStable(types, func(i, j int) bool {
+			return len(servicesByType[types[i]]) > len(servicesByType[types[j]])
+		})
+
+		summary := &ConditionKeySummary{Name: definitions[0].key.Name, Global: IsGlobalConditionKey(lower), Type: types[0], Services: make([]string, 0)}
+		services := map[string]bool{}
+
+		for _, definition := range definitions {
+			if summary.Description == "" && definition.key.Type == summary.Type {
+				summary.Name = definition.key.Name
+				summary.Description = definition.key.Description
+				summary.ReferenceHref = definition.key.ReferenceHref
+			}
+
+			if !services[definition.prefix] {
+				services[definition.prefix] = true
+				summary.Services = append(summary.Services, definition.prefix)
+			}
+		}
+
+		sort.Strings(summary.Services)
+
+		for _, keyType := range types[1:] {
+			if summary.OtherTypes == nil {
+				summary.OtherTypes = map[string][]string{}
+			}
+
+			summary.OtherTypes[keyType] = servicesByType[keyType]
+			sort.Strings(summary.OtherTypes[keyType])
+		}
+
+		result = append(result, summary)
+	}
+
+	return result
+}
@@ -0,0 +1,273 @@
+package authref
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Changes lists the differences between two snapshots of the dataset, grouped by service
+// prefix. Pages that share a prefix are treated as one service.
+type Changes struct {
+	Services []*ServiceChanges `json:"services"`
+}
+
+// ServiceChanges lists the differences in one service between two snapshots.
+type ServiceChanges struct {
+	ServicePrefix string `json:"servicePrefix"`
+	Name          string `json:"name"`
+
+	// True if the service only appears in the new snapshot
+	Added bool `json:"added"`
+
+	// True if the service only appears in the old snapshot
+	Removed bool `json:"removed"`
+
+	AddedActions         []*Action             `json:"addedActions"`
+	RemovedActions       []*Action             `json:"removedActions"`
+	ChangedActions       []*ActionChange       `json:"changedActions"`
+	AddedResourceTypes   []*ResourceType       `json:"addedResourceTypes"`
+	RemovedResourceTypes []*ResourceType       `json:"removedResourceTypes"`
+	ChangedResourceTypes []*ResourceTypeChange `json:"changedResourceTypes"`
+	AddedConditionKeys   []*ConditionKey       `json:"addedConditionKeys"`
+	RemovedConditionKeys []*ConditionKey       `json:"removedConditionKeys"`
+	ChangedConditionKeys []*ConditionKeyChange `json:"changedConditionKeys"`
+}
+
+// ActionChange is an action that exists in both snapshots but differs. Fields lists the
+// JSON names of the fields that changed.
+type ActionChange struct {
+	Old    *Action  `json:"old"`
+	New    *Action  `json:"new"`
+	Fields []string `json:"fields"`
+}
+
+// ResourceTypeChange is a resource type that exists in both snapshots but differs.
+type ResourceTypeChange struct {
+	Old    *ResourceType `json:"old"`
+	New    *ResourceType `json:"new"`
+	Fields []string      `json:"fields"`
+}
+
+// ConditionKeyChange is a condition key that exists in both snapshots but differs.
+type ConditionKeyChange struct {
+	Old    *ConditionKey `json:"old"`
+	New    *ConditionKey `json:"new"`
+	Fields []string      `json:"fields"`
+}
+
+// Empty reports whether there are no changes at all.
+func (changes *Changes) Empty() bool {
+	return len(changes.Services) == 0
+}
+
+func (service *ServiceChanges) empty() bool {
+	return !service.Added && !service.Removed &&
+		len(service.AddedActions) == 0 && len(service.RemovedActions) == 0 && len(service.ChangedActions) == 0 &&
+		len(service.AddedResourceTypes) == 0 && len(service.RemovedResourceTypes) == 0 && len(service.ChangedResourceTypes) == 0 &&
+		len(service.AddedConditionKeys) == 0 && len(service.RemovedConditionKeys) == 0 && len(service.ChangedConditionKeys) == 0
+}
+
+// serviceIndex is every item of one service prefix, keyed by name. The first page
+// to define a name wins.
+type serviceIndex struct {
+	name          string
+	actions       map[string]*Action
+	actionOrder   []string
+	resourceTypes map[string]*ResourceType
+	rtOrder       []string
+	conditionKeys map[string]*ConditionKey
+	ckOrder       []string
+}
+
+func indexByPrefix(authRefs []*ServiceAuthorizationReference) map[string]*serviceIndex {
+	result := map[string]*serviceIndex{}
+
+	for _, authRef := range authRefs {
+		index := result[authRef.ServicePrefix]
+
+		if index == nil {
+			index = &serviceIndex{
+				name:          authRef.Name,
+				actions:       map[string]*Action{},
+				resourceTypes: map[string]*ResourceType{},
+				conditionKeys: map[string]*ConditionKey{},
+			}
+			result[authRef.ServicePrefix] = index
+		}
+
+		for _, action := range authRef.Actions {
+			if index.actions[action.Name] == nil {
+				index.actions[action.Name] = action
+				index.actionOrder = append(index.actionOrder, action.Name)
+			}
+		}
+
+		for _, resourceType := range authRef.ResourceTypes {
+			if index.resourceTypes[resourceType.Name] == nil {
+				index.resourceTypes[resourceType.Name] = resourceType
+				index.rtOrder = append(index.rtOrder, resourceType.Name)
+			}
+		}
+
+		for _, conditionKey := range authRef.ConditionKeys {
+			if index.conditionKeys[conditionKey.Name] == nil {
+				index.conditionKeys[conditionKey.Name] = conditionKey
+				index.ckOrder = append(index.ckOrder, conditionKey.Name)
+			}
+		}
+	}
+
+	return result
+}
+
+// Diff compares two snapshots of the dataset. Services are listed in order of prefix,
+// and items within a service in the order they appear on the page.
+func Diff(oldRefs, newRefs []*ServiceAuthorizationReference) *Changes {
+	oldIndex := indexByPrefix(oldRefs)
+	newIndex := indexByPrefix(newRefs)
+
+	prefixes := make([]string, 0, len(newIndex))
+
+	for prefix := range newIndex {
+		prefixes = append(prefixes, prefix)
+	}
+
+	for prefix := range oldIndex {
+		if newIndex[prefix] == nil {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	sort.Strings(prefixes)
+	changes := &Changes{Services: make([]*ServiceChanges, 0)}
+
+	for _, prefix := range prefixes {
+		oldService, newService := oldIndex[prefix], newIndex[prefix]
+		service := &ServiceChanges{
+			ServicePrefix:        prefix,
+			Added:                oldService == nil,
+			Removed:              newService == nil,
+			AddedActions:         make([]*Action, 0),
+			RemovedActions:       make([]*Action, 0),
+			ChangedActions:       make([]*ActionChange, 0),
+			AddedResourceTypes:   make([]*ResourceType, 0),
+			RemovedResourceTypes: make([]*ResourceType, 0),
+			ChangedResourceTypes: make([]*ResourceTypeChange, 0),
+			AddedConditionKeys:   make([]*ConditionKey, 0),
+			RemovedConditionKeys: make([]*ConditionKey, 0),
+			ChangedConditionKeys: make([]*ConditionKeyChange, 0),
+		}
+
+		if oldService == nil {
+			oldService = &serviceIndex{}
+		}
+
+		if newService == nil {
+			service.Name = oldService.name
+			newService = &serviceIndex{}
+		} else {
+			service.Name = newService.name
+		}
+
+		for _, name := range newService.actionOrder {
+			newAction := newService.actions[name]
+
+			if oldAction := oldService.actions[name]; oldAction == nil {
+				service.AddedActions = append(service.AddedActions, newAction)
+			} else if fields := changedFields(oldAction, newAction); len(fields) != 0 {
+				service.ChangedActions = append(service.ChangedActions, &ActionChange{Old: oldAction, New: newAction, Fields: fields})
+			}
+		}
+
+		for _, name := range oldService.actionOrder {
+			if newService.actions[name] == nil {
+				service.RemovedActions = append(service.RemovedActions, oldService.actions[name])
+			}
+		}
+
+		for _, name := range newService.rtOrder {
+			newResourceType := newService.resourceTypes[name]
+
+			if oldResourceType := oldService.resourceTypes[name]; oldResourceType == nil {
+				service.AddedResourceTypes = append(service.AddedResourceTypes, newResourceType)
+			} else if fields := changedFields(oldResourceType, newResourceType); len(fields) != 0 {
+				service.ChangedResourceTypes = append(service.ChangedResourceTypes, &ResourceTypeChange{Old: oldResourceType, New: newResourceType, Fields: fields})
+			}
+		}
+
+		for _, name := range oldService.rtOrder {
+			if newService.resourceTypes[name] == nil {
+				service.RemovedResourceTypes = append(service.RemovedResourceTypes, oldService.resourceTypes[name])
+			}
+		}
+
+		for _, name := range newService.ckOrder {
+			newConditionKey := newService.conditionKeys[name]
+
+			if oldConditionKey := oldService.conditionKeys[name]; oldConditionKey == nil {
+				service.AddedConditionKeys = append(service.AddedConditionKeys, newConditionKey)
+			} else if fields := changedFields(oldConditionKey, newConditionKey); len(fields) != 0 {
+				service.ChangedConditionKeys = append(service.ChangedConditionKeys, &ConditionKeyChange{Old: oldConditionKey, New: newConditionKey, Fields: fields})
+			}
+		}
+
+		for _, name := range oldService.ckOrder {
+			if newService.conditionKeys[name] == nil {
+				service.RemovedConditionKeys = append(service.RemovedConditionKeys, oldService.conditionKeys[name])
+			}
+		}
+
+		if !service.empty() {
+			changes.Services = append(changes.Services, service)
+		}
+	}
+
+	return changes
+}
+
+// changedFields compares the fields of two values of the same struct type and returns the
+// JSON names of the fields that differ, skipping the name field, the provenance, which
+// changes with every scrape, and the partition ARN patterns, which follow from the ARN
+// pattern. Missing and empty lists are considered equal, so that adding
+// a list field to the format isn't seen as a change.
+func changedFields(oldValue, newValue interface{}) []string {
+	oldStruct := reflect.ValueOf(oldValue).Elem()
+	newStruct := reflect.ValueOf(newValue).Elem()
+	structType := oldStruct.Type()
+	result := make([]string, 0)
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		oldField, newField := oldStruct.Field(i), newStruct.Field(i)
+
+		if field.Name == "Name" || field.Name == "Provenance" || field.Name == "PartitionArnPatterns" {
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Slice && oldField.Len() == 0 && newField.Len() == 0 {
+			continue
+		}
+
+		if !reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			result = append(result, jsonFieldName(field))
+		}
+	}
+
+	return result
+}
+
+// jsonFieldName returns the name a struct field has when encoded as JSON.
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+
+	if i := strings.IndexByte(tag, ','); i != -1 {
+		tag = tag[:i]
+	}
+
+	if tag == "" {
+		return field.Name
+	}
+
+	return tag
+}
@@ -0,0 +1,140 @@
+package authref
+
+import (
+	"sort"
+	"strings"
+)
+
+// QualifiedAction is an action together with the service that defines it.
+type QualifiedAction struct {
+	Service *ServiceAuthorizationReference
+	Action  *Action
+}
+
+// String returns the action as it's written in a policy, such as "s3:GetObject".
+func (action *QualifiedAction) String() string {
+	return action.Service.ServicePrefix + ":" + action.Action.Name
+}
+
+// AllActions lists every action in the dataset, sorted by their policy names. When
+// several pages share a service prefix and define the same action, the first one wins.
+func AllActions(authRefs []*ServiceAuthorizationReference) []*QualifiedAction {
+	seen := map[string]bool{}
+	result := make([]*QualifiedAction, 0)
+
+	for _, authRef := range authRefs {
+		for _, action := range authRef.Actions {
+			qualified := &QualifiedAction{Service: authRef, Action: action}
+			key := strings.ToLower(qualified.String())
+
+			if !seen[key] {
+				seen[key] = true
+				result = append(result, qualified)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].String()) < strings.ToLower(result[j].String())
+	})
+
+	return result
+}
+
+// AllConditionKeys lists every condition key in the dataset, sorted by name. Global keys
+// such as aws:RequestTag/${TagKey} appear on many pages; the first definition wins.
+func AllConditionKeys(authRefs []*ServiceAuthorizationReference) []*ConditionKey {
+	seen := map[string]bool{}
+	result := make([]*ConditionKey, 0)
+
+	for _, authRef := range authRefs {
+		for _, key := range authRef.ConditionKeys {
+			if lower := strings.ToLower(key.Name); !seen[lower] {
+				seen[lower] = true
+				result = append(result, key)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+
+	return result
+}
+
+// matchWildcard reports whether name matches an IAM action pattern, where "*" matches any
+// run of characters and "?" matches any single character. Action names are compared
+// case-insensitively.
+func matchWildcard(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+
+	// Position to resume from after the most recent "*"
+	star, resume := -1, 0
+	p, n := 0, 0
+
+	for n < len(name) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == name[n]):
+			p++
+			n++
+		case p < len(pattern) && pattern[p] == '*':
+			star, resume = p, n
+			p++
+		case star != -1:
+			resume++
+			p, n = star+1, resume
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// MatchActions returns the actions that match any of the given patterns. The patterns
+// aren't checked; use an ActionMatcher to reject ones IAM wouldn't accept.
+func MatchActions(actions []*QualifiedAction, patterns []string) []*QualifiedAction {
+	result := make([]*QualifiedAction, 0)
+
+	for _, action := range actions {
+		name := action.String()
+
+		for _, pattern := range patterns {
+			if matchWildcard(pattern, name) {
+				result = append(result, action)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// StatementActions returns the actions a statement applies to: those matching its Action
+// element, or for a NotAction statement, every action that doesn't match.
+func StatementActions(actions []*QualifiedAction, statement *Statement) []*QualifiedAction {
+	if len(statement.NotAction) == 0 {
+		return MatchActions(actions, statement.Action)
+	}
+
+	excluded := map[*QualifiedAction]bool{}
+
+	for _, action := range MatchActions(actions, statement.NotAction) {
+		excluded[action] = true
+	}
+
+	result := make([]*QualifiedAction, 0, len(actions)-len(excluded))
+
+	for _, action := range actions {
+		if !excluded[action] {
+			result = append(result, action)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,150 @@
+package authref
+
+import "strings"
+
+// ActionFilter is a condition on actions for Index.Actions. Make them with WithService,
+// WithAccessLevel, and the other With functions.
+type ActionFilter struct {
+	keep func(action *QualifiedAction) bool
+
+	// If not nil, returns a list from the index that includes every action the filter keeps
+	candidates func(index *Index) []*QualifiedAction
+}
+
+// conditionKeyBase returns the lowercase name of a condition key up to any "/", so that
+// "aws:ResourceTag/${TagKey}" and "aws:ResourceTag" share the base "aws:resourcetag".
+func conditionKeyBase(name string) string {
+	if i := strings.Index(name, "/"); i >= 0 {
+		name = name[:i]
+	}
+
+	return strings.ToLower(name)
+}
+
+// actionConditionKeys lists the condition keys an action supports, whether for the action
+// as a whole or for one of its resource types.
+func actionConditionKeys(action *Action) []string {
+	keys := append([]string{}, action.ConditionKeys...)
+
+	for _, resourceType := range action.ResourceTypes {
+		keys = append(keys, resourceType.ConditionKeys...)
+	}
+
+	return keys
+}
+
+func (index *Index) actionsWithConditionKeyBase(base string) []*QualifiedAction {
+	index.actionsByKeyOnce.Do(func() {
+		index.actionsByKey = map[string][]*QualifiedAction{}
+
+		for _, action := range index.Actions() {
+			seen := map[string]bool{}
+
+			for _, key := range actionConditionKeys(action.Action) {
+				if keyBase := conditionKeyBase(key); !seen[keyBase] {
+					seen[keyBase] = true
+					index.actionsByKey[keyBase] = append(index.actionsByKey[keyBase], action)
+				}
+			}
+		}
+	})
+
+	return index.actionsByKey[base]
+}
+
+// WithService keeps the actions of a service prefix, such as "ec2", ignoring case.
+func WithService(prefix string) ActionFilter {
+	return ActionFilter{
+		keep: func(action *QualifiedAction) bool {
+			return strings.EqualFold(action.Service.ServicePrefix, prefix)
+		},
+		candidates: func(index *Index) []*QualifiedAction {
+			return index.ActionsWithPrefix(prefix + ":")
+		},
+	}
+}
+
+// WithPattern keeps the actions that match an action pattern from a policy, such as
+// "s3:Get*", as MatchActions would.
+func WithPattern(pattern string) ActionFilter {
+	return ActionFilter{
+		keep: func(action *QualifiedAction) bool {
+			return matchWildcard(pattern, action.String())
+		},
+		candidates: func(index *Index) []*QualifiedAction {
+			if i := strings.IndexAny(pattern, "*?"); i >= 0 {
+				return index.ActionsWithPrefix(pattern[:i])
+			}
+
+			return index.ActionsWithPrefix(pattern)
+		},
+	}
+}
+
+// WithAccessLevel keeps the actions at any of the given access levels.
+func WithAccessLevel(levels ...AccessLevel) ActionFilter {
+	return ActionFilter{
+		keep: func(action *QualifiedAction) bool {
+			for _, level := range levels {
+				if action.Action.AccessLevel == level {
+					return true
+				}
+			}
+
+			return false
+		},
+	}
+}
+
+// WithConditionKey keeps the actions that support a condition key, ignoring case, either
+// for the action or for one of its resource types. A name without a "/" also matches
+// the keys that add one, so "aws:ResourceTag" matches "aws:ResourceTag/${TagKey}".
+func WithConditionKey(name string) ActionFilter {
+	lower := strings.ToLower(name)
+
+	return ActionFilter{
+		keep: func(action *QualifiedAction) bool {
+			for _, key := range actionConditionKeys(action.Action) {
+				if key = strings.ToLower(key); key == lower || (!strings.Contains(lower, "/") && strings.HasPrefix(key, lower+"/")) {
+					return true
+				}
+			}
+
+			return false
+		},
+		candidates: func(index *Index) []*QualifiedAction {
+			return index.actionsWithConditionKeyBase(conditionKeyBase(name))
+		},
+	}
+}
+
+// WithResourceType keeps the actions that can be scoped to a resource type of their
+// service, such as "bucket", ignoring case.
+func WithResourceType(name string) ActionFilter {
+	return ActionFilter{
+		keep: func(action *QualifiedAction) bool {
+			for _, resourceType := range action.Action.ResourceTypes {
+				if strings.EqualFold(resourceType.ResourceType, name) {
+					return true
+				}
+			}
+
+			return false
+		},
+	}
+}
+
+// WithPermissionOnly keeps the actions that are permission-only, or the ones that aren't.
+func WithPermissionOnly(permissionOnly bool) ActionFilter {
+	return ActionFilter{
+		keep: func(action *QualifiedAction) bool {
+			return action.Action.PermissionOnly == permissionOnly
+		},
+	}
+}
+
+// WithFunc keeps the actions for which keep returns true, for conditions the other
+// filters don't cover.
+func WithFunc(keep func(action *QualifiedAction) bool) ActionFilter {
+	return ActionFilter{keep: keep}
+}
@@ -0,0 +1,118 @@
+package authref
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// HistoryDateFormat is the layout of the dates recorded in a History.
+const HistoryDateFormat = "2006-01-02"
+
+// History records when each action, resource type, and condition key first appeared in
+// a scrape and when it disappeared. Each map is keyed by service prefix, then by name.
+type History struct {
+	Actions       map[string]map[string]*HistoryEntry `json:"actions"`
+	ResourceTypes map[string]map[string]*HistoryEntry `json:"resourceTypes"`
+	ConditionKeys map[string]map[string]*HistoryEntry `json:"conditionKeys"`
+}
+
+// HistoryEntry is the history of one item. Dates use HistoryDateFormat.
+type HistoryEntry struct {
+	// The date of the first scrape that included the item
+	FirstSeen string `json:"firstSeen"`
+
+	// The date of the most recent scrape that included the item
+	LastSeen string `json:"lastSeen"`
+
+	// The date of the first scrape after LastSeen that didn't include the item, if any
+	Removed string `json:"removed,omitempty"`
+}
+
+// NewHistory returns an empty history.
+func NewHistory() *History {
+	return &History{
+		Actions:       map[string]map[string]*HistoryEntry{},
+		ResourceTypes: map[string]map[string]*HistoryEntry{},
+		ConditionKeys: map[string]map[string]*HistoryEntry{},
+	}
+}
+
+// LoadHistoryFile reads a history file. A missing file yields an empty history.
+func LoadHistoryFile(filename string) (*History, error) {
+	data, err := os.ReadFile(filename)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return NewHistory(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	history := NewHistory()
+
+	if err := json.Unmarshal(data, history); err != nil {
+		return nil, fmt.Errorf("parse history %s: %w", filename, err)
+	}
+
+	return history, nil
+}
+
+// Update records a scrape taken on date. Items in authRefs are marked as seen, and items
+// that were present before but are missing now are marked as removed.
+func (history *History) Update(authRefs []*ServiceAuthorizationReference, date string) {
+	seenActions := map[string]map[string]bool{}
+	seenResourceTypes := map[string]map[string]bool{}
+	seenConditionKeys := map[string]map[string]bool{}
+
+	for _, authRef := range authRefs {
+		prefix := authRef.ServicePrefix
+
+		for _, action := range authRef.Actions {
+			markSeen(history.Actions, seenActions, prefix, action.Name, date)
+		}
+
+		for _, resourceType := range authRef.ResourceTypes {
+			markSeen(history.ResourceTypes, seenResourceTypes, prefix, resourceType.Name, date)
+		}
+
+		for _, conditionKey := range authRef.ConditionKeys {
+			markSeen(history.ConditionKeys, seenConditionKeys, prefix, conditionKey.Name, date)
+		}
+	}
+
+	markRemoved(history.Actions, seenActions, date)
+	markRemoved(history.ResourceTypes, seenResourceTypes, date)
+	markRemoved(history.ConditionKeys, seenConditionKeys, date)
+}
+
+func markSeen(entries map[string]map[string]*HistoryEntry, seen map[string]map[string]bool, prefix, name, date string) {
+	if entries[prefix] == nil {
+		entries[prefix] = map[string]*HistoryEntry{}
+	}
+
+	if seen[prefix] == nil {
+		seen[prefix] = map[string]bool{}
+	}
+
+	seen[prefix][name] = true
+	entry := entries[prefix][name]
+
+	if entry == nil {
+		entries[prefix][name] = &HistoryEntry{FirstSeen: date, LastSeen: date}
+		return
+	}
+
+	entry.LastSeen = date
+	entry.Removed = ""
+}
+
+func markRemoved(entries map[string]map[string]*HistoryEntry, seen map[string]map[string]bool, date string) {
+	for prefix, names := range entries {
+		for name, entry := range names {
+			if !seen[prefix][name] && entry.Removed == "" {
+				entry.Removed = date
+			}
+		}
+	}
+}
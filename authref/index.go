@@ -0,0 +1,259 @@
+package authref
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Index answers lookups against a dataset without scanning it. Each of its lookup tables
+// is built the first time it's needed, so a program pays only for the lookups it uses.
+// An Index is safe for concurrent use, but the dataset must not be modified after it's
+// been indexed.
+type Index struct {
+	authRefs []*ServiceAuthorizationReference
+
+	servicesOnce sync.Once
+	services     map[string][]*ServiceAuthorizationReference
+
+	sdkServicesOnce sync.Once
+	sdkServices     map[string][]*ServiceAuthorizationReference
+
+	actionsOnce   sync.Once
+	actions       []*QualifiedAction
+	actionsByName map[string]*QualifiedAction
+
+	conditionKeysOnce sync.Once
+	conditionKeys     map[string]*ConditionKey
+
+	// Actions by the lowercase base name of each condition key they support; see conditionKeyBase
+	actionsByKeyOnce sync.Once
+	actionsByKey     map[string][]*QualifiedAction
+
+	trieOnce sync.Once
+	trie     *actionTrie
+}
+
+// NewIndex returns an index over the dataset.
+func NewIndex(authRefs []*ServiceAuthorizationReference) *Index {
+	return &Index{authRefs: authRefs}
+}
+
+// Services returns the dataset the index was built from.
+func (index *Index) Services() []*ServiceAuthorizationReference {
+	return index.authRefs
+}
+
+// ServicesByPrefix returns the pages that use a service prefix, such as "elasticloadbalancing",
+// in the order they appear in the dataset. The prefix is compared case-insensitively.
+func (index *Index) ServicesByPrefix(prefix string) []*ServiceAuthorizationReference {
+	index.servicesOnce.Do(func() {
+		index.services = make(map[string][]*ServiceAuthorizationReference, len(index.authRefs))
+
+		for _, authRef := range index.authRefs {
+			key := strings.ToLower(authRef.ServicePrefix)
+			index.services[key] = append(index.services[key], authRef)
+		}
+	})
+
+	return index.services[strings.ToLower(prefix)]
+}
+
+// ServicesBySdkService returns the pages whose SDK services have the given service ID, such
+// as "SFN", or endpoint prefix, such as "states", compared case-insensitively. It finds
+// nothing for services missing from the hand-maintained SDK mapping.
+func (index *Index) ServicesBySdkService(name string) []*ServiceAuthorizationReference {
+	index.sdkServicesOnce.Do(func() {
+		index.sdkServices = map[string][]*ServiceAuthorizationReference{}
+
+		for _, authRef := range index.authRefs {
+			added := map[string]bool{}
+
+			for _, sdkService := range authRef.SdkServices {
+				for _, key := range []string{strings.ToLower(sdkService.ServiceId), strings.ToLower(sdkService.EndpointPrefix)} {
+					if !added[key] {
+						added[key] = true
+						index.sdkServices[key] = append(index.sdkServices[key], authRef)
+					}
+				}
+			}
+		}
+	})
+
+	return index.sdkServices[strings.ToLower(name)]
+}
+
+func (index *Index) buildActions() {
+	index.actionsOnce.Do(func() {
+		index.actions = AllActions(index.authRefs)
+		index.actionsByName = make(map[string]*QualifiedAction, len(index.actions))
+
+		for _, action := range index.actions {
+			index.actionsByName[strings.ToLower(action.String())] = action
+		}
+	})
+}
+
+// Actions lists the actions in the dataset that pass every filter, in the order of
+// AllActions. With no filters, it lists every action. Filters that name a service, a
+// pattern, or a condition key are answered from the index rather than by scanning every
+// action:
+//
+//	index.Actions(WithService("ec2"), WithAccessLevel(AccessLevelWrite), WithConditionKey("aws:ResourceTag"))
+func (index *Index) Actions(filters ...ActionFilter) []*QualifiedAction {
+	index.buildActions()
+
+	if len(filters) == 0 {
+		return index.actions
+	}
+
+	// Start from the fewest actions any filter can narrow to
+	candidates := index.actions
+
+	for _, filter := range filters {
+		if filter.candidates != nil {
+			if narrowed := filter.candidates(index); len(narrowed) < len(candidates) {
+				candidates = narrowed
+			}
+		}
+	}
+
+	result := make([]*QualifiedAction, 0)
+
+	for _, action := range candidates {
+		passes := true
+
+		for _, filter := range filters {
+			if passes = filter.keep(action); !passes {
+				break
+			}
+		}
+
+		if passes {
+			result = append(result, action)
+		}
+	}
+
+	return result
+}
+
+// Action looks up an action by the name used in policies, such as "s3:GetObject". Like
+// IAM, it ignores case. It returns nil if there's no such action.
+func (index *Index) Action(name string) *QualifiedAction {
+	index.buildActions()
+	return index.actionsByName[strings.ToLower(name)]
+}
+
+// ConditionKey looks up a condition key by name, such as "s3:x-amz-acl", ignoring case.
+// Global keys such as "aws:RequestTag/${TagKey}" are listed by many services; the first
+// definition in the dataset is returned. It returns nil if there's no such key.
+func (index *Index) ConditionKey(name string) *ConditionKey {
+	index.conditionKeysOnce.Do(func() {
+		index.conditionKeys = map[string]*ConditionKey{}
+
+		for _, authRef := range index.authRefs {
+			for _, key := range authRef.ConditionKeys {
+				if lower := strings.ToLower(key.Name); index.conditionKeys[lower] == nil {
+					index.conditionKeys[lower] = key
+				}
+			}
+		}
+	})
+
+	return index.conditionKeys[strings.ToLower(name)]
+}
+
+// actionTrie is a trie of lowercase action names, such as "s3:getobject".
+type actionTrie struct {
+	children map[byte]*actionTrie
+
+	// The action whose name ends at this node, if any
+	action *QualifiedAction
+}
+
+func (trie *actionTrie) insert(name string, action *QualifiedAction) {
+	node := trie
+
+	for i := 0; i < len(name); i++ {
+		child := node.children[name[i]]
+
+		if child == nil {
+			child = &actionTrie{children: map[byte]*actionTrie{}}
+			node.children[name[i]] = child
+		}
+
+		node = child
+	}
+
+	node.action = action
+}
+
+// find returns the node for prefix, or nil if no name starts with it.
+func (trie *actionTrie) find(prefix string) *actionTrie {
+	node := trie
+
+	for i := 0; i < len(prefix) && node != nil; i++ {
+		node = node.children[prefix[i]]
+	}
+
+	return node
+}
+
+// collect appends every action at or below the node, in order of their lowercase names.
+func (trie *actionTrie) collect(result []*QualifiedAction) []*QualifiedAction {
+	if trie.action != nil {
+		result = append(result, trie.action)
+	}
+
+	keys := make([]int, 0, len(trie.children))
+
+	for key := range trie.children {
+		keys = append(keys, int(key))
+	}
+
+	sort.Ints(keys)
+
+	for _, key := range keys {
+		result = trie.children[byte(key)].collect(result)
+	}
+
+	return result
+}
+
+// ActionsWithPrefix returns the actions whose policy names start with prefix, ignoring
+// case, in the same order as Actions. "s3:Get" finds s3:GetObject, and "ec2:" finds every
+// EC2 action.
+func (index *Index) ActionsWithPrefix(prefix string) []*QualifiedAction {
+	index.trieOnce.Do(func() {
+		index.trie = &actionTrie{children: map[byte]*actionTrie{}}
+
+		for _, action := range index.Actions() {
+			index.trie.insert(strings.ToLower(action.String()), action)
+		}
+	})
+
+	node := index.trie.find(strings.ToLower(prefix))
+
+	if node == nil {
+		return make([]*QualifiedAction, 0)
+	}
+
+	return node.collect(make([]*QualifiedAction, 0))
+}
+
+// Match returns the actions that match an action pattern from a policy, such as
+// "s3:Get*". Only the actions that share the pattern's literal prefix are compared
+// against it, so patterns that name a service are fast.
+func (index *Index) Match(pattern string) []*QualifiedAction {
+	i := strings.IndexAny(pattern, "*?")
+
+	if i < 0 {
+		if action := index.Action(pattern); action != nil {
+			return []*QualifiedAction{action}
+		}
+
+		return make([]*QualifiedAction, 0)
+	}
+
+	return MatchActions(index.ActionsWithPrefix(pattern[:i]), []string{pattern})
+}
@@ -0,0 +1,132 @@
+package authref
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckActionPattern returns an error if IAM wouldn't accept a pattern in an Action or
+// NotAction element. A pattern is either "*" or "service:action", where both parts are
+// nonempty and either can contain wildcards.
+func CheckActionPattern(pattern string) error {
+	if pattern == "*" {
+		return nil
+	}
+
+	colon := strings.Index(pattern, ":")
+
+	if colon <= 0 || colon == len(pattern)-1 || strings.Contains(pattern[colon+1:], ":") || strings.ContainsAny(pattern, " \t\r\n") {
+		return fmt.Errorf("%#v isn't of the form \"service:action\"", pattern)
+	}
+
+	return nil
+}
+
+// ActionMatcher matches action names against a set of patterns from a policy, the way
+// IAM does: names and patterns are compared without regard to case, "*" matches any run
+// of characters and "?" any single character, and a pattern names either every action
+// ("*") or a service prefix and an action, either of which can contain wildcards.
+// Patterns in any other form are rejected rather than matched as plain text. An
+// ActionMatcher is safe for concurrent use.
+type ActionMatcher struct {
+	patterns []string
+
+	// Lowercase names of the patterns without wildcards, and the lowercase patterns with them
+	exact     map[string]bool
+	wildcards []string
+	all       bool
+}
+
+// NewActionMatcher compiles patterns such as "s3:Get*" and "iam:PassRole". It fails if any
+// pattern is malformed, as with CheckActionPattern.
+func NewActionMatcher(patterns ...string) (*ActionMatcher, error) {
+	matcher := &ActionMatcher{patterns: append([]string{}, patterns...), exact: map[string]bool{}}
+
+	for _, pattern := range patterns {
+		if err := CheckActionPattern(pattern); err != nil {
+			return nil, err
+		}
+
+		matcher.add(pattern)
+	}
+
+	return matcher, nil
+}
+
+func (matcher *ActionMatcher) add(pattern string) {
+	lower := strings.ToLower(pattern)
+
+	switch {
+	case lower == "*":
+		matcher.all = true
+	case strings.ContainsAny(lower, "*?"):
+		matcher.wildcards = append(matcher.wildcards, lower)
+	default:
+		matcher.exact[lower] = true
+	}
+}
+
+// Patterns returns the patterns the matcher was made from.
+func (matcher *ActionMatcher) Patterns() []string {
+	return append([]string{}, matcher.patterns...)
+}
+
+// Match reports whether an action name, such as "s3:GetObject", matches any of the
+// patterns. The name doesn't have to be one the dataset knows about.
+func (matcher *ActionMatcher) Match(name string) bool {
+	if matcher.all {
+		return true
+	}
+
+	name = strings.ToLower(name)
+
+	if matcher.exact[name] {
+		return true
+	}
+
+	for _, pattern := range matcher.wildcards {
+		if matchWildcard(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter returns the actions that match any of the patterns, in their original order.
+func (matcher *ActionMatcher) Filter(actions []*QualifiedAction) []*QualifiedAction {
+	result := make([]*QualifiedAction, 0)
+
+	for _, action := range actions {
+		if matcher.Match(action.String()) {
+			result = append(result, action)
+		}
+	}
+
+	return result
+}
+
+// Unmatched returns the patterns that match none of the actions, such as misspelled names
+// or services that don't exist.
+func (matcher *ActionMatcher) Unmatched(actions []*QualifiedAction) []string {
+	result := make([]string, 0)
+
+	for _, pattern := range matcher.patterns {
+		single := &ActionMatcher{exact: map[string]bool{}}
+		single.add(pattern)
+		found := false
+
+		for _, action := range actions {
+			if single.Match(action.String()) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			result = append(result, pattern)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,119 @@
+package authref
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// minimizer finds patterns for a set of target actions using a trie of every action.
+type minimizer struct {
+	target map[*QualifiedAction]bool
+
+	// Number of actions at or below each node, and how many of those are targets
+	total, targeted map[*actionTrie]int
+}
+
+func (m *minimizer) count(node *actionTrie) {
+	if node.action != nil {
+		m.total[node]++
+
+		if m.target[node.action] {
+			m.targeted[node]++
+		}
+	}
+
+	for _, child := range node.children {
+		m.count(child)
+		m.total[node] += m.total[child]
+		m.targeted[node] += m.targeted[child]
+	}
+}
+
+// anyAction returns an action at or below the node.
+func (trie *actionTrie) anyAction() *QualifiedAction {
+	node := trie
+
+	for node.action == nil {
+		for _, child := range node.children {
+			node = child
+			break
+		}
+	}
+
+	return node.action
+}
+
+// minimize appends the fewest patterns that match exactly the targets at or below the
+// node, whose names start with depth bytes in common.
+func (m *minimizer) minimize(node *actionTrie, depth int, result []string) []string {
+	switch {
+	case m.targeted[node] == 0:
+		return result
+	case m.total[node] == 1:
+		// A lone action is better named than matched, since a wildcard would also match
+		// actions added later
+		return append(result, node.anyAction().String())
+	case m.targeted[node] == m.total[node]:
+		// Patterns other than "*" have to name the service, as in "s3:*"
+		if prefix := node.anyAction().String()[:depth]; strings.Contains(prefix, ":") {
+			return append(result, prefix+"*")
+		}
+	}
+
+	if node.action != nil && m.target[node.action] {
+		result = append(result, node.action.String())
+	}
+
+	for _, child := range node.children {
+		result = m.minimize(child, depth+1, result)
+	}
+
+	return result
+}
+
+// MinimizePatterns finds the fewest action patterns that match exactly the target actions
+// and no others in universe, using exact action names and wildcards that end in "*", such
+// as "s3:GetObject*" or "s3:*". Among patterns of that form the result is optimal, and
+// where there's a choice it uses the shortest wildcard. If no wildcard helps, the result
+// is the target names themselves. Keep in mind that any wildcard also matches actions AWS
+// adds later.
+//
+// It fails if a target isn't in universe, since then nothing would prove it matches
+// nothing else.
+func MinimizePatterns(universe, target []*QualifiedAction) ([]string, error) {
+	m := &minimizer{target: map[*QualifiedAction]bool{}, total: map[*actionTrie]int{}, targeted: map[*actionTrie]int{}}
+	inUniverse := map[*QualifiedAction]bool{}
+	trie := &actionTrie{children: map[byte]*actionTrie{}}
+
+	for _, action := range universe {
+		inUniverse[action] = true
+		trie.insert(strings.ToLower(action.String()), action)
+	}
+
+	for _, action := range target {
+		if !inUniverse[action] {
+			return nil, fmt.Errorf("%s isn't one of the known actions", action)
+		}
+
+		m.target[action] = true
+	}
+
+	m.count(trie)
+
+	if len(m.target) == len(inUniverse) && len(m.target) != 0 {
+		return []string{"*"}, nil
+	}
+
+	result := make([]string, 0)
+
+	for _, child := range trie.children {
+		result = m.minimize(child, 1, result)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i]) < strings.ToLower(result[j])
+	})
+
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package authref
+
+import "strings"
+
+// ArnPartitions are the partitions ResourceType.PartitionArnPatterns is written for. The
+// isolated partitions in Partitions are left out, since which services they offer isn't
+// published.
+var ArnPartitions = []string{"aws", "aws-cn", "aws-us-gov"}
+
+// ArnPatternInPartition returns an ARN pattern with its "${Partition}" placeholder filled
+// in, as in "arn:aws-cn:s3:::${BucketName}". A pattern that names a partition already is
+// returned as it is.
+func ArnPatternInPartition(pattern, partition string) string {
+	return strings.Replace(pattern, "${Partition}", partition, 1)
+}
+
+// AvailableIn reports whether the service is offered in a partition, such as "aws-cn". A
+// service is assumed to be available unless it lists the partition in
+// UnavailablePartitions, which is only known for some services.
+func (authRef *ServiceAuthorizationReference) AvailableIn(partition string) bool {
+	for _, unavailable := range authRef.UnavailablePartitions {
+		if unavailable == partition {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SetPartitionArnPatterns fills in the PartitionArnPatterns of each of the service's
+// resource types, leaving out the partitions the service isn't available in.
+func (authRef *ServiceAuthorizationReference) SetPartitionArnPatterns() {
+	for _, resourceType := range authRef.ResourceTypes {
+		resourceType.PartitionArnPatterns = map[string]string{}
+
+		for _, partition := range ArnPartitions {
+			if authRef.AvailableIn(partition) {
+				resourceType.PartitionArnPatterns[partition] = ArnPatternInPartition(resourceType.ArnPattern, partition)
+			}
+		}
+	}
+}
+
+// regionPartitions maps the prefixes of region codes outside the aws partition to their
+// partitions. The first matching prefix wins, so longer prefixes come first.
+var regionPartitions = []struct{ prefix, partition string }{
+	{"cn-", "aws-cn"},
+	{"us-gov-", "aws-us-gov"},
+	{"us-isob-", "aws-iso-b"},
+	{"us-isof-", "aws-iso-f"},
+	{"us-iso-", "aws-iso"},
+	{"eu-isoe-", "aws-iso-e"},
+	{"eusc-", "aws-eusc"},
+}
+
+// RegionPartition returns the partition a region belongs to, such as "aws-cn" for
+// "cn-north-1". Regions are assumed to be in the aws partition unless their codes say
+// otherwise.
+func RegionPartition(region string) string {
+	for _, entry := range regionPartitions {
+		if strings.HasPrefix(region, entry.prefix) {
+			return entry.partition
+		}
+	}
+
+	return "aws"
+}
+
+// ArnPatternInRegion returns an ARN pattern with its "${Region}" and "${Partition}"
+// placeholders filled in for a region, as in "arn:aws-cn:ec2:cn-north-1:${Account}:vpc/${VpcId}".
+func ArnPatternInRegion(pattern, region string) string {
+	return strings.Replace(ArnPatternInPartition(pattern, RegionPartition(region)), "${Region}", region, 1)
+}
+
+// RegionalArnPatterns expands an ARN pattern for each of the service's Regions, in order.
+// A pattern without a region, such as an IAM role's, is expanded once per partition
+// instead. The result is empty if the service's regions aren't known.
+func (authRef *ServiceAuthorizationReference) RegionalArnPatterns(pattern string) []string {
+	result := make([]string, 0, len(authRef.Regions))
+	seen := map[string]bool{}
+
+	for _, region := range authRef.Regions {
+		if expanded := ArnPatternInRegion(pattern, region); !seen[expanded] {
+			seen[expanded] = true
+			result = append(result, expanded)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,36 @@
+package authref
+
+// PermissionOnlyAction is an action that IAM checks but that has no API operation of its
+// own, marked "[permission only]" in the reference. Policies need these to allow things
+// like console features or steps inside other operations, and since they don't appear in
+// any API reference, they're easy to miss.
+type PermissionOnlyAction struct {
+	// The action as it's written in a policy, such as "s3:PutAccessPointPolicyForObjectLambda"
+	Action        string      `json:"action"`
+	ServiceName   string      `json:"serviceName"`
+	AccessLevel   AccessLevel `json:"accessLevel"`
+	Description   string      `json:"description"`
+	ReferenceHref string      `json:"referenceHref,omitempty"`
+}
+
+// PermissionOnlyActions lists every permission-only action in the dataset, sorted as in
+// AllActions.
+func PermissionOnlyActions(authRefs []*ServiceAuthorizationReference) []*PermissionOnlyAction {
+	result := make([]*PermissionOnlyAction, 0)
+
+	for _, action := range AllActions(authRefs) {
+		if !action.Action.PermissionOnly {
+			continue
+		}
+
+		result = append(result, &PermissionOnlyAction{
+			Action:        action.String(),
+			ServiceName:   action.Service.Name,
+			AccessLevel:   action.Action.AccessLevel,
+			Description:   action.Action.Description,
+			ReferenceHref: action.Action.ReferenceHref,
+		})
+	}
+
+	return result
+}
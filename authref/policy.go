@@ -0,0 +1,145 @@
+package authref
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Policy is an IAM policy document. Only the parts that refer to actions, resources, and
+// condition keys are decoded.
+type Policy struct {
+	Version   string        `json:"Version,omitempty"`
+	ID        string        `json:"Id,omitempty"`
+	Statement StatementList `json:"Statement"`
+}
+
+// Statement is one statement of a policy.
+type Statement struct {
+	Sid         string                                `json:"Sid,omitempty"`
+	Effect      string                                `json:"Effect"`
+	Action      StringList                            `json:"Action,omitempty"`
+	NotAction   StringList                            `json:"NotAction,omitempty"`
+	Resource    StringList                            `json:"Resource,omitempty"`
+	NotResource StringList                            `json:"NotResource,omitempty"`
+	Condition   map[string]map[string]json.RawMessage `json:"Condition,omitempty"`
+}
+
+// Statement effects.
+const (
+	EffectAllow = "Allow"
+	EffectDeny  = "Deny"
+)
+
+// StringList is a policy element that may be written as either a single string or an
+// array of strings.
+type StringList []string
+
+func (list *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+
+	if err := json.Unmarshal(data, &single); err == nil {
+		*list = StringList{single}
+		return nil
+	}
+
+	var multiple []string
+
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("expected a string or an array of strings")
+	}
+
+	*list = multiple
+	return nil
+}
+
+// Contains reports whether the list contains value exactly.
+func (list StringList) Contains(value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StatementList is the Statement element of a policy, which may be a single statement or
+// an array of them.
+type StatementList []*Statement
+
+func (list *StatementList) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '{' {
+		var single Statement
+
+		if err := json.Unmarshal(data, &single); err != nil {
+			return err
+		}
+
+		*list = StatementList{&single}
+		return nil
+	}
+
+	var multiple []*Statement
+
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+
+	*list = multiple
+	return nil
+}
+
+// Label identifies a statement in messages, using its Sid if it has one.
+func (statement *Statement) Label(index int) string {
+	if statement.Sid != "" {
+		return fmt.Sprintf("statement %d (%s)", index, statement.Sid)
+	}
+
+	return fmt.Sprintf("statement %d", index)
+}
+
+// DecodePolicy reads a policy document.
+func DecodePolicy(r io.Reader) (*Policy, error) {
+	policy := &Policy{}
+
+	if err := json.NewDecoder(r).Decode(policy); err != nil {
+		return nil, fmt.Errorf("decode policy: %w", err)
+	}
+
+	for i, statement := range policy.Statement {
+		if statement == nil {
+			return nil, fmt.Errorf("decode policy: statement %d is null", i)
+		}
+
+		if statement.Effect != EffectAllow && statement.Effect != EffectDeny {
+			return nil, fmt.Errorf("decode policy: %s has invalid effect %#v", statement.Label(i), statement.Effect)
+		}
+
+		if (len(statement.Action) == 0) == (len(statement.NotAction) == 0) {
+			return nil, fmt.Errorf("decode policy: %s must have exactly one of Action or NotAction", statement.Label(i))
+		}
+	}
+
+	return policy, nil
+}
+
+// LoadPolicyFile reads a policy document from a file.
+func LoadPolicyFile(filename string) (*Policy, error) {
+	file, err := os.Open(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	policy, err := DecodePolicy(file)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,534 @@
+package authref
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// The dataset's protocol buffer encoding is described by proto/authref.proto. The
+// messages are simple enough that they're encoded here by hand rather than with generated
+// code, which keeps the library free of the protobuf runtime.
+
+// Protocol buffer wire types.
+const (
+	protoVarint = 0
+	protoBytes  = 2
+)
+
+// ProtoBuffer builds a protocol buffer message in the wire format. Following proto3,
+// fields with zero values aren't written.
+type ProtoBuffer struct {
+	data []byte
+}
+
+// Bytes returns the encoded message.
+func (b *ProtoBuffer) Bytes() []byte {
+	return b.data
+}
+
+func appendUvarint(data []byte, value uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], value)
+	return append(data, buf[:n]...)
+}
+
+func (b *ProtoBuffer) tag(field, wireType int) {
+	b.data = appendUvarint(b.data, uint64(field)<<3|uint64(wireType))
+}
+
+// Uint writes an unsigned integer field.
+func (b *ProtoBuffer) Uint(field int, value uint64) {
+	if value != 0 {
+		b.tag(field, protoVarint)
+		b.data = appendUvarint(b.data, value)
+	}
+}
+
+// Bool writes a boolean field.
+func (b *ProtoBuffer) Bool(field int, value bool) {
+	if value {
+		b.Uint(field, 1)
+	}
+}
+
+// String writes a string field.
+func (b *ProtoBuffer) String(field int, value string) {
+	if value != "" {
+		b.tag(field, protoBytes)
+		b.data = appendUvarint(b.data, uint64(len(value)))
+		b.data = append(b.data, value...)
+	}
+}
+
+// Strings writes a repeated string field.
+func (b *ProtoBuffer) Strings(field int, values []string) {
+	for _, value := range values {
+		b.tag(field, protoBytes)
+		b.data = appendUvarint(b.data, uint64(len(value)))
+		b.data = append(b.data, value...)
+	}
+}
+
+// StringMap writes a map<string, string> field as its repeated entry messages, in key
+// order so that the encoding is stable.
+func (b *ProtoBuffer) StringMap(field int, values map[string]string) {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		b.Message(field, func(entry *ProtoBuffer) {
+			entry.String(1, key)
+			entry.String(2, values[key])
+		})
+	}
+}
+
+// Message writes an embedded message field, which encode fills in. Unlike the other
+// methods, it writes the field even if the message is empty, so it works for repeated
+// fields as well.
+func (b *ProtoBuffer) Message(field int, encode func(b *ProtoBuffer)) {
+	var inner ProtoBuffer
+	encode(&inner)
+
+	b.tag(field, protoBytes)
+	b.data = appendUvarint(b.data, uint64(len(inner.data)))
+	b.data = append(b.data, inner.data...)
+}
+
+// EncodeProto writes the service as an authref.v1.Service message.
+func (authRef *ServiceAuthorizationReference) EncodeProto(b *ProtoBuffer) {
+	b.String(1, authRef.Name)
+	b.String(2, authRef.ServicePrefix)
+	b.String(3, authRef.AuthReferenceHref)
+	b.String(4, authRef.ApiReferenceHref)
+	b.Strings(5, authRef.ServicePrincipals)
+	b.String(9, authRef.LastUpdated)
+	b.Strings(11, authRef.UnavailablePartitions)
+	b.Strings(12, authRef.Regions)
+
+	for _, sdkService := range authRef.SdkServices {
+		b.Message(10, sdkService.EncodeProto)
+	}
+
+	for _, action := range authRef.Actions {
+		b.Message(6, action.EncodeProto)
+	}
+
+	for _, resourceType := range authRef.ResourceTypes {
+		b.Message(7, resourceType.EncodeProto)
+	}
+
+	for _, conditionKey := range authRef.ConditionKeys {
+		b.Message(8, conditionKey.EncodeProto)
+	}
+}
+
+// EncodeProto writes the action as an authref.v1.Action message.
+func (action *Action) EncodeProto(b *ProtoBuffer) {
+	b.String(1, action.Name)
+	b.Bool(2, action.PermissionOnly)
+	b.Strings(3, action.Annotations)
+	b.String(4, action.ReferenceHref)
+	b.String(5, action.Description)
+	b.String(6, string(action.AccessLevel))
+
+	for i := range action.ResourceTypes {
+		b.Message(7, action.ResourceTypes[i].EncodeProto)
+	}
+
+	b.Strings(8, action.ConditionKeys)
+	b.StringMap(9, action.LocalizedDescriptions)
+
+	if action.Provenance != nil {
+		b.Message(10, action.Provenance.EncodeProto)
+	}
+}
+
+// EncodeProto writes the resource type as an authref.v1.ActionResourceType message.
+func (resourceType *ActionResourceType) EncodeProto(b *ProtoBuffer) {
+	b.String(1, resourceType.ResourceType)
+	b.Bool(2, resourceType.Required)
+	b.Strings(3, resourceType.ConditionKeys)
+	b.Strings(4, resourceType.DependentActions)
+	b.Uint(5, uint64(resourceType.RequiredGroup))
+}
+
+// EncodeProto writes the SDK service as an authref.v1.SdkService message.
+func (sdkService *SdkService) EncodeProto(b *ProtoBuffer) {
+	b.String(1, sdkService.ServiceId)
+	b.String(2, sdkService.EndpointPrefix)
+}
+
+// EncodeProto writes the resource type as an authref.v1.ResourceType message.
+func (resourceType *ResourceType) EncodeProto(b *ProtoBuffer) {
+	b.String(1, resourceType.Name)
+	b.String(2, resourceType.ReferenceHref)
+	b.String(3, resourceType.ArnPattern)
+	b.Strings(4, resourceType.ConditionKeys)
+
+	if resourceType.Provenance != nil {
+		b.Message(5, resourceType.Provenance.EncodeProto)
+	}
+
+	b.StringMap(6, resourceType.PartitionArnPatterns)
+}
+
+// EncodeProto writes the condition key as an authref.v1.ConditionKey message.
+func (conditionKey *ConditionKey) EncodeProto(b *ProtoBuffer) {
+	b.String(1, conditionKey.Name)
+	b.String(2, conditionKey.ReferenceHref)
+	b.String(3, conditionKey.Description)
+	b.String(4, conditionKey.Type)
+	b.StringMap(5, conditionKey.LocalizedDescriptions)
+
+	if conditionKey.Provenance != nil {
+		b.Message(6, conditionKey.Provenance.EncodeProto)
+	}
+}
+
+// EncodeProto writes the provenance as an authref.v1.Provenance message.
+func (provenance *Provenance) EncodeProto(b *ProtoBuffer) {
+	b.String(1, provenance.SourceHref)
+	b.String(2, provenance.Anchor)
+	b.String(3, provenance.ScrapedAt)
+}
+
+// ProtoField is one field read from a message by ParseProto. Varint holds the value of
+// integer and boolean fields, and Bytes holds the value of string and message fields.
+type ProtoField struct {
+	Number int
+	Varint uint64
+	Bytes  []byte
+}
+
+var errProtoTruncated = errors.New("parse protobuf: message truncated")
+
+// ParseProto splits a message into its fields. Only varint and length-delimited fields are
+// supported, since those are the only kinds authref.proto uses.
+func ParseProto(data []byte) ([]ProtoField, error) {
+	fields := make([]ProtoField, 0)
+
+	for len(data) != 0 {
+		key, n := binary.Uvarint(data)
+
+		if n <= 0 {
+			return nil, errProtoTruncated
+		}
+
+		data = data[n:]
+		field := ProtoField{Number: int(key >> 3)}
+
+		switch key & 7 {
+		case protoVarint:
+			if field.Varint, n = binary.Uvarint(data); n <= 0 {
+				return nil, errProtoTruncated
+			}
+
+			data = data[n:]
+		case protoBytes:
+			length, n := binary.Uvarint(data)
+
+			if n <= 0 || uint64(len(data)-n) < length {
+				return nil, errProtoTruncated
+			}
+
+			field.Bytes = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return nil, fmt.Errorf("parse protobuf: unsupported wire type %d in field %d", key&7, field.Number)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// MarshalProto encodes a dataset as an authref.v1.Dataset message.
+func MarshalProto(authRefs []*ServiceAuthorizationReference) []byte {
+	var b ProtoBuffer
+
+	for _, authRef := range authRefs {
+		b.Message(1, authRef.EncodeProto)
+	}
+
+	return b.Bytes()
+}
+
+// UnmarshalProto decodes an authref.v1.Dataset message, such as service-auth.pb. Lists
+// that are empty on the wire decode as empty slices, as they would from JSON.
+func UnmarshalProto(data []byte) ([]*ServiceAuthorizationReference, error) {
+	fields, err := ParseProto(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ServiceAuthorizationReference, 0, len(fields))
+
+	for _, field := range fields {
+		if field.Number != 1 {
+			continue
+		}
+
+		authRef, err := decodeProtoService(field.Bytes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, authRef)
+	}
+
+	return result, nil
+}
+
+// UnmarshalProtoAction decodes an authref.v1.Action message, such as the action of an
+// ActionEntry from the gRPC API.
+func UnmarshalProtoAction(data []byte) (*Action, error) {
+	return decodeProtoAction(data)
+}
+
+func decodeProtoService(data []byte) (*ServiceAuthorizationReference, error) {
+	fields, err := ParseProto(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	authRef := &ServiceAuthorizationReference{Actions: []*Action{}, ResourceTypes: []*ResourceType{}, ConditionKeys: []*ConditionKey{}}
+
+	for _, field := range fields {
+		switch field.Number {
+		case 1:
+			authRef.Name = string(field.Bytes)
+		case 2:
+			authRef.ServicePrefix = string(field.Bytes)
+		case 3:
+			authRef.AuthReferenceHref = string(field.Bytes)
+		case 4:
+			authRef.ApiReferenceHref = string(field.Bytes)
+		case 5:
+			authRef.ServicePrincipals = append(authRef.ServicePrincipals, string(field.Bytes))
+		case 9:
+			authRef.LastUpdated = string(field.Bytes)
+		case 11:
+			authRef.UnavailablePartitions = append(authRef.UnavailablePartitions, string(field.Bytes))
+		case 12:
+			authRef.Regions = append(authRef.Regions, string(field.Bytes))
+		case 10:
+			subfields, err := ParseProto(field.Bytes)
+
+			if err != nil {
+				return nil, err
+			}
+
+			sdkService := &SdkService{}
+
+			for _, subfield := range subfields {
+				switch subfield.Number {
+				case 1:
+					sdkService.ServiceId = string(subfield.Bytes)
+				case 2:
+					sdkService.EndpointPrefix = string(subfield.Bytes)
+				}
+			}
+
+			authRef.SdkServices = append(authRef.SdkServices, sdkService)
+		case 6:
+			action, err := decodeProtoAction(field.Bytes)
+
+			if err != nil {
+				return nil, err
+			}
+
+			authRef.Actions = append(authRef.Actions, action)
+		case 7:
+			subfields, err := ParseProto(field.Bytes)
+
+			if err != nil {
+				return nil, err
+			}
+
+			resourceType := &ResourceType{ConditionKeys: []string{}}
+
+			for _, subfield := range subfields {
+				switch subfield.Number {
+				case 1:
+					resourceType.Name = string(subfield.Bytes)
+				case 2:
+					resourceType.ReferenceHref = string(subfield.Bytes)
+				case 3:
+					resourceType.ArnPattern = string(subfield.Bytes)
+				case 4:
+					resourceType.ConditionKeys = append(resourceType.ConditionKeys, string(subfield.Bytes))
+				case 5:
+					if resourceType.Provenance, err = decodeProtoProvenance(subfield.Bytes); err != nil {
+						return nil, err
+					}
+				case 6:
+					if resourceType.PartitionArnPatterns, err = decodeProtoMapEntry(subfield.Bytes, resourceType.PartitionArnPatterns); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			authRef.ResourceTypes = append(authRef.ResourceTypes, resourceType)
+		case 8:
+			subfields, err := ParseProto(field.Bytes)
+
+			if err != nil {
+				return nil, err
+			}
+
+			conditionKey := &ConditionKey{}
+
+			for _, subfield := range subfields {
+				switch subfield.Number {
+				case 1:
+					conditionKey.Name = string(subfield.Bytes)
+				case 2:
+					conditionKey.ReferenceHref = string(subfield.Bytes)
+				case 3:
+					conditionKey.Description = string(subfield.Bytes)
+				case 4:
+					conditionKey.Type = string(subfield.Bytes)
+				case 5:
+					if conditionKey.LocalizedDescriptions, err = decodeProtoMapEntry(subfield.Bytes, conditionKey.LocalizedDescriptions); err != nil {
+						return nil, err
+					}
+				case 6:
+					if conditionKey.Provenance, err = decodeProtoProvenance(subfield.Bytes); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			authRef.ConditionKeys = append(authRef.ConditionKeys, conditionKey)
+		}
+	}
+
+	return authRef, nil
+}
+
+func decodeProtoAction(data []byte) (*Action, error) {
+	fields, err := ParseProto(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	action := &Action{Annotations: []string{}, ResourceTypes: []ActionResourceType{}, ConditionKeys: []string{}}
+
+	for _, field := range fields {
+		switch field.Number {
+		case 1:
+			action.Name = string(field.Bytes)
+		case 2:
+			action.PermissionOnly = field.Varint != 0
+		case 3:
+			action.Annotations = append(action.Annotations, string(field.Bytes))
+		case 4:
+			action.ReferenceHref = string(field.Bytes)
+		case 5:
+			action.Description = string(field.Bytes)
+		case 6:
+			action.AccessLevel = AccessLevel(field.Bytes)
+		case 7:
+			subfields, err := ParseProto(field.Bytes)
+
+			if err != nil {
+				return nil, err
+			}
+
+			resourceType := ActionResourceType{ConditionKeys: []string{}, DependentActions: []string{}}
+
+			for _, subfield := range subfields {
+				switch subfield.Number {
+				case 1:
+					resourceType.ResourceType = string(subfield.Bytes)
+				case 2:
+					resourceType.Required = subfield.Varint != 0
+				case 3:
+					resourceType.ConditionKeys = append(resourceType.ConditionKeys, string(subfield.Bytes))
+				case 4:
+					resourceType.DependentActions = append(resourceType.DependentActions, string(subfield.Bytes))
+				case 5:
+					resourceType.RequiredGroup = int(subfield.Varint)
+				}
+			}
+
+			action.ResourceTypes = append(action.ResourceTypes, resourceType)
+		case 8:
+			action.ConditionKeys = append(action.ConditionKeys, string(field.Bytes))
+		case 9:
+			if action.LocalizedDescriptions, err = decodeProtoMapEntry(field.Bytes, action.LocalizedDescriptions); err != nil {
+				return nil, err
+			}
+		case 10:
+			if action.Provenance, err = decodeProtoProvenance(field.Bytes); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return action, nil
+}
+
+func decodeProtoProvenance(data []byte) (*Provenance, error) {
+	fields, err := ParseProto(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	provenance := &Provenance{}
+
+	for _, field := range fields {
+		switch field.Number {
+		case 1:
+			provenance.SourceHref = string(field.Bytes)
+		case 2:
+			provenance.Anchor = string(field.Bytes)
+		case 3:
+			provenance.ScrapedAt = string(field.Bytes)
+		}
+	}
+
+	return provenance, nil
+}
+
+// decodeProtoMapEntry adds one entry of a map<string, string> field to values, creating
+// the map if it's nil.
+func decodeProtoMapEntry(data []byte, values map[string]string) (map[string]string, error) {
+	fields, err := ParseProto(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var key, value string
+
+	for _, field := range fields {
+		switch field.Number {
+		case 1:
+			key = string(field.Bytes)
+		case 2:
+			value = string(field.Bytes)
+		}
+	}
+
+	if values == nil {
+		values = map[string]string{}
+	}
+
+	values[key] = value
+	return values, nil
+}
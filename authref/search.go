@@ -0,0 +1,243 @@
+package authref
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SearchIndexFile is the name the search index is published under.
+const SearchIndexFile = "search-index.json"
+
+// Kinds of SearchDocument.
+const (
+	SearchKindAction       = "action"
+	SearchKindConditionKey = "conditionKey"
+)
+
+// SearchDocument is one searchable entry of a SearchIndex: an action, such as
+// "s3:GetObject", or a condition key, such as "s3:x-amz-acl".
+type SearchDocument struct {
+	Kind        string      `json:"kind"`
+	Name        string      `json:"name"`
+	AccessLevel AccessLevel `json:"accessLevel,omitempty"`
+	Description string      `json:"description"`
+}
+
+// SearchIndex is an inverted index over the names and descriptions of actions and
+// condition keys. Actions are also indexed by the condition keys they support.
+type SearchIndex struct {
+	Documents []*SearchDocument `json:"documents"`
+
+	// For each lowercase term, the positions in Documents of the documents containing it
+	Terms map[string][]int `json:"terms"`
+
+	sortedTerms []string
+}
+
+// SearchResult is a document that matched a search, with a higher score for a better match.
+type SearchResult struct {
+	*SearchDocument
+	Score int `json:"score"`
+}
+
+// searchTerms splits text into lowercase words. Each word of a name is also split at
+// case changes, so "ListMFADevices" yields "listmfadevices", "list", "mfa" and "devices".
+func searchTerms(text string, name bool) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	result := make([]string, 0, len(fields))
+
+	for _, field := range fields {
+		result = append(result, strings.ToLower(field))
+
+		if !name {
+			continue
+		}
+
+		runes := []rune(field)
+		start := 0
+
+		for i := 1; i < len(runes); i++ {
+			// A word starts at a capital that follows a lowercase letter, or at the last
+			// capital of a run that's followed by a lowercase letter ("MFADevices")
+			if unicode.IsUpper(runes[i]) && (unicode.IsLower(runes[i-1]) ||
+				(unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				result = append(result, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+		}
+
+		if start != 0 {
+			result = append(result, strings.ToLower(string(runes[start:])))
+		}
+	}
+
+	return result
+}
+
+// BuildSearchIndex indexes the actions and condition keys of the dataset.
+func BuildSearchIndex(authRefs []*ServiceAuthorizationReference) *SearchIndex {
+	index := &SearchIndex{Documents: make([]*SearchDocument, 0), Terms: map[string][]int{}}
+
+	add := func(document *SearchDocument, terms []string) {
+		id := len(index.Documents)
+		index.Documents = append(index.Documents, document)
+
+		for _, term := range terms {
+			postings := index.Terms[term]
+
+			if len(postings) == 0 || postings[len(postings)-1] != id {
+				index.Terms[term] = append(postings, id)
+			}
+		}
+	}
+
+	for _, action := range AllActions(authRefs) {
+		terms := searchTerms(action.String(), true)
+		terms = append(terms, searchTerms(action.Action.Description, false)...)
+
+		for _, key := range action.Action.ConditionKeys {
+			terms = append(terms, searchTerms(key, true)...)
+		}
+
+		add(&SearchDocument{
+			Kind:        SearchKindAction,
+			Name:        action.String(),
+			AccessLevel: action.Action.AccessLevel,
+			Description: action.Action.Description,
+		}, terms)
+	}
+
+	for _, key := range AllConditionKeys(authRefs) {
+		terms := append(searchTerms(key.Name, true), searchTerms(key.Description, false)...)
+		add(&SearchDocument{Kind: SearchKindConditionKey, Name: key.Name, Description: key.Description}, terms)
+	}
+
+	return index
+}
+
+// LoadSearchIndexFile reads a search index written by BuildSearchIndex.
+func LoadSearchIndexFile(filename string) (*SearchIndex, error) {
+	data, err := os.ReadFile(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	index := &SearchIndex{}
+
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("parse search index %s: %w", filename, err)
+	}
+
+	for term, postings := range index.Terms {
+		for _, id := range postings {
+			if id < 0 || id >= len(index.Documents) {
+				return nil, fmt.Errorf("%s: term %#v refers to document %d of %d", filename, term, id, len(index.Documents))
+			}
+		}
+	}
+
+	return index, nil
+}
+
+// Search finds the documents that contain every word of the query, either exactly or as
+// the start of a longer word, so "s3 getobj" finds s3:GetObject. Results are ordered by
+// score, then by name. A limit of zero returns every match.
+func (index *SearchIndex) Search(query string, limit int) []*SearchResult {
+	if index.sortedTerms == nil {
+		index.sortedTerms = make([]string, 0, len(index.Terms))
+
+		for term := range index.Terms {
+			index.sortedTerms = append(index.sortedTerms, term)
+		}
+
+		sort.Strings(index.sortedTerms)
+	}
+
+	words := searchTerms(query, false)
+	results := make([]*SearchResult, 0)
+
+	if len(words) == 0 {
+		return results
+	}
+
+	var scores map[int]int
+
+	for _, word := range words {
+		wordScores := map[int]int{}
+
+		for i := sort.SearchStrings(index.sortedTerms, word); i < len(index.sortedTerms) && strings.HasPrefix(index.sortedTerms[i], word); i++ {
+			term := index.sortedTerms[i]
+			score := 1
+
+			if term == word {
+				score = 2
+			}
+
+			for _, id := range index.Terms[term] {
+				if wordScores[id] < score {
+					wordScores[id] = score
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = wordScores
+			continue
+		}
+
+		for id := range scores {
+			if wordScores[id] == 0 {
+				delete(scores, id)
+			} else {
+				scores[id] += wordScores[id]
+			}
+		}
+	}
+
+	for id, score := range scores {
+		document := index.Documents[id]
+
+		// Prefer documents whose name has the word over those that only mention it, and
+		// those where it's the whole prefix or name, so "s3 getobject" puts s3:GetObject first
+		name := strings.ToLower(document.Name)
+		prefix, local := "", name
+
+		if i := strings.Index(name, ":"); i >= 0 {
+			prefix, local = name[:i], name[i+1:]
+		}
+
+		for _, word := range words {
+			if strings.Contains(name, word) {
+				score += 3
+			}
+
+			if word == prefix || word == local {
+				score += 2
+			}
+		}
+
+		results = append(results, &SearchResult{SearchDocument: document, Score: score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+
+		return strings.ToLower(results[i].Name) < strings.ToLower(results[j].Name)
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
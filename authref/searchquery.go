@@ -0,0 +1,201 @@
+package authref
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Fields a SearchQuery can filter on
+const (
+	searchFieldKind        = "kind"
+	searchFieldService     = "service"
+	searchFieldName        = "name"
+	searchFieldAccessLevel = "accessLevel"
+)
+
+var searchFields = []string{searchFieldKind, searchFieldService, searchFieldName, searchFieldAccessLevel}
+
+type searchFilter struct {
+	field, value string
+}
+
+// SearchQuery is a search with field filters, as parsed by ParseSearchQuery.
+type SearchQuery struct {
+	// The words to search for, as with Search; empty if the query only has filters
+	Words string
+
+	filters []searchFilter
+}
+
+// splitSearchQuery splits a query at spaces outside double quotes, removing the quotes.
+func splitSearchQuery(query string) ([]string, error) {
+	result := make([]string, 0)
+	var current strings.Builder
+	quoted, inToken := false, false
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inToken = true
+		case unicode.IsSpace(r) && !quoted:
+			if inToken {
+				result = append(result, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in %#v", query)
+	}
+
+	if inToken {
+		result = append(result, current.String())
+	}
+
+	return result, nil
+}
+
+// ParseSearchQuery parses a search such as `accessLevel:Write service:ec2 name:*Snapshot*
+// volume`. A term of the form field:value filters on a field:
+//
+//   - kind:action or kind:conditionKey
+//   - service:ec2 keeps entries of a service prefix
+//   - name:*Snapshot* keeps entries whose name, without the service prefix, matches; with
+//     a colon, as in name:ec2:Create*, the full name has to match
+//   - accessLevel:Write keeps actions at an access level; quote levels with spaces, as in
+//     accessLevel:"Permissions management"
+//
+// Values of service and name can use the "*" and "?" wildcards, and every filter ignores
+// case. Any other terms, including names such as "s3:GetObject", are words to search for.
+func ParseSearchQuery(query string) (*SearchQuery, error) {
+	terms, err := splitSearchQuery(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SearchQuery{}
+	words := make([]string, 0)
+
+	for _, term := range terms {
+		field := ""
+
+		if i := strings.Index(term, ":"); i >= 0 {
+			for _, known := range searchFields {
+				if strings.EqualFold(term[:i], known) {
+					field = known
+				}
+			}
+		}
+
+		if field == "" {
+			words = append(words, term)
+			continue
+		}
+
+		value := term[len(field)+1:]
+
+		if value == "" {
+			return nil, fmt.Errorf("%s: needs a value", field)
+		}
+
+		switch field {
+		case searchFieldKind:
+			if !strings.EqualFold(value, SearchKindAction) && !strings.EqualFold(value, SearchKindConditionKey) {
+				return nil, fmt.Errorf("kind must be %s or %s, not %#v", SearchKindAction, SearchKindConditionKey, value)
+			}
+		case searchFieldAccessLevel:
+			known := false
+
+			for _, level := range AccessLevels {
+				known = known || strings.EqualFold(value, string(level))
+			}
+
+			if !known {
+				return nil, fmt.Errorf("unknown access level %#v", value)
+			}
+		}
+
+		result.filters = append(result.filters, searchFilter{field, value})
+	}
+
+	result.Words = strings.Join(words, " ")
+	return result, nil
+}
+
+// matches reports whether a document passes every filter of the query.
+func (query *SearchQuery) matches(document *SearchDocument) bool {
+	prefix, local := "", document.Name
+
+	if i := strings.Index(document.Name, ":"); i >= 0 {
+		prefix, local = document.Name[:i], document.Name[i+1:]
+	}
+
+	for _, filter := range query.filters {
+		var ok bool
+
+		switch filter.field {
+		case searchFieldKind:
+			ok = strings.EqualFold(document.Kind, filter.value)
+		case searchFieldService:
+			ok = matchWildcard(filter.value, prefix)
+		case searchFieldName:
+			if strings.Contains(filter.value, ":") {
+				ok = matchWildcard(filter.value, document.Name)
+			} else {
+				ok = matchWildcard(filter.value, local)
+			}
+		case searchFieldAccessLevel:
+			ok = strings.EqualFold(string(document.AccessLevel), filter.value)
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Query finds the documents that pass the query's filters and contain its words, ranked as
+// by Search. A query with no words returns every document that passes the filters, with a
+// score of zero, ordered by name. A limit of zero returns every match.
+func (index *SearchIndex) Query(query *SearchQuery, limit int) []*SearchResult {
+	var candidates []*SearchResult
+
+	if query.Words != "" {
+		candidates = index.Search(query.Words, 0)
+	} else {
+		candidates = make([]*SearchResult, len(index.Documents))
+
+		for i, document := range index.Documents {
+			candidates[i] = &SearchResult{SearchDocument: document}
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return strings.ToLower(candidates[i].Name) < strings.ToLower(candidates[j].Name)
+		})
+	}
+
+	results := make([]*SearchResult, 0)
+
+	for _, candidate := range candidates {
+		if query.matches(candidate.SearchDocument) {
+			results = append(results, candidate)
+
+			if limit > 0 && len(results) == limit {
+				break
+			}
+		}
+	}
+
+	return results
+}
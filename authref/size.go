@@ -0,0 +1,140 @@
+package authref
+
+import (
+	"encoding/json"
+	"sort"
+	"unicode"
+)
+
+// IAM's limits on the size of a policy, in characters, not counting whitespace. See
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_iam-quotas.html
+const (
+	ManagedPolicySizeLimit     = 6144
+	InlineUserPolicySizeLimit  = 2048
+	InlineGroupPolicySizeLimit = 5120
+	InlineRolePolicySizeLimit  = 10240
+	SCPSizeLimit               = 5120
+)
+
+// Representation is one way of writing a set of actions in the Action element of a policy.
+type Representation struct {
+	Name     string   `json:"name"`
+	Patterns []string `json:"patterns"`
+
+	// Size of a policy with a single Allow statement on all resources using the patterns
+	Size int `json:"size"`
+
+	// Actions the patterns match that weren't asked for
+	Extra []string `json:"extra"`
+}
+
+// PolicySize returns the size IAM counts for a policy that allows the patterns on all
+// resources in a single statement, which is the policy without whitespace.
+func PolicySize(patterns []string) int {
+	policy := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []interface{}{
+			map[string]interface{}{"Effect": EffectAllow, "Action": patterns, "Resource": "*"},
+		},
+	}
+
+	// Marshal can't fail on strings and maps
+	data, _ := json.Marshal(policy)
+	return len(data)
+}
+
+// actionVerb returns the first word of an action name, such as "Get" for "GetObject".
+func actionVerb(name string) string {
+	for i, r := range name {
+		if i != 0 && unicode.IsUpper(r) {
+			return name[:i]
+		}
+	}
+
+	return name
+}
+
+// newRepresentation sizes a set of patterns and works out what they match beyond target.
+func newRepresentation(name string, patterns []string, universe []*QualifiedAction, target map[*QualifiedAction]bool) *Representation {
+	sort.Strings(patterns)
+	result := &Representation{Name: name, Patterns: patterns, Size: PolicySize(patterns), Extra: make([]string, 0)}
+
+	for _, action := range MatchActions(universe, patterns) {
+		if !target[action] {
+			result.Extra = append(result.Extra, action.String())
+		}
+	}
+
+	return result
+}
+
+// Representations compares ways of writing the target actions in a policy:
+//
+//   - "explicit" lists every action.
+//   - "minimal" uses the fewest patterns that match nothing else, from MinimizePatterns.
+//   - "exact-verbs" uses a wildcard such as "s3:Get*" wherever it matches only target
+//     actions, and lists the rest.
+//   - "verbs" uses a wildcard for each verb in the target, whatever else it matches.
+//   - "services" uses "prefix:*" for each service in the target.
+//
+// universe is every action in the dataset, which is needed to tell what a wildcard matches.
+func Representations(universe, target []*QualifiedAction) []*Representation {
+	inTarget := map[*QualifiedAction]bool{}
+
+	for _, action := range target {
+		inTarget[action] = true
+	}
+
+	// Target actions of each verb wildcard, such as "s3:Get*"
+	verbTargets := map[string][]*QualifiedAction{}
+	services := map[string]bool{}
+	explicit := make([]string, 0, len(target))
+
+	for _, action := range target {
+		services[action.Service.ServicePrefix] = true
+		verb := action.Service.ServicePrefix + ":" + actionVerb(action.Action.Name) + "*"
+		verbTargets[verb] = append(verbTargets[verb], action)
+		explicit = append(explicit, action.String())
+	}
+
+	exactVerbs := make([]string, 0)
+	verbs := make([]string, 0, len(verbTargets))
+
+	for verb, actions := range verbTargets {
+		verbs = append(verbs, verb)
+
+		// Use the wildcard where it matches nothing else and is shorter than the list
+		exact := len(MatchActions(universe, []string{verb})) == len(actions)
+		listed := 0
+
+		for _, action := range actions {
+			listed += len(action.String()) + 3
+		}
+
+		if exact && len(verb)+3 < listed {
+			exactVerbs = append(exactVerbs, verb)
+			continue
+		}
+
+		for _, action := range actions {
+			exactVerbs = append(exactVerbs, action.String())
+		}
+	}
+
+	servicePatterns := make([]string, 0, len(services))
+
+	for prefix := range services {
+		servicePatterns = append(servicePatterns, prefix+":*")
+	}
+
+	// Targets come from universe, so this can't fail
+	minimal, _ := MinimizePatterns(universe, target)
+
+	return []*Representation{
+		newRepresentation("explicit", explicit, universe, inTarget),
+		newRepresentation("minimal", minimal, universe, inTarget),
+		newRepresentation("exact-verbs", exactVerbs, universe, inTarget),
+		newRepresentation("verbs", verbs, universe, inTarget),
+		newRepresentation("services", servicePatterns, universe, inTarget),
+	}
+}
@@ -0,0 +1,61 @@
+package authref
+
+// UnscopedAction is a Write or Permissions management action that a policy can't narrow
+// at all: the reference lists no resource types for it, so it can only be granted on
+// "*", and no condition keys, so a Condition can't limit it either, beyond global keys
+// such as aws:SourceIp that say nothing about what the action changes. Granting one
+// grants it everywhere in the account.
+type UnscopedAction struct {
+	// The action as it's written in a policy, such as "iam:CreateAccountAlias"
+	Action         string      `json:"action"`
+	ServiceName    string      `json:"serviceName"`
+	AccessLevel    AccessLevel `json:"accessLevel"`
+	PermissionOnly bool        `json:"permissionOnly"`
+	Description    string      `json:"description"`
+	ReferenceHref  string      `json:"referenceHref,omitempty"`
+}
+
+// UnscopedActions lists every Write and Permissions management action that supports no
+// resource types and no condition keys, sorted as in AllActions.
+func UnscopedActions(authRefs []*ServiceAuthorizationReference) []*UnscopedAction {
+	result := make([]*UnscopedAction, 0)
+
+	for _, action := range AllActions(authRefs) {
+		level := action.Action.AccessLevel
+
+		if level != AccessLevelWrite && level != AccessLevelPermissionsManagement {
+			continue
+		}
+
+		if !isUnscoped(action.Action) {
+			continue
+		}
+
+		result = append(result, &UnscopedAction{
+			Action:         action.String(),
+			ServiceName:    action.Service.Name,
+			AccessLevel:    level,
+			PermissionOnly: action.Action.PermissionOnly,
+			Description:    action.Action.Description,
+			ReferenceHref:  action.Action.ReferenceHref,
+		})
+	}
+
+	return result
+}
+
+// isUnscoped reports whether an action names no resource types and no condition keys,
+// whether for the action as a whole or for one of its resource types.
+func isUnscoped(action *Action) bool {
+	if len(action.ConditionKeys) != 0 {
+		return false
+	}
+
+	for _, resourceType := range action.ResourceTypes {
+		if resourceType.ResourceType != "" || len(resourceType.ConditionKeys) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
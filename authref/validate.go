@@ -0,0 +1,247 @@
+package authref
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Finding codes reported by CheckIntegrity.
+const (
+	FindingUnknownAccessLevel   = "unknown-access-level"
+	FindingDanglingResourceType = "dangling-resource-type"
+	FindingResourceTypeMismatch = "resource-type-mismatch"
+	FindingDanglingConditionKey = "dangling-condition-key"
+	FindingUnknownGlobalKey     = "unknown-global-condition-key"
+	FindingConditionKeyMismatch = "condition-key-mismatch"
+	FindingMissingServicePrefix = "missing-service-prefix"
+)
+
+// Finding is a data-quality problem in one service.
+type Finding struct {
+	Code          string `json:"code"`
+	Service       string `json:"service"`
+	ServicePrefix string `json:"servicePrefix"`
+
+	// The action the problem was found in, if any
+	Action string `json:"action,omitempty"`
+
+	// The resource type or condition key name the action refers to, as the page writes it,
+	// for findings about references
+	Reference string `json:"reference,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// CheckIntegrity looks for references within a service that don't resolve: actions that
+// name resource types or condition keys the service doesn't define, along with unknown
+// access levels and a missing service prefix. A global condition key (one starting with
+// "aws:") only needs to be defined if it isn't in GlobalConditionKeys. A name that matches
+// one the service defines except in case, or for a condition key in how its placeholder is
+// written, is reported as a mismatch rather than as dangling, since IAM would likely
+// accept it.
+func CheckIntegrity(authRef *ServiceAuthorizationReference) []*Finding {
+	findings := make([]*Finding, 0)
+	addf := func(code string, action *Action, reference, format string, args ...interface{}) {
+		finding := &Finding{Code: code, Service: authRef.Name, ServicePrefix: authRef.ServicePrefix, Reference: reference, Message: fmt.Sprintf(format, args...)}
+
+		if action != nil {
+			finding.Action = action.Name
+		}
+
+		findings = append(findings, finding)
+	}
+
+	if authRef.ServicePrefix == "" {
+		addf(FindingMissingServicePrefix, nil, "", "no service prefix found")
+	}
+
+	resourceTypes := make(map[string]bool, len(authRef.ResourceTypes))
+	lowerResourceTypes := make(map[string]string, len(authRef.ResourceTypes))
+	conditionKeys := make(map[string]bool, len(authRef.ConditionKeys))
+	normalConditionKeys := make(map[string]string, len(authRef.ConditionKeys))
+
+	for _, resourceType := range authRef.ResourceTypes {
+		resourceTypes[resourceType.Name] = true
+		lowerResourceTypes[strings.ToLower(resourceType.Name)] = resourceType.Name
+	}
+
+	for _, conditionKey := range authRef.ConditionKeys {
+		conditionKeys[conditionKey.Name] = true
+		normalConditionKeys[normalConditionKey(conditionKey.Name)] = conditionKey.Name
+	}
+
+	checkConditionKey := func(action *Action, key string) {
+		if conditionKeys[key] {
+			return
+		}
+
+		if defined, ok := normalConditionKeys[normalConditionKey(key)]; ok {
+			addf(FindingConditionKeyMismatch, action, key, "action %s references condition key %s, which the service defines as %s", action.Name, key, defined)
+		} else if !IsGlobalConditionKey(key) {
+			addf(FindingDanglingConditionKey, action, key, "action %s references undefined condition key %s", action.Name, key)
+		} else if !IsKnownGlobalConditionKey(key) {
+			addf(FindingUnknownGlobalKey, action, key, "action %s references condition key %s, which is neither defined by the service nor a known global key", action.Name, key)
+		}
+	}
+
+	for _, action := range authRef.Actions {
+		if !action.AccessLevel.Known() {
+			addf(FindingUnknownAccessLevel, action, "", "action %s has unknown access level %#v", action.Name, action.AccessLevel)
+		}
+
+		for _, resourceType := range action.ResourceTypes {
+			name := resourceType.ResourceType
+
+			if !resourceTypes[name] {
+				if defined, ok := lowerResourceTypes[strings.ToLower(name)]; ok {
+					addf(FindingResourceTypeMismatch, action, name, "action %s references resource type %s, which the service defines as %s", action.Name, name, defined)
+				} else {
+					addf(FindingDanglingResourceType, action, name, "action %s references undefined resource type %s", action.Name, name)
+				}
+			}
+
+			for _, key := range resourceType.ConditionKeys {
+				checkConditionKey(action, key)
+			}
+		}
+
+		for _, key := range action.ConditionKeys {
+			checkConditionKey(action, key)
+		}
+	}
+
+	return findings
+}
+
+// CheckDatasetIntegrity runs CheckIntegrity on every service, returning the findings in
+// the order of the services.
+func CheckDatasetIntegrity(authRefs []*ServiceAuthorizationReference) []*Finding {
+	findings := make([]*Finding, 0)
+
+	for _, authRef := range authRefs {
+		findings = append(findings, CheckIntegrity(authRef)...)
+	}
+
+	return findings
+}
+
+// DecodeStrict reads a dataset like Decode, but fails on fields that aren't part of the
+// format, then checks that every required field is present. It returns every schema
+// problem it finds, not just the first.
+func DecodeStrict(r io.Reader) ([]*ServiceAuthorizationReference, []error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var authRefs []*ServiceAuthorizationReference
+
+	if err := decoder.Decode(&authRefs); err != nil {
+		return nil, []error{fmt.Errorf("decode service authorization reference: %w", err)}
+	}
+
+	if authRefs == nil {
+		return nil, []error{fmt.Errorf("decode service authorization reference: expected an array")}
+	}
+
+	return authRefs, CheckSchema(authRefs)
+}
+
+// CheckSchema checks that the required fields of every entry are present. Lists that
+// decoded as nil were missing or null in the JSON.
+func CheckSchema(authRefs []*ServiceAuthorizationReference) []error {
+	errs := make([]error, 0)
+	errorf := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	for i, authRef := range authRefs {
+		if authRef == nil {
+			errorf("service %d: null", i)
+			continue
+		}
+
+		where := fmt.Sprintf("service %d (%s)", i, authRef.Name)
+
+		if authRef.Name == "" {
+			errorf("%s: missing name", where)
+		}
+
+		if authRef.ServicePrefix == "" {
+			errorf("%s: missing servicePrefix", where)
+		}
+
+		if authRef.AuthReferenceHref == "" {
+			errorf("%s: missing authReferenceHref", where)
+		}
+
+		if authRef.Actions == nil || authRef.ResourceTypes == nil || authRef.ConditionKeys == nil {
+			errorf("%s: missing actions, resourceTypes, or conditionKeys", where)
+		}
+
+		if authRef.LastUpdated != "" {
+			if _, err := time.Parse("2006-01-02", authRef.LastUpdated); err != nil {
+				errorf("%s: lastUpdated %#v isn't a YYYY-MM-DD date", where, authRef.LastUpdated)
+			}
+		}
+
+		for j, action := range authRef.Actions {
+			if action == nil {
+				errorf("%s: action %d: null", where, j)
+				continue
+			}
+
+			if action.Name == "" {
+				errorf("%s: action %d: missing name", where, j)
+			}
+
+			if action.AccessLevel == "" {
+				errorf("%s: action %s: missing accessLevel", where, action.Name)
+			}
+
+			for locale := range action.LocalizedDescriptions {
+				if !IsLocale(locale) {
+					errorf("%s: action %s: localizedDescriptions has key %#v, which isn't a locale like \"ja_jp\"", where, action.Name, locale)
+				}
+			}
+
+			// Annotations are absent from snapshots made before they were recorded
+			if action.ResourceTypes == nil || action.ConditionKeys == nil {
+				errorf("%s: action %s: missing resourceTypes or conditionKeys", where, action.Name)
+			}
+
+			for _, resourceType := range action.ResourceTypes {
+				if resourceType.ResourceType == "" || resourceType.ConditionKeys == nil || resourceType.DependentActions == nil {
+					errorf("%s: action %s: resource type missing resourceType, conditionKeys, or dependentActions", where, action.Name)
+				}
+
+				// Snapshots made before requiredGroup existed leave it zero on required types
+				if resourceType.RequiredGroup < 0 || (resourceType.RequiredGroup != 0 && !resourceType.Required) {
+					errorf("%s: action %s: resource type %s has requiredGroup %d but required %v", where, action.Name, resourceType.ResourceType, resourceType.RequiredGroup, resourceType.Required)
+				}
+			}
+		}
+
+		for j, resourceType := range authRef.ResourceTypes {
+			if resourceType == nil || resourceType.Name == "" || resourceType.ConditionKeys == nil {
+				errorf("%s: resource type %d: missing name or conditionKeys", where, j)
+			}
+		}
+
+		for j, conditionKey := range authRef.ConditionKeys {
+			if conditionKey == nil || conditionKey.Name == "" || conditionKey.Type == "" {
+				errorf("%s: condition key %d: missing name or type", where, j)
+				continue
+			}
+
+			for locale := range conditionKey.LocalizedDescriptions {
+				if !IsLocale(locale) {
+					errorf("%s: condition key %s: localizedDescriptions has key %#v, which isn't a locale like \"ja_jp\"", where, conditionKey.Name, locale)
+				}
+			}
+		}
+	}
+
+	return errs
+}
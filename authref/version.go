@@ -0,0 +1,289 @@
+package authref
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the version of the dataset's JSON format. It goes up whenever fields
+// are added to, removed from, or change meaning in service-auth.json, and any change to it
+// produces a major version of the dataset.
+const SchemaVersion = 2
+
+// Version is a semantic version of the dataset.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// Bump describes how much the dataset changed between two snapshots.
+type Bump string
+
+const (
+	// Nothing changed.
+	BumpNone Bump = "none"
+
+	// Only descriptions and reference links changed.
+	BumpPatch Bump = "patch"
+
+	// Items were added, or existing items changed in ways other than their descriptions.
+	BumpMinor Bump = "minor"
+
+	// Items were removed, or the schema changed.
+	BumpMajor Bump = "major"
+)
+
+var bumpOrder = map[Bump]int{BumpNone: 0, BumpPatch: 1, BumpMinor: 2, BumpMajor: 3}
+
+// patchFields lists the JSON fields whose changes only warrant a patch version.
+var patchFields = map[string]bool{
+	"description":   true,
+	"referenceHref": true,
+}
+
+func (version Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+}
+
+// ParseVersion parses a version in "major.minor.patch" form.
+func ParseVersion(text string) (Version, error) {
+	parts := strings.Split(text, ".")
+
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version %#v", text)
+	}
+
+	numbers := make([]int, 3)
+
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+
+		if err != nil || number < 0 {
+			return Version{}, fmt.Errorf("invalid version %#v", text)
+		}
+
+		numbers[i] = number
+	}
+
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+func (version Version) MarshalText() ([]byte, error) {
+	return []byte(version.String()), nil
+}
+
+func (version *Version) UnmarshalText(text []byte) error {
+	parsed, err := ParseVersion(string(text))
+
+	if err != nil {
+		return err
+	}
+
+	*version = parsed
+	return nil
+}
+
+// Next returns the version that follows this one after a change of the given size.
+func (version Version) Next(bump Bump) Version {
+	switch bump {
+	case BumpMajor:
+		return Version{Major: version.Major + 1}
+	case BumpMinor:
+		return Version{Major: version.Major, Minor: version.Minor + 1}
+	case BumpPatch:
+		return Version{Major: version.Major, Minor: version.Minor, Patch: version.Patch + 1}
+	default:
+		return version
+	}
+}
+
+// ClassifyChanges decides how much the dataset changed.
+func ClassifyChanges(changes *Changes) Bump {
+	bump := BumpNone
+
+	raise := func(to Bump) {
+		if bumpOrder[to] > bumpOrder[bump] {
+			bump = to
+		}
+	}
+
+	raiseForFields := func(fields []string) {
+		for _, field := range fields {
+			if patchFields[field] {
+				raise(BumpPatch)
+			} else {
+				raise(BumpMinor)
+			}
+		}
+	}
+
+	for _, service := range changes.Services {
+		if service.Removed || len(service.RemovedActions) != 0 || len(service.RemovedResourceTypes) != 0 || len(service.RemovedConditionKeys) != 0 {
+			return BumpMajor
+		}
+
+		if service.Added || len(service.AddedActions) != 0 || len(service.AddedResourceTypes) != 0 || len(service.AddedConditionKeys) != 0 {
+			raise(BumpMinor)
+		}
+
+		for _, change := range service.ChangedActions {
+			raiseForFields(change.Fields)
+		}
+
+		for _, change := range service.ChangedResourceTypes {
+			raiseForFields(change.Fields)
+		}
+
+		for _, change := range service.ChangedConditionKeys {
+			raiseForFields(change.Fields)
+		}
+	}
+
+	return bump
+}
+
+// Metadata describes one snapshot of the dataset. It's published as metadata.json
+// alongside service-auth.json.
+type Metadata struct {
+	Version         Version `json:"version"`
+	PreviousVersion Version `json:"previousVersion"`
+	Bump            Bump    `json:"bump"`
+	SchemaVersion   int     `json:"schemaVersion"`
+	GeneratedAt     string  `json:"generatedAt"`
+	Services        int     `json:"services"`
+	Actions         int     `json:"actions"`
+	ResourceTypes   int     `json:"resourceTypes"`
+	ConditionKeys   int     `json:"conditionKeys"`
+
+	// SHA-256 digests of the other artifacts published with this snapshot, keyed by file name
+	Checksums map[string]string `json:"checksums,omitempty"`
+
+	// The build of the scraper that produced this snapshot
+	Generator *BuildInfo `json:"generator,omitempty"`
+}
+
+// InitialVersion is the version given to the first snapshot that has metadata.
+var InitialVersion = Version{Major: 1}
+
+// NewMetadata describes a snapshot that follows the one described by previous, which may
+// be nil if there was no earlier metadata.
+func NewMetadata(authRefs []*ServiceAuthorizationReference, previous *Metadata, changes *Changes, generatedAt string) *Metadata {
+	metadata := &Metadata{SchemaVersion: SchemaVersion, GeneratedAt: generatedAt}
+
+	for _, authRef := range authRefs {
+		metadata.Services++
+		metadata.Actions += len(authRef.Actions)
+		metadata.ResourceTypes += len(authRef.ResourceTypes)
+		metadata.ConditionKeys += len(authRef.ConditionKeys)
+	}
+
+	if previous == nil {
+		metadata.Version = InitialVersion
+		metadata.PreviousVersion = InitialVersion
+		metadata.Bump = BumpNone
+		return metadata
+	}
+
+	metadata.Bump = ClassifyChanges(changes)
+
+	if previous.SchemaVersion != SchemaVersion {
+		metadata.Bump = BumpMajor
+	}
+
+	metadata.PreviousVersion = previous.Version
+	metadata.Version = previous.Version.Next(metadata.Bump)
+	return metadata
+}
+
+// LoadMetadataFile reads a metadata file. A missing file yields nil and no error.
+func LoadMetadataFile(filename string) (*Metadata, error) {
+	data, err := os.ReadFile(filename)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	metadata := &Metadata{}
+
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, fmt.Errorf("parse metadata %s: %w", filename, err)
+	}
+
+	return metadata, nil
+}
+
+// LatestMetadataURL is where the metadata of the most recently published snapshot lives.
+const LatestMetadataURL = "https://raw.githubusercontent.com/fluggo/aws-service-auth-reference/master/metadata.json"
+
+// FetchMetadata downloads a metadata file, such as the one at LatestMetadataURL. It's a few
+// hundred bytes, so checking for a new snapshot doesn't mean downloading the dataset. If
+// client is nil, http.DefaultClient is used.
+func FetchMetadata(client *http.Client, url string) (*Metadata, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch metadata %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("fetch metadata %s: %w", url, err)
+	}
+
+	metadata := &Metadata{}
+
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, fmt.Errorf("parse metadata %s: %w", url, err)
+	}
+
+	return metadata, nil
+}
+
+// LatestDatasetURL is where the most recently published service-auth.json lives.
+const LatestDatasetURL = "https://raw.githubusercontent.com/fluggo/aws-service-auth-reference/master/service-auth.json"
+
+// FetchDataset downloads a dataset in the service-auth.json format, such as the one at
+// LatestDatasetURL. If client is nil, http.DefaultClient is used.
+func FetchDataset(client *http.Client, url string) ([]*ServiceAuthorizationReference, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch dataset %s: %s", url, resp.Status)
+	}
+
+	result, err := Decode(resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("fetch dataset %s: %w", url, err)
+	}
+
+	return result, nil
+}
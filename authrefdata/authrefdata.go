@@ -0,0 +1,80 @@
+// Package authrefdata embeds a snapshot of the AWS Service Authorization Reference, so
+// that Go programs can use the dataset without reading files or going to the network.
+//
+// The snapshot is regenerated by authref scrape with each weekly update, and Version
+// matches the version of the dataset in metadata.json.
+package authrefdata
+
+import (
+	_ "embed"
+	"fmt"
+	"sync"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// The dataset as an authref.v1.Dataset message, written by authref scrape
+//
+//go:embed service-auth.pb
+var data []byte
+
+// Load decodes the embedded dataset. Each call returns a new copy that the caller is free
+// to modify.
+func Load() ([]*authref.ServiceAuthorizationReference, error) {
+	authRefs, err := authref.UnmarshalProto(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("decode embedded dataset %s: %w", Version, err)
+	}
+
+	return authRefs, nil
+}
+
+// MustLoad is like Load, but panics if the embedded dataset can't be decoded.
+func MustLoad() []*authref.ServiceAuthorizationReference {
+	authRefs, err := Load()
+
+	if err != nil {
+		panic(err)
+	}
+
+	return authRefs
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultAuthRefs []*authref.ServiceAuthorizationReference
+	defaultErr      error
+
+	indexOnce sync.Once
+	index     *authref.Index
+)
+
+// Default returns the embedded dataset, decoded on the first call and shared by every
+// caller after that, including Index. It's safe to call from concurrent goroutines, such
+// as the invocations of a Lambda handler, and only the first call pays for decoding. The
+// result must not be modified; use Load for a copy of your own.
+func Default() ([]*authref.ServiceAuthorizationReference, error) {
+	defaultOnce.Do(func() {
+		defaultAuthRefs, defaultErr = Load()
+	})
+
+	return defaultAuthRefs, defaultErr
+}
+
+// Index returns an index over the dataset from Default, for fast lookups of actions and
+// condition keys. Like Default, it's built once and shared. It panics if the embedded
+// dataset can't be decoded.
+func Index() *authref.Index {
+	indexOnce.Do(func() {
+		authRefs, err := Default()
+
+		if err != nil {
+			panic(err)
+		}
+
+		index = authref.NewIndex(authRefs)
+	})
+
+	return index
+}
@@ -0,0 +1,86 @@
+package authrefdata
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// The snapshot published at the root of the repository, which the embedded dataset must
+// have been generated with
+const (
+	publishedDataset  = "../service-auth.json"
+	publishedMetadata = "../metadata.json"
+)
+
+func TestEmbeddedMatchesMetadata(t *testing.T) {
+	metadata, err := authref.LoadMetadataFile(publishedMetadata)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata == nil {
+		t.Fatalf("%s not found", publishedMetadata)
+	}
+
+	if metadata.Version.String() != Version || metadata.GeneratedAt != GeneratedAt {
+		t.Errorf("the embedded dataset is version %s from %s, but %s has %s from %s", Version, GeneratedAt, publishedMetadata, metadata.Version, metadata.GeneratedAt)
+	}
+
+	authRefs, err := Load()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embedded := authref.NewMetadata(authRefs, nil, nil, "")
+
+	if embedded.Services != metadata.Services || embedded.Actions != metadata.Actions ||
+		embedded.ResourceTypes != metadata.ResourceTypes || embedded.ConditionKeys != metadata.ConditionKeys {
+		t.Errorf("the embedded dataset has %d services, %d actions, %d resource types, and %d condition keys, but %s counts %d, %d, %d, and %d",
+			embedded.Services, embedded.Actions, embedded.ResourceTypes, embedded.ConditionKeys, publishedMetadata,
+			metadata.Services, metadata.Actions, metadata.ResourceTypes, metadata.ConditionKeys)
+	}
+}
+
+func TestEmbeddedMatchesDataset(t *testing.T) {
+	metadata, err := authref.LoadMetadataFile(publishedMetadata)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata == nil {
+		t.Fatalf("%s not found", publishedMetadata)
+	}
+
+	checksum, err := authref.SHA256File(publishedDataset)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := metadata.Checksums["service-auth.json"]; checksum != want {
+		t.Errorf("%s has checksum %s, but %s expects %s", publishedDataset, checksum, publishedMetadata, want)
+	}
+
+	published, err := authref.LoadFile(publishedDataset)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Compare by way of the proto encoding, which both sides can make and which ignores
+	// the difference between nil and empty lists
+	embedded, err := Load()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(authref.MarshalProto(embedded), authref.MarshalProto(published)) {
+		t.Errorf("the embedded dataset differs from %s", publishedDataset)
+	}
+}
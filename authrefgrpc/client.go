@@ -0,0 +1,268 @@
+// Package authrefgrpc is a client for the gRPC API that "authref serve --grpc-addr" serves,
+// as described by proto/authref.proto.
+//
+// Like the server, it speaks just enough of the gRPC protocol for the API's unary calls,
+// over HTTP/2 without TLS, and decodes the messages with the authref package, so it doesn't
+// need the grpc and protobuf modules. Clients in other languages can be generated from
+// proto/authref.proto with protoc as usual.
+package authrefgrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+	"golang.org/x/net/http2"
+)
+
+// Service is the full name of the gRPC service in proto/authref.proto.
+const Service = "authref.v1.AuthRef"
+
+// maxResponse limits the size of response messages. GetService and ListActions can be
+// several megabytes for a large service or the whole dataset.
+const maxResponse = 256 * 1024 * 1024
+
+// Error is a call that the server answered with a gRPC status other than OK.
+type Error struct {
+	// The gRPC status code, such as 5 for NOT_FOUND
+	Code    int
+	Message string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("gRPC status %d: %s", err.Code, err.Message)
+}
+
+// ServiceSummary is a service as ListServices describes it.
+type ServiceSummary struct {
+	Name              string
+	ServicePrefix     string
+	AuthReferenceHref string
+	ServicePrincipals []string
+	Actions           int
+	ResourceTypes     int
+	ConditionKeys     int
+	LastUpdated       string
+}
+
+// ActionEntry is an action as ListActions returns it, with the service it belongs to.
+type ActionEntry struct {
+	ServicePrefix string
+	ServiceName   string
+	Action        *authref.Action
+}
+
+// Client calls the AuthRef service on one server.
+type Client struct {
+	address string
+	client  *http.Client
+}
+
+// NewClient returns a client for the server listening at address, such as
+// "localhost:9090".
+func NewClient(address string) *Client {
+	transport := &http2.Transport{
+		// The server speaks HTTP/2 without TLS
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}
+
+	return &Client{address: address, client: &http.Client{Transport: transport}}
+}
+
+// call sends one request message to a method of the service and returns the response
+// message.
+func (c *Client) call(ctx context.Context, method string, request []byte) ([]byte, error) {
+	body := make([]byte, 5, 5+len(request))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(request)))
+	body = append(body, request...)
+
+	r, err := http.NewRequest(http.MethodPost, "http://"+c.address+"/"+Service+"/"+method, bytes.NewReader(body))
+
+	if err != nil {
+		return nil, err
+	}
+
+	r = r.WithContext(ctx)
+	r.Header.Set("Content-Type", "application/grpc")
+	r.Header.Set("TE", "trailers")
+
+	response, err := c.client.Do(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: server returned %s", method, response.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxResponse+5))
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+
+	// The status is in the trailers, or in the headers if the call failed before the
+	// server wrote a message
+	status := response.Trailer.Get("Grpc-Status")
+	message := response.Trailer.Get("Grpc-Message")
+
+	if status == "" {
+		status = response.Header.Get("Grpc-Status")
+		message = response.Header.Get("Grpc-Message")
+	}
+
+	code, err := strconv.Atoi(status)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: the response has no gRPC status", method)
+	}
+
+	if code != 0 {
+		return nil, &Error{Code: code, Message: message}
+	}
+
+	if len(data) < 5 || data[0] != 0 || int(binary.BigEndian.Uint32(data[1:5])) != len(data)-5 {
+		return nil, fmt.Errorf("%s: expected one uncompressed response message", method)
+	}
+
+	return data[5:], nil
+}
+
+// prefixRequest encodes the request of GetService and ListActions, whose only field is
+// a service prefix.
+func prefixRequest(prefix string) []byte {
+	var b authref.ProtoBuffer
+	b.String(1, prefix)
+	return b.Bytes()
+}
+
+// ListServices lists every service with its counts of actions, resource types, and
+// condition keys.
+func (c *Client) ListServices(ctx context.Context) ([]*ServiceSummary, error) {
+	response, err := c.call(ctx, "ListServices", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := authref.ParseProto(response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ServiceSummary, 0, len(fields))
+
+	for _, field := range fields {
+		if field.Number != 1 {
+			continue
+		}
+
+		summaryFields, err := authref.ParseProto(field.Bytes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		summary := &ServiceSummary{ServicePrincipals: []string{}}
+
+		for _, summaryField := range summaryFields {
+			switch summaryField.Number {
+			case 1:
+				summary.Name = string(summaryField.Bytes)
+			case 2:
+				summary.ServicePrefix = string(summaryField.Bytes)
+			case 3:
+				summary.AuthReferenceHref = string(summaryField.Bytes)
+			case 4:
+				summary.ServicePrincipals = append(summary.ServicePrincipals, string(summaryField.Bytes))
+			case 5:
+				summary.Actions = int(summaryField.Varint)
+			case 6:
+				summary.ResourceTypes = int(summaryField.Varint)
+			case 7:
+				summary.ConditionKeys = int(summaryField.Varint)
+			case 8:
+				summary.LastUpdated = string(summaryField.Bytes)
+			}
+		}
+
+		result = append(result, summary)
+	}
+
+	return result, nil
+}
+
+// GetService returns the full record of every page with a service prefix. A prefix the
+// server doesn't know is an *Error with code 5, NOT_FOUND.
+func (c *Client) GetService(ctx context.Context, prefix string) ([]*authref.ServiceAuthorizationReference, error) {
+	response, err := c.call(ctx, "GetService", prefixRequest(prefix))
+
+	if err != nil {
+		return nil, err
+	}
+
+	// GetServiceResponse has the same layout as Dataset
+	return authref.UnmarshalProto(response)
+}
+
+// ListActions lists the actions of a service prefix, or of every service if prefix is "".
+func (c *Client) ListActions(ctx context.Context, prefix string) ([]*ActionEntry, error) {
+	response, err := c.call(ctx, "ListActions", prefixRequest(prefix))
+
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := authref.ParseProto(response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ActionEntry, 0, len(fields))
+
+	for _, field := range fields {
+		if field.Number != 1 {
+			continue
+		}
+
+		entryFields, err := authref.ParseProto(field.Bytes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &ActionEntry{}
+
+		for _, entryField := range entryFields {
+			switch entryField.Number {
+			case 1:
+				entry.ServicePrefix = string(entryField.Bytes)
+			case 2:
+				entry.ServiceName = string(entryField.Bytes)
+			case 3:
+				if entry.Action, err = authref.UnmarshalProtoAction(entryField.Bytes); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+func runAccess(args []string) error {
+	flags := flag.NewFlagSet("access", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	jsonOutput := flags.Bool("json", false, "print the breakdown as JSON")
+	verbose := flags.Bool("v", false, "list the granted actions, not just their counts")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref access [flags] policy.json\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	policy, err := authref.LoadPolicyFile(flags.Arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	access := authref.AnalyzeAccess(authref.AllActions(authRefs), policy)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(access)
+	}
+
+	if err := printSummary(access.ActionSummary, *verbose); err != nil {
+		return err
+	}
+
+	if len(access.WildcardGrants) != 0 {
+		fmt.Printf("\nWildcards that grant permissions management actions:\n")
+
+		for _, grant := range access.WildcardGrants {
+			fmt.Printf("  %s: %s matches %s\n", policy.Statement[grant.Statement].Label(grant.Statement), grant.Pattern, strings.Join(grant.PermissionsManagement, ", "))
+		}
+	}
+
+	return nil
+}
+
+// printSummary prints a table of the actions in each service by access level, followed
+// by the actions themselves if verbose is set.
+func printSummary(summary *authref.ActionSummary, verbose bool) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "PREFIX\tLIST\tREAD\tWRITE\tPERMS\tTAGGING\tNAME\n")
+
+	for _, service := range summary.Services {
+		fmt.Fprintf(w, "%s", service.ServicePrefix)
+
+		for _, level := range authref.AccessLevels {
+			fmt.Fprintf(w, "\t%d", len(service.Actions[level]))
+		}
+
+		fmt.Fprintf(w, "\t%s\n", service.Name)
+	}
+
+	fmt.Fprintf(w, "TOTAL")
+
+	for _, level := range authref.AccessLevels {
+		fmt.Fprintf(w, "\t%d", summary.Totals[level])
+	}
+
+	fmt.Fprintf(w, "\t\n")
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if verbose {
+		for _, service := range summary.Services {
+			fmt.Printf("\n%s:\n", service.ServicePrefix)
+
+			for _, level := range authref.AccessLevels {
+				if names := service.Actions[level]; len(names) != 0 {
+					fmt.Printf("  %s: %s\n", level, strings.Join(names, ", "))
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// accessAdvisorReport is the output of "aws iam get-service-last-accessed-details".
+type accessAdvisorReport struct {
+	JobType              string                  `json:"JobType"`
+	JobCompletionDate    string                  `json:"JobCompletionDate"`
+	ServicesLastAccessed []*accessAdvisorService `json:"ServicesLastAccessed"`
+}
+
+type accessAdvisorService struct {
+	ServiceName       string `json:"ServiceName"`
+	ServiceNamespace  string `json:"ServiceNamespace"`
+	LastAuthenticated string `json:"LastAuthenticated"`
+
+	// Only in reports with a JobType of ACTION_LEVEL, and only for the services IAM tracks
+	// actions for
+	TrackedActionsLastAccessed []*accessAdvisorAction `json:"TrackedActionsLastAccessed"`
+}
+
+type accessAdvisorAction struct {
+	ActionName       string `json:"ActionName"`
+	LastAccessedTime string `json:"LastAccessedTime"`
+}
+
+// advisorService is a service with permissions that went unused, and the actions behind them.
+type advisorService struct {
+	ServicePrefix     string `json:"servicePrefix"`
+	Name              string `json:"name"`
+	LastAuthenticated string `json:"lastAuthenticated,omitempty"`
+
+	// Whether the whole service went unused, rather than just some of its tracked actions
+	Unused bool `json:"unused"`
+
+	// Unused actions by access level: every action in the service, or with a policy, every
+	// action the policy allows. For a service that was used, only the tracked actions that
+	// weren't.
+	Actions map[authref.AccessLevel][]string `json:"actions"`
+}
+
+// Kinds of advisorSuggestion
+const (
+	adviseRemoveStatement = "remove-statement"
+	adviseRemovePattern   = "remove-pattern"
+)
+
+type advisorSuggestion struct {
+	Statement int    `json:"statement"`
+	Sid       string `json:"sid,omitempty"`
+	Kind      string `json:"kind"`
+	Pattern   string `json:"pattern,omitempty"`
+
+	// Unused actions the statement or pattern allows
+	Actions int `json:"actions"`
+}
+
+type advisorResult struct {
+	Since       string               `json:"since"`
+	Services    []*advisorService    `json:"services"`
+	Suggestions []*advisorSuggestion `json:"suggestions"`
+}
+
+// parseAdvisorTime reads a timestamp from an Access Advisor report, which the CLI writes
+// in RFC 3339 with or without fractional seconds.
+func parseAdvisorTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339, value)
+}
+
+// adviseUnused works out which of the actions in the universe went unused according to the
+// report, from since onward, and which statements or patterns of the policy could go
+// as a result. The policy may be nil. Services missing from the report are assumed to be
+// used, since Access Advisor only reports on services some policy grants.
+func adviseUnused(actions []*authref.QualifiedAction, report *accessAdvisorReport, since time.Time, policy *authref.Policy) (*advisorResult, error) {
+	result := &advisorResult{Since: since.Format(time.RFC3339), Services: make([]*advisorService, 0), Suggestions: make([]*advisorSuggestion, 0)}
+	unusedServices := map[string]bool{}
+	unusedActions := map[string]bool{}
+
+	for _, service := range report.ServicesLastAccessed {
+		prefix := strings.ToLower(service.ServiceNamespace)
+		used := false
+
+		if service.LastAuthenticated != "" {
+			t, err := parseAdvisorTime(service.LastAuthenticated)
+
+			if err != nil {
+				return nil, fmt.Errorf("service %s: %w", service.ServiceNamespace, err)
+			}
+
+			used = !t.Before(since)
+		}
+
+		if !used {
+			unusedServices[prefix] = true
+			continue
+		}
+
+		for _, action := range service.TrackedActionsLastAccessed {
+			if action.LastAccessedTime != "" {
+				t, err := parseAdvisorTime(action.LastAccessedTime)
+
+				if err != nil {
+					return nil, fmt.Errorf("action %s:%s: %w", service.ServiceNamespace, action.ActionName, err)
+				}
+
+				if !t.Before(since) {
+					continue
+				}
+			}
+
+			unusedActions[prefix+":"+strings.ToLower(action.ActionName)] = true
+		}
+	}
+
+	isUnused := func(action *authref.QualifiedAction) bool {
+		return unusedServices[strings.ToLower(action.Service.ServicePrefix)] || unusedActions[strings.ToLower(action.String())]
+	}
+
+	candidates := actions
+
+	if policy != nil {
+		candidates = authref.AllowedActions(actions, policy)
+	}
+
+	unused := make([]*authref.QualifiedAction, 0)
+
+	for _, action := range candidates {
+		if isUnused(action) {
+			unused = append(unused, action)
+		}
+	}
+
+	for _, service := range authref.SummarizeActions(unused).Services {
+		entry := &advisorService{ServicePrefix: service.ServicePrefix, Name: service.Name, Unused: unusedServices[strings.ToLower(service.ServicePrefix)], Actions: service.Actions}
+
+		for _, reported := range report.ServicesLastAccessed {
+			if strings.EqualFold(reported.ServiceNamespace, service.ServicePrefix) {
+				entry.LastAuthenticated = reported.LastAuthenticated
+			}
+		}
+
+		result.Services = append(result.Services, entry)
+	}
+
+	if policy == nil {
+		return result, nil
+	}
+
+	countUnused := func(matched []*authref.QualifiedAction) (int, bool) {
+		count := 0
+
+		for _, action := range matched {
+			if isUnused(action) {
+				count++
+			}
+		}
+
+		return count, count != 0 && count == len(matched)
+	}
+
+	for i, statement := range policy.Statement {
+		if statement.Effect != authref.EffectAllow {
+			continue
+		}
+
+		if count, all := countUnused(authref.StatementActions(actions, statement)); all {
+			result.Suggestions = append(result.Suggestions, &advisorSuggestion{Statement: i, Sid: statement.Sid, Kind: adviseRemoveStatement, Actions: count})
+			continue
+		}
+
+		// Patterns of a NotAction statement exclude actions, so removing one would grant more
+		for _, pattern := range statement.Action {
+			if count, all := countUnused(authref.MatchActions(actions, []string{pattern})); all {
+				result.Suggestions = append(result.Suggestions, &advisorSuggestion{Statement: i, Sid: statement.Sid, Kind: adviseRemovePattern, Pattern: pattern, Actions: count})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func runAccessAdvisor(args []string) error {
+	flags := flag.NewFlagSet("access-advisor", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	policyFile := flags.String("policy", "", "policy attached to the entity, to suggest statements and patterns to remove")
+	days := flags.Int("days", 90, "treat permissions not used in this many days before the report as unused")
+	verbose := flags.Bool("v", false, "list the unused actions, not just their counts")
+	jsonOutput := flags.Bool("json", false, "print the unused services and suggestions as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref access-advisor [flags] report.json\n\nreport.json is the output of \"aws iam get-service-last-accessed-details\".\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	var report accessAdvisorReport
+
+	if err := json.Unmarshal(data, &report); err != nil {
+		return fmt.Errorf("%s: %w", flags.Arg(0), err)
+	}
+
+	if report.ServicesLastAccessed == nil {
+		return fmt.Errorf("%s: no ServicesLastAccessed; is this the output of get-service-last-accessed-details?", flags.Arg(0))
+	}
+
+	var policy *authref.Policy
+
+	if *policyFile != "" {
+		if policy, err = authref.LoadPolicyFile(*policyFile); err != nil {
+			return err
+		}
+	}
+
+	// Measure from when the report was made, so an old report gives the same answer
+	asOf := time.Now()
+
+	if report.JobCompletionDate != "" {
+		if asOf, err = parseAdvisorTime(report.JobCompletionDate); err != nil {
+			return fmt.Errorf("%s: %w", flags.Arg(0), err)
+		}
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	result, err := adviseUnused(authref.AllActions(authRefs), &report, asOf.AddDate(0, 0, -*days), policy)
+
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	fmt.Printf("Unused since %s:\n", result.Since)
+
+	for _, service := range result.Services {
+		what, total := "unused actions", 0
+		counts := make([]string, 0, len(authref.AccessLevels))
+
+		if service.Unused {
+			what = "service unused"
+
+			if service.LastAuthenticated == "" {
+				what = "service never used"
+			}
+		}
+
+		for _, level := range authref.AccessLevels {
+			if names := service.Actions[level]; len(names) != 0 {
+				counts = append(counts, fmt.Sprintf("%s %d", level, len(names)))
+				total += len(names)
+			}
+		}
+
+		fmt.Printf("  %-24s %s, %d action(s) (%s)\n", service.ServicePrefix, what, total, strings.Join(counts, ", "))
+
+		if *verbose {
+			for _, level := range authref.AccessLevels {
+				for _, name := range service.Actions[level] {
+					fmt.Printf("    %s:%s (%s)\n", service.ServicePrefix, name, level)
+				}
+			}
+		}
+	}
+
+	if len(result.Suggestions) != 0 {
+		fmt.Printf("\nSuggestions:\n")
+
+		for _, suggestion := range result.Suggestions {
+			label := policy.Statement[suggestion.Statement].Label(suggestion.Statement)
+
+			if suggestion.Kind == adviseRemoveStatement {
+				fmt.Printf("  %s: remove the statement; none of the %d action(s) it allows were used\n", label, suggestion.Actions)
+			} else {
+				fmt.Printf("  %s: remove %s; none of the %d action(s) it matches were used\n", label, suggestion.Pattern, suggestion.Actions)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// statementExpansion lists the actions one statement of a policy applies to.
+type statementExpansion struct {
+	Statement int    `json:"statement"`
+	Sid       string `json:"sid,omitempty"`
+	Effect    string `json:"effect"`
+
+	// The actions matched by Action, or not excluded by NotAction, sorted by name
+	Actions []string `json:"actions"`
+}
+
+// unknownAction is an action pattern in a policy that names no actions in the dataset.
+type unknownAction struct {
+	Statement int    `json:"statement"`
+	Sid       string `json:"sid,omitempty"`
+	Pattern   string `json:"pattern"`
+	Message   string `json:"message"`
+}
+
+// unsupportedConditionKey is a condition key in a statement that none of the statement's
+// actions support, so the condition never applies the way it seems to.
+type unsupportedConditionKey struct {
+	Statement int    `json:"statement"`
+	Sid       string `json:"sid,omitempty"`
+	Key       string `json:"key"`
+	Message   string `json:"message"`
+}
+
+type policyAnalysis struct {
+	Statements []*statementExpansion `json:"statements"`
+
+	// What the policy allows, by service and access level, as with authref access
+	Access *authref.PolicyAccess `json:"access"`
+
+	UnknownActions           []*unknownAction           `json:"unknownActions"`
+	UnsupportedConditionKeys []*unsupportedConditionKey `json:"unsupportedConditionKeys"`
+}
+
+// conditionKeyMatches reports whether a condition key used in a policy, such as
+// "s3:RequestObjectTag/Project", is one the reference defines, such as
+// "s3:RequestObjectTag/${TagKey}". Pages write placeholders as either "${TagKey}" or
+// "<key>". Names are compared without regard to case.
+func conditionKeyMatches(defined, used string) bool {
+	defined, used = strings.ToLower(defined), strings.ToLower(used)
+
+	if i := strings.IndexAny(defined, "$<"); i >= 0 {
+		return len(used) > i && strings.HasPrefix(used, defined[:i])
+	}
+
+	return defined == used
+}
+
+// supportsConditionKey reports whether any of the actions supports a condition key, either
+// for the action or for one of its resource types.
+func supportsConditionKey(actions []*authref.QualifiedAction, key string) bool {
+	for _, action := range actions {
+		for _, defined := range action.Action.ConditionKeys {
+			if conditionKeyMatches(defined, key) {
+				return true
+			}
+		}
+
+		for _, resourceType := range action.Action.ResourceTypes {
+			for _, defined := range resourceType.ConditionKeys {
+				if conditionKeyMatches(defined, key) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// analyzePolicy answers a request to /analyze-policy. Global condition keys, those starting
+// with "aws:", are taken to be supported everywhere, since the reference doesn't list every
+// action that supports them.
+func analyzePolicy(data *dataset, r *http.Request) (*policyAnalysis, error) {
+	body, err := readBody(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := authref.DecodePolicy(bytes.NewReader(body))
+
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+
+	actions := data.index.Actions()
+	result := &policyAnalysis{
+		Statements:               make([]*statementExpansion, 0, len(policy.Statement)),
+		Access:                   authref.AnalyzeAccess(actions, policy),
+		UnknownActions:           make([]*unknownAction, 0),
+		UnsupportedConditionKeys: make([]*unsupportedConditionKey, 0),
+	}
+
+	for i, statement := range policy.Statement {
+		covered := authref.StatementActions(actions, statement)
+		expansion := &statementExpansion{Statement: i, Sid: statement.Sid, Effect: statement.Effect, Actions: make([]string, 0, len(covered))}
+
+		for _, action := range covered {
+			expansion.Actions = append(expansion.Actions, action.String())
+		}
+
+		result.Statements = append(result.Statements, expansion)
+
+		for _, pattern := range append(append([]string{}, statement.Action...), statement.NotAction...) {
+			message := ""
+
+			if err := authref.CheckActionPattern(pattern); err != nil {
+				message = err.Error()
+			} else if len(data.index.Match(pattern)) == 0 {
+				message = fmt.Sprintf("%#v matches no actions", pattern)
+			}
+
+			if message != "" {
+				result.UnknownActions = append(result.UnknownActions, &unknownAction{Statement: i, Sid: statement.Sid, Pattern: pattern, Message: message})
+			}
+		}
+
+		// A key can appear under several operators; report it once
+		keys := make([]string, 0)
+		seen := map[string]bool{}
+
+		for _, values := range statement.Condition {
+			for key := range values {
+				if lower := strings.ToLower(key); !seen[lower] {
+					seen[lower] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if authref.IsGlobalConditionKey(key) || supportsConditionKey(covered, key) {
+				continue
+			}
+
+			result.UnsupportedConditionKeys = append(result.UnsupportedConditionKeys, &unsupportedConditionKey{
+				Statement: i,
+				Sid:       statement.Sid,
+				Key:       key,
+				Message:   fmt.Sprintf("none of the statement's %d actions support %s", len(covered), key),
+			})
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// arnResourceType is a resource type matching an ARN, in the JSON output of actions-for-arn.
+type arnResourceType struct {
+	ServicePrefix string                           `json:"servicePrefix"`
+	ServiceName   string                           `json:"serviceName"`
+	ResourceType  string                           `json:"resourceType"`
+	ArnPattern    string                           `json:"arnPattern"`
+	Actions       map[authref.AccessLevel][]string `json:"actions"`
+
+	// Actions that can't be used without naming a resource of this type, or of another type
+	// in the same group of required resource types
+	Required []string `json:"required"`
+}
+
+func runActionsForArn(args []string) error {
+	flags := flag.NewFlagSet("actions-for-arn", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	verbose := flags.Bool("v", false, "list every action instead of counting them")
+	jsonOutput := flags.Bool("json", false, "print the matching resource types and their actions as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref actions-for-arn [flags] arn\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	arn := flags.Arg(0)
+
+	if authref.ArnNamespace(arn) == "" {
+		return fmt.Errorf("%#v isn't an ARN", arn)
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	matches, err := authref.MatchArn(authRefs, arn)
+
+	if err != nil {
+		return err
+	}
+
+	if len(matches) == 0 {
+		return fmt.Errorf("no resource type's ARN pattern matches %s", arn)
+	}
+
+	result := make([]*arnResourceType, len(matches))
+
+	for i, match := range matches {
+		entry := &arnResourceType{
+			ServicePrefix: match.Service.ServicePrefix,
+			ServiceName:   match.Service.Name,
+			ResourceType:  match.ResourceType.Name,
+			ArnPattern:    match.ResourceType.ArnPattern,
+			Actions:       map[authref.AccessLevel][]string{},
+			Required:      make([]string, 0),
+		}
+
+		for _, action := range match.Actions {
+			entry.Actions[action.Action.AccessLevel] = append(entry.Actions[action.Action.AccessLevel], action.String())
+
+			for _, group := range action.Action.RequiredResourceGroups() {
+				for _, resourceType := range group {
+					if resourceType == match.ResourceType.Name {
+						entry.Required = append(entry.Required, action.String())
+					}
+				}
+			}
+		}
+
+		result[i] = entry
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	for i, entry := range result {
+		if i != 0 {
+			fmt.Println()
+		}
+
+		fmt.Printf("%s %s (%s)\n", entry.ServicePrefix, entry.ResourceType, entry.ArnPattern)
+
+		for _, level := range authref.AccessLevels {
+			names := entry.Actions[level]
+
+			if len(names) == 0 {
+				continue
+			}
+
+			if *verbose {
+				fmt.Printf("  %s: %s\n", level, strings.Join(names, ", "))
+			} else {
+				fmt.Printf("  %-24s %d\n", level, len(names))
+			}
+		}
+	}
+
+	if len(result) > 1 {
+		fmt.Fprintf(os.Stderr, "\n%d resource types match, most specific first\n", len(result))
+	}
+
+	return nil
+}
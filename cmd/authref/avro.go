@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// avroSchema describes the records in actions.avro: one per action, with the service it
+// belongs to. Access levels are strings rather than an enum, since Avro enum symbols
+// can't contain spaces.
+const avroSchema = `{
+  "type": "record",
+  "name": "Action",
+  "namespace": "com.fluggo.authref",
+  "doc": "An action from the AWS Service Authorization Reference",
+  "fields": [
+    {"name": "servicePrefix", "type": "string"},
+    {"name": "serviceName", "type": "string"},
+    {"name": "name", "type": "string"},
+    {"name": "permissionOnly", "type": "boolean"},
+    {"name": "annotations", "type": {"type": "array", "items": "string"}},
+    {"name": "referenceHref", "type": "string", "default": ""},
+    {"name": "description", "type": "string"},
+    {"name": "accessLevel", "type": "string"},
+    {"name": "resourceTypes", "type": {"type": "array", "items": {
+      "type": "record",
+      "name": "ActionResourceType",
+      "fields": [
+        {"name": "resourceType", "type": "string"},
+        {"name": "required", "type": "boolean"},
+        {"name": "conditionKeys", "type": {"type": "array", "items": "string"}},
+        {"name": "dependentActions", "type": {"type": "array", "items": "string"}},
+        {"name": "requiredGroup", "type": "int", "default": 0}
+      ]
+    }}},
+    {"name": "conditionKeys", "type": {"type": "array", "items": "string"}},
+    {"name": "localizedDescriptions", "type": {"type": "map", "values": "string"}, "default": {}}
+  ]
+}
+`
+
+// avroBlockSize is the number of records in each block of the container file.
+const avroBlockSize = 1000
+
+func avroLong(buf *bytes.Buffer, value int64) {
+	zigzag := uint64(value<<1) ^ uint64(value>>63)
+
+	for zigzag >= 0x80 {
+		buf.WriteByte(byte(zigzag) | 0x80)
+		zigzag >>= 7
+	}
+
+	buf.WriteByte(byte(zigzag))
+}
+
+func avroString(buf *bytes.Buffer, value string) {
+	avroLong(buf, int64(len(value)))
+	buf.WriteString(value)
+}
+
+func avroBool(buf *bytes.Buffer, value bool) {
+	if value {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+}
+
+func avroStrings(buf *bytes.Buffer, values []string) {
+	if len(values) != 0 {
+		avroLong(buf, int64(len(values)))
+
+		for _, value := range values {
+			avroString(buf, value)
+		}
+	}
+
+	avroLong(buf, 0)
+}
+
+// avroStringMap writes a map of strings as a single block, in key order.
+func avroStringMap(buf *bytes.Buffer, values map[string]string) {
+	if len(values) != 0 {
+		keys := make([]string, 0, len(values))
+
+		for key := range values {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		avroLong(buf, int64(len(keys)))
+
+		for _, key := range keys {
+			avroString(buf, key)
+			avroString(buf, values[key])
+		}
+	}
+
+	avroLong(buf, 0)
+}
+
+func avroAction(buf *bytes.Buffer, action *authref.QualifiedAction) {
+	avroString(buf, action.Service.ServicePrefix)
+	avroString(buf, action.Service.Name)
+	avroString(buf, action.Action.Name)
+	avroBool(buf, action.Action.PermissionOnly)
+	avroStrings(buf, action.Action.Annotations)
+	avroString(buf, action.Action.ReferenceHref)
+	avroString(buf, action.Action.Description)
+	avroString(buf, string(action.Action.AccessLevel))
+
+	if len(action.Action.ResourceTypes) != 0 {
+		avroLong(buf, int64(len(action.Action.ResourceTypes)))
+
+		for _, resourceType := range action.Action.ResourceTypes {
+			avroString(buf, resourceType.ResourceType)
+			avroBool(buf, resourceType.Required)
+			avroStrings(buf, resourceType.ConditionKeys)
+			avroStrings(buf, resourceType.DependentActions)
+			avroLong(buf, int64(resourceType.RequiredGroup))
+		}
+	}
+
+	avroLong(buf, 0)
+	avroStrings(buf, action.Action.ConditionKeys)
+	avroStringMap(buf, action.Action.LocalizedDescriptions)
+}
+
+// exportAvro writes the actions as an Avro object container file, along with its schema.
+// See https://avro.apache.org/docs/current/specification/#object-container-files
+func exportAvro(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	actions := authref.AllActions(authRefs)
+	blocks := make([][]byte, 0)
+
+	for start := 0; start < len(actions); start += avroBlockSize {
+		end := start + avroBlockSize
+
+		if end > len(actions) {
+			end = len(actions)
+		}
+
+		var block bytes.Buffer
+
+		for _, action := range actions[start:end] {
+			avroAction(&block, action)
+		}
+
+		blocks = append(blocks, block.Bytes())
+	}
+
+	// The sync marker only has to be unlikely to appear in the data; deriving it from the
+	// data rather than choosing it at random keeps the output reproducible
+	hash := sha256.New()
+
+	for _, block := range blocks {
+		hash.Write(block)
+	}
+
+	sync := hash.Sum(nil)[:16]
+
+	var schema bytes.Buffer
+
+	if err := json.Compact(&schema, []byte(avroSchema)); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("Obj\x01")
+	avroLong(&buf, 2)
+	avroString(&buf, "avro.schema")
+	avroString(&buf, schema.String())
+	avroString(&buf, "avro.codec")
+	avroString(&buf, "null")
+	avroLong(&buf, 0)
+	buf.Write(sync)
+
+	for i, block := range blocks {
+		count := avroBlockSize
+
+		if i == len(blocks)-1 {
+			count = len(actions) - i*avroBlockSize
+		}
+
+		avroLong(&buf, int64(count))
+		avroLong(&buf, int64(len(block)))
+		buf.Write(block)
+		buf.Write(sync)
+	}
+
+	dataFile := filepath.Join(dir, "actions.avro")
+	schemaFile := filepath.Join(dir, "actions.avsc")
+
+	if err := os.WriteFile(dataFile, buf.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(schemaFile, []byte(avroSchema), 0644); err != nil {
+		return nil, err
+	}
+
+	return []string{dataFile, schemaFile}, nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// avroReader decodes Avro binary data as described by a schema, independently of the
+// writer in avro.go.
+type avroReader struct {
+	data  []byte
+	err   error
+	named map[string]interface{}
+}
+
+func (r *avroReader) fail(format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (r *avroReader) next(n int) []byte {
+	if r.err != nil || n < 0 || len(r.data) < n {
+		r.fail("unexpected end of data")
+		return nil
+	}
+
+	result := r.data[:n]
+	r.data = r.data[n:]
+	return result
+}
+
+func (r *avroReader) long() int64 {
+	var zigzag uint64
+
+	for shift := uint(0); shift < 64; shift += 7 {
+		b := r.next(1)
+
+		if b == nil {
+			return 0
+		}
+
+		zigzag |= uint64(b[0]&0x7f) << shift
+
+		if b[0]&0x80 == 0 {
+			return int64(zigzag>>1) ^ -int64(zigzag&1)
+		}
+	}
+
+	r.fail("varint too long")
+	return 0
+}
+
+// blocks reads the blocks of an array or map, calling item for each entry.
+func (r *avroReader) blocks(item func()) {
+	for r.err == nil {
+		count := r.long()
+
+		if count == 0 {
+			return
+		}
+
+		if count < 0 {
+			// A negative count is followed by the size of the block in bytes
+			count = -count
+			r.long()
+		}
+
+		for i := int64(0); i < count && r.err == nil; i++ {
+			item()
+		}
+	}
+}
+
+func (r *avroReader) value(schema interface{}) interface{} {
+	switch schema := schema.(type) {
+	case string:
+		switch schema {
+		case "string":
+			return string(r.next(int(r.long())))
+		case "boolean":
+			b := r.next(1)
+			return b != nil && b[0] == 1
+		case "int", "long":
+			return r.long()
+		}
+
+		if named, ok := r.named[schema]; ok {
+			return r.value(named)
+		}
+	case map[string]interface{}:
+		switch schema["type"] {
+		case "record":
+			r.named[schema["name"].(string)] = schema
+			result := map[string]interface{}{}
+
+			for _, field := range schema["fields"].([]interface{}) {
+				field := field.(map[string]interface{})
+				result[field["name"].(string)] = r.value(field["type"])
+			}
+
+			return result
+		case "array":
+			result := []interface{}{}
+			r.blocks(func() { result = append(result, r.value(schema["items"])) })
+			return result
+		case "map":
+			result := map[string]interface{}{}
+			r.blocks(func() {
+				key := r.value("string").(string)
+				result[key] = r.value(schema["values"])
+			})
+			return result
+		}
+	}
+
+	r.fail("unsupported schema %v", schema)
+	return nil
+}
+
+// readAvroContainer reads an object container file, returning its metadata and records.
+func readAvroContainer(t *testing.T, data []byte) (map[string]string, []interface{}) {
+	r := &avroReader{data: data, named: map[string]interface{}{}}
+
+	if magic := r.next(4); !bytes.Equal(magic, []byte("Obj\x01")) {
+		t.Fatalf("magic is %q", magic)
+	}
+
+	metadata := map[string]string{}
+	r.blocks(func() {
+		key := r.value("string").(string)
+		metadata[key] = r.value("string").(string)
+	})
+	sync := r.next(16)
+
+	if r.err != nil {
+		t.Fatalf("header: %v", r.err)
+	}
+
+	var schema interface{}
+
+	if err := json.Unmarshal([]byte(metadata["avro.schema"]), &schema); err != nil {
+		t.Fatalf("avro.schema: %v", err)
+	}
+
+	records := make([]interface{}, 0)
+
+	for len(r.data) != 0 {
+		count, size := r.long(), r.long()
+		block := &avroReader{data: r.next(int(size)), named: r.named}
+
+		for i := int64(0); i < count && block.err == nil; i++ {
+			records = append(records, block.value(schema))
+		}
+
+		if block.err != nil {
+			t.Fatalf("block %d: %v", len(records)/avroBlockSize, block.err)
+		}
+
+		if len(block.data) != 0 {
+			t.Fatalf("block %d has %d bytes after its %d records", len(records)/avroBlockSize, len(block.data), count)
+		}
+
+		if marker := r.next(16); !bytes.Equal(marker, sync) {
+			t.Fatalf("block %d ends with % x instead of the sync marker", len(records)/avroBlockSize, marker)
+		}
+	}
+
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+
+	return metadata, records
+}
+
+func avroTestStrings(values []string) []interface{} {
+	result := []interface{}{}
+
+	for _, value := range values {
+		result = append(result, value)
+	}
+
+	return result
+}
+
+func TestExportAvro(t *testing.T) {
+	authRefs := []*authref.ServiceAuthorizationReference{
+		{
+			Name:          "Amazon S3",
+			ServicePrefix: "s3",
+			Actions: []*authref.Action{
+				{
+					Name:          "GetObject",
+					Annotations:   []string{"Deprecated"},
+					ReferenceHref: "https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html",
+					Description:   "Grants permission to retrieve objects",
+					AccessLevel:   authref.AccessLevelRead,
+					ResourceTypes: []authref.ActionResourceType{
+						{ResourceType: "object", Required: true, RequiredGroup: 1, ConditionKeys: []string{"s3:ExistingObjectTag/<key>"}, DependentActions: []string{"kms:Decrypt"}},
+						{ResourceType: "accesspoint"},
+					},
+					ConditionKeys:         []string{"aws:ResourceTag/${TagKey}", "s3:authType"},
+					LocalizedDescriptions: map[string]string{"ja_jp": "オブジェクトを取得する", "de_de": "Objekte abrufen"},
+				},
+				{Name: "PassRole", AccessLevel: authref.AccessLevelWrite, PermissionOnly: true},
+			},
+		},
+		{Name: "Amazon EC2", ServicePrefix: "ec2"},
+	}
+
+	// Enough actions for several blocks
+	for i := 0; i < 2*avroBlockSize+1; i++ {
+		authRefs[1].Actions = append(authRefs[1].Actions, &authref.Action{Name: fmt.Sprintf("Action%04d", i), AccessLevel: authref.AccessLevelList})
+	}
+
+	dir := t.TempDir()
+
+	if _, err := exportAvro(dir, authRefs); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "actions.avro"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, records := readAvroContainer(t, data)
+
+	if metadata["avro.codec"] != "null" {
+		t.Errorf("avro.codec is %q", metadata["avro.codec"])
+	}
+
+	var headerSchema, fileSchema interface{}
+	schemaText, err := os.ReadFile(filepath.Join(dir, "actions.avsc"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if json.Unmarshal([]byte(metadata["avro.schema"]), &headerSchema) != nil || json.Unmarshal(schemaText, &fileSchema) != nil || !reflect.DeepEqual(headerSchema, fileSchema) {
+		t.Errorf("the schema in actions.avro doesn't match actions.avsc")
+	}
+
+	actions := authref.AllActions(authRefs)
+
+	if len(records) != len(actions) {
+		t.Fatalf("actions.avro has %d records, want %d", len(records), len(actions))
+	}
+
+	for i, action := range actions {
+		resourceTypes := []interface{}{}
+
+		for _, resourceType := range action.Action.ResourceTypes {
+			resourceTypes = append(resourceTypes, map[string]interface{}{
+				"resourceType":     resourceType.ResourceType,
+				"required":         resourceType.Required,
+				"conditionKeys":    avroTestStrings(resourceType.ConditionKeys),
+				"dependentActions": avroTestStrings(resourceType.DependentActions),
+				"requiredGroup":    int64(resourceType.RequiredGroup),
+			})
+		}
+
+		localizedDescriptions := map[string]interface{}{}
+
+		for locale, description := range action.Action.LocalizedDescriptions {
+			localizedDescriptions[locale] = description
+		}
+
+		want := map[string]interface{}{
+			"servicePrefix":         action.Service.ServicePrefix,
+			"serviceName":           action.Service.Name,
+			"name":                  action.Action.Name,
+			"permissionOnly":        action.Action.PermissionOnly,
+			"annotations":           avroTestStrings(action.Action.Annotations),
+			"referenceHref":         action.Action.ReferenceHref,
+			"description":           action.Action.Description,
+			"accessLevel":           string(action.Action.AccessLevel),
+			"resourceTypes":         resourceTypes,
+			"conditionKeys":         avroTestStrings(action.Action.ConditionKeys),
+			"localizedDescriptions": localizedDescriptions,
+		}
+
+		if !reflect.DeepEqual(records[i], want) {
+			t.Errorf("record %d is\n%v\nwant\n%v", i, records[i], want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// bigQuerySchema is the table schema for actions.ndjson, in the format accepted by
+// "bq load --schema".
+const bigQuerySchema = `[
+  {"name": "servicePrefix", "type": "STRING", "mode": "REQUIRED"},
+  {"name": "serviceName", "type": "STRING", "mode": "REQUIRED"},
+  {"name": "action", "type": "STRING", "mode": "REQUIRED", "description": "Action as written in a policy, such as s3:GetObject"},
+  {"name": "name", "type": "STRING", "mode": "REQUIRED"},
+  {"name": "permissionOnly", "type": "BOOLEAN", "mode": "REQUIRED"},
+  {"name": "annotations", "type": "STRING", "mode": "REPEATED"},
+  {"name": "referenceHref", "type": "STRING", "mode": "NULLABLE"},
+  {"name": "description", "type": "STRING", "mode": "REQUIRED"},
+  {"name": "accessLevel", "type": "STRING", "mode": "REQUIRED"},
+  {"name": "resourceTypes", "type": "RECORD", "mode": "REPEATED", "fields": [
+    {"name": "resourceType", "type": "STRING", "mode": "REQUIRED"},
+    {"name": "required", "type": "BOOLEAN", "mode": "REQUIRED"},
+    {"name": "conditionKeys", "type": "STRING", "mode": "REPEATED"},
+    {"name": "dependentActions", "type": "STRING", "mode": "REPEATED"},
+    {"name": "requiredGroup", "type": "INTEGER", "mode": "NULLABLE"}
+  ]},
+  {"name": "conditionKeys", "type": "STRING", "mode": "REPEATED"},
+  {"name": "localizedDescriptions", "type": "RECORD", "mode": "REPEATED", "description": "Translations of description", "fields": [
+    {"name": "locale", "type": "STRING", "mode": "REQUIRED", "description": "Documentation locale, such as ja_jp"},
+    {"name": "description", "type": "STRING", "mode": "REQUIRED"}
+  ]}
+]
+`
+
+// bigQueryAction is one row of actions.ndjson. BigQuery doesn't accept null for repeated
+// fields, so lists are always present.
+type bigQueryAction struct {
+	ServicePrefix  string                       `json:"servicePrefix"`
+	ServiceName    string                       `json:"serviceName"`
+	QualifiedName  string                       `json:"action"`
+	Name           string                       `json:"name"`
+	PermissionOnly bool                         `json:"permissionOnly"`
+	Annotations    []string                     `json:"annotations"`
+	ReferenceHref  string                       `json:"referenceHref,omitempty"`
+	Description    string                       `json:"description"`
+	AccessLevel    authref.AccessLevel          `json:"accessLevel"`
+	ResourceTypes  []authref.ActionResourceType `json:"resourceTypes"`
+	ConditionKeys  []string                     `json:"conditionKeys"`
+
+	// BigQuery has no map type, so translations are a list of records in locale order
+	LocalizedDescriptions []bigQueryTranslation `json:"localizedDescriptions"`
+}
+
+type bigQueryTranslation struct {
+	Locale      string `json:"locale"`
+	Description string `json:"description"`
+}
+
+// bigQueryTranslations lists the translations of a description in locale order.
+func bigQueryTranslations(descriptions map[string]string) []bigQueryTranslation {
+	result := make([]bigQueryTranslation, 0, len(descriptions))
+
+	for locale, description := range descriptions {
+		result = append(result, bigQueryTranslation{Locale: locale, Description: description})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Locale < result[j].Locale })
+	return result
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
+
+// newBigQueryAction flattens an action into a row of actions.ndjson.
+func newBigQueryAction(action *authref.QualifiedAction) *bigQueryAction {
+	row := &bigQueryAction{
+		ServicePrefix:  action.Service.ServicePrefix,
+		ServiceName:    action.Service.Name,
+		QualifiedName:  action.String(),
+		Name:           action.Action.Name,
+		PermissionOnly: action.Action.PermissionOnly,
+		Annotations:    nonNil(action.Action.Annotations),
+		ReferenceHref:  action.Action.ReferenceHref,
+		Description:    action.Action.Description,
+		AccessLevel:    action.Action.AccessLevel,
+		ResourceTypes:  make([]authref.ActionResourceType, len(action.Action.ResourceTypes)),
+		ConditionKeys:  nonNil(action.Action.ConditionKeys),
+
+		LocalizedDescriptions: bigQueryTranslations(action.Action.LocalizedDescriptions),
+	}
+
+	for i, resourceType := range action.Action.ResourceTypes {
+		resourceType.ConditionKeys = nonNil(resourceType.ConditionKeys)
+		resourceType.DependentActions = nonNil(resourceType.DependentActions)
+		row.ResourceTypes[i] = resourceType
+	}
+
+	return row
+}
+
+// exportBigQuery writes the actions as newline-delimited JSON, along with the matching
+// table schema.
+func exportBigQuery(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	dataFile := filepath.Join(dir, "actions.ndjson")
+	schemaFile := filepath.Join(dir, "actions.bigquery.json")
+
+	file, err := os.Create(dataFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+
+	for _, action := range authref.AllActions(authRefs) {
+		if err := encoder.Encode(newBigQueryAction(action)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(schemaFile, []byte(bigQuerySchema), 0644); err != nil {
+		return nil, err
+	}
+
+	return []string{dataFile, schemaFile}, nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// encodeMsgpack appends the MessagePack encoding of a node to buf, using the smallest
+// representation for each value. See https://github.com/msgpack/msgpack/blob/master/spec.md
+func encodeMsgpack(buf *bytes.Buffer, n *node) {
+	sized := func(length int, fix, fixMax byte, base8, base16, base32 byte) {
+		switch {
+		case length <= int(fixMax):
+			buf.WriteByte(fix | byte(length))
+		case base8 != 0 && length <= math.MaxUint8:
+			buf.WriteByte(base8)
+			buf.WriteByte(byte(length))
+		case length <= math.MaxUint16:
+			buf.WriteByte(base16)
+			binary.Write(buf, binary.BigEndian, uint16(length))
+		default:
+			buf.WriteByte(base32)
+			binary.Write(buf, binary.BigEndian, uint32(length))
+		}
+	}
+
+	switch n.kind {
+	case nodeNull:
+		buf.WriteByte(0xc0)
+	case nodeBool:
+		if n.bool {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case nodeInt:
+		switch {
+		case n.int >= 0 && n.int <= 0x7f:
+			buf.WriteByte(byte(n.int))
+		case n.int < 0 && n.int >= -32:
+			buf.WriteByte(byte(n.int))
+		case n.int >= 0 && n.int <= math.MaxUint8:
+			buf.WriteByte(0xcc)
+			buf.WriteByte(byte(n.int))
+		case n.int >= 0 && n.int <= math.MaxUint16:
+			buf.WriteByte(0xcd)
+			binary.Write(buf, binary.BigEndian, uint16(n.int))
+		case n.int >= 0 && n.int <= math.MaxUint32:
+			buf.WriteByte(0xce)
+			binary.Write(buf, binary.BigEndian, uint32(n.int))
+		case n.int >= 0:
+			buf.WriteByte(0xcf)
+			binary.Write(buf, binary.BigEndian, uint64(n.int))
+		case n.int >= math.MinInt8:
+			buf.WriteByte(0xd0)
+			buf.WriteByte(byte(n.int))
+		case n.int >= math.MinInt16:
+			buf.WriteByte(0xd1)
+			binary.Write(buf, binary.BigEndian, int16(n.int))
+		case n.int >= math.MinInt32:
+			buf.WriteByte(0xd2)
+			binary.Write(buf, binary.BigEndian, int32(n.int))
+		default:
+			buf.WriteByte(0xd3)
+			binary.Write(buf, binary.BigEndian, n.int)
+		}
+	case nodeFloat:
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, n.float)
+	case nodeString:
+		sized(len(n.string), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buf.WriteString(n.string)
+	case nodeArray:
+		sized(len(n.values), 0x90, 15, 0, 0xdc, 0xdd)
+
+		for _, value := range n.values {
+			encodeMsgpack(buf, value)
+		}
+	case nodeObject:
+		sized(len(n.values), 0x80, 15, 0, 0xde, 0xdf)
+
+		for i, value := range n.values {
+			encodeMsgpack(buf, &node{kind: nodeString, string: n.keys[i]})
+			encodeMsgpack(buf, value)
+		}
+	}
+}
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborSimple = 7
+)
+
+// cborHead appends the initial bytes of a CBOR data item of the given major type.
+func cborHead(buf *bytes.Buffer, major byte, value uint64) {
+	switch {
+	case value < 24:
+		buf.WriteByte(major<<5 | byte(value))
+	case value <= math.MaxUint8:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(value))
+	case value <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		binary.Write(buf, binary.BigEndian, uint16(value))
+	case value <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		binary.Write(buf, binary.BigEndian, uint32(value))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		binary.Write(buf, binary.BigEndian, value)
+	}
+}
+
+// encodeCBOR appends the CBOR encoding (RFC 8949) of a node to buf, using definite
+// lengths throughout.
+func encodeCBOR(buf *bytes.Buffer, n *node) {
+	switch n.kind {
+	case nodeNull:
+		buf.WriteByte(cborSimple<<5 | 22)
+	case nodeBool:
+		if n.bool {
+			buf.WriteByte(cborSimple<<5 | 21)
+		} else {
+			buf.WriteByte(cborSimple<<5 | 20)
+		}
+	case nodeInt:
+		if n.int >= 0 {
+			cborHead(buf, cborUint, uint64(n.int))
+		} else {
+			cborHead(buf, cborNegInt, uint64(-(n.int + 1)))
+		}
+	case nodeFloat:
+		buf.WriteByte(cborSimple<<5 | 27)
+		binary.Write(buf, binary.BigEndian, n.float)
+	case nodeString:
+		cborHead(buf, cborText, uint64(len(n.string)))
+		buf.WriteString(n.string)
+	case nodeArray:
+		cborHead(buf, cborArray, uint64(len(n.values)))
+
+		for _, value := range n.values {
+			encodeCBOR(buf, value)
+		}
+	case nodeObject:
+		cborHead(buf, cborMap, uint64(len(n.values)))
+
+		for i, value := range n.values {
+			cborHead(buf, cborText, uint64(len(n.keys[i])))
+			buf.WriteString(n.keys[i])
+			encodeCBOR(buf, value)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// binaryReader reads the big-endian values of a MessagePack or CBOR encoding.
+type binaryReader struct {
+	data []byte
+	err  error
+}
+
+func (r *binaryReader) next(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+
+	if len(r.data) < n {
+		r.err = fmt.Errorf("unexpected end of data")
+		return make([]byte, n)
+	}
+
+	result := r.data[:n]
+	r.data = r.data[n:]
+	return result
+}
+
+func (r *binaryReader) byte() byte {
+	return r.next(1)[0]
+}
+
+func (r *binaryReader) uint(size int) uint64 {
+	b := r.next(size)
+
+	switch size {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b))
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b))
+	default:
+		return binary.BigEndian.Uint64(b)
+	}
+}
+
+// decodeMsgpack reads back a node written by encodeMsgpack.
+func decodeMsgpack(r *binaryReader) *node {
+	b := r.byte()
+
+	sized := func(kind int, length int) *node {
+		n := &node{kind: kind}
+
+		switch kind {
+		case nodeString:
+			n.string = string(r.next(length))
+		case nodeArray:
+			for i := 0; i < length && r.err == nil; i++ {
+				n.values = append(n.values, decodeMsgpack(r))
+			}
+		case nodeObject:
+			for i := 0; i < length && r.err == nil; i++ {
+				n.keys = append(n.keys, decodeMsgpack(r).string)
+				n.values = append(n.values, decodeMsgpack(r))
+			}
+		}
+
+		return n
+	}
+
+	switch {
+	case b <= 0x7f:
+		return &node{kind: nodeInt, int: int64(b)}
+	case b >= 0xe0:
+		return &node{kind: nodeInt, int: int64(int8(b))}
+	case b&0xe0 == 0xa0:
+		return sized(nodeString, int(b&0x1f))
+	case b&0xf0 == 0x90:
+		return sized(nodeArray, int(b&0x0f))
+	case b&0xf0 == 0x80:
+		return sized(nodeObject, int(b&0x0f))
+	}
+
+	switch b {
+	case 0xc0:
+		return &node{kind: nodeNull}
+	case 0xc2, 0xc3:
+		return &node{kind: nodeBool, bool: b == 0xc3}
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return &node{kind: nodeInt, int: int64(r.uint(1 << (b - 0xcc)))}
+	case 0xd0:
+		return &node{kind: nodeInt, int: int64(int8(r.uint(1)))}
+	case 0xd1:
+		return &node{kind: nodeInt, int: int64(int16(r.uint(2)))}
+	case 0xd2:
+		return &node{kind: nodeInt, int: int64(int32(r.uint(4)))}
+	case 0xd3:
+		return &node{kind: nodeInt, int: int64(r.uint(8))}
+	case 0xcb:
+		return &node{kind: nodeFloat, float: math.Float64frombits(r.uint(8))}
+	case 0xd9, 0xda, 0xdb:
+		return sized(nodeString, int(r.uint(1<<(b-0xd9))))
+	case 0xdc, 0xdd:
+		return sized(nodeArray, int(r.uint(2<<(b-0xdc))))
+	case 0xde, 0xdf:
+		return sized(nodeObject, int(r.uint(2<<(b-0xde))))
+	}
+
+	r.err = fmt.Errorf("unexpected MessagePack type 0x%02x", b)
+	return &node{}
+}
+
+// decodeCBOR reads back a node written by encodeCBOR.
+func decodeCBOR(r *binaryReader) *node {
+	b := r.byte()
+	major, info := b>>5, b&0x1f
+	value := uint64(info)
+
+	switch {
+	case major == cborSimple:
+		switch info {
+		case 20, 21:
+			return &node{kind: nodeBool, bool: info == 21}
+		case 22:
+			return &node{kind: nodeNull}
+		case 27:
+			return &node{kind: nodeFloat, float: math.Float64frombits(r.uint(8))}
+		}
+
+		r.err = fmt.Errorf("unexpected CBOR simple value %d", info)
+		return &node{}
+	case info >= 24 && info <= 27:
+		value = r.uint(1 << (info - 24))
+	case info > 27:
+		r.err = fmt.Errorf("unexpected CBOR length 0x%02x", b)
+		return &node{}
+	}
+
+	switch major {
+	case cborUint:
+		return &node{kind: nodeInt, int: int64(value)}
+	case cborNegInt:
+		return &node{kind: nodeInt, int: -1 - int64(value)}
+	case cborText:
+		return &node{kind: nodeString, string: string(r.next(int(value)))}
+	case cborArray:
+		n := &node{kind: nodeArray}
+
+		for i := uint64(0); i < value && r.err == nil; i++ {
+			n.values = append(n.values, decodeCBOR(r))
+		}
+
+		return n
+	case cborMap:
+		n := &node{kind: nodeObject}
+
+		for i := uint64(0); i < value && r.err == nil; i++ {
+			n.keys = append(n.keys, decodeCBOR(r).string)
+			n.values = append(n.values, decodeCBOR(r))
+		}
+
+		return n
+	}
+
+	r.err = fmt.Errorf("unexpected CBOR major type %d", major)
+	return &node{}
+}
+
+func TestBinaryIntegers(t *testing.T) {
+	// Each integer with the size of its smallest MessagePack and CBOR encodings
+	tests := []struct {
+		value         int64
+		msgpack, cbor int
+	}{
+		{0, 1, 1},
+		{23, 1, 1},
+		{24, 1, 2},
+		{127, 1, 2},
+		{128, 2, 2},
+		{255, 2, 2},
+		{256, 3, 3},
+		{65535, 3, 3},
+		{65536, 5, 5},
+		{math.MaxUint32, 5, 5},
+		{math.MaxUint32 + 1, 9, 9},
+		{math.MaxInt64, 9, 9},
+		{-1, 1, 1},
+		{-24, 1, 1},
+		{-25, 1, 2},
+		{-32, 1, 2},
+		{-33, 2, 2},
+		{-128, 2, 2},
+		{-129, 3, 2},
+		{-256, 3, 2},
+		{-257, 3, 3},
+		{math.MinInt16, 3, 3},
+		{math.MinInt16 - 1, 5, 3},
+		{math.MinInt32, 5, 5},
+		{math.MinInt32 - 1, 9, 5},
+		{math.MinInt64, 9, 9},
+	}
+
+	for _, test := range tests {
+		n := &node{kind: nodeInt, int: test.value}
+
+		for _, format := range []struct {
+			name   string
+			encode func(buf *bytes.Buffer, n *node)
+			decode func(r *binaryReader) *node
+			size   int
+		}{
+			{"msgpack", encodeMsgpack, decodeMsgpack, test.msgpack},
+			{"cbor", encodeCBOR, decodeCBOR, test.cbor},
+		} {
+			var buf bytes.Buffer
+			format.encode(&buf, n)
+
+			if buf.Len() != format.size {
+				t.Errorf("%s of %d is % x, want %d bytes", format.name, test.value, buf.Bytes(), format.size)
+			}
+
+			r := &binaryReader{data: buf.Bytes()}
+
+			if got := format.decode(r); r.err != nil || len(r.data) != 0 || got.kind != nodeInt || got.int != test.value {
+				t.Errorf("%s of %d (% x) decoded to %+v (%v)", format.name, test.value, buf.Bytes(), got, r.err)
+			}
+		}
+	}
+}
+
+func TestExportBinaryRoundTrip(t *testing.T) {
+	authRefs := []*authref.ServiceAuthorizationReference{
+		{
+			Name:          "Amazon S3",
+			ServicePrefix: "s3",
+			Actions: []*authref.Action{
+				{
+					Name:        "GetObject",
+					AccessLevel: authref.AccessLevelRead,
+					Description: "Grants permission to retrieve objects, with a description longer than thirty-one bytes",
+					ResourceTypes: []authref.ActionResourceType{
+						{ResourceType: "object", Required: true, RequiredGroup: 1, ConditionKeys: []string{"s3:ExistingObjectTag/<key>"}},
+						{ResourceType: "accesspoint", RequiredGroup: 300},
+					},
+				},
+				{Name: "PutObject", AccessLevel: authref.AccessLevelWrite},
+			},
+			ResourceTypes: []*authref.ResourceType{{Name: "object", ArnPattern: "arn:${Partition}:s3:::${BucketName}/${ObjectName}"}},
+		},
+		{
+			Name:          "AWS Identity and Access Management (IAM)",
+			ServicePrefix: "iam",
+			Actions:       []*authref.Action{{Name: "PassRole", AccessLevel: authref.AccessLevelWrite, PermissionOnly: true}},
+		},
+	}
+
+	// Integers of every size, which the dataset itself is short on
+	integers := []int64{0, 1, 127, 128, 255, 256, 65535, 65536, math.MaxUint32, math.MaxUint32 + 1, math.MaxInt64,
+		-1, -32, -33, -128, -129, math.MinInt16 - 1, math.MinInt32 - 1, math.MinInt64}
+
+	dir := t.TempDir()
+
+	for _, export := range []func(string, []*authref.ServiceAuthorizationReference) ([]string, error){exportJSON, exportMsgpack, exportCBOR} {
+		if _, err := export(dir, authRefs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := func(file string) []byte {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return data
+	}
+
+	want, err := toTree(authRefs)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(read("service-auth.json")))
+	decoder.UseNumber()
+
+	if got, err := readNode(decoder); err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("service-auth.json doesn't match the dataset (%v)", err)
+	}
+
+	wantIntegers, err := toTree(integers)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []struct {
+		file   string
+		encode func(buf *bytes.Buffer, n *node)
+		decode func(r *binaryReader) *node
+	}{
+		{"service-auth.msgpack", encodeMsgpack, decodeMsgpack},
+		{"service-auth.cbor", encodeCBOR, decodeCBOR},
+	} {
+		r := &binaryReader{data: read(format.file)}
+
+		if got := format.decode(r); r.err != nil {
+			t.Errorf("%s: %v", format.file, r.err)
+		} else if len(r.data) != 0 {
+			t.Errorf("%s: %d bytes after the dataset", format.file, len(r.data))
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s doesn't match service-auth.json", format.file)
+		}
+
+		var buf bytes.Buffer
+		format.encode(&buf, wantIntegers)
+		r = &binaryReader{data: buf.Bytes()}
+
+		if got := format.decode(r); r.err != nil || !reflect.DeepEqual(got, wantIntegers) {
+			t.Errorf("%s: integers %v decoded to %+v (%v)", format.file, integers, got, r.err)
+		}
+	}
+}
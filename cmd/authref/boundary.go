@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+func runBoundary(args []string) error {
+	flags := flag.NewFlagSet("boundary", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	boundaryFile := flags.String("boundary", "", "path to the permissions boundary policy (required)")
+	jsonOutput := flags.Bool("json", false, "print the analysis as JSON")
+	verbose := flags.Bool("v", false, "list the actions, not just their counts")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref boundary [flags] --boundary boundary.json identity.json\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || *boundaryFile == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	identity, err := authref.LoadPolicyFile(flags.Arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	boundary, err := authref.LoadPolicyFile(*boundaryFile)
+
+	if err != nil {
+		return err
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	access := authref.AnalyzeBoundary(authref.AllActions(authRefs), identity, boundary)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(access)
+	}
+
+	fmt.Printf("Allowed by both the identity policy and the boundary:\n\n")
+
+	if err := printSummary(access.Effective, *verbose); err != nil {
+		return err
+	}
+
+	if len(access.Blocked.Services) != 0 {
+		fmt.Printf("\nAllowed by the identity policy but blocked by the boundary:\n\n")
+
+		if err := printSummary(access.Blocked, *verbose); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("\nThe boundary also allows %d actions the identity policy doesn't grant.\n", access.BoundaryOnly)
+	return nil
+}
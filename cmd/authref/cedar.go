@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// The namespace that holds the entity types shared by every service
+const cedarRootNamespace = "AWS"
+
+// Action groups are named after access levels with this prefix, which can't collide with
+// an action name
+const cedarAccessLevelGroup = "accessLevel:"
+
+var cedarInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// cedarIdentifier turns a service prefix or resource type name, such as "kafka-cluster",
+// into a Cedar identifier, such as "kafka_cluster".
+func cedarIdentifier(name string) string {
+	return cedarInvalidChars.ReplaceAllString(name, "_")
+}
+
+// cedarConditionKeyType maps the type of a condition key to a Cedar type.
+func cedarConditionKeyType(keyType string) map[string]interface{} {
+	switch keyType {
+	case "Numeric":
+		return map[string]interface{}{"type": "Long", "required": false}
+	case "Bool":
+		return map[string]interface{}{"type": "Boolean", "required": false}
+	case "IPAddress":
+		return map[string]interface{}{"type": "Extension", "name": "ipaddr", "required": false}
+	case "ArrayOfString", "ArrayOfARN":
+		return map[string]interface{}{"type": "Set", "element": map[string]interface{}{"type": "String"}, "required": false}
+	case "ArrayOfBool":
+		return map[string]interface{}{"type": "Set", "element": map[string]interface{}{"type": "Boolean"}, "required": false}
+	default:
+		// String, ARN, and Date keys are compared as strings
+		return map[string]interface{}{"type": "String", "required": false}
+	}
+}
+
+func cedarRecord(attributes map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "Record", "attributes": attributes}
+}
+
+// cedarSchema builds a Cedar schema, in its JSON format, with a namespace for each service
+// prefix, such as AWS::s3. Each resource type becomes an entity type with an arn attribute,
+// and each action applies to the resource types it can be scoped to, with the condition
+// keys it supports as optional context attributes. Actions that can only be granted on
+// all resources apply to AWS::Account. Each action is also a member of a group for its
+// access level, such as AWS::s3::Action::"accessLevel:Read".
+func cedarSchema(authRefs []*authref.ServiceAuthorizationReference) map[string]interface{} {
+	arnShape := func() map[string]interface{} {
+		return map[string]interface{}{
+			"shape": cedarRecord(map[string]interface{}{"arn": map[string]interface{}{"type": "String"}}),
+		}
+	}
+
+	schema := map[string]interface{}{
+		cedarRootNamespace: map[string]interface{}{
+			"entityTypes": map[string]interface{}{"Principal": arnShape(), "Account": arnShape()},
+			"actions":     map[string]interface{}{},
+		},
+	}
+
+	keyTypes := map[string]string{}
+
+	for _, key := range authref.AllConditionKeys(authRefs) {
+		keyTypes[strings.ToLower(key.Name)] = key.Type
+	}
+
+	type namespace struct {
+		name        string
+		entityTypes map[string]interface{}
+		actions     map[string]interface{}
+	}
+
+	namespaces := map[string]*namespace{}
+
+	for _, authRef := range authRefs {
+		ns := namespaces[authRef.ServicePrefix]
+
+		if ns == nil {
+			ns = &namespace{
+				name:        cedarRootNamespace + "::" + cedarIdentifier(authRef.ServicePrefix),
+				entityTypes: map[string]interface{}{},
+				actions:     map[string]interface{}{},
+			}
+			namespaces[authRef.ServicePrefix] = ns
+			schema[ns.name] = map[string]interface{}{"entityTypes": ns.entityTypes, "actions": ns.actions}
+		}
+
+		for _, resourceType := range authRef.ResourceTypes {
+			ns.entityTypes[cedarIdentifier(resourceType.Name)] = arnShape()
+		}
+	}
+
+	for _, action := range authref.AllActions(authRefs) {
+		ns := namespaces[action.Service.ServicePrefix]
+		resourceTypes := make([]string, 0)
+		seen := map[string]bool{}
+		context := map[string]interface{}{}
+
+		addKeys := func(keys []string) {
+			for _, key := range keys {
+				// Keys with a template element, such as aws:RequestTag/${TagKey}, name no
+				// single attribute
+				if !strings.Contains(key, "${") {
+					context[key] = cedarConditionKeyType(keyTypes[strings.ToLower(key)])
+				}
+			}
+		}
+
+		addKeys(action.Action.ConditionKeys)
+
+		for _, resourceType := range action.Action.ResourceTypes {
+			entityType := cedarIdentifier(resourceType.ResourceType)
+			name := ns.name + "::" + entityType
+
+			// A few actions refer to resource types their page doesn't define; declare
+			// them anyway, since Cedar rejects references to unknown types
+			if ns.entityTypes[entityType] == nil {
+				ns.entityTypes[entityType] = arnShape()
+			}
+
+			if !seen[name] {
+				seen[name] = true
+				resourceTypes = append(resourceTypes, name)
+			}
+
+			addKeys(resourceType.ConditionKeys)
+		}
+
+		if len(resourceTypes) == 0 {
+			resourceTypes = append(resourceTypes, cedarRootNamespace+"::Account")
+		}
+
+		group := cedarAccessLevelGroup + string(action.Action.AccessLevel)
+		ns.actions[group] = map[string]interface{}{}
+		ns.actions[action.Action.Name] = map[string]interface{}{
+			"memberOf": []interface{}{map[string]interface{}{"id": group}},
+			"appliesTo": map[string]interface{}{
+				"principalTypes": []string{cedarRootNamespace + "::Principal"},
+				"resourceTypes":  resourceTypes,
+				"context":        cedarRecord(context),
+			},
+		}
+	}
+
+	return schema
+}
+
+// exportCedar writes a Cedar schema of the services' actions and resource types.
+func exportCedar(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	filename := filepath.Join(dir, "authref.cedarschema.json")
+	data, err := json.MarshalIndent(cedarSchema(authRefs), "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{filename}, os.WriteFile(filename, append(data, '\n'), 0644)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// resolvedEvent is a distinct event source and name along with what it resolved to.
+type resolvedEvent struct {
+	*authref.EventResolution
+	Count  int `json:"count"`
+	Errors int `json:"errors"`
+}
+
+// readEventFiles reads CloudTrail events from each file, or from standard input if there
+// are none.
+func readEventFiles(filenames []string) ([]*authref.CloudTrailEvent, error) {
+	if len(filenames) == 0 {
+		return authref.ReadCloudTrailEvents(os.Stdin)
+	}
+
+	result := make([]*authref.CloudTrailEvent, 0)
+
+	for _, filename := range filenames {
+		file, err := os.Open(filename)
+
+		if err != nil {
+			return nil, err
+		}
+
+		events, err := authref.ReadCloudTrailEvents(file)
+		file.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+
+		result = append(result, events...)
+	}
+
+	return result, nil
+}
+
+func runCloudTrail(args []string) error {
+	flags := flag.NewFlagSet("cloudtrail", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	policyOutput := flags.Bool("policy", false, "print a policy that allows every resolved action instead of the table")
+	jsonOutput := flags.Bool("json", false, "print each distinct event and its actions as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref cloudtrail [flags] [events.json...]\n\nReads CloudTrail events from the files, or from standard input if none are given.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	events, err := readEventFiles(flags.Args())
+
+	if err != nil {
+		return err
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	index := authref.NewIndex(authRefs)
+	byEvent := map[string]*resolvedEvent{}
+	resolved := make([]*resolvedEvent, 0)
+
+	for _, event := range events {
+		key := event.EventSource + " " + event.EventName
+		entry := byEvent[key]
+
+		if entry == nil {
+			entry = &resolvedEvent{EventResolution: index.ResolveCloudTrailEvent(event)}
+			byEvent[key] = entry
+			resolved = append(resolved, entry)
+		}
+
+		entry.Count++
+
+		if event.ErrorCode != "" {
+			entry.Errors++
+		}
+	}
+
+	sort.Slice(resolved, func(i, j int) bool {
+		if resolved[i].EventSource != resolved[j].EventSource {
+			return resolved[i].EventSource < resolved[j].EventSource
+		}
+
+		return resolved[i].EventName < resolved[j].EventName
+	})
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(resolved)
+	}
+
+	unknown := 0
+
+	for _, entry := range resolved {
+		if entry.Status == authref.EventUnknown {
+			unknown++
+		}
+	}
+
+	if *policyOutput {
+		seen := map[string]bool{}
+		actions := make([]string, 0)
+
+		for _, entry := range resolved {
+			for _, action := range entry.Actions {
+				if !seen[action] {
+					seen[action] = true
+					actions = append(actions, action)
+				}
+			}
+		}
+
+		sort.Strings(actions)
+		policy := &authref.Policy{
+			Version:   "2012-10-17",
+			Statement: authref.StatementList{{Effect: authref.EffectAllow, Action: actions, Resource: authref.StringList{"*"}}},
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(policy); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "SOURCE\tEVENT\tCOUNT\tERRORS\tACTIONS\n")
+
+		for _, entry := range resolved {
+			actions := strings.Join(entry.Actions, ", ")
+
+			if entry.Status == authref.EventUnknown || entry.Status == authref.EventNoAction {
+				actions = "(" + entry.Status + ")"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", entry.EventSource, entry.EventName, entry.Count, entry.Errors, actions)
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if unknown != 0 {
+		fmt.Fprintf(os.Stderr, "%d distinct event(s) didn't match any action\n", unknown)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// collision is an action name that more than one service defines.
+type collision struct {
+	Name string `json:"name"`
+
+	// Services that define the action, keyed by the access level they give it
+	AccessLevels map[authref.AccessLevel][]string `json:"accessLevels"`
+
+	// Services where the action is permission-only, with no API call behind it
+	PermissionOnly []string `json:"permissionOnly,omitempty"`
+
+	Services int `json:"services"`
+}
+
+// differs reports whether the services disagree about what the action is.
+func (c *collision) differs() bool {
+	return len(c.AccessLevels) > 1 || (len(c.PermissionOnly) != 0 && len(c.PermissionOnly) != c.Services)
+}
+
+// findCollisions groups actions by name, ignoring case, and returns the names used by at
+// least minServices services, most widely used first.
+func findCollisions(actions []*authref.QualifiedAction, minServices int) []*collision {
+	byName := map[string]*collision{}
+
+	for _, action := range actions {
+		key := strings.ToLower(action.Action.Name)
+		c := byName[key]
+
+		if c == nil {
+			c = &collision{Name: action.Action.Name, AccessLevels: map[authref.AccessLevel][]string{}}
+			byName[key] = c
+		}
+
+		level := action.Action.AccessLevel
+		c.AccessLevels[level] = append(c.AccessLevels[level], action.Service.ServicePrefix)
+		c.Services++
+
+		if action.Action.PermissionOnly {
+			c.PermissionOnly = append(c.PermissionOnly, action.Service.ServicePrefix)
+		}
+	}
+
+	result := make([]*collision, 0)
+
+	for _, c := range byName {
+		if c.Services >= minServices {
+			result = append(result, c)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Services != result[j].Services {
+			return result[i].Services > result[j].Services
+		}
+
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+func runCollisions(args []string) error {
+	flags := flag.NewFlagSet("collisions", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	all := flags.Bool("all", false, "include names that every service classifies the same way")
+	minServices := flags.Int("min-services", 2, "only report names defined by at least this many services")
+	verbose := flags.Bool("v", false, "list the services at each access level")
+	jsonOutput := flags.Bool("json", false, "print the collisions as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref collisions [flags]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	collisions := make([]*collision, 0)
+
+	for _, c := range findCollisions(authref.AllActions(authRefs), *minServices) {
+		if *all || c.differs() {
+			collisions = append(collisions, c)
+		}
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(collisions)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "NAME\tSERVICES\tACCESS LEVELS\tPERMISSION-ONLY\n")
+
+	for _, c := range collisions {
+		levels := make([]string, 0, len(c.AccessLevels))
+
+		for _, level := range authref.AccessLevels {
+			if services := c.AccessLevels[level]; len(services) != 0 {
+				levels = append(levels, fmt.Sprintf("%s %d", level, len(services)))
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\n", c.Name, c.Services, strings.Join(levels, ", "), len(c.PermissionOnly))
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if *verbose {
+		for _, c := range collisions {
+			fmt.Printf("\n%s:\n", c.Name)
+
+			for _, level := range authref.AccessLevels {
+				if services := c.AccessLevels[level]; len(services) != 0 {
+					fmt.Printf("  %s: %s\n", level, strings.Join(services, ", "))
+				}
+			}
+		}
+	}
+
+	return nil
+}
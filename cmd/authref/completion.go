@@ -0,0 +1,286 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// completer suggests values for the word being completed, given the arguments before it
+// that aren't flags. Commands without one get the shell's own completion of filenames.
+type completer func(index *authref.Index, args []string, word string) []string
+
+// completionValueFlags are the flags of commands with a completer that take a value, so
+// the word after them isn't mistaken for an argument. Boolean flags aren't listed.
+var completionValueFlags = map[string]bool{
+	"data":          true,
+	"policy":        true,
+	"boundary":      true,
+	"principal":     true,
+	"resource":      true,
+	"access-levels": true,
+	"batch-size":    true,
+	"out":           true,
+	"pattern":       true,
+}
+
+const bashCompletion = `# bash completion for authref
+_authref() {
+	local line=${COMP_LINE:0:COMP_POINT} words word candidate
+	read -ra words <<< "$line"
+
+	if [[ $line == *[[:space:]] ]]; then
+		words+=("")
+	fi
+
+	word=${words[${#words[@]}-1]}
+	local IFS=$'\n'
+	COMPREPLY=($(authref __complete "${words[@]:1}" 2>/dev/null))
+
+	for candidate in "${COMPREPLY[@]}"; do
+		if [[ $candidate == *[:=] ]]; then
+			compopt -o nospace
+		fi
+	done
+
+	# Bash splits words at colons, so only the part after the last one is replaced
+	if [[ $word == *:* && $COMP_WORDBREAKS == *:* ]]; then
+		local i
+
+		for i in "${!COMPREPLY[@]}"; do
+			COMPREPLY[i]=${COMPREPLY[i]#"${word%:*}:"}
+		done
+	fi
+}
+complete -o default -F _authref authref
+`
+
+const zshCompletion = `#compdef authref
+_authref() {
+	local -a candidates
+	candidates=("${(@f)$(authref __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+
+	if [[ -z ${candidates[1]} ]]; then
+		_files
+		return
+	fi
+
+	compadd -U -S '' -- ${(M)candidates:#*[:=]}
+	compadd -U -- ${candidates:#*[:=]}
+}
+compdef _authref authref
+`
+
+const fishCompletion = `# fish completion for authref
+function __authref_complete
+	set -l candidates (authref __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)
+
+	if test (count $candidates) -eq 0
+		__fish_complete_path (commandline -ct)
+	else
+		printf '%s\n' $candidates
+	end
+end
+complete -c authref -f -a '(__authref_complete)'
+`
+
+var completionScripts = map[string]string{
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+func runCompletion(args []string) error {
+	flags := flag.NewFlagSet("completion", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref completion bash|zsh|fish\n\n")
+		fmt.Fprintf(flags.Output(), "Prints a script that completes commands, service prefixes, and action names from\n")
+		fmt.Fprintf(flags.Output(), "the dataset. Load it with, for example,\n")
+		fmt.Fprintf(flags.Output(), "  source <(authref completion bash)\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	script, ok := completionScripts[flags.Arg(0)]
+
+	if !ok {
+		return fmt.Errorf("unknown shell %#v; use bash, zsh, or fish", flags.Arg(0))
+	}
+
+	_, err := io.WriteString(os.Stdout, script)
+	return err
+}
+
+// runComplete is called by the completion scripts with the words of the command line
+// after "authref", ending with the word being completed, and prints a candidate for that
+// word on each line. It prints nothing when the shell should complete a filename instead.
+func runComplete(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	word := args[len(args)-1]
+
+	if len(args) == 1 {
+		for _, cmd := range commands {
+			if !cmd.hidden && strings.HasPrefix(cmd.name, word) {
+				fmt.Println(cmd.name)
+			}
+		}
+
+		return nil
+	}
+
+	cmd := findCommand(args[0])
+
+	if cmd == nil || cmd.complete == nil || strings.HasPrefix(word, "-") {
+		return nil
+	}
+
+	// Find the arguments so far and the dataset the command would use
+	dataFile := "service-auth.json"
+	words := args[1 : len(args)-1]
+	positional := make([]string, 0)
+
+	for i := 0; i < len(words); i++ {
+		if words[i] == "--" {
+			positional = append(positional, words[i+1:]...)
+			break
+		}
+
+		if !strings.HasPrefix(words[i], "-") || words[i] == "-" {
+			positional = append(positional, words[i])
+			continue
+		}
+
+		name := strings.TrimLeft(words[i], "-")
+		value := ""
+
+		if j := strings.IndexByte(name, '='); j >= 0 {
+			name, value = name[:j], name[j+1:]
+		} else if completionValueFlags[name] {
+			if i+1 == len(words) {
+				// The word being completed is the flag's value
+				return nil
+			}
+
+			i++
+			value = words[i]
+		}
+
+		if name == "data" {
+			dataFile = value
+		}
+	}
+
+	authRefs, err := loadData(dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range cmd.complete(authref.NewIndex(authRefs), positional, word) {
+		fmt.Println(candidate)
+	}
+
+	return nil
+}
+
+// completeServicePrefixes suggests the service prefixes that start with word, ignoring
+// case, each followed by suffix.
+func completeServicePrefixes(index *authref.Index, word, suffix string) []string {
+	seen := map[string]bool{}
+	result := make([]string, 0)
+
+	for _, authRef := range index.Services() {
+		prefix := authRef.ServicePrefix
+
+		if !seen[prefix] && strings.HasPrefix(strings.ToLower(prefix), strings.ToLower(word)) {
+			seen[prefix] = true
+			result = append(result, prefix+suffix)
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+// completeActions suggests service prefixes until the word has a colon, and then the
+// names of the actions it starts, as in "iam:Cre" for iam:CreateRole. Patterns with
+// wildcards are left alone.
+func completeActions(index *authref.Index, args []string, word string) []string {
+	if strings.ContainsAny(word, "*?") {
+		return nil
+	}
+
+	if !strings.Contains(word, ":") {
+		return completeServicePrefixes(index, word, ":")
+	}
+
+	actions := index.ActionsWithPrefix(word)
+	result := make([]string, len(actions))
+
+	for i, action := range actions {
+		result[i] = action.String()
+	}
+
+	return result
+}
+
+// completeFillArn suggests a service prefix, then one of its resource types, and then the
+// placeholders of the resource type's ARN pattern that haven't been given values.
+func completeFillArn(index *authref.Index, args []string, word string) []string {
+	switch len(args) {
+	case 0:
+		return completeServicePrefixes(index, word, "")
+	case 1:
+		seen := map[string]bool{}
+		result := make([]string, 0)
+
+		for _, authRef := range index.ServicesByPrefix(args[0]) {
+			for _, resourceType := range authRef.ResourceTypes {
+				if !seen[resourceType.Name] && strings.HasPrefix(strings.ToLower(resourceType.Name), strings.ToLower(word)) {
+					seen[resourceType.Name] = true
+					result = append(result, resourceType.Name)
+				}
+			}
+		}
+
+		sort.Strings(result)
+		return result
+	}
+
+	pattern, err := resourceTypeArnPattern(index, args[0], args[1])
+
+	if err != nil {
+		return nil
+	}
+
+	given := map[string]bool{}
+
+	for _, arg := range args[2:] {
+		if i := strings.IndexByte(arg, '='); i >= 0 {
+			given[strings.ToLower(arg[:i])] = true
+		}
+	}
+
+	result := make([]string, 0)
+
+	for _, name := range authref.ArnPlaceholders(pattern) {
+		if !given[strings.ToLower(name)] && strings.HasPrefix(strings.ToLower(name), strings.ToLower(word)) {
+			result = append(result, name+"=")
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// corsMaxAge is how long browsers may cache the answer to a preflight request.
+const corsMaxAge = 10 * time.Minute
+
+// corsPolicy decides which web pages may call the server from a browser.
+type corsPolicy struct {
+	// Allowed origins, such as "https://tools.example.com"
+	origins map[string]bool
+
+	// Whether any origin is allowed
+	anyOrigin bool
+
+	// Value of Access-Control-Allow-Methods, such as "GET, HEAD"
+	methods string
+}
+
+// parseCORS reads comma-separated lists of allowed origins and methods. It returns nil if
+// no origins are allowed, in which case the server sends no CORS headers at all.
+func parseCORS(originList, methodList string) (*corsPolicy, error) {
+	policy := &corsPolicy{origins: map[string]bool{}}
+
+	for _, origin := range strings.Split(originList, ",") {
+		if origin = strings.TrimSpace(origin); origin == "" {
+			continue
+		}
+
+		if origin == "*" {
+			policy.anyOrigin = true
+			continue
+		}
+
+		parsed, err := url.Parse(origin)
+
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") {
+			return nil, fmt.Errorf("%#v isn't an origin like \"https://tools.example.com\"", origin)
+		}
+
+		policy.origins[parsed.Scheme+"://"+parsed.Host] = true
+	}
+
+	if !policy.anyOrigin && len(policy.origins) == 0 {
+		return nil, nil
+	}
+
+	methods := make([]string, 0)
+
+	for _, method := range strings.Split(methodList, ",") {
+		if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+			methods = append(methods, method)
+		}
+	}
+
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no methods given for CORS")
+	}
+
+	policy.methods = strings.Join(methods, ", ")
+	return policy, nil
+}
+
+// apply adds the CORS headers for a request, and answers it if it's a preflight request.
+// It returns true if the request has been answered.
+func (policy *corsPolicy) apply(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+
+	if !policy.anyOrigin {
+		// The answer depends on the origin, so caches have to keep one per origin
+		w.Header().Add("Vary", "Origin")
+	}
+
+	if origin == "" || !(policy.anyOrigin || policy.origins[origin]) {
+		return false
+	}
+
+	if policy.anyOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+
+	// Let clients see the ETag, so they can send it back in If-None-Match
+	w.Header().Set("Access-Control-Expose-Headers", "ETag")
+
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", policy.methods)
+	// Content-Type for the JSON body of a POST
+	w.Header().Set("Access-Control-Allow-Headers", "If-None-Match, Content-Type")
+	w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
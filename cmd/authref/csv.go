@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// exportCSV writes each sheet of datasetSheets to its own CSV file, named after the sheet,
+// such as actions.csv and resource-types.csv.
+func exportCSV(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	files := make([]string, 0)
+
+	for _, s := range datasetSheets(authRefs) {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(s.header)
+
+		for _, row := range s.rows {
+			record := make([]string, len(row))
+
+			for i, cell := range row {
+				record[i] = fmt.Sprint(cell)
+			}
+
+			w.Write(record)
+		}
+
+		w.Flush()
+
+		if err := w.Error(); err != nil {
+			return nil, err
+		}
+
+		filename := filepath.Join(dir, strings.ReplaceAll(strings.ToLower(s.name), " ", "-")+".csv")
+
+		if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+
+		files = append(files, filename)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// cueDefinitions describes service-auth.json and IAM policies in CUE. The list of action
+// names is appended as #ActionName.
+const cueDefinitions = `// Code generated by authref export. DO NOT EDIT.
+
+// Package authref describes the AWS Service Authorization Reference dataset and IAM
+// policies whose actions are limited to those in the reference.
+package authref
+
+#AccessLevel: "List" | "Read" | "Write" | "Permissions management" | "Tagging"
+
+// A service as it appears in service-auth.json
+#Service: {
+	name:               string
+	servicePrefix:      string
+	authReferenceHref:  string
+	apiReferenceHref?:  string
+	servicePrincipals?: [...string]
+	sdkServices?: [...{serviceId: string, endpointPrefix: string}]
+	lastUpdated?:       =~"^[0-9]{4}-[0-9]{2}-[0-9]{2}$"
+	unavailablePartitions?: [...#ArnPartition]
+	regions?: [...string]
+	actions: [...#Action]
+	resourceTypes: [...#ResourceType]
+	conditionKeys: [...#ConditionKey]
+}
+
+#Action: {
+	name:           string
+	permissionOnly: bool
+	annotations: [...string]
+	referenceHref?: string
+	description:    string
+	accessLevel:    #AccessLevel
+	resourceTypes: [...#ActionResourceType]
+	conditionKeys: [...string]
+	localizedDescriptions?: [#Locale]: string
+	provenance?:            #Provenance
+}
+
+#ActionResourceType: {
+	resourceType: string
+	required:     bool
+	conditionKeys: [...string]
+	dependentActions: [...string]
+	requiredGroup?:   int & >=1
+}
+
+#ResourceType: {
+	name:           string
+	referenceHref?: string
+	arnPattern:     string
+	conditionKeys: [...string]
+	partitionArnPatterns?: [#ArnPartition]: string
+	provenance?:           #Provenance
+}
+
+#ConditionKey: {
+	name:           string
+	referenceHref?: string
+	description:    string
+	type:           string
+	localizedDescriptions?: [#Locale]: string
+	provenance?:            #Provenance
+}
+
+#Provenance: {
+	sourceHref: string
+	anchor?:    string
+	scrapedAt:  string
+}
+
+// A documentation locale, such as "ja_jp"
+#Locale: =~"^[a-z]{2}_[a-z]{2}$"
+
+// A partition with ARN pattern variants
+#ArnPartition: "aws" | "aws-cn" | "aws-us-gov"
+
+#Dataset: [...#Service]
+
+// An action as written in a policy: an exact action name from the reference, or a pattern
+// with wildcards. Exact names must use the reference's capitalization.
+#ActionPattern: #ActionName | =~%s
+
+#StringOrList: string | [...string]
+
+#Statement: {
+	Sid?:          string
+	Effect:        "Allow" | "Deny"
+	Principal?:    "*" | {[string]: #StringOrList}
+	NotPrincipal?: "*" | {[string]: #StringOrList}
+	Action?:       #ActionPattern | [...#ActionPattern]
+	NotAction?:    #ActionPattern | [...#ActionPattern]
+	Resource?:     #StringOrList
+	NotResource?:  #StringOrList
+	Condition?: {[string]: {[string]: _}}
+}
+
+#Policy: {
+	Version?:  "2012-10-17" | "2008-10-17"
+	Id?:       string
+	Statement: #Statement | [...#Statement]
+}
+
+// Every action in the reference
+#ActionName:
+`
+
+// exportCUE writes CUE definitions of the dataset and of policies, constraining actions
+// to those in the dataset.
+func exportCUE(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	filename := filepath.Join(dir, "authref.cue")
+	pattern, err := json.Marshal(wildcardActionPattern)
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	fmt.Fprintf(w, cueDefinitions, pattern)
+
+	actions := authref.AllActions(authRefs)
+
+	for i, action := range actions {
+		name, err := json.Marshal(action.String())
+
+		if err != nil {
+			return nil, err
+		}
+
+		// CUE ends a line after a string with a comma, so the "|" has to end the line
+		if i == len(actions)-1 {
+			fmt.Fprintf(w, "\t%s\n", name)
+		} else {
+			fmt.Fprintf(w, "\t%s |\n", name)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return []string{filename}, file.Close()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// custodianActions returns the action names of each service prefix, in the layout of Cloud
+// Custodian's c7n/data/iam-actions.json, which it checks the actions in iam policies and
+// check-permissions filters against. Services that share a prefix are merged, and the
+// names are sorted as in authref.AllActions.
+func custodianActions(authRefs []*authref.ServiceAuthorizationReference) map[string][]string {
+	result := map[string][]string{}
+
+	for _, action := range authref.AllActions(authRefs) {
+		prefix := action.Service.ServicePrefix
+		result[prefix] = append(result[prefix], action.Action.Name)
+	}
+
+	return result
+}
+
+// exportCustodian writes iam-actions.json for Cloud Custodian. encoding/json sorts the
+// service prefixes, so the file diffs cleanly against the one Custodian ships.
+func exportCustodian(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	filename := filepath.Join(dir, "iam-actions.json")
+	data, err := json.MarshalIndent(custodianActions(authRefs), "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{filename}, os.WriteFile(filename, append(data, '\n'), 0644)
+}
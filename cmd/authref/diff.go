@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print the changes as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref diff [flags] old.json new.json\n\n")
+		fmt.Fprintf(flags.Output(), "Lists the actions, resource types, and condition keys added, removed, or changed\n")
+		fmt.Fprintf(flags.Output(), "between two snapshots of the dataset.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	oldAuthRefs, err := loadData(flags.Arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	newAuthRefs, err := loadData(flags.Arg(1))
+
+	if err != nil {
+		return err
+	}
+
+	changes := authref.Diff(oldAuthRefs, newAuthRefs)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(changes)
+	}
+
+	for i, service := range changes.Services {
+		if i != 0 {
+			fmt.Println()
+		}
+
+		switch {
+		case service.Added:
+			fmt.Printf("%s (%s), new service\n", service.ServicePrefix, service.Name)
+		case service.Removed:
+			fmt.Printf("%s (%s), removed\n", service.ServicePrefix, service.Name)
+		default:
+			fmt.Printf("%s (%s)\n", service.ServicePrefix, service.Name)
+		}
+
+		for _, action := range service.AddedActions {
+			fmt.Printf("  + %s:%s (%s)\n", service.ServicePrefix, action.Name, action.AccessLevel)
+		}
+
+		for _, action := range service.RemovedActions {
+			fmt.Printf("  - %s:%s (%s)\n", service.ServicePrefix, action.Name, action.AccessLevel)
+		}
+
+		for _, change := range service.ChangedActions {
+			fmt.Printf("  ~ %s:%s: %s\n", service.ServicePrefix, change.New.Name, strings.Join(change.Fields, ", "))
+		}
+
+		for _, resourceType := range service.AddedResourceTypes {
+			fmt.Printf("  + resource type %s\n", resourceType.Name)
+		}
+
+		for _, resourceType := range service.RemovedResourceTypes {
+			fmt.Printf("  - resource type %s\n", resourceType.Name)
+		}
+
+		for _, change := range service.ChangedResourceTypes {
+			fmt.Printf("  ~ resource type %s: %s\n", change.New.Name, strings.Join(change.Fields, ", "))
+		}
+
+		for _, conditionKey := range service.AddedConditionKeys {
+			fmt.Printf("  + condition key %s\n", conditionKey.Name)
+		}
+
+		for _, conditionKey := range service.RemovedConditionKeys {
+			fmt.Printf("  - condition key %s\n", conditionKey.Name)
+		}
+
+		for _, change := range service.ChangedConditionKeys {
+			fmt.Printf("  ~ condition key %s: %s\n", change.New.Name, strings.Join(change.Fields, ", "))
+		}
+	}
+
+	if changes.Empty() {
+		fmt.Fprintf(os.Stderr, "no changes\n")
+	}
+
+	return nil
+}
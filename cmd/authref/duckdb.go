@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// duckdbScript builds authref.duckdb from the Parquet export next to it: a table for each
+// Parquet table, with the service prefix as a column, and views of common questions.
+const duckdbScript = `-- Builds a DuckDB database of the AWS Service Authorization Reference from the Parquet
+-- files in parquet/, written by authref duckdb-init. Run it from this directory:
+--   duckdb authref.duckdb < authref.duckdb.sql
+-- The tables are copied into the database, so it doesn't need the Parquet files after.
+
+CREATE OR REPLACE TABLE service AS
+    SELECT * FROM read_parquet('parquet/service/*/*.parquet', hive_partitioning = true);
+CREATE OR REPLACE TABLE resource_type AS
+    SELECT * FROM read_parquet('parquet/resource_type/*/*.parquet', hive_partitioning = true);
+CREATE OR REPLACE TABLE condition_key AS
+    SELECT * FROM read_parquet('parquet/condition_key/*/*.parquet', hive_partitioning = true);
+CREATE OR REPLACE TABLE action AS
+    SELECT * FROM read_parquet('parquet/action/*/*.parquet', hive_partitioning = true);
+CREATE OR REPLACE TABLE action_resource_type AS
+    SELECT * FROM read_parquet('parquet/action_resource_type/*/*.parquet', hive_partitioning = true);
+
+-- Every action by the name used in policies, such as s3:GetObject
+CREATE OR REPLACE VIEW qualified_action AS
+    SELECT service_prefix || ':' || name AS action, * FROM action;
+
+-- Each service's actions counted by access level
+CREATE OR REPLACE VIEW service_access_level AS
+    SELECT
+        service_prefix,
+        count(*) AS actions,
+        count(*) FILTER (WHERE access_level = 'List') AS list,
+        count(*) FILTER (WHERE access_level = 'Read') AS read,
+        count(*) FILTER (WHERE access_level = 'Write') AS write,
+        count(*) FILTER (WHERE access_level = 'Permissions management') AS permissions_management,
+        count(*) FILTER (WHERE access_level = 'Tagging') AS tagging
+    FROM action
+    GROUP BY service_prefix;
+
+-- Actions that can change permissions, which deserve a close look in any policy
+CREATE OR REPLACE VIEW permissions_management_action AS
+    SELECT service_prefix || ':' || name AS action, description
+    FROM action
+    WHERE access_level = 'Permissions management';
+
+-- Actions that change something but can't be limited to particular resources, so a
+-- policy can only grant them on "*"
+CREATE OR REPLACE VIEW unscoped_action AS
+    SELECT service_prefix || ':' || a.name AS action, access_level, description
+    FROM action a
+    WHERE access_level IN ('Write', 'Permissions management', 'Tagging')
+        AND NOT EXISTS (
+            SELECT 1 FROM action_resource_type r
+            WHERE r.service_prefix = a.service_prefix AND r.action = a.name
+        );
+
+-- The actions each condition key can be used with, whether for any resource or only for
+-- some resource type
+CREATE OR REPLACE VIEW condition_key_action AS
+    SELECT DISTINCT condition_key, service_prefix || ':' || action AS action
+    FROM (
+        SELECT unnest(condition_keys) AS condition_key, service_prefix, name AS action FROM action
+        UNION ALL
+        SELECT unnest(condition_keys), service_prefix, action FROM action_resource_type
+    );
+`
+
+func runDuckDBInit(args []string) error {
+	flags := flag.NewFlagSet("duckdb-init", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	outDir := flags.String("out", ".", "directory to write the Parquet files, the script, and the database to")
+	duckdb := flags.String("duckdb", "duckdb", "the DuckDB command-line client to build the database with")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref duckdb-init [flags]\n\n")
+		fmt.Fprintf(flags.Output(), "Writes the parquet export, a script that loads it into DuckDB with views of common\n")
+		fmt.Fprintf(flags.Output(), "questions, and, if the duckdb client is installed, the database, authref.duckdb.\n")
+		fmt.Fprintf(flags.Output(), "Use it from DuckDB with ATTACH 'authref.duckdb' AS authref.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+
+	if _, err := exportParquet(*outDir, authRefs); err != nil {
+		return err
+	}
+
+	scriptFile := filepath.Join(*outDir, "authref.duckdb.sql")
+
+	if err := os.WriteFile(scriptFile, []byte(duckdbScript), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s and the Parquet files in %s\n", scriptFile, filepath.Join(*outDir, "parquet"))
+	duckdbPath, err := exec.LookPath(*duckdb)
+
+	if err != nil {
+		fmt.Printf("%s isn't installed, so the database wasn't built; to build it, run\n", *duckdb)
+		fmt.Printf("  cd %s && duckdb authref.duckdb < authref.duckdb.sql\n", *outDir)
+		return nil
+	}
+
+	script, err := os.Open(scriptFile)
+
+	if err != nil {
+		return err
+	}
+
+	defer script.Close()
+
+	// The script's paths are relative to its own directory
+	cmd := exec.Command(duckdbPath, "authref.duckdb")
+	cmd.Dir = *outDir
+	cmd.Stdin = script
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("duckdb: %w", err)
+	}
+
+	fmt.Printf("wrote %s\n", filepath.Join(*outDir, "authref.duckdb"))
+	return nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// completionItem is an entry in completions.json. The field names follow the
+// CompletionItem of the Language Server Protocol, so a language server can hand the
+// entries to an editor with little translation.
+type completionItem struct {
+	Label         string `json:"label"`
+	Detail        string `json:"detail,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+
+	// A snippet in the LSP snippet syntax, with tab stops for each placeholder
+	InsertText string `json:"insertText,omitempty"`
+
+	// Where the item is documented
+	ReferenceHref string `json:"referenceHref,omitempty"`
+}
+
+type completions struct {
+	Actions       []*completionItem `json:"actions"`
+	Resources     []*completionItem `json:"resources"`
+	ConditionKeys []*completionItem `json:"conditionKeys"`
+}
+
+var arnPlaceholder = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// arnSnippet turns an ARN pattern such as "arn:${Partition}:s3:::${BucketName}" into a
+// snippet with a tab stop for each placeholder: "arn:${1:Partition}:s3:::${2:BucketName}".
+// Placeholders already in stops reuse their tab stop, so that a value such as the account
+// is typed once for every ARN in a snippet; new ones are added after the highest stop.
+func arnSnippet(pattern string, stops map[string]int) string {
+	return arnPlaceholder.ReplaceAllStringFunc(pattern, func(placeholder string) string {
+		name := arnPlaceholder.FindStringSubmatch(placeholder)[1]
+		stop, ok := stops[name]
+
+		if !ok {
+			for _, existing := range stops {
+				if existing > stop {
+					stop = existing
+				}
+			}
+
+			stop++
+			stops[name] = stop
+		}
+
+		return fmt.Sprintf("${%d:%s}", stop, name)
+	})
+}
+
+func buildCompletions(authRefs []*authref.ServiceAuthorizationReference) *completions {
+	result := &completions{
+		Actions:       make([]*completionItem, 0),
+		Resources:     make([]*completionItem, 0),
+		ConditionKeys: make([]*completionItem, 0),
+	}
+
+	for _, action := range authref.AllActions(authRefs) {
+		result.Actions = append(result.Actions, &completionItem{
+			Label:         action.String(),
+			Detail:        string(action.Action.AccessLevel),
+			Documentation: action.Action.Description,
+			ReferenceHref: action.Action.ReferenceHref,
+		})
+	}
+
+	seenPatterns := map[string]bool{}
+
+	for _, authRef := range authRefs {
+		for _, resourceType := range authRef.ResourceTypes {
+			if seenPatterns[resourceType.ArnPattern] {
+				continue
+			}
+
+			seenPatterns[resourceType.ArnPattern] = true
+			result.Resources = append(result.Resources, &completionItem{
+				Label:         resourceType.ArnPattern,
+				Detail:        authRef.ServicePrefix + " " + resourceType.Name,
+				InsertText:    arnSnippet(resourceType.ArnPattern, map[string]int{}),
+				ReferenceHref: resourceType.ReferenceHref,
+			})
+		}
+	}
+
+	sort.Slice(result.Resources, func(i, j int) bool {
+		return result.Resources[i].Label < result.Resources[j].Label
+	})
+
+	for _, key := range authref.AllConditionKeys(authRefs) {
+		item := &completionItem{
+			Label:         key.Name,
+			Detail:        key.Type,
+			Documentation: key.Description,
+			ReferenceHref: key.ReferenceHref,
+		}
+
+		if strings.Contains(key.Name, "${") {
+			item.InsertText = arnSnippet(key.Name, map[string]int{})
+		}
+
+		result.ConditionKeys = append(result.ConditionKeys, item)
+	}
+
+	return result
+}
+
+// wildcardActionPattern accepts action patterns that contain a wildcard, which can't be
+// enumerated. Exact action names must come from the enumeration.
+const wildcardActionPattern = `^(\*|[a-z0-9-]+:[A-Za-z0-9*?]*[*?][A-Za-z0-9*?]*)$`
+
+// policySchema builds a JSON Schema for IAM policy documents that enumerates the actions,
+// resource ARN patterns, and condition keys of the dataset.
+func policySchema(authRefs []*authref.ServiceAuthorizationReference, items *completions) map[string]interface{} {
+	actionNames := make([]string, 0, len(items.Actions)+len(authRefs)+1)
+	actionDescriptions := make([]string, 0, cap(actionNames))
+	seenPrefixes := map[string]bool{}
+
+	actionNames = append(actionNames, "*")
+	actionDescriptions = append(actionDescriptions, "Every action of every service")
+
+	for _, authRef := range authRefs {
+		if !seenPrefixes[authRef.ServicePrefix] {
+			seenPrefixes[authRef.ServicePrefix] = true
+			actionNames = append(actionNames, authRef.ServicePrefix+":*")
+			actionDescriptions = append(actionDescriptions, "Every action of "+authRef.Name)
+		}
+	}
+
+	for _, item := range items.Actions {
+		actionNames = append(actionNames, item.Label)
+		actionDescriptions = append(actionDescriptions, fmt.Sprintf("%s: %s", item.Detail, item.Documentation))
+	}
+
+	arnPatterns := make([]string, 0, len(items.Resources)+1)
+	arnPatterns = append(arnPatterns, "*")
+
+	for _, item := range items.Resources {
+		arnPatterns = append(arnPatterns, item.Label)
+	}
+
+	// Keys with a template element, such as aws:RequestTag/${TagKey}, are left open
+	conditionKeys := map[string]interface{}{}
+
+	for _, item := range items.ConditionKeys {
+		if item.InsertText == "" {
+			conditionKeys[item.Label] = map[string]interface{}{
+				"description": fmt.Sprintf("%s (%s)", item.Documentation, item.Detail),
+			}
+		}
+	}
+
+	stringOrList := func(schema map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"oneOf": []interface{}{schema, map[string]interface{}{"type": "array", "items": schema}},
+		}
+	}
+
+	action := map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"enum": actionNames, "enumDescriptions": actionDescriptions},
+			map[string]interface{}{"type": "string", "pattern": wildcardActionPattern},
+		},
+	}
+
+	resource := map[string]interface{}{"type": "string", "examples": arnPatterns}
+
+	principal := map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"const": "*"},
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"AWS":           stringOrList(map[string]interface{}{"type": "string"}),
+					"Service":       stringOrList(map[string]interface{}{"type": "string"}),
+					"Federated":     stringOrList(map[string]interface{}{"type": "string"}),
+					"CanonicalUser": stringOrList(map[string]interface{}{"type": "string"}),
+				},
+				"additionalProperties": false,
+			},
+		},
+	}
+
+	statement := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"Effect"},
+		"properties": map[string]interface{}{
+			"Sid":          map[string]interface{}{"type": "string"},
+			"Effect":       map[string]interface{}{"enum": []string{"Allow", "Deny"}},
+			"Principal":    principal,
+			"NotPrincipal": principal,
+			"Action":       map[string]interface{}{"$ref": "#/definitions/actionList"},
+			"NotAction":    map[string]interface{}{"$ref": "#/definitions/actionList"},
+			"Resource":     map[string]interface{}{"$ref": "#/definitions/resourceList"},
+			"NotResource":  map[string]interface{}{"$ref": "#/definitions/resourceList"},
+			"Condition": map[string]interface{}{
+				"type": "object",
+				"additionalProperties": map[string]interface{}{
+					"type":       "object",
+					"properties": conditionKeys,
+				},
+			},
+		},
+		"oneOf": []interface{}{
+			map[string]interface{}{"required": []string{"Action"}},
+			map[string]interface{}{"required": []string{"NotAction"}},
+		},
+		"additionalProperties": false,
+	}
+
+	return map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       "IAM policy document",
+		"description": "An AWS IAM policy document, with the actions, resources, and condition keys of the AWS Service Authorization Reference",
+		"type":        "object",
+		"required":    []string{"Statement"},
+		"properties": map[string]interface{}{
+			"Version": map[string]interface{}{"enum": []string{"2012-10-17", "2008-10-17"}},
+			"Id":      map[string]interface{}{"type": "string"},
+			"Statement": map[string]interface{}{
+				"oneOf": []interface{}{
+					map[string]interface{}{"$ref": "#/definitions/statement"},
+					map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/definitions/statement"}},
+				},
+			},
+		},
+		"additionalProperties": false,
+		"definitions": map[string]interface{}{
+			"statement":    statement,
+			"action":       action,
+			"actionList":   stringOrList(map[string]interface{}{"$ref": "#/definitions/action"}),
+			"resource":     resource,
+			"resourceList": stringOrList(map[string]interface{}{"$ref": "#/definitions/resource"}),
+		},
+	}
+}
+
+// exportEditor writes the completion data and the policy schema that editors and
+// language servers use for autocompletion.
+func exportEditor(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	completionsFile := filepath.Join(dir, "completions.json")
+	schemaFile := filepath.Join(dir, "iam-policy.schema.json")
+	items := buildCompletions(authRefs)
+
+	for filename, value := range map[string]interface{}{completionsFile: items, schemaFile: policySchema(authRefs, items)} {
+		data, err := json.MarshalIndent(value, "", "  ")
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	return []string{completionsFile, schemaFile}, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// actionColumns are the columns expand can print for each action.
+var actionColumns = []*tableColumn{
+	{name: "name", value: func(row interface{}) string { return row.(*actionDetail).FullName() }},
+	{name: "service", value: func(row interface{}) string { return row.(*actionDetail).ServiceName }},
+	{name: "access-level", value: func(row interface{}) string { return string(row.(*actionDetail).AccessLevel) }, compare: compareAccessLevels},
+	{name: "permission-only", value: func(row interface{}) string { return strconv.FormatBool(row.(*actionDetail).PermissionOnly) }},
+	{name: "resource-types", value: func(row interface{}) string {
+		names := make([]string, 0)
+
+		for _, resourceType := range row.(*actionDetail).ResourceTypes {
+			if resourceType.Required {
+				names = append(names, resourceType.ResourceType+"*")
+			} else {
+				names = append(names, resourceType.ResourceType)
+			}
+		}
+
+		return strings.Join(names, ", ")
+	}},
+	{name: "condition-keys", value: func(row interface{}) string { return strings.Join(row.(*actionDetail).ConditionKeys, ", ") }},
+	{name: "description", value: func(row interface{}) string { return row.(*actionDetail).Description }},
+	{name: "reference", value: func(row interface{}) string { return row.(*actionDetail).ReferenceHref }},
+}
+
+func runExpand(args []string) error {
+	flags := flag.NewFlagSet("expand", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	jsonOutput := flags.Bool("json", false, "print the actions each pattern matches as JSON, as the server's /expand does")
+	templateText := templateFlag(flags)
+	columns := tableFlags(flags, actionColumns, "")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref expand [flags] <pattern>...\n\n")
+		fmt.Fprintf(flags.Output(), "Lists the actions that patterns such as s3:Get* match, as in a policy's Action\n")
+		fmt.Fprintf(flags.Output(), "element. Each action is printed once, sorted by name. Use --columns for a table\n")
+		fmt.Fprintf(flags.Output(), "of their details.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	tmpl, err := parseTemplate(*templateText)
+
+	if err != nil {
+		return err
+	}
+
+	if err := columns.parse(); err != nil {
+		return err
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	index := authref.NewIndex(authRefs)
+	results := expandPatterns(index, flags.Args())
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(&expandResponse{Results: results})
+	}
+
+	seen := map[string]bool{}
+	names := make([]string, 0)
+	failed := 0
+
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", result.Pattern, result.Error)
+			failed++
+			continue
+		}
+
+		if len(result.Actions) == 0 {
+			fmt.Fprintf(os.Stderr, "%s: matches no actions\n", result.Pattern)
+		}
+
+		for _, name := range result.Actions {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	details := make([]*actionDetail, len(names))
+
+	for i, name := range names {
+		action := index.Action(name)
+		details[i] = newActionDetail(index.ServicesByPrefix(action.Service.ServicePrefix),
+			action.Service.ServicePrefix, action.Service.Name, action.Action)
+	}
+
+	columns.sort(details)
+
+	switch {
+	case tmpl != nil:
+		err = printTemplate(tmpl, details)
+	case len(columns.selected) != 0:
+		err = columns.print(details)
+	default:
+		for _, detail := range details {
+			fmt.Println(detail.FullName())
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d patterns aren't valid", failed, len(results))
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// exporter writes the dataset in one format to the output directory, returning the names
+// of the files it wrote.
+type exporter struct {
+	name    string
+	summary string
+	export  func(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error)
+}
+
+var exporters []*exporter
+
+func init() {
+	exporters = []*exporter{
+		{name: "json", summary: "service-auth.json, as published", export: exportJSON},
+		{name: "msgpack", summary: "service-auth.msgpack, the same structure in MessagePack", export: exportMsgpack},
+		{name: "cbor", summary: "service-auth.cbor, the same structure in CBOR", export: exportCBOR},
+		{name: "yaml", summary: "service-auth.yaml, the same structure in YAML", export: exportYAML},
+		{name: "csv", summary: "a CSV file for each sheet of the xlsx workbook, such as actions.csv", export: exportCSV},
+		{name: "avro", summary: "actions.avro, an Avro container file of actions, and its schema in actions.avsc", export: exportAvro},
+		{name: "bigquery", summary: "actions.ndjson, newline-delimited JSON of actions, and its BigQuery table schema in actions.bigquery.json", export: exportBigQuery},
+		{name: "opensearch", summary: "actions.bulk.ndjson, actions in the Elasticsearch and OpenSearch _bulk format, and their index mapping in actions.mapping.json", export: exportOpenSearch},
+		{name: "redis", summary: "authref.redis, commands for redis-cli --pipe that load actions and condition keys as hashes", export: exportRedis},
+		{name: "postgres", summary: "postgres/schema.sql, a normalized PostgreSQL schema, with a COPY data file per table and a psql script to load them", export: exportPostgres},
+		{name: "sqlite", summary: "authref.sqlite, an SQLite database with the tables of the postgres export, as authref sql uses", export: exportSQLite},
+		{name: "parquet", summary: "parquet/<table>/service_prefix=<prefix>/data.parquet, the tables of the postgres export as Parquet files partitioned by service prefix", export: exportParquet},
+		{name: "editor", summary: "completions.json, completion data for editors, and iam-policy.schema.json, a JSON Schema for policies", export: exportEditor},
+		{name: "vscode", summary: "iam-policy.code-snippets, VS Code snippets of statements for each service and access level", export: exportVSCodeSnippets},
+		{name: "cue", summary: "authref.cue, CUE definitions of the dataset and of policies limited to its actions", export: exportCUE},
+		{name: "jsonnet", summary: "authref.libsonnet, a Jsonnet library of actions with functions to check policies", export: exportJsonnet},
+		{name: "opa", summary: "authref-bundle.tar.gz, an OPA bundle of the actions at data.aws.authref with Rego helpers", export: exportOPA},
+		{name: "cedar", summary: "authref.cedarschema.json, a Cedar schema with a namespace of actions and resource types per service", export: exportCedar},
+		{name: "custodian", summary: "iam-actions.json, the actions of each service in the layout of Cloud Custodian's c7n/data/iam-actions.json", export: exportCustodian},
+		{name: "policies", summary: "policies/<prefix>/*.json, read-only, power-user, and tagging-only starter policies for each service", export: exportPolicies},
+		{name: "xlsx", summary: "service-auth.xlsx, an Excel workbook with a sheet per entity and actions counted by access level", export: exportXLSX},
+	}
+}
+
+func findExporter(name string) *exporter {
+	for _, e := range exporters {
+		if e.name == name {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func exportJSON(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	filename := filepath.Join(dir, "service-auth.json")
+	data, err := json.MarshalIndent(authRefs, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{filename}, os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// writeTree writes the dataset to filename using one of the node tree encoders.
+func writeTree(filename string, authRefs []*authref.ServiceAuthorizationReference, encode func(buf *bytes.Buffer, n *node)) ([]string, error) {
+	tree, err := toTree(authRefs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encode(&buf, tree)
+
+	return []string{filename}, os.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+func exportMsgpack(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	return writeTree(filepath.Join(dir, "service-auth.msgpack"), authRefs, encodeMsgpack)
+}
+
+func exportCBOR(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	return writeTree(filepath.Join(dir, "service-auth.cbor"), authRefs, encodeCBOR)
+}
+
+func exportYAML(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	return writeTree(filepath.Join(dir, "service-auth.yaml"), authRefs, encodeYAML)
+}
+
+// parseFormats reads a comma-separated list of export formats, dropping repeats.
+func parseFormats(list string) ([]*exporter, error) {
+	result := make([]*exporter, 0)
+	seen := map[*exporter]bool{}
+
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		e := findExporter(name)
+
+		if e == nil {
+			names := make([]string, len(exporters))
+
+			for i, e := range exporters {
+				names[i] = e.name
+			}
+
+			return nil, fmt.Errorf("unknown format %#v (expected one of %s)", name, strings.Join(names, ", "))
+		}
+
+		if !seen[e] {
+			seen[e] = true
+			result = append(result, e)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no format given")
+	}
+
+	return result, nil
+}
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	format := flags.String("format", "json", "output format, or a comma-separated list of formats to write in one run")
+	outDir := flags.String("out", ".", "directory to write the exported files to")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref export --format <format>[,<format>...] [flags]\n\nformats:\n")
+
+		for _, e := range exporters {
+			fmt.Fprintf(flags.Output(), "  %-12s %s\n", e.name, e.summary)
+		}
+
+		fmt.Fprintf(flags.Output(), "\nflags:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// Check every format before doing any work, so a typo doesn't leave a partial export
+	formats, err := parseFormats(*format)
+
+	if err != nil {
+		return err
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	return exportFormats(formats, *outDir, authRefs, func(file string) {
+		fmt.Println(file)
+	})
+}
+
+// exportFormats writes the dataset in each format to dir, calling wrote with the name of
+// each file as it's written. The dataset is loaded once and shared by every format.
+func exportFormats(formats []*exporter, dir string, authRefs []*authref.ServiceAuthorizationReference, wrote func(file string)) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, e := range formats {
+		files, err := e.export(dir, authRefs)
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.name, err)
+		}
+
+		for _, file := range files {
+			wrote(file)
+		}
+	}
+
+	return nil
+}
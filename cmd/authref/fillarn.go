@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+func runFillArn(args []string) error {
+	flags := flag.NewFlagSet("fill-arn", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	pattern := flags.String("pattern", "", "fill in this ARN pattern instead of a resource type's")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref fill-arn [flags] service-prefix resource-type [name=value...]\n")
+		fmt.Fprintf(flags.Output(), "       authref fill-arn [flags] -pattern arn-pattern [name=value...]\n\n")
+		fmt.Fprintf(flags.Output(), "Fills in the placeholders of an ARN pattern, such as BucketName=reports. The\n")
+		fmt.Fprintf(flags.Output(), "partition is worked out from Region if it isn't given, and is otherwise aws.\n")
+		fmt.Fprintf(flags.Output(), "Run with no values to list the placeholders.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	rest := flags.Args()
+
+	if *pattern == "" {
+		if len(rest) < 2 {
+			flags.Usage()
+			os.Exit(2)
+		}
+
+		authRefs, err := loadData(*dataFile)
+
+		if err != nil {
+			return err
+		}
+
+		*pattern, err = resourceTypeArnPattern(authref.NewIndex(authRefs), rest[0], rest[1])
+
+		if err != nil {
+			return err
+		}
+
+		rest = rest[2:]
+	}
+
+	values := map[string]string{}
+
+	for _, arg := range rest {
+		i := strings.IndexByte(arg, '=')
+
+		if i <= 0 {
+			return fmt.Errorf("%#v isn't of the form name=value", arg)
+		}
+
+		values[arg[:i]] = arg[i+1:]
+	}
+
+	if len(values) == 0 {
+		fmt.Printf("%s\n\nplaceholders: %s\n", *pattern, strings.Join(authref.ArnPlaceholders(*pattern), ", "))
+		return nil
+	}
+
+	arn, err := authref.FillArnPattern(*pattern, values)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(arn)
+	return nil
+}
+
+// resourceTypeArnPattern finds the ARN pattern of a service's resource type, ignoring case
+// in the name.
+func resourceTypeArnPattern(index *authref.Index, prefix, name string) (string, error) {
+	services := index.ServicesByPrefix(prefix)
+
+	if len(services) == 0 {
+		return "", fmt.Errorf("no service has the prefix %#v", prefix)
+	}
+
+	for _, authRef := range services {
+		for _, resourceType := range authRef.ResourceTypes {
+			if strings.EqualFold(resourceType.Name, name) {
+				return resourceType.ArnPattern, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("service %s has no resource type %#v", prefix, name)
+}
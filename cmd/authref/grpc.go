@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// grpcService is the full name of the gRPC service defined in proto/authref.proto.
+const grpcService = "authref.v1.AuthRef"
+
+// gRPC status codes.
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcNotFound        = 5
+	grpcUnimplemented   = 12
+	grpcInternal        = 13
+)
+
+// grpcMaxRequest limits the size of request messages, which are all tiny.
+const grpcMaxRequest = 64 * 1024
+
+type grpcError struct {
+	code    int
+	message string
+}
+
+func (err *grpcError) Error() string {
+	return err.message
+}
+
+// grpcMethods implements the unary methods of the AuthRef service. Each takes the
+// request message and returns the encoded response message.
+var grpcMethods = map[string]func(data *dataset, request []byte) ([]byte, error){
+	"ListServices": grpcListServices,
+	"GetService":   grpcGetService,
+	"ListActions":  grpcListActions,
+}
+
+// grpcHandler serves the AuthRef service. It speaks just enough of the gRPC protocol for
+// unary calls without compression, which is all the service needs, so it has to be served
+// over HTTP/2.
+type grpcHandler struct {
+	server *server
+}
+
+func (h *grpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "expected a gRPC request", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	start := time.Now()
+	response, err := h.call(r)
+
+	if err != nil {
+		code := grpcInternal
+
+		if grpcErr, ok := err.(*grpcError); ok {
+			code = grpcErr.code
+		}
+
+		h.observe(r, code, time.Since(start))
+		w.Header().Set("Grpc-Status", fmt.Sprint(code))
+		w.Header().Set("Grpc-Message", err.Error())
+		return
+	}
+
+	h.observe(r, grpcOK, time.Since(start))
+
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(response)))
+	w.Write(prefix[:])
+	w.Write(response)
+
+	w.Header().Set("Grpc-Status", fmt.Sprint(grpcOK))
+	w.Header().Set("Grpc-Message", "")
+}
+
+// observe records a call in the server's metrics, under its method name if it's one of
+// the service's.
+func (h *grpcHandler) observe(r *http.Request, code int, duration time.Duration) {
+	name := strings.TrimPrefix(r.URL.Path, "/"+grpcService+"/")
+
+	if grpcMethods[name] == nil {
+		name = "other"
+	}
+
+	h.server.metrics.grpc.observe(name, code, duration)
+}
+
+func (h *grpcHandler) call(r *http.Request) ([]byte, error) {
+	name := strings.TrimPrefix(r.URL.Path, "/"+grpcService+"/")
+	method := grpcMethods[name]
+
+	if method == nil || name == r.URL.Path {
+		return nil, &grpcError{code: grpcUnimplemented, message: fmt.Sprintf("unknown method %s", r.URL.Path)}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, grpcMaxRequest+5))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) < 5 || body[0] != 0 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		return nil, &grpcError{code: grpcInvalidArgument, message: "expected one uncompressed request message"}
+	}
+
+	return method(h.server.current(), body[5:])
+}
+
+// grpcStringField returns the value of a string field of a request message.
+func grpcStringField(request []byte, number int) (string, error) {
+	fields, err := authref.ParseProto(request)
+
+	if err != nil {
+		return "", &grpcError{code: grpcInvalidArgument, message: err.Error()}
+	}
+
+	value := ""
+
+	for _, field := range fields {
+		if field.Number == number {
+			value = string(field.Bytes)
+		}
+	}
+
+	return value, nil
+}
+
+func grpcListServices(data *dataset, request []byte) ([]byte, error) {
+	var b authref.ProtoBuffer
+
+	for _, service := range data.services {
+		b.Message(1, func(b *authref.ProtoBuffer) {
+			b.String(1, service.Name)
+			b.String(2, service.ServicePrefix)
+			b.String(3, service.AuthReferenceHref)
+			b.Strings(4, service.ServicePrincipals)
+			b.Uint(5, uint64(service.Actions))
+			b.Uint(6, uint64(service.ResourceTypes))
+			b.Uint(7, uint64(service.ConditionKeys))
+			b.String(8, service.LastUpdated)
+		})
+	}
+
+	return b.Bytes(), nil
+}
+
+func grpcGetService(data *dataset, request []byte) ([]byte, error) {
+	prefix, err := grpcStringField(request, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pages := data.byPrefix[prefix]
+
+	if len(pages) == 0 {
+		return nil, &grpcError{code: grpcNotFound, message: fmt.Sprintf("no service with prefix %#v", prefix)}
+	}
+
+	var b authref.ProtoBuffer
+
+	for _, authRef := range pages {
+		b.Message(1, authRef.EncodeProto)
+	}
+
+	return b.Bytes(), nil
+}
+
+func grpcListActions(data *dataset, request []byte) ([]byte, error) {
+	prefix, err := grpcStringField(request, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var b authref.ProtoBuffer
+
+	for _, action := range data.actions {
+		if prefix != "" && action.ServicePrefix != prefix {
+			continue
+		}
+
+		b.Message(1, func(b *authref.ProtoBuffer) {
+			b.String(1, action.ServicePrefix)
+			b.String(2, action.ServiceName)
+			b.Message(3, action.Action.EncodeProto)
+		})
+	}
+
+	return b.Bytes(), nil
+}
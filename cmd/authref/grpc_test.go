@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+	"github.com/fluggo/aws-service-auth-reference/authrefgrpc"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestGRPCClient(t *testing.T) {
+	authRefs := []*authref.ServiceAuthorizationReference{
+		{
+			Name:          "Amazon S3",
+			ServicePrefix: "s3",
+			Actions: []*authref.Action{
+				{Name: "GetObject", AccessLevel: authref.AccessLevelRead, Description: "Grants permission to retrieve objects"},
+				{Name: "PutObject", AccessLevel: authref.AccessLevelWrite, Description: "Grants permission to add an object"},
+			},
+			ResourceTypes: []*authref.ResourceType{{Name: "object", ArnPattern: "arn:${Partition}:s3:::${BucketName}/${ObjectName}"}},
+		},
+		{
+			Name:          "AWS Identity and Access Management (IAM)",
+			ServicePrefix: "iam",
+			Actions:       []*authref.Action{{Name: "PassRole", AccessLevel: authref.AccessLevelWrite, PermissionOnly: true}},
+		},
+	}
+
+	s := newServer(newDataset(authRefs, nil), time.Minute)
+	httpServer := httptest.NewServer(h2c.NewHandler(&grpcHandler{server: s}, &http2.Server{}))
+	defer httpServer.Close()
+
+	client := authrefgrpc.NewClient(httpServer.Listener.Addr().String())
+	ctx := context.Background()
+
+	services, err := client.ListServices(ctx)
+
+	if err != nil {
+		t.Fatalf("ListServices: %v", err)
+	}
+
+	if len(services) != 2 || services[0].ServicePrefix != "s3" || services[0].Actions != 2 || services[0].ResourceTypes != 1 {
+		t.Errorf("ListServices returned %+v", services)
+	}
+
+	pages, err := client.GetService(ctx, "s3")
+
+	if err != nil {
+		t.Fatalf("GetService: %v", err)
+	}
+
+	if len(pages) != 1 || pages[0].Name != "Amazon S3" || len(pages[0].Actions) != 2 || pages[0].ResourceTypes[0].ArnPattern != authRefs[0].ResourceTypes[0].ArnPattern {
+		t.Errorf("GetService returned %+v", pages)
+	}
+
+	if _, err := client.GetService(ctx, "nope"); err == nil {
+		t.Errorf("GetService of an unknown prefix succeeded")
+	} else if grpcErr, ok := err.(*authrefgrpc.Error); !ok || grpcErr.Code != grpcNotFound {
+		t.Errorf("GetService of an unknown prefix returned %v, want NOT_FOUND", err)
+	}
+
+	actions, err := client.ListActions(ctx, "iam")
+
+	if err != nil {
+		t.Fatalf("ListActions: %v", err)
+	}
+
+	if len(actions) != 1 || actions[0].ServiceName != authRefs[1].Name || actions[0].Action.Name != "PassRole" || !actions[0].Action.PermissionOnly {
+		t.Errorf("ListActions returned %+v", actions)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// jsonnetLibrary is the body of authref.libsonnet, which follows the local definitions of
+// actions and prefixes.
+const jsonnetLibrary = `local byLowerName = { [std.asciiLower(name)]: name for name in std.objectFields(actions) };
+local list(value) = if std.isArray(value) then value else [value];
+local hasWildcard(pattern) = std.length(std.findSubstr('*', pattern)) > 0 || std.length(std.findSubstr('?', pattern)) > 0;
+
+{
+  accessLevels: ['List', 'Read', 'Write', 'Permissions management', 'Tagging'],
+
+  // Access level of each action, keyed by its name as written in the reference.
+  actions: actions,
+
+  servicePrefixes: std.objectFields(prefixes),
+
+  // The name of an action as written in the reference, or null if there's no such
+  // action. Like IAM, it ignores case.
+  canonicalAction(name):: std.get(byLowerName, std.asciiLower(name), null),
+
+  isAction(name):: self.canonicalAction(name) != null,
+
+  // The access level of an action, or null if there's no such action.
+  accessLevel(name)::
+    local canonical = self.canonicalAction(name);
+    if canonical == null then null else actions[canonical],
+
+  // Whether a pattern from an Action or NotAction element can match anything: "*", an
+  // action in the reference, or a pattern with wildcards for a known service prefix.
+  isActionPattern(pattern)::
+    pattern == '*' || self.isAction(pattern) ||
+    (hasWildcard(pattern) && std.length(std.findSubstr(':', pattern)) > 0 &&
+     std.objectHas(prefixes, std.asciiLower(std.splitLimit(pattern, ':', 1)[0]))),
+
+  // Returns the policy unchanged, failing if any statement names an unknown action.
+  checkPolicy(policy)::
+    local unknown = [
+      pattern
+      for statement in list(policy.Statement)
+      for element in ['Action', 'NotAction']
+      if std.objectHas(statement, element)
+      for pattern in list(statement[element])
+      if !$.isActionPattern(pattern)
+    ];
+    assert unknown == [] : 'unknown actions: ' + std.join(', ', unknown);
+    policy,
+}
+`
+
+// exportJsonnet writes a Jsonnet library with the actions of the dataset and functions
+// to check the actions of policies against them.
+func exportJsonnet(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	filename := filepath.Join(dir, "authref.libsonnet")
+	file, err := os.Create(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	w := bufio.NewWriter(file)
+
+	fmt.Fprintf(w, "// Code generated by authref export. DO NOT EDIT.\n//\n")
+	fmt.Fprintf(w, "// Actions of the AWS Service Authorization Reference, for checking IAM policies.\n\n")
+	fmt.Fprintf(w, "local actions = {\n")
+
+	for _, action := range authref.AllActions(authRefs) {
+		name, err := json.Marshal(action.String())
+
+		if err != nil {
+			return nil, err
+		}
+
+		level, err := json.Marshal(action.Action.AccessLevel)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(w, "  %s: %s,\n", name, level)
+	}
+
+	fmt.Fprintf(w, "};\n\nlocal prefixes = {\n")
+	seen := map[string]bool{}
+
+	for _, authRef := range authRefs {
+		if seen[authRef.ServicePrefix] {
+			continue
+		}
+
+		seen[authRef.ServicePrefix] = true
+		prefix, err := json.Marshal(authRef.ServicePrefix)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(w, "  %s: true,\n", prefix)
+	}
+
+	fmt.Fprintf(w, "};\n\n%s", jsonnetLibrary)
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return []string{filename}, file.Close()
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// Lint codes.
+const (
+	lintNotActionAllow = "not-action-allow"
+	lintNotActionDeny  = "not-action-deny"
+)
+
+type lintFinding struct {
+	Statement int    `json:"statement"`
+	Sid       string `json:"sid,omitempty"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+
+	// Number of actions the statement covers, by access level
+	ActionsByAccessLevel map[authref.AccessLevel]int `json:"actionsByAccessLevel,omitempty"`
+
+	// Permissions management actions the statement covers
+	PermissionsManagement []string `json:"permissionsManagement,omitempty"`
+}
+
+// lintNotAction expands a NotAction statement and describes what it really covers. An
+// Allow with NotAction grants everything not listed, including services added after the
+// policy was written, so any permissions management actions it sweeps in are flagged.
+func lintNotAction(actions []*authref.QualifiedAction, index int, statement *authref.Statement) *lintFinding {
+	covered := authref.StatementActions(actions, statement)
+	finding := &lintFinding{
+		Statement:             index,
+		Sid:                   statement.Sid,
+		ActionsByAccessLevel:  map[authref.AccessLevel]int{},
+		PermissionsManagement: make([]string, 0),
+	}
+	services := map[string]bool{}
+
+	for _, action := range covered {
+		services[action.Service.ServicePrefix] = true
+		finding.ActionsByAccessLevel[action.Action.AccessLevel]++
+
+		if action.Action.AccessLevel == authref.AccessLevelPermissionsManagement {
+			finding.PermissionsManagement = append(finding.PermissionsManagement, action.String())
+		}
+	}
+
+	if statement.Effect == authref.EffectAllow {
+		finding.Code = lintNotActionAllow
+		finding.Message = fmt.Sprintf("Allow with NotAction grants %d actions across %d services, including %d permissions management actions",
+			len(covered), len(services), len(finding.PermissionsManagement))
+	} else {
+		finding.Code = lintNotActionDeny
+		finding.Message = fmt.Sprintf("Deny with NotAction denies %d actions across %d services", len(covered), len(services))
+	}
+
+	return finding
+}
+
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	jsonOutput := flags.Bool("json", false, "print the findings as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref lint [flags] policy.json\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	policy, err := authref.LoadPolicyFile(flags.Arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	actions := authref.AllActions(authRefs)
+	findings := make([]*lintFinding, 0)
+	problems := 0
+
+	for i, statement := range policy.Statement {
+		if len(statement.NotAction) != 0 {
+			finding := lintNotAction(actions, i, statement)
+			findings = append(findings, finding)
+
+			if finding.Code == lintNotActionAllow && len(finding.PermissionsManagement) != 0 {
+				problems++
+			}
+		}
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(findings); err != nil {
+			return err
+		}
+	} else {
+		for _, finding := range findings {
+			fmt.Printf("%s: %s: %s\n", policy.Statement[finding.Statement].Label(finding.Statement), finding.Code, finding.Message)
+
+			for _, level := range authref.AccessLevels {
+				if count := finding.ActionsByAccessLevel[level]; count != 0 {
+					fmt.Printf("    %-24s %d\n", level, count)
+				}
+			}
+
+			if finding.Code == lintNotActionAllow && len(finding.PermissionsManagement) != 0 {
+				fmt.Printf("    permissions management actions:\n")
+
+				for _, name := range finding.PermissionsManagement {
+					fmt.Printf("      %s\n", name)
+				}
+			}
+		}
+	}
+
+	if problems != 0 {
+		return fmt.Errorf("permissions management actions granted through NotAction in %d statement(s)", problems)
+	}
+
+	return nil
+}
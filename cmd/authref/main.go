@@ -0,0 +1,129 @@
+// Command authref answers questions about the AWS Service Authorization Reference
+// using a service-auth.json file, and builds that file with its scrape command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+
+	// Suggests arguments for shell completion, if they come from the dataset
+	complete completer
+
+	// Left out of the usage message
+	hidden bool
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "scrape", summary: "build service-auth.json and the files published with it from the AWS documentation", run: runScrape},
+		{name: "diff", summary: "list what changed between two snapshots of the dataset", run: runDiff},
+		{name: "stats", summary: "print totals and per-service breakdowns of the dataset", run: runStats},
+		{name: "verify", summary: "check a dataset's schema, checksums, signature, and internal references", run: runVerify},
+		{name: "validate", summary: "another name for verify", run: runVerify},
+		{name: "show", summary: "print the details of actions such as iam:CreateRole", run: runShow, complete: completeActions},
+		{name: "search", summary: "find actions and condition keys by name or description", run: runSearch},
+		{name: "query", summary: "list actions and condition keys by service, name, and access level", run: runQuery},
+		{name: "expand", summary: "list the actions that wildcard patterns such as s3:Get* match", run: runExpand, complete: completeActions},
+		{name: "sql", summary: "run an SQL query against the dataset in an in-memory SQLite database", run: runSQL},
+		{name: "duckdb-init", summary: "write the dataset as Parquet files and build a DuckDB database of them", run: runDuckDBInit},
+		{name: "lint", summary: "look for statements in a policy that grant more than they appear to", run: runLint},
+		{name: "check-scp", summary: "check a service control policy's actions and report what its Deny statements cover", run: runCheckSCP},
+		{name: "access", summary: "break down the actions a policy grants by service and access level", run: runAccess},
+		{name: "access-advisor", summary: "turn an Access Advisor report into the unused actions and the policy statements to remove", run: runAccessAdvisor},
+		{name: "boundary", summary: "work out which actions an identity policy leaves allowed under a permissions boundary", run: runBoundary},
+		{name: "size", summary: "compare the policy size of listing actions against using wildcards", run: runSize, complete: completeActions},
+		{name: "minimize", summary: "find the fewest wildcard patterns that match exactly a set of actions", run: runMinimize, complete: completeActions},
+		{name: "actions-for-arn", summary: "list the actions that can act on the resource an ARN names", run: runActionsForArn},
+		{name: "fill-arn", summary: "fill in the placeholders of a resource type's ARN pattern", run: runFillArn, complete: completeFillArn},
+		{name: "cloudtrail", summary: "work out the IAM actions behind CloudTrail events", run: runCloudTrail},
+		{name: "unscoped", summary: "list the write and permissions management actions a policy can't limit to resources or conditions", run: runUnscoped},
+		{name: "collisions", summary: "list action names that several services define with different access levels", run: runCollisions},
+		{name: "simulate", summary: "write IAM policy simulator requests covering a set of actions", run: runSimulate, complete: completeActions},
+		{name: "simulate-results", summary: "summarize IAM policy simulator results by service and access level", run: runSimulateResults},
+		{name: "serve", summary: "serve the dataset over HTTP, described by an OpenAPI document", run: runServe},
+		{name: "export", summary: "write the dataset in another format", run: runExport},
+		{name: "site", summary: "build a static, searchable website of the dataset", run: runSite},
+		{name: "check-update", summary: "check whether a newer snapshot of the dataset has been published", run: runCheckUpdate},
+		{name: "version", summary: "print the build of this tool and a fingerprint of the dataset, for bug reports", run: runVersion},
+		{name: "completion", summary: "print a shell script that completes action names and service prefixes", run: runCompletion},
+		{name: "__complete", run: runComplete, hidden: true},
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: authref <command> [flags]\n\ncommands:\n")
+	width := 0
+
+	for _, cmd := range commands {
+		if !cmd.hidden && len(cmd.name) > width {
+			width = len(cmd.name)
+		}
+	}
+
+	for _, cmd := range commands {
+		if !cmd.hidden {
+			fmt.Fprintf(os.Stderr, "  %-*s  %s\n", width, cmd.name, cmd.summary)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "\nRun \"authref <command> -h\" for the flags of a command.\n")
+}
+
+// dataFlag adds the flag that selects the dataset file to a command's flag set.
+func dataFlag(flags *flag.FlagSet) *string {
+	return flags.String("data", "service-auth.json", "path to the service-auth.json dataset")
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+
+	return nil
+}
+
+func loadData(filename string) ([]*authref.ServiceAuthorizationReference, error) {
+	authRefs, err := authref.LoadFile(filename)
+
+	if err != nil {
+		return nil, fmt.Errorf("load dataset: %w", err)
+	}
+
+	return authRefs, nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	if cmd := findCommand(os.Args[1]); cmd != nil {
+		if err := cmd.run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "authref %s: %v\n", cmd.name, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if os.Args[1] != "-h" && os.Args[1] != "--help" && os.Args[1] != "help" {
+		fmt.Fprintf(os.Stderr, "authref: unknown command %#v\n\n", os.Args[1])
+	}
+
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// metricsPath is where the server publishes its metrics in the Prometheus text format.
+const metricsPath = "/metrics"
+
+// latencyBuckets are the upper bounds, in seconds, of the request duration histograms.
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// requestKey identifies a series of requestMetrics.
+type requestKey struct {
+	name string
+	code int
+}
+
+// requestStats is the histogram of one endpoint's request durations.
+type requestStats struct {
+	// Counts of requests at or below each of latencyBuckets
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// requestMetrics counts requests and their durations by endpoint and response code.
+type requestMetrics struct {
+	// Prefix of the metric names, such as "authref_http"
+	prefix string
+
+	// Label names for the endpoint and the response code
+	nameLabel, codeLabel string
+
+	mutex     sync.Mutex
+	counts    map[requestKey]uint64
+	durations map[string]*requestStats
+}
+
+func newRequestMetrics(prefix, nameLabel, codeLabel string) *requestMetrics {
+	return &requestMetrics{
+		prefix:    prefix,
+		nameLabel: nameLabel,
+		codeLabel: codeLabel,
+		counts:    map[requestKey]uint64{},
+		durations: map[string]*requestStats{},
+	}
+}
+
+// observe records a request. The name must come from a fixed set, such as the server's
+// endpoints, so that the number of series stays bounded.
+func (metrics *requestMetrics) observe(name string, code int, duration time.Duration) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	metrics.counts[requestKey{name, code}]++
+	stats := metrics.durations[name]
+
+	if stats == nil {
+		stats = &requestStats{buckets: make([]uint64, len(latencyBuckets))}
+		metrics.durations[name] = stats
+	}
+
+	seconds := duration.Seconds()
+	stats.count++
+	stats.sum += seconds
+
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			stats.buckets[i]++
+		}
+	}
+}
+
+func (metrics *requestMetrics) write(w io.Writer) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	keys := make([]requestKey, 0, len(metrics.counts))
+
+	for key := range metrics.counts {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+
+		return keys[i].code < keys[j].code
+	})
+
+	fmt.Fprintf(w, "# HELP %s_requests_total Requests answered, by %s and %s.\n", metrics.prefix, metrics.nameLabel, metrics.codeLabel)
+	fmt.Fprintf(w, "# TYPE %s_requests_total counter\n", metrics.prefix)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s_requests_total{%s=%q,%s=\"%d\"} %d\n", metrics.prefix, metrics.nameLabel, key.name, metrics.codeLabel, key.code, metrics.counts[key])
+	}
+
+	names := make([]string, 0, len(metrics.durations))
+
+	for name := range metrics.durations {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	fmt.Fprintf(w, "# HELP %s_request_duration_seconds Time taken to answer requests, by %s.\n", metrics.prefix, metrics.nameLabel)
+	fmt.Fprintf(w, "# TYPE %s_request_duration_seconds histogram\n", metrics.prefix)
+
+	for _, name := range names {
+		stats := metrics.durations[name]
+
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(w, "%s_request_duration_seconds_bucket{%s=%q,le=%q} %d\n", metrics.prefix, metrics.nameLabel, name, formatFloat(bound), stats.buckets[i])
+		}
+
+		fmt.Fprintf(w, "%s_request_duration_seconds_bucket{%s=%q,le=\"+Inf\"} %d\n", metrics.prefix, metrics.nameLabel, name, stats.count)
+		fmt.Fprintf(w, "%s_request_duration_seconds_sum{%s=%q} %s\n", metrics.prefix, metrics.nameLabel, name, formatFloat(stats.sum))
+		fmt.Fprintf(w, "%s_request_duration_seconds_count{%s=%q} %d\n", metrics.prefix, metrics.nameLabel, name, stats.count)
+	}
+}
+
+// serverMetrics is what the server reports at metricsPath. It's written out by hand in
+// the Prometheus text format rather than through the Prometheus client library, which
+// would be the tool's only other dependency.
+type serverMetrics struct {
+	http *requestMetrics
+	grpc *requestMetrics
+
+	mutex           sync.Mutex
+	refreshes       uint64
+	refreshFailures uint64
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{
+		http: newRequestMetrics("authref_http", "endpoint", "code"),
+		grpc: newRequestMetrics("authref_grpc", "method", "code"),
+	}
+}
+
+// refreshed records an attempt to refresh the dataset.
+func (metrics *serverMetrics) refreshed(ok bool) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	if ok {
+		metrics.refreshes++
+	} else {
+		metrics.refreshFailures++
+	}
+}
+
+// write writes every metric, including those describing the dataset being served.
+func (metrics *serverMetrics) write(w io.Writer, data *dataset, now time.Time) {
+	metrics.http.write(w)
+	metrics.grpc.write(w)
+
+	metrics.mutex.Lock()
+	fmt.Fprintf(w, "# HELP authref_dataset_refreshes_total Attempts to refresh the dataset, by result.\n")
+	fmt.Fprintf(w, "# TYPE authref_dataset_refreshes_total counter\n")
+	fmt.Fprintf(w, "authref_dataset_refreshes_total{result=\"success\"} %d\n", metrics.refreshes)
+	fmt.Fprintf(w, "authref_dataset_refreshes_total{result=\"failure\"} %d\n", metrics.refreshFailures)
+	metrics.mutex.Unlock()
+
+	fmt.Fprintf(w, "# HELP authref_dataset_services Services in the dataset being served.\n")
+	fmt.Fprintf(w, "# TYPE authref_dataset_services gauge\n")
+	fmt.Fprintf(w, "authref_dataset_services %d\n", len(data.authRefs))
+	fmt.Fprintf(w, "# HELP authref_dataset_actions Actions in the dataset being served.\n")
+	fmt.Fprintf(w, "# TYPE authref_dataset_actions gauge\n")
+	fmt.Fprintf(w, "authref_dataset_actions %d\n", len(data.actions))
+	fmt.Fprintf(w, "# HELP authref_dataset_loaded_timestamp_seconds When the dataset being served was loaded.\n")
+	fmt.Fprintf(w, "# TYPE authref_dataset_loaded_timestamp_seconds gauge\n")
+	fmt.Fprintf(w, "authref_dataset_loaded_timestamp_seconds %s\n", formatFloat(float64(data.loadedAt.UnixNano())/1e9))
+
+	// The rest comes from metadata.json, which a dataset doesn't always have
+	if data.metadata == nil {
+		return
+	}
+
+	fmt.Fprintf(w, "# HELP authref_dataset_info Version of the dataset being served.\n")
+	fmt.Fprintf(w, "# TYPE authref_dataset_info gauge\n")
+	fmt.Fprintf(w, "authref_dataset_info{version=%q,schema_version=\"%d\"} 1\n", data.metadata.Version.String(), data.metadata.SchemaVersion)
+
+	if generatedAt, err := time.Parse(time.RFC3339, data.metadata.GeneratedAt); err == nil {
+		fmt.Fprintf(w, "# HELP authref_dataset_age_seconds Time since the dataset being served was generated.\n")
+		fmt.Fprintf(w, "# TYPE authref_dataset_age_seconds gauge\n")
+		fmt.Fprintf(w, "authref_dataset_age_seconds %s\n", formatFloat(now.Sub(generatedAt).Seconds()))
+	}
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
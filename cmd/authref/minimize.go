@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+func runMinimize(args []string) error {
+	flags := flag.NewFlagSet("minimize", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	policyFile := flags.String("policy", "", "take the target actions from what this policy allows instead of from the arguments")
+	jsonOutput := flags.Bool("json", false, "print the patterns as a JSON array, ready for an Action element")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref minimize [flags] <action>...\n       authref minimize [flags] --policy policy.json\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if (flags.NArg() == 0) == (*policyFile == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	index := authref.NewIndex(authRefs)
+	universe := index.Actions()
+	var target []*authref.QualifiedAction
+
+	if *policyFile != "" {
+		policy, err := authref.LoadPolicyFile(*policyFile)
+
+		if err != nil {
+			return err
+		}
+
+		target = authref.AllowedActions(universe, policy)
+	} else {
+		for _, name := range flags.Args() {
+			// Patterns are expanded, so the output can tighten an existing Action element
+			matched := index.Match(name)
+
+			if len(matched) == 0 {
+				return fmt.Errorf("%#v matches no actions", name)
+			}
+
+			target = append(target, matched...)
+		}
+	}
+
+	patterns, err := authref.MinimizePatterns(universe, target)
+
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(patterns)
+	}
+
+	for _, pattern := range patterns {
+		fmt.Println(pattern)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// opaRoot is where the bundle puts the dataset in OPA's data document, as data.aws.authref.
+const opaRoot = "aws/authref"
+
+// opaPolicy holds the helper functions in the bundle, in the same package as the data.
+const opaPolicy = `# Code generated by authref export. DO NOT EDIT.
+
+# Helpers for IAM actions, using the AWS Service Authorization Reference in
+# data.aws.authref.actions (keyed by lowercase action name) and data.aws.authref.services
+# (keyed by service prefix).
+package aws.authref
+
+import rego.v1
+
+as_list(value) := value if is_array(value)
+
+as_list(value) := [value] if is_string(value)
+
+# is_action(name) is true if name is an action in the reference. Like IAM, it ignores case.
+is_action(name) if data.aws.authref.actions[lower(name)]
+
+# access_level(name) is the access level of an action, such as "Read".
+access_level(name) := data.aws.authref.actions[lower(name)].accessLevel
+
+# expand(pattern) is the set of actions a pattern such as "s3:Get*" matches, by the
+# names used in the reference.
+expand(pattern) := {action.name |
+	some key, action in data.aws.authref.actions
+	glob.match(lower(pattern), [], key)
+}
+
+# expand_all(patterns) is the set of actions any of the patterns match. patterns can be
+# a string or an array, as in a policy.
+expand_all(patterns) := {name |
+	some pattern in as_list(patterns)
+	some name in expand(pattern)
+}
+
+# statement_actions(statement) is the set of actions a statement applies to: those
+# matching its Action element, or for a NotAction statement, every other action.
+statement_actions(statement) := expand_all(statement.Action) if not statement.NotAction
+
+statement_actions(statement) := {action.name |
+	excluded := expand_all(statement.NotAction)
+	some action in data.aws.authref.actions
+	not excluded[action.name]
+} if statement.NotAction
+
+# unknown_patterns(patterns) is the set of patterns that match no action.
+unknown_patterns(patterns) := {pattern |
+	some pattern in as_list(patterns)
+	count(expand(pattern)) == 0
+}
+
+# access_levels(names) counts the actions by access level.
+access_levels(names) := {level: count(matching) |
+	some level in {access_level(name) | some name in names}
+	matching := {name | some name in names; access_level(name) == level}
+}
+`
+
+// opaAction is an action in the bundle's data.
+type opaAction struct {
+	Name           string                       `json:"name"`
+	ServicePrefix  string                       `json:"servicePrefix"`
+	AccessLevel    authref.AccessLevel          `json:"accessLevel"`
+	PermissionOnly bool                         `json:"permissionOnly"`
+	ResourceTypes  []authref.ActionResourceType `json:"resourceTypes"`
+	ConditionKeys  []string                     `json:"conditionKeys"`
+}
+
+type opaService struct {
+	Name              string `json:"name"`
+	AuthReferenceHref string `json:"authReferenceHref"`
+}
+
+type opaData struct {
+	Actions  map[string]*opaAction  `json:"actions"`
+	Services map[string]*opaService `json:"services"`
+}
+
+// exportOPA writes an OPA bundle that puts the dataset at data.aws.authref, along with
+// Rego helpers for expanding action patterns and looking up access levels.
+func exportOPA(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	filename := filepath.Join(dir, "authref-bundle.tar.gz")
+	bundleData := &opaData{Actions: map[string]*opaAction{}, Services: map[string]*opaService{}}
+
+	for _, action := range authref.AllActions(authRefs) {
+		bundleData.Actions[strings.ToLower(action.String())] = &opaAction{
+			Name:           action.String(),
+			ServicePrefix:  action.Service.ServicePrefix,
+			AccessLevel:    action.Action.AccessLevel,
+			PermissionOnly: action.Action.PermissionOnly,
+			ResourceTypes:  action.Action.ResourceTypes,
+			ConditionKeys:  nonNil(action.Action.ConditionKeys),
+		}
+	}
+
+	for _, authRef := range authRefs {
+		if bundleData.Services[authRef.ServicePrefix] == nil {
+			bundleData.Services[authRef.ServicePrefix] = &opaService{Name: authRef.Name, AuthReferenceHref: authRef.AuthReferenceHref}
+		}
+	}
+
+	dataJSON, err := json.Marshal(bundleData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The revision identifies the data, so OPA can tell when a new bundle changes it
+	digest := sha256.Sum256(dataJSON)
+	manifest, err := json.Marshal(map[string]interface{}{
+		"revision": hex.EncodeToString(digest[:]),
+		"roots":    []string{opaRoot},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{"/.manifest", manifest},
+		{"/" + opaRoot + "/data.json", dataJSON},
+		{"/" + opaRoot + "/authref.rego", []byte(opaPolicy)},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.data))}); err != nil {
+			return nil, err
+		}
+
+		if _, err := tw.Write(entry.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return []string{filename}, file.Close()
+}
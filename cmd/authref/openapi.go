@@ -0,0 +1,230 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// openAPIBuilder derives OpenAPI 3 schemas from Go types. Named struct types are added to
+// the document's components and referred to by name, so each is described once.
+type openAPIBuilder struct {
+	schemas map[string]interface{}
+}
+
+var accessLevelType = reflect.TypeOf(authref.AccessLevel(""))
+
+func (b *openAPIBuilder) schema(t reflect.Type) map[string]interface{} {
+	if t == accessLevelType {
+		levels := make([]string, len(authref.AccessLevels))
+
+		for i, level := range authref.AccessLevels {
+			levels[i] = string(level)
+		}
+
+		return map[string]interface{}{"type": "string", "enum": levels}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+
+		if _, ok := b.schemas[name]; !ok {
+			// Reserve the name first, in case the type refers to itself
+			b.schemas[name] = nil
+			b.schemas[name] = b.structSchema(t)
+		}
+
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema describes a struct's JSON encoding. Fields without omitempty are required,
+// and embedded structs contribute their fields directly, unless the outer struct has a
+// field of the same name.
+func (b *openAPIBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := make([]string, 0)
+
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		embeddedTypes := make([]reflect.Type, 0)
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+
+			if tag == "-" {
+				continue
+			}
+
+			if field.Anonymous && tag == "" {
+				embedded := field.Type
+
+				if embedded.Kind() == reflect.Ptr {
+					embedded = embedded.Elem()
+				}
+
+				embeddedTypes = append(embeddedTypes, embedded)
+				continue
+			}
+
+			if field.PkgPath != "" {
+				continue
+			}
+
+			parts := strings.Split(tag, ",")
+			name := parts[0]
+
+			if name == "" {
+				name = field.Name
+			}
+
+			if _, ok := properties[name]; ok {
+				continue
+			}
+
+			properties[name] = b.schema(field.Type)
+			omitempty := false
+
+			for _, option := range parts[1:] {
+				if option == "omitempty" {
+					omitempty = true
+				}
+			}
+
+			if !omitempty {
+				required = append(required, name)
+			}
+		}
+
+		// Embedded fields come after the outer struct's, which hide them
+		for _, embedded := range embeddedTypes {
+			addFields(embedded)
+		}
+	}
+
+	addFields(t)
+	result := map[string]interface{}{"type": "object", "properties": properties}
+
+	if len(required) != 0 {
+		result["required"] = required
+	}
+
+	return result
+}
+
+// openAPIDocument describes the server's endpoints.
+func openAPIDocument(endpoints []*endpoint) map[string]interface{} {
+	b := &openAPIBuilder{schemas: map[string]interface{}{}}
+	paths := map[string]interface{}{}
+	errorSchema := b.schema(reflect.TypeOf(errorResponse{}))
+
+	for _, e := range endpoints {
+		parameters := make([]interface{}, 0, len(e.parameters))
+
+		for _, p := range e.parameters {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"description": p.description,
+				"required":    p.in == "path",
+				"schema":      map[string]interface{}{"type": "string"},
+			})
+		}
+
+		responses := map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "OK",
+				"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": b.schema(reflect.TypeOf(e.response))}},
+			},
+			"default": map[string]interface{}{
+				"description": "Error",
+				"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": errorSchema}},
+			},
+		}
+
+		operation := map[string]interface{}{
+			"summary":    e.summary,
+			"parameters": parameters,
+			"responses":  responses,
+		}
+
+		if e.request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": b.schema(reflect.TypeOf(e.request))}},
+			}
+		}
+
+		// Only GET responses are cached
+		if e.method == http.MethodGet {
+			addConditionalGet(operation)
+		}
+
+		if paths[e.path] == nil {
+			paths[e.path] = map[string]interface{}{}
+		}
+
+		paths[e.path].(map[string]interface{})[strings.ToLower(e.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "AWS service authorization reference",
+			"description": "Actions, resource types, and condition keys from the AWS Service Authorization Reference.",
+			"version":     "1",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": b.schemas},
+	}
+}
+
+// addConditionalGet documents the ETag and If-None-Match headers of a GET operation.
+func addConditionalGet(operation map[string]interface{}) {
+	operation["parameters"] = append(operation["parameters"].([]interface{}), map[string]interface{}{
+		"name":        "If-None-Match",
+		"in":          "header",
+		"description": "ETag of a cached response; if it's still current, the server responds with 304 and no body",
+		"required":    false,
+		"schema":      map[string]interface{}{"type": "string"},
+	})
+
+	etagHeader := map[string]interface{}{
+		"ETag": map[string]interface{}{
+			"description": "Changes whenever the response does, such as when the dataset is refreshed",
+			"schema":      map[string]interface{}{"type": "string"},
+		},
+	}
+
+	responses := operation["responses"].(map[string]interface{})
+	responses["200"].(map[string]interface{})["headers"] = etagHeader
+	responses["304"] = map[string]interface{}{
+		"description": "Not modified since the response with the ETag in If-None-Match",
+		"headers":     etagHeader,
+	}
+}
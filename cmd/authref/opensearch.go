@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// openSearchMapping is the index definition for actions.bulk.ndjson, to be sent with
+// "PUT /<index>" before loading the documents. It works for both Elasticsearch and
+// OpenSearch. Names and prefixes are keywords, so they can be matched exactly next to the
+// eventName and eventSource of CloudTrail records, and resource types are nested, so that
+// a query can ask for a required resource type rather than any type on an action that
+// has some required one.
+const openSearchMapping = `{
+  "mappings": {
+    "dynamic": "strict",
+    "properties": {
+      "servicePrefix": {"type": "keyword"},
+      "serviceName": {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+      "action": {"type": "keyword"},
+      "name": {"type": "keyword"},
+      "permissionOnly": {"type": "boolean"},
+      "annotations": {"type": "keyword"},
+      "referenceHref": {"type": "keyword", "index": false},
+      "description": {"type": "text"},
+      "accessLevel": {"type": "keyword"},
+      "resourceTypes": {"type": "nested", "properties": {
+        "resourceType": {"type": "keyword"},
+        "required": {"type": "boolean"},
+        "requiredGroup": {"type": "integer"},
+        "conditionKeys": {"type": "keyword"},
+        "dependentActions": {"type": "keyword"}
+      }},
+      "conditionKeys": {"type": "keyword"},
+      "localizedDescriptions": {"properties": {
+        "locale": {"type": "keyword"},
+        "description": {"type": "text"}
+      }}
+    }
+  }
+}
+`
+
+// openSearchBulkAction is the action line before each document in a _bulk request. The
+// index isn't named, so the file loads into whichever index the request's URL names.
+type openSearchBulkAction struct {
+	Index struct {
+		ID string `json:"_id"`
+	} `json:"index"`
+}
+
+// exportOpenSearch writes the actions in the _bulk format of Elasticsearch and OpenSearch,
+// with the same documents as the BigQuery export, along with the index mapping. Each
+// document's ID is its policy name, so loading a newer release updates the index in place.
+func exportOpenSearch(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	dataFile := filepath.Join(dir, "actions.bulk.ndjson")
+	mappingFile := filepath.Join(dir, "actions.mapping.json")
+
+	file, err := os.Create(dataFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+
+	for _, action := range authref.AllActions(authRefs) {
+		var bulkAction openSearchBulkAction
+		bulkAction.Index.ID = action.String()
+
+		if err := encoder.Encode(&bulkAction); err != nil {
+			return nil, err
+		}
+
+		if err := encoder.Encode(newBigQueryAction(action)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(mappingFile, []byte(openSearchMapping), 0644); err != nil {
+		return nil, err
+	}
+
+	return []string{dataFile, mappingFile}, nil
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// Types of Thrift compact protocol fields, as used in Parquet's metadata.
+// See https://github.com/apache/thrift/blob/master/doc/specs/thrift-compact-protocol.md
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes structs in the Thrift compact protocol. Fields must be written in
+// the order of their IDs.
+type thriftWriter struct {
+	bytes.Buffer
+
+	// The last field ID written in each struct being written, innermost last
+	lastField []int16
+}
+
+func (w *thriftWriter) varint(value uint64) {
+	for value >= 0x80 {
+		w.WriteByte(byte(value) | 0x80)
+		value >>= 7
+	}
+
+	w.WriteByte(byte(value))
+}
+
+func (w *thriftWriter) zigzag(value int64) {
+	w.varint(uint64(value<<1) ^ uint64(value>>63))
+}
+
+func (w *thriftWriter) field(id int16, fieldType byte) {
+	last := &w.lastField[len(w.lastField)-1]
+
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		w.WriteByte(fieldType)
+		w.zigzag(int64(id))
+	}
+
+	*last = id
+}
+
+// structBody writes the fields written by fields, followed by the end of the struct.
+func (w *thriftWriter) structBody(fields func()) {
+	w.lastField = append(w.lastField, 0)
+	fields()
+	w.WriteByte(0)
+	w.lastField = w.lastField[:len(w.lastField)-1]
+}
+
+func (w *thriftWriter) structField(id int16, fields func()) {
+	w.field(id, thriftStruct)
+	w.structBody(fields)
+}
+
+func (w *thriftWriter) i32Field(id int16, value int32) {
+	w.field(id, thriftI32)
+	w.zigzag(int64(value))
+}
+
+func (w *thriftWriter) i64Field(id int16, value int64) {
+	w.field(id, thriftI64)
+	w.zigzag(value)
+}
+
+func (w *thriftWriter) binary(value string) {
+	w.varint(uint64(len(value)))
+	w.WriteString(value)
+}
+
+func (w *thriftWriter) stringField(id int16, value string) {
+	w.field(id, thriftBinary)
+	w.binary(value)
+}
+
+// listField writes a list of count elements of elementType, each written by element.
+func (w *thriftWriter) listField(id int16, elementType byte, count int, element func(i int)) {
+	w.field(id, thriftList)
+
+	if count < 15 {
+		w.WriteByte(byte(count)<<4 | elementType)
+	} else {
+		w.WriteByte(0xf0 | elementType)
+		w.varint(uint64(count))
+	}
+
+	for i := 0; i < count; i++ {
+		element(i)
+	}
+}
+
+// Values of Parquet's enums, from parquet.thrift.
+// See https://github.com/apache/parquet-format/blob/master/src/main/thrift/parquet.thrift
+const (
+	parquetBoolean   = 0
+	parquetInt32     = 1
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+	parquetRepeated = 2
+
+	parquetConvertedUTF8 = 0
+	parquetConvertedList = 3
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+// parquetKind is the type of values in a parquetColumn.
+type parquetKind int
+
+const (
+	parquetString parquetKind = iota
+	parquetInt
+	parquetBool
+	parquetStringList
+)
+
+// parquetColumn is a column of a Parquet file. Strings, ints, and bools can be optional,
+// taking nil for null. Lists of strings are stored with Parquet's three-level LIST
+// structure, and are never null, though they can be empty.
+type parquetColumn struct {
+	name     string
+	kind     parquetKind
+	optional bool
+}
+
+// parquetFile collects the rows of a Parquet file, which is written as one row group with
+// one uncompressed page per column. That keeps the writer simple, and with a file per
+// service prefix, the files are small.
+type parquetFile struct {
+	columns []*parquetColumn
+	rows    [][]interface{}
+}
+
+// row adds a row, with a value for each column: a string, int, bool, []string, or nil.
+func (file *parquetFile) row(values ...interface{}) {
+	if len(values) != len(file.columns) {
+		panic(fmt.Sprintf("%d values for %d Parquet columns", len(values), len(file.columns)))
+	}
+
+	file.rows = append(file.rows, values)
+}
+
+// parquetLevels encodes repetition or definition levels of 0 or 1 with the RLE/bit-packing
+// hybrid encoding, using only RLE runs, preceded by their length.
+func parquetLevels(levels []int) []byte {
+	var runs thriftWriter
+
+	for i := 0; i < len(levels); {
+		j := i
+
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+
+		runs.varint(uint64(j-i) << 1)
+		runs.WriteByte(byte(levels[i]))
+		i = j
+	}
+
+	result := make([]byte, 4, 4+runs.Len())
+	binary.LittleEndian.PutUint32(result, uint32(runs.Len()))
+	return append(result, runs.Bytes()...)
+}
+
+// page encodes the column at index as a data page, returning the page's contents, the
+// number of values it has (including nulls and empty lists), and the column's physical type.
+func (file *parquetFile) page(index int) (data []byte, count int, physicalType int32) {
+	column := file.columns[index]
+	repetition, definition := make([]int, 0), make([]int, 0)
+	var values bytes.Buffer
+	bools := make([]bool, 0)
+
+	plainString := func(value string) {
+		var length [4]byte
+		binary.LittleEndian.PutUint32(length[:], uint32(len(value)))
+		values.Write(length[:])
+		values.WriteString(value)
+	}
+
+	for _, row := range file.rows {
+		value := row[index]
+
+		if column.kind == parquetStringList {
+			list := value.([]string)
+
+			if len(list) == 0 {
+				repetition = append(repetition, 0)
+				definition = append(definition, 0)
+			}
+
+			for i, element := range list {
+				if i == 0 {
+					repetition = append(repetition, 0)
+				} else {
+					repetition = append(repetition, 1)
+				}
+
+				definition = append(definition, 1)
+				plainString(element)
+			}
+
+			continue
+		}
+
+		if value == nil {
+			definition = append(definition, 0)
+			continue
+		}
+
+		definition = append(definition, 1)
+
+		switch value := value.(type) {
+		case string:
+			plainString(value)
+		case int:
+			var buf [4]byte
+			binary.LittleEndian.PutUint32(buf[:], uint32(int32(value)))
+			values.Write(buf[:])
+		case bool:
+			bools = append(bools, value)
+		default:
+			panic(fmt.Sprintf("unexpected %T in Parquet column %s", value, column.name))
+		}
+	}
+
+	// Booleans are bit-packed, starting with the least significant bit
+	if column.kind == parquetBool {
+		packed := make([]byte, (len(bools)+7)/8)
+
+		for i, value := range bools {
+			if value {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+
+		values.Write(packed)
+	}
+
+	var page bytes.Buffer
+
+	if column.kind == parquetStringList {
+		page.Write(parquetLevels(repetition))
+	}
+
+	if column.kind == parquetStringList || column.optional {
+		page.Write(parquetLevels(definition))
+	}
+
+	page.Write(values.Bytes())
+
+	switch column.kind {
+	case parquetInt:
+		physicalType = parquetInt32
+	case parquetBool:
+		physicalType = parquetBoolean
+	default:
+		physicalType = parquetByteArray
+	}
+
+	return page.Bytes(), len(definition), physicalType
+}
+
+// schema writes the elements of the file's schema, flattened depth first.
+func (file *parquetFile) schema(w *thriftWriter) {
+	elements := make([]func(), 0)
+	elements = append(elements, func() {
+		w.stringField(4, "schema")
+		w.i32Field(5, int32(len(file.columns)))
+	})
+
+	stringType := func() {
+		w.i32Field(6, parquetConvertedUTF8)
+		w.structField(10, func() {
+			w.structField(1, func() {})
+		})
+	}
+
+	for _, column := range file.columns {
+		column := column
+		repetition := int32(parquetRequired)
+
+		if column.optional {
+			repetition = parquetOptional
+		}
+
+		switch column.kind {
+		case parquetString:
+			elements = append(elements, func() {
+				w.i32Field(1, parquetByteArray)
+				w.i32Field(3, repetition)
+				w.stringField(4, column.name)
+				stringType()
+			})
+		case parquetInt:
+			elements = append(elements, func() {
+				w.i32Field(1, parquetInt32)
+				w.i32Field(3, repetition)
+				w.stringField(4, column.name)
+			})
+		case parquetBool:
+			elements = append(elements, func() {
+				w.i32Field(1, parquetBoolean)
+				w.i32Field(3, repetition)
+				w.stringField(4, column.name)
+			})
+		case parquetStringList:
+			elements = append(elements, func() {
+				w.i32Field(3, parquetRequired)
+				w.stringField(4, column.name)
+				w.i32Field(5, 1)
+				w.i32Field(6, parquetConvertedList)
+				w.structField(10, func() {
+					w.structField(3, func() {})
+				})
+			}, func() {
+				w.i32Field(3, parquetRepeated)
+				w.stringField(4, "list")
+				w.i32Field(5, 1)
+			}, func() {
+				w.i32Field(1, parquetByteArray)
+				w.i32Field(3, parquetRequired)
+				w.stringField(4, "element")
+				stringType()
+			})
+		}
+	}
+
+	w.listField(2, thriftStruct, len(elements), func(i int) {
+		w.structBody(elements[i])
+	})
+}
+
+// encode returns the contents of the Parquet file.
+// See https://parquet.apache.org/docs/file-format/
+func (file *parquetFile) encode() []byte {
+	type chunk struct {
+		physicalType int32
+		path         []string
+		count        int
+		offset       int64
+		size         int64
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("PAR1")
+	chunks := make([]*chunk, len(file.columns))
+	var totalSize int64
+
+	for i, column := range file.columns {
+		data, count, physicalType := file.page(i)
+
+		var header thriftWriter
+		header.structBody(func() {
+			header.i32Field(1, parquetDataPage)
+			header.i32Field(2, int32(len(data)))
+			header.i32Field(3, int32(len(data)))
+			header.structField(5, func() {
+				header.i32Field(1, int32(count))
+				header.i32Field(2, parquetPlain)
+				header.i32Field(3, parquetRLE)
+				header.i32Field(4, parquetRLE)
+			})
+		})
+
+		chunks[i] = &chunk{
+			physicalType: physicalType,
+			path:         []string{column.name},
+			count:        count,
+			offset:       int64(buf.Len()),
+			size:         int64(header.Len() + len(data)),
+		}
+
+		if column.kind == parquetStringList {
+			chunks[i].path = []string{column.name, "list", "element"}
+		}
+
+		buf.Write(header.Bytes())
+		buf.Write(data)
+		totalSize += chunks[i].size
+	}
+
+	var footer thriftWriter
+	footer.structBody(func() {
+		footer.i32Field(1, 1)
+		file.schema(&footer)
+		footer.i64Field(3, int64(len(file.rows)))
+		footer.listField(4, thriftStruct, 1, func(int) {
+			footer.structBody(func() {
+				footer.listField(1, thriftStruct, len(chunks), func(i int) {
+					c := chunks[i]
+
+					footer.structBody(func() {
+						footer.i64Field(2, c.offset)
+						footer.structField(3, func() {
+							footer.i32Field(1, c.physicalType)
+							footer.listField(2, thriftI32, 2, func(i int) {
+								footer.zigzag([]int64{parquetPlain, parquetRLE}[i])
+							})
+							footer.listField(3, thriftBinary, len(c.path), func(i int) {
+								footer.binary(c.path[i])
+							})
+							footer.i32Field(4, parquetUncompressed)
+							footer.i64Field(5, int64(c.count))
+							footer.i64Field(6, c.size)
+							footer.i64Field(7, c.size)
+							footer.i64Field(9, c.offset)
+						})
+					})
+				})
+				footer.i64Field(2, totalSize)
+				footer.i64Field(3, int64(len(file.rows)))
+			})
+		})
+		footer.stringField(6, "authref export")
+	})
+
+	buf.Write(footer.Bytes())
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(footer.Len()))
+	buf.Write(length[:])
+	buf.WriteString("PAR1")
+	return buf.Bytes()
+}
+
+// parquetTables are the tables of the Parquet export, named as in the postgres export.
+// The service prefix isn't a column; it comes from the directory each file is in.
+var parquetTables = []struct {
+	name    string
+	columns []*parquetColumn
+}{
+	{name: "service", columns: []*parquetColumn{
+		{name: "name", kind: parquetString},
+		{name: "reference_href", kind: parquetString},
+		{name: "api_reference_href", kind: parquetString, optional: true},
+		{name: "service_principals", kind: parquetStringList},
+		{name: "last_updated", kind: parquetString, optional: true},
+	}},
+	{name: "resource_type", columns: []*parquetColumn{
+		{name: "service_name", kind: parquetString},
+		{name: "name", kind: parquetString},
+		{name: "arn_pattern", kind: parquetString},
+		{name: "reference_href", kind: parquetString, optional: true},
+		{name: "condition_keys", kind: parquetStringList},
+	}},
+	{name: "condition_key", columns: []*parquetColumn{
+		{name: "service_name", kind: parquetString},
+		{name: "name", kind: parquetString},
+		{name: "type", kind: parquetString},
+		{name: "description", kind: parquetString},
+		{name: "reference_href", kind: parquetString, optional: true},
+	}},
+	{name: "action", columns: []*parquetColumn{
+		{name: "service_name", kind: parquetString},
+		{name: "name", kind: parquetString},
+		{name: "access_level", kind: parquetString},
+		{name: "permission_only", kind: parquetBool},
+		{name: "description", kind: parquetString},
+		{name: "reference_href", kind: parquetString, optional: true},
+		{name: "annotations", kind: parquetStringList},
+		{name: "condition_keys", kind: parquetStringList},
+	}},
+	{name: "action_resource_type", columns: []*parquetColumn{
+		{name: "action", kind: parquetString},
+		{name: "position", kind: parquetInt},
+		{name: "resource_type", kind: parquetString},
+		{name: "required", kind: parquetBool},
+		{name: "required_group", kind: parquetInt, optional: true},
+		{name: "condition_keys", kind: parquetStringList},
+		{name: "dependent_actions", kind: parquetStringList},
+	}},
+}
+
+// exportParquet writes a Parquet file for each table and service prefix, in directories
+// named for Hive partitioning, such as parquet/action/service_prefix=s3/data.parquet, so
+// that DuckDB, Spark, and the like can read the prefix as a column and skip the files of
+// services a query doesn't need.
+func exportParquet(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	// Files by table name and then prefix
+	files := map[string]map[string]*parquetFile{}
+
+	fileFor := func(table, prefix string) *parquetFile {
+		if files[table] == nil {
+			files[table] = map[string]*parquetFile{}
+		}
+
+		if files[table][prefix] == nil {
+			for _, t := range parquetTables {
+				if t.name == table {
+					files[table][prefix] = &parquetFile{columns: t.columns}
+				}
+			}
+		}
+
+		return files[table][prefix]
+	}
+
+	for _, authRef := range authRefs {
+		prefix := authRef.ServicePrefix
+		fileFor("service", prefix).row(authRef.Name, authRef.AuthReferenceHref, optional(authRef.ApiReferenceHref),
+			nonNil(authRef.ServicePrincipals), optional(authRef.LastUpdated))
+
+		for _, resourceType := range authRef.ResourceTypes {
+			fileFor("resource_type", prefix).row(authRef.Name, resourceType.Name, resourceType.ArnPattern, optional(resourceType.ReferenceHref),
+				nonNil(resourceType.ConditionKeys))
+		}
+
+		for _, conditionKey := range authRef.ConditionKeys {
+			fileFor("condition_key", prefix).row(authRef.Name, conditionKey.Name, conditionKey.Type, conditionKey.Description,
+				optional(conditionKey.ReferenceHref))
+		}
+
+		for _, action := range authRef.Actions {
+			fileFor("action", prefix).row(authRef.Name, action.Name, string(action.AccessLevel), action.PermissionOnly, action.Description,
+				optional(action.ReferenceHref), nonNil(action.Annotations), nonNil(action.ConditionKeys))
+
+			for i, resourceType := range action.ResourceTypes {
+				var group interface{}
+
+				if resourceType.RequiredGroup != 0 {
+					group = resourceType.RequiredGroup
+				}
+
+				fileFor("action_resource_type", prefix).row(action.Name, i+1, resourceType.ResourceType, resourceType.Required, group,
+					nonNil(resourceType.ConditionKeys), nonNil(resourceType.DependentActions))
+			}
+		}
+	}
+
+	written := make([]string, 0)
+
+	for _, table := range parquetTables {
+		prefixes := make([]string, 0, len(files[table.name]))
+
+		for prefix := range files[table.name] {
+			prefixes = append(prefixes, prefix)
+		}
+
+		sort.Strings(prefixes)
+
+		for _, prefix := range prefixes {
+			partition := filepath.Join(dir, "parquet", table.name, "service_prefix="+prefix)
+
+			if err := os.MkdirAll(partition, 0755); err != nil {
+				return nil, err
+			}
+
+			filename := filepath.Join(partition, "data.parquet")
+
+			if err := os.WriteFile(filename, files[table.name][prefix].encode(), 0644); err != nil {
+				return nil, err
+			}
+
+			written = append(written, filename)
+		}
+	}
+
+	return written, nil
+}
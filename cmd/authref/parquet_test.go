@@ -0,0 +1,436 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// thriftReader decodes structs in the Thrift compact protocol into maps of field IDs to
+// values, independently of the writer in parquet.go.
+type thriftReader struct {
+	data []byte
+	err  error
+}
+
+func (r *thriftReader) fail(format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (r *thriftReader) next(n int) []byte {
+	if r.err != nil || n < 0 || len(r.data) < n {
+		r.fail("unexpected end of data")
+		return make([]byte, n)
+	}
+
+	result := r.data[:n]
+	r.data = r.data[n:]
+	return result
+}
+
+func (r *thriftReader) varint() uint64 {
+	var result uint64
+
+	for shift := uint(0); shift < 64 && r.err == nil; shift += 7 {
+		b := r.next(1)[0]
+		result |= uint64(b&0x7f) << shift
+
+		if b&0x80 == 0 {
+			return result
+		}
+	}
+
+	r.fail("varint too long")
+	return 0
+}
+
+func (r *thriftReader) zigzag() int64 {
+	value := r.varint()
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+func (r *thriftReader) value(fieldType byte) interface{} {
+	switch fieldType {
+	case 1, 2:
+		return fieldType == 1
+	case 3:
+		return int64(int8(r.next(1)[0]))
+	case 4, 5, 6:
+		return r.zigzag()
+	case 8:
+		return string(r.next(int(r.varint())))
+	case 9:
+		header := r.next(1)[0]
+		count := int(header >> 4)
+
+		if count == 15 {
+			count = int(r.varint())
+		}
+
+		result := make([]interface{}, 0, count)
+
+		for i := 0; i < count && r.err == nil; i++ {
+			result = append(result, r.value(header&0x0f))
+		}
+
+		return result
+	case 12:
+		return r.structure()
+	}
+
+	r.fail("unsupported Thrift type %d", fieldType)
+	return nil
+}
+
+func (r *thriftReader) structure() map[int16]interface{} {
+	result := map[int16]interface{}{}
+	var last int16
+
+	for r.err == nil {
+		header := r.next(1)[0]
+
+		if header == 0 {
+			break
+		}
+
+		id := last + int16(header>>4)
+
+		if header>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+
+		result[id] = r.value(header & 0x0f)
+		last = id
+	}
+
+	return result
+}
+
+// parquetLevelsAt decodes levels of 0 or 1 in the RLE/bit-packing hybrid encoding, with
+// their length in front, returning them and the bytes after them.
+func parquetLevelsAt(t *testing.T, data []byte, count int) ([]int, []byte) {
+	length := binary.LittleEndian.Uint32(data)
+	r := &thriftReader{data: data[4 : 4+length]}
+	levels := make([]int, 0, count)
+
+	for len(r.data) != 0 && r.err == nil {
+		header := r.varint()
+
+		if header&1 != 0 {
+			// Bit-packed groups of eight
+			for _, b := range r.next(int(header >> 1)) {
+				for bit := 0; bit < 8; bit++ {
+					levels = append(levels, int(b>>bit&1))
+				}
+			}
+		} else {
+			value := int(r.next(1)[0])
+
+			for i := uint64(0); i < header>>1; i++ {
+				levels = append(levels, value)
+			}
+		}
+	}
+
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+
+	return levels[:count], data[4+length:]
+}
+
+// readParquet decodes a Parquet file written with one row group and plain-encoded
+// pages, returning the names of its schema elements, depth first, and its rows with
+// lists as []string.
+func readParquet(t *testing.T, data []byte) ([]string, [][]interface{}) {
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatalf("no PAR1 magic at the start and end")
+	}
+
+	footerLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{data: data[len(data)-8-footerLength : len(data)-8]}
+	metadata := r.structure()
+
+	if r.err != nil || len(r.data) != 0 {
+		t.Fatalf("footer: %v, %d bytes left", r.err, len(r.data))
+	}
+
+	names := make([]string, 0)
+
+	for _, element := range metadata[2].([]interface{}) {
+		names = append(names, element.(map[int16]interface{})[4].(string))
+	}
+
+	rowCount := int(metadata[3].(int64))
+	rowGroups := metadata[4].([]interface{})
+
+	if len(rowGroups) != 1 || int(rowGroups[0].(map[int16]interface{})[3].(int64)) != rowCount {
+		t.Fatalf("expected one row group of %d rows, got %v", rowCount, rowGroups)
+	}
+
+	rows := make([][]interface{}, rowCount)
+
+	for i := range rows {
+		rows[i] = make([]interface{}, 0)
+	}
+
+	leaves := 0
+
+	for _, element := range metadata[2].([]interface{})[1:] {
+		if _, ok := element.(map[int16]interface{})[1]; ok {
+			leaves++
+		}
+	}
+
+	chunks := rowGroups[0].(map[int16]interface{})[1].([]interface{})
+
+	if len(chunks) != leaves {
+		t.Fatalf("%d column chunks for %d leaf columns", len(chunks), leaves)
+	}
+
+	for _, c := range chunks {
+		chunk := c.(map[int16]interface{})[3].(map[int16]interface{})
+		physicalType := chunk[1].(int64)
+		path := chunk[3].([]interface{})
+		isList := len(path) == 3
+		optional := false
+
+		for _, element := range metadata[2].([]interface{}) {
+			element := element.(map[int16]interface{})
+
+			if element[4] == path[0] && element[3].(int64) == parquetOptional {
+				optional = true
+			}
+		}
+
+		r := &thriftReader{data: data[chunk[9].(int64):]}
+		header := r.structure()
+		page := r.next(int(header[3].(int64)))
+		count := int(header[5].(map[int16]interface{})[1].(int64))
+
+		if r.err != nil {
+			t.Fatalf("column %v: %v", path, r.err)
+		}
+
+		if int(chunk[5].(int64)) != count {
+			t.Errorf("column %v: the chunk has %d values but its page %d", path, chunk[5], count)
+		}
+
+		var repetition, definition []int
+
+		if isList {
+			repetition, page = parquetLevelsAt(t, page, count)
+		}
+
+		if isList || optional {
+			definition, page = parquetLevelsAt(t, page, count)
+		}
+
+		values := &thriftReader{data: page}
+		bit := 0
+
+		readValue := func() interface{} {
+			switch physicalType {
+			case parquetByteArray:
+				return string(values.next(int(binary.LittleEndian.Uint32(values.next(4)))))
+			case parquetInt32:
+				return int(int32(binary.LittleEndian.Uint32(values.next(4))))
+			default:
+				value := values.data[bit/8]>>(bit%8)&1 == 1
+				bit++
+				return value
+			}
+		}
+
+		row := -1
+
+		for i := 0; i < count; i++ {
+			if !isList || repetition[i] == 0 {
+				row++
+
+				if isList {
+					rows[row] = append(rows[row], []string{})
+				}
+			}
+
+			if row >= rowCount {
+				t.Fatalf("column %v has more than %d rows", path, rowCount)
+			}
+
+			switch {
+			case isList:
+				if definition[i] == 1 {
+					list := &rows[row][len(rows[row])-1]
+					*list = append((*list).([]string), readValue().(string))
+				}
+			case optional && definition[i] == 0:
+				rows[row] = append(rows[row], nil)
+			default:
+				rows[row] = append(rows[row], readValue())
+			}
+		}
+
+		if values.err != nil {
+			t.Fatalf("column %v: %v", path, values.err)
+		}
+
+		if row != rowCount-1 {
+			t.Errorf("column %v has %d rows, want %d", path, row+1, rowCount)
+		}
+	}
+
+	return names, rows
+}
+
+func TestExportParquet(t *testing.T) {
+	authRefs := []*authref.ServiceAuthorizationReference{
+		{
+			Name:              "Amazon S3",
+			ServicePrefix:     "s3",
+			AuthReferenceHref: "https://docs.aws.amazon.com/service-authorization/latest/reference/list_amazons3.html",
+			ServicePrincipals: []string{"s3.amazonaws.com"},
+			LastUpdated:       "2026-10-01",
+			Actions: []*authref.Action{
+				{
+					Name:          "GetObject",
+					Annotations:   []string{"Deprecated"},
+					ReferenceHref: "https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html",
+					Description:   "Grants permission to retrieve objects",
+					AccessLevel:   authref.AccessLevelRead,
+					ResourceTypes: []authref.ActionResourceType{
+						{ResourceType: "object", Required: true, RequiredGroup: 1, ConditionKeys: []string{"s3:ExistingObjectTag/<key>"}, DependentActions: []string{"kms:Decrypt", "kms:GenerateDataKey"}},
+						{ResourceType: "accesspoint"},
+					},
+					ConditionKeys: []string{"aws:ResourceTag/${TagKey}", "s3:authType"},
+				},
+				{Name: "PutObject", AccessLevel: authref.AccessLevelWrite, PermissionOnly: true},
+			},
+			ResourceTypes: []*authref.ResourceType{
+				{Name: "object", ArnPattern: "arn:${Partition}:s3:::${BucketName}/${ObjectName}", ConditionKeys: []string{"s3:ExistingObjectTag/<key>"}},
+			},
+			ConditionKeys: []*authref.ConditionKey{
+				{Name: "s3:authType", Type: "String", Description: "Filters access by authentication method", ReferenceHref: "https://example.com"},
+			},
+		},
+		{Name: "Amazon EC2", ServicePrefix: "ec2", AuthReferenceHref: "https://docs.aws.amazon.com/service-authorization/latest/reference/list_amazonec2.html"},
+	}
+
+	// Enough actions for list runs longer than fit in a byte, and to spread booleans
+	// across several bytes
+	for i := 0; i < 300; i++ {
+		authRefs[1].Actions = append(authRefs[1].Actions, &authref.Action{
+			Name:           fmt.Sprintf("Action%03d", i),
+			AccessLevel:    authref.AccessLevelList,
+			PermissionOnly: i%3 == 0,
+			ConditionKeys:  []string{fmt.Sprintf("ec2:Key%d", i%2)}[:i%2],
+		})
+	}
+
+	dir := t.TempDir()
+
+	if _, err := exportParquet(dir, authRefs); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rows each file should have, built from the dataset
+	want := map[string]map[string][][]interface{}{}
+
+	add := func(table, prefix string, values ...interface{}) {
+		if want[table] == nil {
+			want[table] = map[string][][]interface{}{}
+		}
+
+		want[table][prefix] = append(want[table][prefix], values)
+	}
+
+	orNil := func(value string) interface{} {
+		if value == "" {
+			return nil
+		}
+
+		return value
+	}
+
+	list := func(values []string) []string {
+		return append([]string{}, values...)
+	}
+
+	for _, authRef := range authRefs {
+		prefix := authRef.ServicePrefix
+		add("service", prefix, authRef.Name, authRef.AuthReferenceHref, orNil(authRef.ApiReferenceHref), list(authRef.ServicePrincipals), orNil(authRef.LastUpdated))
+
+		for _, resourceType := range authRef.ResourceTypes {
+			add("resource_type", prefix, authRef.Name, resourceType.Name, resourceType.ArnPattern, orNil(resourceType.ReferenceHref), list(resourceType.ConditionKeys))
+		}
+
+		for _, conditionKey := range authRef.ConditionKeys {
+			add("condition_key", prefix, authRef.Name, conditionKey.Name, conditionKey.Type, conditionKey.Description, orNil(conditionKey.ReferenceHref))
+		}
+
+		for _, action := range authRef.Actions {
+			add("action", prefix, authRef.Name, action.Name, string(action.AccessLevel), action.PermissionOnly, action.Description,
+				orNil(action.ReferenceHref), list(action.Annotations), list(action.ConditionKeys))
+
+			for i, resourceType := range action.ResourceTypes {
+				var group interface{}
+
+				if resourceType.RequiredGroup != 0 {
+					group = resourceType.RequiredGroup
+				}
+
+				add("action_resource_type", prefix, action.Name, i+1, resourceType.ResourceType, resourceType.Required, group,
+					list(resourceType.ConditionKeys), list(resourceType.DependentActions))
+			}
+		}
+	}
+
+	for _, table := range parquetTables {
+		wantNames := []string{"schema"}
+
+		for _, column := range table.columns {
+			wantNames = append(wantNames, column.name)
+
+			if column.kind == parquetStringList {
+				wantNames = append(wantNames, "list", "element")
+			}
+		}
+
+		for prefix, wantRows := range want[table.name] {
+			filename := filepath.Join(dir, "parquet", table.name, "service_prefix="+prefix, "data.parquet")
+			data, err := os.ReadFile(filename)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names, rows := readParquet(t, data)
+
+			if !reflect.DeepEqual(names, wantNames) {
+				t.Errorf("%s: schema is %v, want %v", filename, names, wantNames)
+			}
+
+			if len(rows) != len(wantRows) {
+				t.Errorf("%s: %d rows, want %d", filename, len(rows), len(wantRows))
+				continue
+			}
+
+			for i := range rows {
+				if !reflect.DeepEqual(rows[i], wantRows[i]) {
+					t.Errorf("%s: row %d is %v, want %v", filename, i, rows[i], wantRows[i])
+				}
+			}
+		}
+	}
+
+	// Tables a service has no rows for get no file
+	if _, err := os.Stat(filepath.Join(dir, "parquet", "resource_type", "service_prefix=ec2")); !os.IsNotExist(err) {
+		t.Errorf("ec2 has a resource_type partition, though it has no resource types")
+	}
+}
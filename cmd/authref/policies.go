@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// starterPolicy is a kind of policy generated for every service from its access levels.
+type starterPolicy struct {
+	name   string
+	sid    string
+	levels []authref.AccessLevel
+}
+
+var starterPolicies = []*starterPolicy{
+	{name: "read-only", sid: "ReadOnly", levels: []authref.AccessLevel{authref.AccessLevelList, authref.AccessLevelRead}},
+	{name: "power-user", sid: "PowerUser", levels: []authref.AccessLevel{authref.AccessLevelList, authref.AccessLevelRead, authref.AccessLevelWrite, authref.AccessLevelTagging}},
+	{name: "tagging-only", sid: "TaggingOnly", levels: []authref.AccessLevel{authref.AccessLevelTagging}},
+}
+
+// exportPolicies writes starter policies for each service prefix to policies/<prefix>/:
+// read-only.json allows the List and Read actions, power-user.json everything but
+// Permissions management, and tagging-only.json the Tagging actions. Each uses the
+// fewest patterns that match exactly those actions today, as from MinimizePatterns, so
+// the policies stay small; the patterns may match actions added later at other access
+// levels. Policies that would allow nothing aren't written.
+func exportPolicies(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	byPrefix := map[string][]*authref.QualifiedAction{}
+	prefixes := make([]string, 0)
+
+	for _, action := range authref.AllActions(authRefs) {
+		prefix := action.Service.ServicePrefix
+
+		if byPrefix[prefix] == nil {
+			prefixes = append(prefixes, prefix)
+		}
+
+		byPrefix[prefix] = append(byPrefix[prefix], action)
+	}
+
+	sort.Strings(prefixes)
+	files := make([]string, 0)
+
+	for _, prefix := range prefixes {
+		serviceDir := filepath.Join(dir, "policies", prefix)
+
+		for _, starter := range starterPolicies {
+			included := map[authref.AccessLevel]bool{}
+
+			for _, level := range starter.levels {
+				included[level] = true
+			}
+
+			target := make([]*authref.QualifiedAction, 0)
+
+			for _, action := range byPrefix[prefix] {
+				if included[action.Action.AccessLevel] {
+					target = append(target, action)
+				}
+			}
+
+			if len(target) == 0 {
+				continue
+			}
+
+			// Patterns can't reach outside the service, so its own actions are enough of a universe
+			patterns, err := authref.MinimizePatterns(byPrefix[prefix], target)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if len(patterns) == 1 && patterns[0] == "*" {
+				patterns = []string{prefix + ":*"}
+			}
+
+			policy := &authref.Policy{
+				Version: "2012-10-17",
+				Statement: authref.StatementList{
+					{Sid: starter.sid, Effect: authref.EffectAllow, Action: patterns, Resource: authref.StringList{"*"}},
+				},
+			}
+
+			data, err := json.MarshalIndent(policy, "", "  ")
+
+			if err != nil {
+				return nil, err
+			}
+
+			if err := os.MkdirAll(serviceDir, 0755); err != nil {
+				return nil, err
+			}
+
+			filename := filepath.Join(serviceDir, starter.name+".json")
+
+			if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+				return nil, err
+			}
+
+			files = append(files, filename)
+		}
+	}
+
+	return files, nil
+}
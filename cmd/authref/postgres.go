@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// postgresSchema creates the tables of the PostgreSQL export in their own schema, which is
+// dropped first so that loading a newer release replaces the old one. Pages that share a
+// service prefix are separate services, so rows are keyed by generated IDs rather than by
+// prefix. Actions can name resource types and condition keys their page doesn't define;
+// those rows keep the name, with a null ID.
+const postgresSchema = `-- Tables of the AWS Service Authorization Reference, written by authref export
+DROP SCHEMA IF EXISTS authref CASCADE;
+CREATE SCHEMA authref;
+
+-- One page of the reference
+CREATE TABLE authref.service (
+    id integer PRIMARY KEY,
+    prefix text NOT NULL,
+    name text NOT NULL,
+    reference_href text NOT NULL,
+    api_reference_href text,
+    service_principals text[] NOT NULL,
+    last_updated date
+);
+
+CREATE INDEX ON authref.service (prefix);
+
+CREATE TABLE authref.resource_type (
+    id integer PRIMARY KEY,
+    service_id integer NOT NULL REFERENCES authref.service,
+    name text NOT NULL,
+    arn_pattern text NOT NULL,
+    reference_href text,
+    UNIQUE (service_id, name)
+);
+
+CREATE TABLE authref.condition_key (
+    id integer PRIMARY KEY,
+    service_id integer NOT NULL REFERENCES authref.service,
+    name text NOT NULL,
+    type text NOT NULL,
+    description text NOT NULL,
+    reference_href text,
+    UNIQUE (service_id, name)
+);
+
+CREATE TABLE authref.action (
+    id integer PRIMARY KEY,
+    service_id integer NOT NULL REFERENCES authref.service,
+    name text NOT NULL,
+    access_level text NOT NULL,
+    permission_only boolean NOT NULL,
+    description text NOT NULL,
+    reference_href text,
+    annotations text[] NOT NULL,
+    UNIQUE (service_id, name)
+);
+
+-- Condition keys that apply to an action whatever resource it names
+CREATE TABLE authref.action_condition_key (
+    action_id integer NOT NULL REFERENCES authref.action,
+    condition_key text NOT NULL,
+    condition_key_id integer REFERENCES authref.condition_key,
+    PRIMARY KEY (action_id, condition_key)
+);
+
+-- A row of an action's resource types, numbered from 1 in the order of the page
+CREATE TABLE authref.action_resource_type (
+    action_id integer NOT NULL REFERENCES authref.action,
+    position integer NOT NULL,
+    resource_type text NOT NULL,
+    resource_type_id integer REFERENCES authref.resource_type,
+    required boolean NOT NULL,
+    required_group integer,
+    PRIMARY KEY (action_id, position)
+);
+
+CREATE TABLE authref.action_resource_type_condition_key (
+    action_id integer NOT NULL,
+    position integer NOT NULL,
+    condition_key text NOT NULL,
+    condition_key_id integer REFERENCES authref.condition_key,
+    FOREIGN KEY (action_id, position) REFERENCES authref.action_resource_type
+);
+
+-- Dependent actions can belong to any service, and sometimes to none in the dataset, so
+-- they're kept by name, such as "s3:GetObject"
+CREATE TABLE authref.action_resource_type_dependent_action (
+    action_id integer NOT NULL,
+    position integer NOT NULL,
+    dependent_action text NOT NULL,
+    FOREIGN KEY (action_id, position) REFERENCES authref.action_resource_type
+);
+`
+
+// postgresTable is the rows of one table of the PostgreSQL export.
+type postgresTable struct {
+	name string
+	rows [][]interface{}
+}
+
+// row adds a row. Values are strings, ints, bools, string slices (as arrays), or nil.
+func (table *postgresTable) row(values ...interface{}) {
+	table.rows = append(table.rows, values)
+}
+
+// copyText returns the rows in PostgreSQL's COPY text format.
+func (table *postgresTable) copyText() []byte {
+	var buf bytes.Buffer
+
+	for _, row := range table.rows {
+		for i, value := range row {
+			if i != 0 {
+				buf.WriteByte('\t')
+			}
+
+			switch value := value.(type) {
+			case nil:
+				buf.WriteString(`\N`)
+			case string:
+				buf.WriteString(postgresCopyText(value))
+			case int:
+				buf.WriteString(strconv.Itoa(value))
+			case bool:
+				buf.WriteString(strconv.FormatBool(value))
+			case []string:
+				buf.WriteString(postgresCopyText(postgresArray(value)))
+			default:
+				panic(fmt.Sprintf("unexpected %T in a PostgreSQL row", value))
+			}
+		}
+
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
+
+// postgresCopyText escapes a value for the COPY text format.
+func postgresCopyText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(value)
+}
+
+// postgresArray writes a text array literal, quoting every element.
+func postgresArray(values []string) string {
+	quoted := make([]string, len(values))
+
+	for i, value := range values {
+		quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+
+	return "{" + strings.Join(quoted, ",") + "}"
+}
+
+// optional returns nil for an empty string, to store a missing value as NULL.
+func optional(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
+
+// postgresTables lays out the dataset as the rows of the tables in postgresSchema, in an
+// order that loads without breaking a foreign key.
+func postgresTables(authRefs []*authref.ServiceAuthorizationReference) []*postgresTable {
+	services := &postgresTable{name: "service"}
+	resourceTypes := &postgresTable{name: "resource_type"}
+	conditionKeys := &postgresTable{name: "condition_key"}
+	actions := &postgresTable{name: "action"}
+	actionConditionKeys := &postgresTable{name: "action_condition_key"}
+	actionResourceTypes := &postgresTable{name: "action_resource_type"}
+	actionResourceTypeConditionKeys := &postgresTable{name: "action_resource_type_condition_key"}
+	dependentActions := &postgresTable{name: "action_resource_type_dependent_action"}
+	resourceTypeID, conditionKeyID, actionID := 0, 0, 0
+
+	for i, authRef := range authRefs {
+		serviceID := i + 1
+		services.row(serviceID, authRef.ServicePrefix, authRef.Name, authRef.AuthReferenceHref, optional(authRef.ApiReferenceHref),
+			nonNil(authRef.ServicePrincipals), optional(authRef.LastUpdated))
+
+		resourceTypeIDs := map[string]int{}
+		conditionKeyIDs := map[string]int{}
+
+		for _, resourceType := range authRef.ResourceTypes {
+			resourceTypeID++
+			resourceTypeIDs[resourceType.Name] = resourceTypeID
+			resourceTypes.row(resourceTypeID, serviceID, resourceType.Name, resourceType.ArnPattern, optional(resourceType.ReferenceHref))
+		}
+
+		for _, conditionKey := range authRef.ConditionKeys {
+			conditionKeyID++
+			conditionKeyIDs[conditionKey.Name] = conditionKeyID
+			conditionKeys.row(conditionKeyID, serviceID, conditionKey.Name, conditionKey.Type, conditionKey.Description, optional(conditionKey.ReferenceHref))
+		}
+
+		// A reference to something the page doesn't define is stored with a null ID
+		idOf := func(ids map[string]int, name string) interface{} {
+			if id, ok := ids[name]; ok {
+				return id
+			}
+
+			return nil
+		}
+
+		for _, action := range authRef.Actions {
+			actionID++
+			actions.row(actionID, serviceID, action.Name, string(action.AccessLevel), action.PermissionOnly, action.Description,
+				optional(action.ReferenceHref), nonNil(action.Annotations))
+
+			seen := map[string]bool{}
+
+			for _, key := range action.ConditionKeys {
+				if !seen[key] {
+					seen[key] = true
+					actionConditionKeys.row(actionID, key, idOf(conditionKeyIDs, key))
+				}
+			}
+
+			for j, resourceType := range action.ResourceTypes {
+				position := j + 1
+				var group interface{}
+
+				if resourceType.RequiredGroup != 0 {
+					group = resourceType.RequiredGroup
+				}
+
+				actionResourceTypes.row(actionID, position, resourceType.ResourceType, idOf(resourceTypeIDs, resourceType.ResourceType), resourceType.Required, group)
+
+				for _, key := range resourceType.ConditionKeys {
+					actionResourceTypeConditionKeys.row(actionID, position, key, idOf(conditionKeyIDs, key))
+				}
+
+				for _, dependent := range resourceType.DependentActions {
+					dependentActions.row(actionID, position, dependent)
+				}
+			}
+		}
+	}
+
+	return []*postgresTable{services, resourceTypes, conditionKeys, actions, actionConditionKeys, actionResourceTypes, actionResourceTypeConditionKeys, dependentActions}
+}
+
+// exportPostgres writes the DDL of a normalized PostgreSQL schema, a data file in COPY
+// format for each table, and a psql script that loads them all in one transaction.
+func exportPostgres(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	postgresDir := filepath.Join(dir, "postgres")
+
+	if err := os.MkdirAll(postgresDir, 0755); err != nil {
+		return nil, err
+	}
+
+	schemaFile := filepath.Join(postgresDir, "schema.sql")
+
+	if err := os.WriteFile(schemaFile, []byte(postgresSchema), 0644); err != nil {
+		return nil, err
+	}
+
+	files := []string{schemaFile}
+	var load bytes.Buffer
+	load.WriteString("-- Run from this directory: psql -f load.sql\n\\set ON_ERROR_STOP on\nBEGIN;\n\\ir schema.sql\n")
+
+	for _, table := range postgresTables(authRefs) {
+		filename := filepath.Join(postgresDir, table.name+".copy")
+
+		if err := os.WriteFile(filename, table.copyText(), 0644); err != nil {
+			return nil, err
+		}
+
+		files = append(files, filename)
+		fmt.Fprintf(&load, "\\copy authref.%s FROM '%s.copy'\n", table.name, table.name)
+	}
+
+	load.WriteString("COMMIT;\n")
+	loadFile := filepath.Join(postgresDir, "load.sql")
+
+	if err := os.WriteFile(loadFile, load.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+
+	return append(files, loadFile), nil
+}
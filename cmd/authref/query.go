@@ -0,0 +1,397 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// maxPageSize is the largest limit a list request can ask for.
+const maxPageSize = 10000
+
+// Query parameters shared by the list endpoints
+var (
+	serviceParameter     = &parameter{name: "service", in: "query", description: "only list entries for this service prefix, such as \"s3\""}
+	accessLevelParameter = &parameter{name: "accessLevel", in: "query", description: "only list actions at this access level, such as \"Permissions management\""}
+	fieldsParameter      = &parameter{name: "fields", in: "query", description: "comma-separated fields to include in each entry, such as \"name,accessLevel\"; all if absent"}
+	limitParameter       = &parameter{name: "limit", in: "query", description: fmt.Sprintf("return at most this many entries, up to %d, with a nextCursor for the rest; all if absent", maxPageSize)}
+	cursorParameter      = &parameter{name: "cursor", in: "query", description: "the nextCursor of the previous page"}
+)
+
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+// listQuery holds the query parameters of a request to a list endpoint.
+type listQuery struct {
+	service     string
+	accessLevel authref.AccessLevel
+	q           string
+	fields      []string
+	limit       int
+	offset      int
+}
+
+// parseListQuery reads the query parameters of a list request. Fields are checked against
+// the JSON fields of itemType, the type of the entries in the list. A cursor is only
+// accepted for the dataset it was issued for, since positions change between snapshots.
+func parseListQuery(values url.Values, data *dataset, itemType reflect.Type) (*listQuery, error) {
+	query := &listQuery{
+		service:     values.Get("service"),
+		accessLevel: authref.AccessLevel(values.Get("accessLevel")),
+		q:           values.Get("q"),
+	}
+
+	if query.accessLevel != "" && !query.accessLevel.Known() {
+		return nil, badRequest("unknown access level %#v", string(query.accessLevel))
+	}
+
+	if list := values.Get("fields"); list != "" {
+		known := (&openAPIBuilder{schemas: map[string]interface{}{}}).structSchema(itemType)["properties"].(map[string]interface{})
+
+		for _, field := range strings.Split(list, ",") {
+			if field = strings.TrimSpace(field); field == "" {
+				continue
+			}
+
+			if known[field] == nil {
+				return nil, badRequest("unknown field %#v", field)
+			}
+
+			query.fields = append(query.fields, field)
+		}
+	}
+
+	if text := values.Get("limit"); text != "" {
+		limit, err := strconv.Atoi(text)
+
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return nil, badRequest("limit must be a number from 1 to %d", maxPageSize)
+		}
+
+		query.limit = limit
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		offset, err := decodeCursor(cursor, data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		query.offset = offset
+	}
+
+	return query, nil
+}
+
+// encodeCursor returns the cursor for the entry at offset in a list from data.
+func encodeCursor(data *dataset, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", data.fingerprint[:16], offset)))
+}
+
+func decodeCursor(cursor string, data *dataset) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	parts := strings.SplitN(string(decoded), ":", 2)
+
+	if err != nil || len(parts) != 2 {
+		return 0, badRequest("invalid cursor")
+	}
+
+	offset, err := strconv.Atoi(parts[1])
+
+	if err != nil || offset < 0 {
+		return 0, badRequest("invalid cursor")
+	}
+
+	if parts[0] != data.fingerprint[:16] {
+		return 0, badRequest("the cursor is from an older snapshot of the dataset; start from the first page")
+	}
+
+	return offset, nil
+}
+
+// page returns the bounds of the page of a list of count entries the query asks for,
+// and the cursor of the next page, if there is one.
+func (query *listQuery) page(data *dataset, count int) (start, end int, next string) {
+	start, end = query.offset, count
+
+	if start > count {
+		start = count
+	}
+
+	if query.limit != 0 && start+query.limit < count {
+		end = start + query.limit
+		next = encodeCursor(data, end)
+	}
+
+	return start, end, next
+}
+
+// selectFields reduces each entry of a list to the query's fields. If the query doesn't
+// name any fields, the list is returned as it is.
+func (query *listQuery) selectFields(list interface{}) (interface{}, error) {
+	if query.fields == nil {
+		return list, nil
+	}
+
+	value := reflect.ValueOf(list)
+	result := make([]map[string]json.RawMessage, value.Len())
+
+	for i := range result {
+		encoded, err := json.Marshal(value.Index(i).Interface())
+
+		if err != nil {
+			return nil, err
+		}
+
+		var all map[string]json.RawMessage
+
+		if err := json.Unmarshal(encoded, &all); err != nil {
+			return nil, err
+		}
+
+		result[i] = map[string]json.RawMessage{}
+
+		for _, field := range query.fields {
+			if fieldValue, ok := all[field]; ok {
+				result[i][field] = fieldValue
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// selectedList is the response to a list request with fields, whose entries don't match
+// the endpoint's usual response type.
+func selectedList(key string, entries interface{}, next string) map[string]interface{} {
+	result := map[string]interface{}{key: entries}
+
+	if next != "" {
+		result["nextCursor"] = next
+	}
+
+	return result
+}
+
+// listServices answers a request to /services.
+func listServices(data *dataset, values url.Values) (interface{}, error) {
+	query, err := parseListQuery(values, data, reflect.TypeOf(serviceSummary{}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if query.accessLevel != "" {
+		return nil, badRequest("accessLevel only applies to actions")
+	}
+
+	q := strings.ToLower(query.q)
+	services := make([]*serviceSummary, 0, len(data.services))
+
+	for _, service := range data.services {
+		if query.service != "" && service.ServicePrefix != query.service {
+			continue
+		}
+
+		if q != "" && !strings.Contains(strings.ToLower(service.Name), q) && !strings.Contains(service.ServicePrefix, q) {
+			continue
+		}
+
+		services = append(services, service)
+	}
+
+	start, end, next := query.page(data, len(services))
+	selected, err := query.selectFields(services[start:end])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if query.fields != nil {
+		return selectedList("services", selected, next), nil
+	}
+
+	return &serviceList{Services: services[start:end], NextCursor: next}, nil
+}
+
+// listActions answers a request to /actions. The q parameter is matched the way authref
+// search matches it, but the results stay in the dataset's order so they can be paged.
+func listActions(data *dataset, values url.Values) (interface{}, error) {
+	query, err := parseListQuery(values, data, reflect.TypeOf(actionEntry{}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var found map[string]bool
+
+	if query.q != "" {
+		found = map[string]bool{}
+
+		for _, result := range data.search.Search(query.q, 0) {
+			if result.Kind == authref.SearchKindAction {
+				found[strings.ToLower(result.Name)] = true
+			}
+		}
+	}
+
+	actions := make([]*actionEntry, 0)
+
+	for _, action := range data.actions {
+		if query.service != "" && action.ServicePrefix != query.service {
+			continue
+		}
+
+		if query.accessLevel != "" && action.AccessLevel != query.accessLevel {
+			continue
+		}
+
+		if found != nil && !found[strings.ToLower(action.ServicePrefix+":"+action.Name)] {
+			continue
+		}
+
+		actions = append(actions, action)
+	}
+
+	start, end, next := query.page(data, len(actions))
+	selected, err := query.selectFields(actions[start:end])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if query.fields != nil {
+		return selectedList("actions", selected, next), nil
+	}
+
+	return &actionList{Actions: actions[start:end], NextCursor: next}, nil
+}
+
+// defaultSearchLimit is the number of results /search returns if the request doesn't say.
+const defaultSearchLimit = 50
+
+type searchResults struct {
+	Results []*authref.SearchResult `json:"results"`
+
+	// Number of matches, including those beyond the limit
+	Total int `json:"total"`
+}
+
+// search answers a request to /search.
+func search(data *dataset, values url.Values) (*searchResults, error) {
+	query, err := authref.ParseSearchQuery(values.Get("q"))
+
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+
+	limit := defaultSearchLimit
+
+	if text := values.Get("limit"); text != "" {
+		if limit, err = strconv.Atoi(text); err != nil || limit < 1 || limit > maxPageSize {
+			return nil, badRequest("limit must be a number from 1 to %d", maxPageSize)
+		}
+	}
+
+	results := data.search.Query(query, 0)
+	total := len(results)
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return &searchResults{Results: results, Total: total}, nil
+}
+
+// maxRequestBody is the largest request body a POST endpoint reads, which is room for tens
+// of thousands of patterns or a policy far larger than IAM allows.
+const maxRequestBody = 1 << 20
+
+// readBody reads the body of a POST request, up to maxRequestBody.
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody+1))
+
+	if err != nil {
+		return nil, badRequest("read request: %v", err)
+	}
+
+	if len(body) > maxRequestBody {
+		return nil, &httpError{status: http.StatusRequestEntityTooLarge, message: fmt.Sprintf("the request is larger than %d bytes", maxRequestBody)}
+	}
+
+	return body, nil
+}
+
+type expandRequest struct {
+	// Action patterns such as "s3:Get*", as in a policy's Action element
+	Patterns []string `json:"patterns"`
+}
+
+type expandResult struct {
+	Pattern string `json:"pattern"`
+
+	// The actions the pattern matches, such as "s3:GetObject", sorted by name
+	Actions []string `json:"actions"`
+
+	// Why IAM wouldn't accept the pattern, if it wouldn't; Actions is empty
+	Error string `json:"error,omitempty"`
+}
+
+type expandResponse struct {
+	// One result per pattern, in the order of the request
+	Results []*expandResult `json:"results"`
+}
+
+// expand answers a request to /expand. A malformed pattern gets an error in its own result
+// rather than failing the request, so a client checking many policies at once learns about
+// every bad pattern in one round trip.
+func expand(data *dataset, r *http.Request) (*expandResponse, error) {
+	body, err := readBody(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var request expandRequest
+
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, badRequest("invalid request: %v", err)
+	}
+
+	if request.Patterns == nil {
+		return nil, badRequest("the request needs a list of patterns")
+	}
+
+	return &expandResponse{Results: expandPatterns(data.index, request.Patterns)}, nil
+}
+
+// expandPatterns expands each action pattern, in order, as for /expand and authref expand.
+func expandPatterns(index *authref.Index, patterns []string) []*expandResult {
+	results := make([]*expandResult, len(patterns))
+
+	for i, pattern := range patterns {
+		result := &expandResult{Pattern: pattern, Actions: make([]string, 0)}
+		results[i] = result
+
+		if err := authref.CheckActionPattern(pattern); err != nil {
+			result.Error = err.Error()
+			continue
+		}
+
+		for _, action := range index.Match(pattern) {
+			result.Actions = append(result.Actions, action.String())
+		}
+	}
+
+	return results
+}
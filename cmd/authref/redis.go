@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// Key layout of the Redis export. Names in keys are lowercase, since IAM ignores case in
+// action and condition key names; the hashes keep the names as the reference writes them.
+const (
+	// Hash of an action, such as "authref:action:s3:getobject"
+	redisActionKey = "authref:action:"
+
+	// Hash of a condition key, such as "authref:condition-key:s3:existingobjecttag/<key>"
+	redisConditionKeyKey = "authref:condition-key:"
+
+	// Set of the lowercase names of a service's actions, such as "authref:service:s3:actions"
+	redisServiceActionsKey = "authref:service:%s:actions"
+
+	// Hash describing the dataset that was loaded
+	redisDatasetKey = "authref:dataset"
+)
+
+// redisWriter writes Redis commands in the Redis serialization protocol, the format
+// "redis-cli --pipe" sends to the server as it is.
+type redisWriter struct {
+	w *bufio.Writer
+}
+
+func (w *redisWriter) command(args ...string) {
+	w.w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+
+	for _, arg := range args {
+		w.w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+}
+
+// replace replaces a key with a new hash or set, made by a command such as HSET or SADD,
+// so fields dropped from the reference don't linger from an earlier load. It happens in a
+// transaction, so a client reading during a load never finds the key missing.
+func (w *redisWriter) replace(command, key string, args ...string) {
+	w.command("MULTI")
+	w.command("DEL", key)
+	w.command(append([]string{command, key}, args...)...)
+	w.command("EXEC")
+}
+
+// redisJSON encodes a list or map as a hash field. Hashes only hold strings, so anything
+// with structure is stored as JSON.
+func redisJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return string(data)
+}
+
+// exportRedis writes the commands that load the actions and condition keys into Redis as
+// hashes, for services that need lookups faster than reading the dataset themselves.
+// Loading a newer release over an older one updates every key it writes, but doesn't
+// remove actions or condition keys that were dropped from the reference.
+func exportRedis(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	filename := filepath.Join(dir, "authref.redis")
+	file, err := os.Create(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	w := &redisWriter{w: bufio.NewWriter(file)}
+	actions := authref.AllActions(authRefs)
+	byService := map[string][]string{}
+	services := make([]string, 0)
+
+	for _, action := range actions {
+		lower := strings.ToLower(action.String())
+		prefix := strings.ToLower(action.Service.ServicePrefix)
+
+		if byService[prefix] == nil {
+			services = append(services, prefix)
+		}
+
+		byService[prefix] = append(byService[prefix], lower)
+		w.replace("HSET", redisActionKey+lower,
+			"name", action.String(),
+			"servicePrefix", action.Service.ServicePrefix,
+			"serviceName", action.Service.Name,
+			"accessLevel", string(action.Action.AccessLevel),
+			"description", action.Action.Description,
+			"permissionOnly", strconv.FormatBool(action.Action.PermissionOnly),
+			"referenceHref", action.Action.ReferenceHref,
+			"resourceTypes", redisJSON(nonNilResourceTypes(action.Action.ResourceTypes)),
+			"conditionKeys", redisJSON(nonNil(action.Action.ConditionKeys)),
+		)
+	}
+
+	for _, prefix := range services {
+		w.replace("SADD", fmt.Sprintf(redisServiceActionsKey, prefix), byService[prefix]...)
+	}
+
+	for _, conditionKey := range authref.SummarizeConditionKeys(authRefs) {
+		w.replace("HSET", redisConditionKeyKey+strings.ToLower(conditionKey.Name),
+			"name", conditionKey.Name,
+			"type", conditionKey.Type,
+			"description", conditionKey.Description,
+			"global", strconv.FormatBool(conditionKey.Global),
+			"referenceHref", conditionKey.ReferenceHref,
+			"services", redisJSON(conditionKey.Services),
+		)
+	}
+
+	// The same fingerprint authref serve computes, so clients can tell which snapshot
+	// they're reading
+	encoded, err := json.Marshal(authRefs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(encoded)
+	w.replace("HSET", redisDatasetKey,
+		"fingerprint", hex.EncodeToString(digest[:]),
+		"services", strconv.Itoa(len(services)),
+		"actions", strconv.Itoa(len(actions)),
+	)
+
+	if err := w.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return []string{filename}, file.Close()
+}
+
+func nonNilResourceTypes(resourceTypes []authref.ActionResourceType) []authref.ActionResourceType {
+	if resourceTypes == nil {
+		return []authref.ActionResourceType{}
+	}
+
+	return resourceTypes
+}
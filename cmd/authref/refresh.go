@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// refreshJitter is the fraction of the refresh interval that each wait varies by, so that
+// several servers started together don't all download the dataset at the same moment.
+const refreshJitter = 0.1
+
+// jitter returns interval varied at random by up to refreshJitter either way.
+func jitter(random *rand.Rand, interval time.Duration) time.Duration {
+	spread := int64(float64(interval) * refreshJitter)
+
+	if spread <= 0 {
+		return interval
+	}
+
+	return interval - time.Duration(spread) + time.Duration(random.Int63n(2*spread+1))
+}
+
+// refresh loads the dataset again and swaps it in. Requests already being answered finish
+// with the old dataset. If loading fails, the server keeps the data it has.
+func (s *server) refresh(load func() (*dataset, error)) error {
+	data, err := load()
+
+	if err != nil {
+		return err
+	}
+
+	if len(data.authRefs) == 0 {
+		return fmt.Errorf("the new dataset is empty")
+	}
+
+	s.data.Store(data)
+	log.Printf("refreshed the dataset: %d services", len(data.authRefs))
+	return nil
+}
+
+// loadServedFile loads a dataset file to serve, along with the metadata.json beside it if
+// there is one. Metadata that can't be read is logged and left out, since the data can be
+// served without it.
+func loadServedFile(filename string) (*dataset, error) {
+	authRefs, err := loadData(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := authref.LoadMetadataFile(filepath.Join(filepath.Dir(filename), "metadata.json"))
+
+	if err != nil {
+		log.Printf("%v; serving without metadata", err)
+	}
+
+	return newDataset(authRefs, metadata), nil
+}
+
+// fetchServedData downloads a dataset to serve, along with the metadata.json published
+// beside it if there is one.
+func fetchServedData(client *http.Client, dataUrl string) (*dataset, error) {
+	authRefs, err := authref.FetchDataset(client, dataUrl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(dataUrl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	metadataUrl := base.ResolveReference(&url.URL{Path: "metadata.json"}).String()
+	metadata, err := authref.FetchMetadata(client, metadataUrl)
+
+	if err != nil {
+		log.Printf("%v; serving without metadata", err)
+	}
+
+	return newDataset(authRefs, metadata), nil
+}
+
+// refreshEvery calls refresh about once every interval, forever.
+func (s *server) refreshEvery(interval time.Duration, load func() (*dataset, error)) {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for {
+		time.Sleep(jitter(random, interval))
+
+		err := s.refresh(load)
+		s.metrics.refreshed(err == nil)
+
+		if err != nil {
+			log.Printf("refresh the dataset: %v; still serving the previous one", err)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// SCP check codes.
+const (
+	scpInvalidPattern = "invalid-pattern"
+	scpUnknownService = "unknown-service"
+	scpNoMatch        = "no-match"
+)
+
+// Severities of SCP findings.
+const (
+	scpError   = "error"
+	scpWarning = "warning"
+)
+
+type scpFinding struct {
+	Statement int    `json:"statement"`
+	Sid       string `json:"sid,omitempty"`
+	Severity  string `json:"severity"`
+	Code      string `json:"code"`
+	Pattern   string `json:"pattern"`
+	Message   string `json:"message"`
+}
+
+// scpDeny describes what a Deny statement of an SCP covers.
+type scpDeny struct {
+	Statement int    `json:"statement"`
+	Sid       string `json:"sid,omitempty"`
+
+	// Whether the statement has conditions or applies to less than all resources, in which
+	// case it only denies the actions some of the time
+	Conditional bool `json:"conditional"`
+
+	Total    int                      `json:"total"`
+	Services []*authref.ServiceAccess `json:"services"`
+}
+
+type scpReport struct {
+	Findings []*scpFinding `json:"findings"`
+	Denies   []*scpDeny    `json:"denies"`
+}
+
+// checkSCPPattern looks for mistakes in one action pattern. AWS accepts an SCP that names
+// actions or services that don't exist, and such a statement simply never applies.
+func checkSCPPattern(actions []*authref.QualifiedAction, prefixes map[string]bool, pattern string) (code, severity, message string) {
+	if pattern == "*" {
+		return "", "", ""
+	}
+
+	if err := authref.CheckActionPattern(pattern); err != nil {
+		return scpInvalidPattern, scpError, err.Error()
+	}
+
+	colon := strings.Index(pattern, ":")
+
+	if prefix := strings.ToLower(pattern[:colon]); !strings.ContainsAny(prefix, "*?") && !prefixes[prefix] {
+		return scpUnknownService, scpError, fmt.Sprintf("%#v names service prefix %#v, which doesn't exist", pattern, pattern[:colon])
+	}
+
+	if len(authref.MatchActions(actions, []string{pattern})) == 0 {
+		return scpNoMatch, scpWarning, fmt.Sprintf("%#v matches no actions", pattern)
+	}
+
+	return "", "", ""
+}
+
+// validSCPPatterns returns a copy of a statement without the action patterns AWS would
+// reject, so that they don't count toward what it covers. A NotAction statement whose
+// patterns are all invalid excludes nothing.
+func validSCPPatterns(statement *authref.Statement) *authref.Statement {
+	valid := func(patterns []string) []string {
+		result := make([]string, 0, len(patterns))
+
+		for _, pattern := range patterns {
+			if authref.CheckActionPattern(pattern) == nil {
+				result = append(result, pattern)
+			}
+		}
+
+		return result
+	}
+
+	result := *statement
+	result.Action = valid(statement.Action)
+
+	if len(statement.NotAction) != 0 {
+		result.NotAction = valid(statement.NotAction)
+
+		if len(result.NotAction) == 0 {
+			result.Action = []string{"*"}
+		}
+	}
+
+	return &result
+}
+
+// checkSCP validates the action patterns of a service control policy and works out what
+// each of its Deny statements covers.
+func checkSCP(actions []*authref.QualifiedAction, policy *authref.Policy) *scpReport {
+	report := &scpReport{Findings: make([]*scpFinding, 0), Denies: make([]*scpDeny, 0)}
+	prefixes := map[string]bool{}
+
+	for _, action := range actions {
+		prefixes[strings.ToLower(action.Service.ServicePrefix)] = true
+	}
+
+	for i, statement := range policy.Statement {
+		patterns := append(append([]string{}, statement.Action...), statement.NotAction...)
+
+		for _, pattern := range patterns {
+			if code, severity, message := checkSCPPattern(actions, prefixes, pattern); code != "" {
+				report.Findings = append(report.Findings, &scpFinding{
+					Statement: i,
+					Sid:       statement.Sid,
+					Severity:  severity,
+					Code:      code,
+					Pattern:   pattern,
+					Message:   message,
+				})
+			}
+		}
+
+		if statement.Effect != authref.EffectDeny {
+			continue
+		}
+
+		denied := authref.StatementActions(actions, validSCPPatterns(statement))
+		deny := &scpDeny{
+			Statement:   i,
+			Sid:         statement.Sid,
+			Conditional: len(statement.Condition) != 0 || len(statement.NotResource) != 0 || !statement.Resource.Contains("*"),
+			Total:       len(denied),
+			Services:    authref.SummarizeActions(denied).Services,
+		}
+
+		report.Denies = append(report.Denies, deny)
+	}
+
+	return report
+}
+
+func runCheckSCP(args []string) error {
+	flags := flag.NewFlagSet("check-scp", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	verbose := flags.Bool("v", false, "list the actions each Deny statement covers")
+	strict := flags.Bool("strict", false, "fail on warnings, such as patterns that match no actions")
+	jsonOutput := flags.Bool("json", false, "print the findings and Deny coverage as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref check-scp [flags] scp.json\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	policy, err := authref.LoadPolicyFile(flags.Arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	report := checkSCP(authref.AllActions(authRefs), policy)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		for _, finding := range report.Findings {
+			fmt.Printf("%s: %s: %s: %s\n", policy.Statement[finding.Statement].Label(finding.Statement), finding.Severity, finding.Code, finding.Message)
+		}
+
+		for _, deny := range report.Denies {
+			qualifier := ""
+
+			if deny.Conditional {
+				qualifier = ", subject to its conditions and resources"
+			}
+
+			fmt.Printf("%s: denies %d actions in %d services%s\n", policy.Statement[deny.Statement].Label(deny.Statement), deny.Total, len(deny.Services), qualifier)
+
+			for _, service := range deny.Services {
+				counts := make([]string, 0, len(authref.AccessLevels))
+				total := 0
+
+				for _, level := range authref.AccessLevels {
+					if names := service.Actions[level]; len(names) != 0 {
+						counts = append(counts, fmt.Sprintf("%s %d", level, len(names)))
+						total += len(names)
+					}
+				}
+
+				fmt.Printf("    %-24s %d (%s)\n", service.ServicePrefix, total, strings.Join(counts, ", "))
+
+				if *verbose {
+					for _, level := range authref.AccessLevels {
+						for _, name := range service.Actions[level] {
+							fmt.Printf("      %s:%s (%s)\n", service.ServicePrefix, name, level)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	errors, warnings := 0, 0
+
+	for _, finding := range report.Findings {
+		if finding.Severity == scpError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+
+	if errors != 0 || (*strict && warnings != 0) {
+		return fmt.Errorf("%d error(s) and %d warning(s)", errors, warnings)
+	}
+
+	return nil
+}
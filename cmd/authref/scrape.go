@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+	"github.com/fluggo/aws-service-auth-reference/internal/scraper"
+)
+
+// scrapeExporter gives the scraper's --format the formats of authref export.
+type scrapeExporter struct{}
+
+func (scrapeExporter) CheckFormats(list string) error {
+	_, err := parseFormats(list)
+	return err
+}
+
+func (scrapeExporter) Export(list, dir string, authRefs []*authref.ServiceAuthorizationReference) error {
+	formats, err := parseFormats(list)
+
+	if err != nil {
+		return err
+	}
+
+	return exportFormats(formats, dir, authRefs, func(file string) {
+		fmt.Fprintf(os.Stderr, "wrote %s\n", file)
+	})
+}
+
+func runScrape(args []string) error {
+	scraper.Main(args, scrapeExporter{})
+	return nil
+}
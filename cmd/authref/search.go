@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+const defaultSearchColumns = "name,kind,access-level,description"
+
+// searchColumns are the columns search and query can print for each result.
+var searchColumns = []*tableColumn{
+	{name: "name", value: func(row interface{}) string { return row.(*authref.SearchResult).Name }},
+	{name: "kind", value: func(row interface{}) string { return row.(*authref.SearchResult).Kind }},
+	{name: "access-level", value: func(row interface{}) string { return string(row.(*authref.SearchResult).AccessLevel) }, compare: compareAccessLevels},
+	{name: "description", value: func(row interface{}) string { return row.(*authref.SearchResult).Description }},
+	{name: "score", value: func(row interface{}) string { return strconv.Itoa(row.(*authref.SearchResult).Score) }},
+}
+
+func runSearch(args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	indexFile := flags.String("index", "", "path to a prebuilt "+authref.SearchIndexFile+" to search instead of indexing the dataset")
+	limit := flags.Int("limit", 20, "maximum number of results to print, or 0 for all")
+	jsonOutput := flags.Bool("json", false, "print the results as JSON")
+	templateText := templateFlag(flags)
+	columns := tableFlags(flags, searchColumns, defaultSearchColumns)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref search [flags] <words>...\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	tmpl, err := parseTemplate(*templateText)
+
+	if err != nil {
+		return err
+	}
+
+	if err := columns.parse(); err != nil {
+		return err
+	}
+
+	var index *authref.SearchIndex
+
+	if *indexFile != "" {
+		if index, err = authref.LoadSearchIndexFile(*indexFile); err != nil {
+			return err
+		}
+	} else {
+		authRefs, err := loadData(*dataFile)
+
+		if err != nil {
+			return err
+		}
+
+		index = authref.BuildSearchIndex(authRefs)
+	}
+
+	return printSearchResults(index.Search(strings.Join(flags.Args(), " "), *limit), *jsonOutput, tmpl, columns)
+}
+
+func runQuery(args []string) error {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	limit := flags.Int("limit", 0, "maximum number of results to print, or 0 for all")
+	jsonOutput := flags.Bool("json", false, "print the results as JSON")
+	templateText := templateFlag(flags)
+	columns := tableFlags(flags, searchColumns, defaultSearchColumns)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref query [flags] <query>...\n\n")
+		fmt.Fprintf(flags.Output(), "Lists the actions and condition keys that pass field filters, such as\n")
+		fmt.Fprintf(flags.Output(), "  authref query accessLevel:Write service:ec2 'name:*Snapshot*'\n")
+		fmt.Fprintf(flags.Output(), "Filters are kind, service, name, and accessLevel; quote values with spaces, as in\n")
+		fmt.Fprintf(flags.Output(), "'accessLevel:\"Permissions management\"'. Other words are searched for as with\n")
+		fmt.Fprintf(flags.Output(), "authref search.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	tmpl, err := parseTemplate(*templateText)
+
+	if err != nil {
+		return err
+	}
+
+	if err := columns.parse(); err != nil {
+		return err
+	}
+
+	query, err := authref.ParseSearchQuery(strings.Join(flags.Args(), " "))
+
+	if err != nil {
+		return err
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	return printSearchResults(authref.BuildSearchIndex(authRefs).Query(query, *limit), *jsonOutput, tmpl, columns)
+}
+
+func printSearchResults(results []*authref.SearchResult, jsonOutput bool, tmpl *template.Template, columns *table) error {
+	columns.sort(results)
+
+	if tmpl != nil {
+		return printTemplate(tmpl, results)
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	return columns.print(results)
+}
@@ -0,0 +1,650 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// dataset is the data the server answers from, along with the indexes built from it.
+type dataset struct {
+	authRefs []*authref.ServiceAuthorizationReference
+	byPrefix map[string][]*authref.ServiceAuthorizationReference
+	services []*serviceSummary
+	actions  []*actionEntry
+
+	// The first action with each qualified name, keyed in lowercase, such as "s3:getobject"
+	actionsByName map[string]*actionEntry
+
+	// The metadata published with the dataset, or nil if there wasn't any
+	metadata *authref.Metadata
+	loadedAt time.Time
+
+	// Hex SHA-256 of the dataset's JSON encoding, which tells snapshots apart
+	fingerprint string
+
+	// For the q parameter of /actions
+	search *authref.SearchIndex
+
+	// For /expand
+	index *authref.Index
+}
+
+type serviceSummary struct {
+	Name              string   `json:"name"`
+	ServicePrefix     string   `json:"servicePrefix"`
+	AuthReferenceHref string   `json:"authReferenceHref"`
+	ServicePrincipals []string `json:"servicePrincipals,omitempty"`
+	Actions           int      `json:"actions"`
+	ResourceTypes     int      `json:"resourceTypes"`
+	ConditionKeys     int      `json:"conditionKeys"`
+	LastUpdated       string   `json:"lastUpdated,omitempty"`
+}
+
+// actionEntry is an action along with the service it belongs to.
+type actionEntry struct {
+	ServicePrefix string `json:"servicePrefix"`
+	ServiceName   string `json:"serviceName"`
+	*authref.Action
+}
+
+type serviceList struct {
+	Services   []*serviceSummary `json:"services"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+type actionList struct {
+	Actions    []*actionEntry `json:"actions"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+// serviceRecord is every page of the reference with one service prefix. Most prefixes
+// have one page, but a few services are documented across several.
+type serviceRecord struct {
+	ServicePrefix string                                   `json:"servicePrefix"`
+	Pages         []*authref.ServiceAuthorizationReference `json:"pages"`
+}
+
+// actionDetail is an action with the definitions of its resource types and condition keys
+// filled in from its service, so a client doesn't need the rest of the service to use it.
+type actionDetail struct {
+	ServicePrefix string `json:"servicePrefix"`
+	ServiceName   string `json:"serviceName"`
+	*authref.Action
+
+	// Replaces the action's own list, with each entry's definition filled in
+	ResourceTypes []*resolvedResourceType `json:"resourceTypes"`
+
+	// Definitions of the condition keys named by the action and its resource types that the
+	// service defines. Global keys, such as aws:RequestTag/${TagKey}, aren't included.
+	ConditionKeyDetails []*authref.ConditionKey `json:"conditionKeyDetails"`
+}
+
+// FullName returns the action's name as it's written in a policy, such as "s3:GetObject".
+func (detail *actionDetail) FullName() string {
+	return detail.ServicePrefix + ":" + detail.Name
+}
+
+// resolvedResourceType is a resource type an action lists, along with its definition.
+type resolvedResourceType struct {
+	authref.ActionResourceType
+
+	// The service's definition of the resource type, or nil if it doesn't define it
+	Definition *authref.ResourceType `json:"definition"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func newDataset(authRefs []*authref.ServiceAuthorizationReference, metadata *authref.Metadata) *dataset {
+	data := &dataset{
+		authRefs: authRefs,
+		metadata: metadata,
+		loadedAt: time.Now(),
+		byPrefix: map[string][]*authref.ServiceAuthorizationReference{},
+		services: make([]*serviceSummary, 0, len(authRefs)),
+		actions:  make([]*actionEntry, 0),
+		search:   authref.BuildSearchIndex(authRefs),
+		index:    authref.NewIndex(authRefs),
+
+		actionsByName: map[string]*actionEntry{},
+	}
+
+	for _, authRef := range authRefs {
+		data.byPrefix[authRef.ServicePrefix] = append(data.byPrefix[authRef.ServicePrefix], authRef)
+		data.services = append(data.services, &serviceSummary{
+			Name:              authRef.Name,
+			ServicePrefix:     authRef.ServicePrefix,
+			AuthReferenceHref: authRef.AuthReferenceHref,
+			ServicePrincipals: authRef.ServicePrincipals,
+			Actions:           len(authRef.Actions),
+			ResourceTypes:     len(authRef.ResourceTypes),
+			ConditionKeys:     len(authRef.ConditionKeys),
+			LastUpdated:       authRef.LastUpdated,
+		})
+	}
+
+	for _, action := range authref.AllActions(authRefs) {
+		entry := &actionEntry{ServicePrefix: action.Service.ServicePrefix, ServiceName: action.Service.Name, Action: action.Action}
+		data.actions = append(data.actions, entry)
+		key := strings.ToLower(action.String())
+
+		if data.actionsByName[key] == nil {
+			data.actionsByName[key] = entry
+		}
+	}
+
+	// Search sorts its terms on first use; do that now, before requests share the index
+	data.search.Search("", 0)
+
+	// The dataset was decoded from JSON, so it can't fail to encode
+	encoded, _ := json.Marshal(authRefs)
+	digest := sha256.Sum256(encoded)
+	data.fingerprint = hex.EncodeToString(digest[:])
+
+	return data
+}
+
+// httpError is an error with the status code to report it with.
+type httpError struct {
+	status  int
+	message string
+}
+
+func (err *httpError) Error() string {
+	return err.message
+}
+
+type parameter struct {
+	name        string
+	in          string
+	description string
+}
+
+// endpoint describes one operation of the server. The same table drives both routing and
+// the OpenAPI document, so the two can't disagree.
+type endpoint struct {
+	method     string
+	path       string
+	summary    string
+	parameters []*parameter
+
+	// Values of the types the endpoint accepts in the request body, if any, and responds
+	// with, used to describe it in the OpenAPI document
+	request  interface{}
+	response interface{}
+
+	// Answers a request from data, which is the same snapshot the response's ETag describes.
+	// Params holds the values of the path's parameters.
+	handle func(s *server, data *dataset, r *http.Request, params map[string]string) (interface{}, error)
+}
+
+var endpoints []*endpoint
+
+func init() {
+	endpoints = []*endpoint{
+		{
+			method:  http.MethodGet,
+			path:    "/services",
+			summary: "List services",
+			parameters: []*parameter{
+				serviceParameter,
+				{name: "q", in: "query", description: "only list services whose name or prefix contains this text"},
+				fieldsParameter,
+				limitParameter,
+				cursorParameter,
+			},
+			response: serviceList{},
+			handle: func(s *server, data *dataset, r *http.Request, params map[string]string) (interface{}, error) {
+				return listServices(data, r.URL.Query())
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/actions",
+			summary: "List actions",
+			parameters: []*parameter{
+				serviceParameter,
+				accessLevelParameter,
+				{name: "q", in: "query", description: "only list actions matching this search, as in authref search, such as \"s3 getobj\""},
+				fieldsParameter,
+				limitParameter,
+				cursorParameter,
+			},
+			response: actionList{},
+			handle: func(s *server, data *dataset, r *http.Request, params map[string]string) (interface{}, error) {
+				return listActions(data, r.URL.Query())
+			},
+		},
+		{
+			method:     http.MethodGet,
+			path:       "/services/{prefix}",
+			summary:    "Get every page for a service prefix",
+			parameters: []*parameter{{name: "prefix", in: "path", description: "service prefix, such as \"s3\"; matched regardless of case"}},
+			response:   serviceRecord{},
+			handle: func(s *server, data *dataset, r *http.Request, params map[string]string) (interface{}, error) {
+				return getService(data, params["prefix"])
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/services/{prefix}/actions/{name}",
+			summary: "Get an action with its resource types and condition keys",
+			parameters: []*parameter{
+				{name: "prefix", in: "path", description: "service prefix, such as \"s3\"; matched regardless of case"},
+				{name: "name", in: "path", description: "action name, such as \"GetObject\"; matched regardless of case"},
+			},
+			response: actionDetail{},
+			handle: func(s *server, data *dataset, r *http.Request, params map[string]string) (interface{}, error) {
+				return getAction(data, params["prefix"], params["name"])
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/search",
+			summary: "Search actions and condition keys",
+			parameters: []*parameter{
+				{name: "q", in: "query", description: "words to search for and field filters, such as \"accessLevel:Write service:ec2 name:*Snapshot*\""},
+				{name: "limit", in: "query", description: fmt.Sprintf("return at most this many results, up to %d; %d if absent", maxPageSize, defaultSearchLimit)},
+			},
+			response: searchResults{},
+			handle: func(s *server, data *dataset, r *http.Request, params map[string]string) (interface{}, error) {
+				return search(data, r.URL.Query())
+			},
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/expand",
+			summary:  "Expand action patterns into the actions they match",
+			request:  expandRequest{},
+			response: expandResponse{},
+			handle: func(s *server, data *dataset, r *http.Request, params map[string]string) (interface{}, error) {
+				return expand(data, r)
+			},
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/analyze-policy",
+			summary:  "Expand an IAM policy and report its access, unknown actions, and unsupported condition keys",
+			request:  authref.Policy{},
+			response: policyAnalysis{},
+			handle: func(s *server, data *dataset, r *http.Request, params map[string]string) (interface{}, error) {
+				return analyzePolicy(data, r)
+			},
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/openapi.json",
+			summary:  "Describe this API",
+			response: map[string]interface{}{},
+			handle: func(s *server, data *dataset, r *http.Request, params map[string]string) (interface{}, error) {
+				return s.openAPI, nil
+			},
+		},
+	}
+}
+
+type server struct {
+	// The *dataset requests are answered from, which refreshing replaces as a whole
+	data    atomic.Value
+	openAPI map[string]interface{}
+	mux     *http.ServeMux
+	metrics *serverMetrics
+
+	// Identifies this build's responses, so that ETags change when the server's output does
+	// even if the dataset doesn't
+	build string
+
+	// Cache-Control header for successful responses
+	cacheControl string
+
+	// Which browser pages may call the server, or nil to send no CORS headers
+	cors *corsPolicy
+}
+
+// newServer creates a server for a dataset. Clients may cache its responses for maxAge
+// before checking with the server whether they've changed.
+func newServer(data *dataset, maxAge time.Duration) *server {
+	s := &server{openAPI: openAPIDocument(endpoints), mux: http.NewServeMux(), metrics: newServerMetrics()}
+	s.data.Store(data)
+	s.build = authref.ReadBuildInfo().String()
+	s.cacheControl = fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+
+	// Endpoints with path parameters are found by matchPath among those that share the part
+	// of their path before the first parameter, which the mux sends everything under
+	templated := map[string][]*endpoint{}
+
+	for _, e := range endpoints {
+		if i := strings.Index(e.path, "{"); i >= 0 {
+			templated[e.path[:i]] = append(templated[e.path[:i]], e)
+		} else {
+			s.mux.Handle(e.path, s.handler(e))
+		}
+	}
+
+	for route, group := range templated {
+		s.mux.Handle(route, s.templateHandler(group))
+	}
+
+	s.mux.HandleFunc(metricsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		s.metrics.write(w, s.current(), time.Now())
+	})
+
+	s.mux.HandleFunc("/", s.notFound)
+	return s
+}
+
+func (s *server) notFound(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusNotFound, &errorResponse{Error: "not found"})
+	s.metrics.http.observe("other", http.StatusNotFound, time.Since(start))
+}
+
+// matchPath matches a path against an endpoint's path, in which a parameter such as
+// "{prefix}" matches one non-empty segment. It returns the values of the parameters.
+func matchPath(template, path string) (map[string]string, bool) {
+	templateParts, pathParts := strings.Split(template, "/"), strings.Split(path, "/")
+
+	if len(templateParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+
+	for i, part := range templateParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return nil, false
+			}
+
+			params[part[1:len(part)-1]] = pathParts[i]
+		} else if part != pathParts[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// templateHandler serves whichever of a group of endpoints with path parameters matches
+// the request's path.
+func (s *server) templateHandler(group []*endpoint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, e := range group {
+			if _, ok := matchPath(e.path, r.URL.Path); ok {
+				s.handler(e).ServeHTTP(w, r)
+				return
+			}
+		}
+
+		s.notFound(w, r)
+	})
+}
+
+// getService answers a request for one service prefix. A prefix that only differs in case
+// from a known one gets that service, since prefixes are case-insensitive in policies.
+func getService(data *dataset, prefix string) (*serviceRecord, error) {
+	pages := data.byPrefix[prefix]
+
+	if len(pages) == 0 {
+		for known, knownPages := range data.byPrefix {
+			if strings.EqualFold(known, prefix) {
+				prefix, pages = known, knownPages
+				break
+			}
+		}
+	}
+
+	if len(pages) == 0 {
+		return nil, &httpError{status: http.StatusNotFound, message: fmt.Sprintf("no service with prefix %#v", prefix)}
+	}
+
+	return &serviceRecord{ServicePrefix: prefix, Pages: pages}, nil
+}
+
+// getAction answers a request for one action, matching the prefix and name regardless of
+// case. Resource types and condition keys are looked up in every page with the prefix.
+func getAction(data *dataset, prefix, name string) (*actionDetail, error) {
+	entry := data.actionsByName[strings.ToLower(prefix+":"+name)]
+
+	if entry == nil {
+		return nil, &httpError{status: http.StatusNotFound, message: fmt.Sprintf("no action %s:%s", prefix, name)}
+	}
+
+	return newActionDetail(data.byPrefix[entry.ServicePrefix], entry.ServicePrefix, entry.ServiceName, entry.Action), nil
+}
+
+// newActionDetail fills in the resource types and condition keys of an action from the
+// pages with its service prefix.
+func newActionDetail(pages []*authref.ServiceAuthorizationReference, prefix, serviceName string, action *authref.Action) *actionDetail {
+	resourceTypes := map[string]*authref.ResourceType{}
+	conditionKeys := map[string]*authref.ConditionKey{}
+
+	for _, page := range pages {
+		for _, resourceType := range page.ResourceTypes {
+			if resourceTypes[resourceType.Name] == nil {
+				resourceTypes[resourceType.Name] = resourceType
+			}
+		}
+
+		for _, conditionKey := range page.ConditionKeys {
+			if conditionKeys[conditionKey.Name] == nil {
+				conditionKeys[conditionKey.Name] = conditionKey
+			}
+		}
+	}
+
+	detail := &actionDetail{
+		ServicePrefix:       prefix,
+		ServiceName:         serviceName,
+		Action:              action,
+		ResourceTypes:       make([]*resolvedResourceType, 0, len(action.ResourceTypes)),
+		ConditionKeyDetails: make([]*authref.ConditionKey, 0),
+	}
+
+	seen := map[string]bool{}
+	addConditionKeys := func(names []string) {
+		for _, name := range names {
+			if conditionKey := conditionKeys[name]; conditionKey != nil && !seen[name] {
+				seen[name] = true
+				detail.ConditionKeyDetails = append(detail.ConditionKeyDetails, conditionKey)
+			}
+		}
+	}
+
+	for _, resourceType := range action.ResourceTypes {
+		definition := resourceTypes[resourceType.ResourceType]
+		detail.ResourceTypes = append(detail.ResourceTypes, &resolvedResourceType{ActionResourceType: resourceType, Definition: definition})
+		addConditionKeys(resourceType.ConditionKeys)
+
+		if definition != nil {
+			addConditionKeys(definition.ConditionKeys)
+		}
+	}
+
+	addConditionKeys(action.ConditionKeys)
+	return detail
+}
+
+// current returns the dataset as of now. A request should call it once and keep using
+// the result, so that a refresh partway through can't mix two snapshots.
+func (s *server) current() *dataset {
+	return s.data.Load().(*dataset)
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.cors != nil && s.cors.apply(w, r) {
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *server) handler(e *endpoint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		status := s.serve(e, w, r)
+		s.metrics.http.observe(e.path, status, time.Since(start))
+	})
+}
+
+// etag returns the strong ETag of the response to a request for a dataset. Every response
+// is determined by the path, the query, the dataset, and the build of the server, so a
+// digest of those can stand in for a digest of the response without encoding it.
+func (s *server) etag(e *endpoint, r *http.Request, data *dataset) string {
+	digest := sha256.Sum256([]byte(r.URL.Path + "?" + r.URL.RawQuery + "\n" + data.fingerprint + "\n" + s.build))
+	return `"` + hex.EncodeToString(digest[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header lists etag. The comparison is weak, as
+// RFC 9110 requires for If-None-Match, so a "W/" prefix is ignored.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// serve answers a request to an endpoint and returns the status code it responded with.
+func (s *server) serve(e *endpoint, w http.ResponseWriter, r *http.Request) int {
+	if r.Method != e.method && !(e.method == http.MethodGet && r.Method == http.MethodHead) {
+		w.Header().Set("Allow", e.method)
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusMethodNotAllowed, &errorResponse{Error: "method not allowed"})
+		return http.StatusMethodNotAllowed
+	}
+
+	data := s.current()
+	etag := ""
+
+	// The ETag only identifies a response by its URL, so only GET responses are cached
+	if e.method == http.MethodGet {
+		etag = s.etag(e, r, data)
+	}
+
+	if header := r.Header.Get("If-None-Match"); etag != "" && header != "" && etagMatches(header, etag) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", s.cacheControl)
+		w.WriteHeader(http.StatusNotModified)
+		return http.StatusNotModified
+	}
+
+	params, _ := matchPath(e.path, r.URL.Path)
+	result, err := e.handle(s, data, r, params)
+
+	if err != nil {
+		status := http.StatusInternalServerError
+
+		if httpErr, ok := err.(*httpError); ok {
+			status = httpErr.status
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, status, &errorResponse{Error: err.Error()})
+		return status
+	}
+
+	if etag == "" {
+		w.Header().Set("Cache-Control", "no-store")
+	} else {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", s.cacheControl)
+	}
+
+	writeJSON(w, http.StatusOK, result)
+	return http.StatusOK
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("write response: %v", err)
+	}
+}
+
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	grpcAddr := flags.String("grpc-addr", "", "if set, also serve the gRPC API described by proto/authref.proto on this address")
+	refresh := flags.Duration("refresh", 0, "if set, reload the dataset this often, such as 24h, give or take a tenth")
+	refreshUrl := flags.String("refresh-url", authref.LatestDatasetURL, "with --refresh, download the dataset from this URL; if empty, reread --data")
+	maxAge := flags.Duration("max-age", 5*time.Minute, "how long clients may cache responses before checking whether they've changed")
+	corsOrigins := flags.String("cors-origins", "", "comma-separated origins that browser pages may call the API from, such as https://tools.example.com, or * for any")
+	corsMethods := flags.String("cors-methods", "GET, HEAD, POST", "comma-separated methods to allow from --cors-origins")
+	flags.Parse(args)
+
+	if *refresh < 0 || *maxAge < 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cors, err := parseCORS(*corsOrigins, *corsMethods)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := loadServedFile(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	s := newServer(data, *maxAge)
+	s.cors = cors
+	errs := make(chan error, 2)
+
+	if *refresh != 0 {
+		load := func() (*dataset, error) {
+			return loadServedFile(*dataFile)
+		}
+
+		if *refreshUrl != "" {
+			client := &http.Client{Timeout: 5 * time.Minute}
+			load = func() (*dataset, error) {
+				return fetchServedData(client, *refreshUrl)
+			}
+		}
+
+		go s.refreshEvery(*refresh, load)
+	}
+
+	go func() {
+		log.Printf("serving %d services on http://%s (API description at /openapi.json, metrics at %s)", len(data.authRefs), *addr, metricsPath)
+		errs <- http.ListenAndServe(*addr, s)
+	}()
+
+	if *grpcAddr != "" {
+		go func() {
+			// gRPC needs HTTP/2; without TLS, that means accepting HTTP/2 in cleartext
+			log.Printf("serving gRPC service %s on %s", grpcService, *grpcAddr)
+			errs <- http.ListenAndServe(*grpcAddr, h2c.NewHandler(&grpcHandler{server: s}, &http2.Server{}))
+		}()
+	}
+
+	return fmt.Errorf("listen: %w", <-errs)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+func runShow(args []string) error {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	jsonOutput := flags.Bool("json", false, "print the actions as JSON, as the server's /services/{prefix}/actions/{name} does")
+	templateText := templateFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref show [flags] <action>...\n\n")
+		fmt.Fprintf(flags.Output(), "Prints the access level, description, resource types, and condition keys of\n")
+		fmt.Fprintf(flags.Output(), "actions such as iam:CreateRole.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	tmpl, err := parseTemplate(*templateText)
+
+	if err != nil {
+		return err
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	index := authref.NewIndex(authRefs)
+	details := make([]*actionDetail, 0, flags.NArg())
+
+	for _, name := range flags.Args() {
+		action := index.Action(name)
+
+		if action == nil {
+			return fmt.Errorf("no action %s", name)
+		}
+
+		details = append(details, newActionDetail(index.ServicesByPrefix(action.Service.ServicePrefix),
+			action.Service.ServicePrefix, action.Service.Name, action.Action))
+	}
+
+	if tmpl != nil {
+		return printTemplate(tmpl, details)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(details)
+	}
+
+	for i, detail := range details {
+		if i != 0 {
+			fmt.Println()
+		}
+
+		printActionDetail(detail)
+	}
+
+	return nil
+}
+
+func printActionDetail(detail *actionDetail) {
+	fmt.Printf("%s:%s (%s)\n", detail.ServicePrefix, detail.Name, detail.AccessLevel)
+	fmt.Printf("  %s\n", detail.Description)
+
+	if detail.PermissionOnly {
+		fmt.Printf("  Permission only; there's no API call of the same name.\n")
+	}
+
+	if detail.ReferenceHref != "" {
+		fmt.Printf("  %s\n", detail.ReferenceHref)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	if len(detail.ResourceTypes) != 0 {
+		fmt.Fprintf(w, "\n  RESOURCE TYPE\tARN PATTERN\tDEPENDENT ACTIONS\n")
+
+		for _, resourceType := range detail.ResourceTypes {
+			name := resourceType.ResourceType
+			pattern := ""
+
+			if resourceType.Required {
+				name += "*"
+			}
+
+			if resourceType.Definition != nil {
+				pattern = resourceType.Definition.ArnPattern
+			}
+
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", name, pattern, strings.Join(resourceType.DependentActions, ", "))
+		}
+	}
+
+	if len(detail.Action.ConditionKeys) != 0 {
+		w.Flush()
+		fmt.Fprintf(w, "\n  CONDITION KEY\tTYPE\tDESCRIPTION\n")
+		definitions := map[string]string{}
+		types := map[string]string{}
+
+		for _, conditionKey := range detail.ConditionKeyDetails {
+			definitions[conditionKey.Name] = conditionKey.Description
+			types[conditionKey.Name] = conditionKey.Type
+		}
+
+		for _, name := range detail.Action.ConditionKeys {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", name, types[name], definitions[name])
+		}
+	}
+
+	w.Flush()
+}
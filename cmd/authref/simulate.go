@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// Decisions the policy simulator returns in EvalDecision
+const (
+	simulatorAllowed      = "allowed"
+	simulatorExplicitDeny = "explicitDeny"
+	simulatorImplicitDeny = "implicitDeny"
+)
+
+// simulateRequest is the input of "aws iam simulate-custom-policy" or
+// "aws iam simulate-principal-policy", as given to --cli-input-json.
+type simulateRequest struct {
+	PolicySourceArn                    string   `json:"PolicySourceArn,omitempty"`
+	PolicyInputList                    []string `json:"PolicyInputList,omitempty"`
+	PermissionsBoundaryPolicyInputList []string `json:"PermissionsBoundaryPolicyInputList,omitempty"`
+	ActionNames                        []string `json:"ActionNames"`
+	ResourceArns                       []string `json:"ResourceArns,omitempty"`
+}
+
+// simulateResponse is the part of the simulator's output the summary needs.
+type simulateResponse struct {
+	EvaluationResults []struct {
+		EvalActionName string `json:"EvalActionName"`
+		EvalDecision   string `json:"EvalDecision"`
+	} `json:"EvaluationResults"`
+}
+
+// simulateService counts the simulator's decisions for one service.
+type simulateService struct {
+	ServicePrefix string                           `json:"servicePrefix"`
+	Allowed       map[authref.AccessLevel][]string `json:"allowed"`
+	ExplicitDeny  int                              `json:"explicitDeny"`
+	ImplicitDeny  int                              `json:"implicitDeny"`
+}
+
+// parseAccessLevels reads a comma-separated list of access levels.
+func parseAccessLevels(list string) (map[authref.AccessLevel]bool, error) {
+	result := map[authref.AccessLevel]bool{}
+
+	for _, level := range strings.Split(list, ",") {
+		if level = strings.TrimSpace(level); level != "" {
+			accessLevel := authref.AccessLevel(level)
+
+			if !accessLevel.Known() {
+				return nil, fmt.Errorf("unknown access level %#v", level)
+			}
+
+			result[accessLevel] = true
+		}
+	}
+
+	return result, nil
+}
+
+// readPolicyText reads a policy file as the string the simulator expects, after checking
+// that it parses.
+func readPolicyText(filename string) (string, error) {
+	if _, err := authref.LoadPolicyFile(filename); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filename)
+	return string(data), err
+}
+
+func runSimulate(args []string) error {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	policyFile := flags.String("policy", "", "simulate this policy with simulate-custom-policy")
+	boundaryFile := flags.String("boundary", "", "with --policy, also apply this permissions boundary")
+	principal := flags.String("principal", "", "simulate the policies of this user, group, or role ARN with simulate-principal-policy")
+	resource := flags.String("resource", "*", "resource ARN to simulate against")
+	levels := flags.String("access-levels", "", "comma-separated access levels to simulate, such as \"Permissions management\"; all if empty")
+	batchSize := flags.Int("batch-size", 100, "number of actions in each request")
+	outDir := flags.String("out", ".", "directory to write the requests to")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref simulate [flags] (--policy policy.json | --principal arn) <action>...\n\n"+
+			"Writes request files for the IAM policy simulator covering the actions, which can be patterns such\n"+
+			"as \"iam:*\", and prints the AWS CLI commands to run them. Summarize the results with\n"+
+			"authref simulate-results.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || (*policyFile == "") == (*principal == "") || *batchSize <= 0 || (*boundaryFile != "" && *policyFile == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	accessLevels, err := parseAccessLevels(*levels)
+
+	if err != nil {
+		return err
+	}
+
+	template := simulateRequest{ResourceArns: []string{*resource}}
+	command := "simulate-principal-policy"
+
+	if *policyFile != "" {
+		command = "simulate-custom-policy"
+		policy, err := readPolicyText(*policyFile)
+
+		if err != nil {
+			return err
+		}
+
+		template.PolicyInputList = []string{policy}
+
+		if *boundaryFile != "" {
+			boundary, err := readPolicyText(*boundaryFile)
+
+			if err != nil {
+				return err
+			}
+
+			template.PermissionsBoundaryPolicyInputList = []string{boundary}
+		}
+	} else {
+		template.PolicySourceArn = *principal
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	index := authref.NewIndex(authRefs)
+	seen := map[*authref.QualifiedAction]bool{}
+	names := make([]string, 0)
+
+	for _, pattern := range flags.Args() {
+		matched := index.Match(pattern)
+
+		if len(matched) == 0 {
+			return fmt.Errorf("%#v matches no actions", pattern)
+		}
+
+		for _, action := range matched {
+			if !seen[action] && (len(accessLevels) == 0 || accessLevels[action.Action.AccessLevel]) {
+				seen[action] = true
+				names = append(names, action.String())
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("no actions at the chosen access levels")
+	}
+
+	sort.Strings(names)
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+
+	for batch := 0; batch*(*batchSize) < len(names); batch++ {
+		end := (batch + 1) * *batchSize
+
+		if end > len(names) {
+			end = len(names)
+		}
+
+		request := template
+		request.ActionNames = names[batch**batchSize : end]
+		requestFile := filepath.Join(*outDir, fmt.Sprintf("simulate-%03d.json", batch+1))
+		data, err := json.MarshalIndent(&request, "", "  ")
+
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(requestFile, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+
+		resultFile := filepath.Join(*outDir, fmt.Sprintf("result-%03d.json", batch+1))
+		fmt.Printf("aws iam %s --cli-input-json file://%s > %s\n", command, requestFile, resultFile)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d actions in %d request(s)\n", len(names), (len(names)+*batchSize-1) / *batchSize)
+	return nil
+}
+
+func runSimulateResults(args []string) error {
+	flags := flag.NewFlagSet("simulate-results", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	verbose := flags.Bool("v", false, "list the allowed actions, not just their counts")
+	jsonOutput := flags.Bool("json", false, "print the summary as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref simulate-results [flags] result.json...\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	index := authref.NewIndex(authRefs)
+	services := map[string]*simulateService{}
+	prefixes := make([]string, 0)
+
+	for _, filename := range flags.Args() {
+		data, err := os.ReadFile(filename)
+
+		if err != nil {
+			return err
+		}
+
+		var response simulateResponse
+
+		if err := json.Unmarshal(data, &response); err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+
+		for _, result := range response.EvaluationResults {
+			action := index.Action(result.EvalActionName)
+
+			if action == nil {
+				return fmt.Errorf("%s: %s isn't one of the known actions", filename, result.EvalActionName)
+			}
+
+			service := services[action.Service.ServicePrefix]
+
+			if service == nil {
+				service = &simulateService{ServicePrefix: action.Service.ServicePrefix, Allowed: map[authref.AccessLevel][]string{}}
+				services[action.Service.ServicePrefix] = service
+				prefixes = append(prefixes, action.Service.ServicePrefix)
+			}
+
+			switch result.EvalDecision {
+			case simulatorAllowed:
+				service.Allowed[action.Action.AccessLevel] = append(service.Allowed[action.Action.AccessLevel], action.Action.Name)
+			case simulatorExplicitDeny:
+				service.ExplicitDeny++
+			case simulatorImplicitDeny:
+				service.ImplicitDeny++
+			default:
+				return fmt.Errorf("%s: %s: unknown decision %#v", filename, result.EvalActionName, result.EvalDecision)
+			}
+		}
+	}
+
+	sort.Strings(prefixes)
+	summary := make([]*simulateService, len(prefixes))
+
+	for i, prefix := range prefixes {
+		summary[i] = services[prefix]
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summary)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "PREFIX\tLIST\tREAD\tWRITE\tPERMS\tTAGGING\tEXPLICIT-DENY\tIMPLICIT-DENY\n")
+
+	for _, service := range summary {
+		fmt.Fprintf(w, "%s", service.ServicePrefix)
+
+		for _, level := range authref.AccessLevels {
+			fmt.Fprintf(w, "\t%d", len(service.Allowed[level]))
+		}
+
+		fmt.Fprintf(w, "\t%d\t%d\n", service.ExplicitDeny, service.ImplicitDeny)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if *verbose {
+		for _, service := range summary {
+			if len(service.Allowed) == 0 {
+				continue
+			}
+
+			fmt.Printf("\n%s allowed:\n", service.ServicePrefix)
+
+			for _, level := range authref.AccessLevels {
+				if names := service.Allowed[level]; len(names) != 0 {
+					fmt.Printf("  %s: %s\n", level, strings.Join(names, ", "))
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+//go:embed site
+var siteFiles embed.FS
+
+var siteTemplates = template.Must(template.ParseFS(siteFiles, "site/*.html"))
+
+// sitePage holds what every page template needs.
+type sitePage struct {
+	Title        string
+	Root         string
+	Version      string
+	AccessLevels []authref.AccessLevel
+}
+
+type siteIndex struct {
+	sitePage
+	Services []*serviceSummary
+}
+
+// siteService is the page for a service prefix, which may combine several pages of the
+// reference.
+type siteService struct {
+	sitePage
+	Prefix        string
+	Pages         []*authref.ServiceAuthorizationReference
+	Actions       []*authref.Action
+	ResourceTypes []*authref.ResourceType
+	ConditionKeys []*authref.ConditionKey
+}
+
+type siteAction struct {
+	sitePage
+	Prefix      string
+	ServiceName string
+	Action      *authref.Action
+	ArnPatterns map[string]string
+}
+
+func writeTemplate(filename, name string, data interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+	w := bufio.NewWriter(file)
+
+	if err := siteTemplates.ExecuteTemplate(w, name, data); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// buildSite writes a static website for the dataset to dir: an index of services with a
+// client-side action search, a page per service prefix, and a page per action.
+func buildSite(dir string, authRefs []*authref.ServiceAuthorizationReference, version string) (int, error) {
+	data := newDataset(authRefs, nil)
+	page := func(title, root string) sitePage {
+		return sitePage{Title: title, Root: root, Version: version, AccessLevels: authref.AccessLevels}
+	}
+
+	pages := 0
+
+	for _, name := range []string{"style.css", "search.js"} {
+		content, err := siteFiles.ReadFile("site/" + name)
+
+		if err != nil {
+			return pages, err
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			return pages, err
+		}
+	}
+
+	// Each entry is [action, access level, description, page]
+	searchIndex := make([][]string, 0, len(data.actions))
+
+	for _, action := range data.actions {
+		searchIndex = append(searchIndex, []string{
+			action.ServicePrefix + ":" + action.Name, string(action.AccessLevel), action.Description,
+			"services/" + action.ServicePrefix + "/" + action.Name + ".html",
+		})
+	}
+
+	indexData, err := json.Marshal(searchIndex)
+
+	if err != nil {
+		return pages, err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "search.json"), indexData, 0644); err != nil {
+		return pages, err
+	}
+
+	index := &siteIndex{sitePage: page("Services", ""), Services: make([]*serviceSummary, 0)}
+	seen := map[string]bool{}
+
+	for _, service := range data.services {
+		if !seen[service.ServicePrefix] {
+			seen[service.ServicePrefix] = true
+			index.Services = append(index.Services, service)
+		}
+	}
+
+	if err := writeTemplate(filepath.Join(dir, "index.html"), "index.html", index); err != nil {
+		return pages, err
+	}
+
+	pages++
+	actionsByPrefix := map[string][]*actionEntry{}
+
+	for _, action := range data.actions {
+		actionsByPrefix[action.ServicePrefix] = append(actionsByPrefix[action.ServicePrefix], action)
+	}
+
+	for _, summary := range index.Services {
+		prefix := summary.ServicePrefix
+		service := &siteService{sitePage: page(prefix, "../"), Prefix: prefix, Pages: data.byPrefix[prefix]}
+		arnPatterns := map[string]string{}
+		seenKeys := map[string]bool{}
+
+		for _, authRef := range service.Pages {
+			for _, resourceType := range authRef.ResourceTypes {
+				if _, ok := arnPatterns[resourceType.Name]; !ok {
+					arnPatterns[resourceType.Name] = resourceType.ArnPattern
+					service.ResourceTypes = append(service.ResourceTypes, resourceType)
+				}
+			}
+
+			for _, conditionKey := range authRef.ConditionKeys {
+				if !seenKeys[conditionKey.Name] {
+					seenKeys[conditionKey.Name] = true
+					service.ConditionKeys = append(service.ConditionKeys, conditionKey)
+				}
+			}
+		}
+
+		for _, action := range actionsByPrefix[prefix] {
+			service.Actions = append(service.Actions, action.Action)
+
+			actionPage := &siteAction{
+				sitePage:    page(prefix+":"+action.Name, "../../"),
+				Prefix:      prefix,
+				ServiceName: action.ServiceName,
+				Action:      action.Action,
+				ArnPatterns: arnPatterns,
+			}
+
+			if err := writeTemplate(filepath.Join(dir, "services", prefix, action.Name+".html"), "action.html", actionPage); err != nil {
+				return pages, err
+			}
+
+			pages++
+		}
+
+		if err := writeTemplate(filepath.Join(dir, "services", prefix+".html"), "service.html", service); err != nil {
+			return pages, err
+		}
+
+		pages++
+	}
+
+	return pages, nil
+}
+
+func runSite(args []string) error {
+	flags := flag.NewFlagSet("site", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	outDir := flags.String("out", "_site", "directory to write the site to")
+	flags.Parse(args)
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	version := ""
+	metadata, err := authref.LoadMetadataFile(filepath.Join(filepath.Dir(*dataFile), "metadata.json"))
+
+	if err != nil {
+		return err
+	}
+
+	if metadata != nil {
+		version = metadata.Version.String()
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+
+	pages, err := buildSite(*outDir, authRefs, version)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %d pages to %s\n", pages, *outDir)
+	return nil
+}
@@ -0,0 +1,113 @@
+// Client-side search and access level filters for the generated site.
+(function () {
+  "use strict";
+
+  var maxResults = 200;
+
+  function selectedLevels() {
+    var levels = {};
+    document.querySelectorAll(".levels input").forEach(function (input) {
+      if (input.checked) {
+        levels[input.value] = true;
+      }
+    });
+    return levels;
+  }
+
+  // Turns an IAM-style pattern such as "s3:Get*" into a regular expression.
+  function patternToRegExp(pattern) {
+    var escaped = pattern.replace(/[.+^${}()|[\]\\]/g, "\\$&").replace(/\*/g, ".*").replace(/\?/g, ".");
+    return new RegExp("^" + escaped + "$", "i");
+  }
+
+  function matcher(query) {
+    query = query.trim();
+
+    if (/[*?]/.test(query) && query.indexOf(" ") === -1) {
+      var re = patternToRegExp(query);
+      return function (entry) { return re.test(entry[0]); };
+    }
+
+    var words = query.toLowerCase().replace(/"/g, "").split(/\s+/);
+    return function (entry) {
+      var text = (entry[0] + " " + entry[2]).toLowerCase();
+      return words.every(function (word) { return text.indexOf(word) !== -1; });
+    };
+  }
+
+  var search = document.getElementById("search");
+
+  // Service pages: filter the actions table by access level
+  if (!search) {
+    document.querySelectorAll(".levels input").forEach(function (input) {
+      input.addEventListener("change", function () {
+        var levels = selectedLevels();
+        document.querySelectorAll("table.actions tbody tr").forEach(function (row) {
+          row.hidden = !levels[row.getAttribute("data-level")];
+        });
+      });
+    });
+    return;
+  }
+
+  // Index page: search every action. Each entry of search.json is
+  // [action, access level, description, page].
+  var entries = null;
+  var results = document.getElementById("results");
+  var services = document.getElementById("services");
+  var more = document.getElementById("more");
+
+  function update() {
+    var query = search.value;
+
+    if (!query.trim() || !entries) {
+      results.hidden = true;
+      more.hidden = true;
+      services.hidden = false;
+      return;
+    }
+
+    var matches = matcher(query);
+    var levels = selectedLevels();
+    var body = results.querySelector("tbody");
+    var count = 0;
+    body.textContent = "";
+
+    entries.forEach(function (entry) {
+      if (!levels[entry[1]] || !matches(entry)) {
+        return;
+      }
+
+      count++;
+
+      if (count > maxResults) {
+        return;
+      }
+
+      var row = body.insertRow();
+      var link = document.createElement("a");
+      var code = document.createElement("code");
+      link.href = entry[3];
+      code.textContent = entry[0];
+      link.appendChild(code);
+      row.insertCell().appendChild(link);
+      row.insertCell().textContent = entry[1];
+      row.insertCell().textContent = entry[2];
+    });
+
+    results.hidden = false;
+    services.hidden = true;
+    more.hidden = count <= maxResults;
+    more.textContent = "Showing " + maxResults + " of " + count + " matching actions.";
+  }
+
+  fetch("search.json").then(function (response) { return response.json(); }).then(function (data) {
+    entries = data;
+    update();
+  });
+
+  search.addEventListener("input", update);
+  document.querySelectorAll(".levels input").forEach(function (input) {
+    input.addEventListener("change", update);
+  });
+})();
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// sizeLimits are the policy size limits compared against, in the order they're printed.
+var sizeLimits = []struct {
+	name  string
+	limit int
+}{
+	{"managed", authref.ManagedPolicySizeLimit},
+	{"user inline", authref.InlineUserPolicySizeLimit},
+	{"group inline", authref.InlineGroupPolicySizeLimit},
+	{"role inline", authref.InlineRolePolicySizeLimit},
+	{"SCP", authref.SCPSizeLimit},
+}
+
+func runSize(args []string) error {
+	flags := flag.NewFlagSet("size", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	policyFile := flags.String("policy", "", "take the target actions from what this policy allows instead of from the arguments")
+	jsonOutput := flags.Bool("json", false, "print the representations as JSON")
+	verbose := flags.Bool("v", false, "print the patterns of each representation and the extra actions they match")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref size [flags] <action pattern>...\n       authref size [flags] --policy policy.json\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if (flags.NArg() == 0) == (*policyFile == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	universe := authref.AllActions(authRefs)
+	var target []*authref.QualifiedAction
+
+	if *policyFile != "" {
+		policy, err := authref.LoadPolicyFile(*policyFile)
+
+		if err != nil {
+			return err
+		}
+
+		target = authref.AllowedActions(universe, policy)
+	} else {
+		target = authref.MatchActions(universe, flags.Args())
+	}
+
+	if len(target) == 0 {
+		return fmt.Errorf("no actions to size")
+	}
+
+	representations := authref.Representations(universe, target)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(representations)
+	}
+
+	fmt.Printf("%d actions\n\n", len(target))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "REPRESENTATION\tPATTERNS\tSIZE\tEXTRA ACTIONS")
+
+	for _, limit := range sizeLimits {
+		fmt.Fprintf(w, "\t%s (%d)", limit.name, limit.limit)
+	}
+
+	fmt.Fprintf(w, "\n")
+
+	for _, representation := range representations {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d", representation.Name, len(representation.Patterns), representation.Size, len(representation.Extra))
+
+		for _, limit := range sizeLimits {
+			if representation.Size <= limit.limit {
+				fmt.Fprintf(w, "\tfits")
+			} else {
+				fmt.Fprintf(w, "\tover by %d", representation.Size-limit.limit)
+			}
+		}
+
+		fmt.Fprintf(w, "\n")
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if *verbose {
+		for _, representation := range representations {
+			fmt.Printf("\n%s:\n", representation.Name)
+
+			for _, pattern := range representation.Patterns {
+				fmt.Printf("  %s\n", pattern)
+			}
+
+			if len(representation.Extra) != 0 {
+				fmt.Printf("  also matches:\n")
+
+				for _, name := range representation.Extra {
+					fmt.Printf("    %s\n", name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// vsCodeSnippet is one entry of a VS Code .code-snippets file.
+type vsCodeSnippet struct {
+	Scope       string   `json:"scope"`
+	Prefix      string   `json:"prefix"`
+	Body        []string `json:"body"`
+	Description string   `json:"description"`
+}
+
+// snippetStatement is a statement a snippet inserts. Effect and Sid hold snippet syntax,
+// so that the first tab stop chooses the effect and the last ones name the statements.
+type snippetStatement struct {
+	Sid      string   `json:"Sid"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+// accessLevelSlug shortens an access level for use in a snippet prefix.
+func accessLevelSlug(level authref.AccessLevel) string {
+	return strings.ToLower(strings.ReplaceAll(string(level), " ", "-"))
+}
+
+// statementSnippet builds the snippet for the actions of one service at one access level.
+// Actions that require a resource go in a statement whose Resource lists the ARN patterns
+// of their required resource types, with the placeholders as tab stops. Actions that can
+// only be granted on all resources go in a second statement on "*".
+func statementSnippet(prefix string, level authref.AccessLevel, actions []*authref.QualifiedAction) (*vsCodeSnippet, error) {
+	scoped := &snippetStatement{Action: make([]string, 0), Resource: make([]string, 0)}
+	unscoped := &snippetStatement{Action: make([]string, 0), Resource: []string{"*"}}
+	patterns := map[string]bool{}
+
+	for _, action := range actions {
+		required := false
+
+		for _, actionResourceType := range action.Action.ResourceTypes {
+			if !actionResourceType.Required {
+				continue
+			}
+
+			for _, resourceType := range action.Service.ResourceTypes {
+				if resourceType.Name == actionResourceType.ResourceType {
+					patterns[resourceType.ArnPattern] = true
+					required = true
+				}
+			}
+		}
+
+		if required {
+			scoped.Action = append(scoped.Action, action.String())
+		} else {
+			unscoped.Action = append(unscoped.Action, action.String())
+		}
+	}
+
+	sorted := make([]string, 0, len(patterns))
+
+	for pattern := range patterns {
+		sorted = append(sorted, pattern)
+	}
+
+	sort.Strings(sorted)
+
+	// Tab stop 1 chooses the effect of every statement
+	stops := map[string]int{"": 1}
+
+	for _, pattern := range sorted {
+		scoped.Resource = append(scoped.Resource, arnSnippet(pattern, stops))
+	}
+
+	sid := strings.ReplaceAll(strings.Title(prefix), "-", "") + strings.ReplaceAll(strings.Title(string(level)), " ", "")
+	statements := make([]*snippetStatement, 0, 2)
+
+	if len(scoped.Action) != 0 {
+		scoped.Sid = sid
+		statements = append(statements, scoped)
+	}
+
+	if len(unscoped.Action) != 0 {
+		unscoped.Sid = sid + "AllResources"
+		statements = append(statements, unscoped)
+	}
+
+	body := make([]string, 0)
+
+	for i, statement := range statements {
+		statement.Effect = "${1|Allow,Deny|}"
+		statement.Sid = fmt.Sprintf("${%d:%s}", len(stops)+1+i, statement.Sid)
+
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(statement); err != nil {
+			return nil, err
+		}
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+
+		if i != len(statements)-1 {
+			lines[len(lines)-1] += ","
+		}
+
+		body = append(body, lines...)
+	}
+
+	return &vsCodeSnippet{
+		Scope:       "json,jsonc",
+		Prefix:      fmt.Sprintf("iam-%s-%s", prefix, accessLevelSlug(level)),
+		Body:        body,
+		Description: fmt.Sprintf("IAM statements granting the %d %s actions of %s", len(actions), level, actions[0].Service.Name),
+	}, nil
+}
+
+// exportVSCodeSnippets writes a VS Code snippets file with a statement template for each
+// service and access level.
+func exportVSCodeSnippets(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	filename := filepath.Join(dir, "iam-policy.code-snippets")
+	groups := map[string]map[authref.AccessLevel][]*authref.QualifiedAction{}
+
+	for _, action := range authref.AllActions(authRefs) {
+		prefix := action.Service.ServicePrefix
+
+		if groups[prefix] == nil {
+			groups[prefix] = map[authref.AccessLevel][]*authref.QualifiedAction{}
+		}
+
+		groups[prefix][action.Action.AccessLevel] = append(groups[prefix][action.Action.AccessLevel], action)
+	}
+
+	snippets := map[string]*vsCodeSnippet{}
+
+	for prefix, levels := range groups {
+		for level, actions := range levels {
+			snippet, err := statementSnippet(prefix, level, actions)
+
+			if err != nil {
+				return nil, err
+			}
+
+			snippets[fmt.Sprintf("%s: %s", prefix, level)] = snippet
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(snippets); err != nil {
+		return nil, err
+	}
+
+	return []string{filename}, os.WriteFile(filename, buf.Bytes(), 0644)
+}
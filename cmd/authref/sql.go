@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// sqliteSchema is postgresSchema, rewritten for SQLite, so the PostgreSQL export, the
+// sqlite export, and the sql command share one definition of the tables, and queries
+// written against one work with the others. The tables go in a database attached as
+// "authref", which sqliteLoadScript attaches first. SQLite has no arrays, so array
+// columns hold JSON arrays, which json_each can expand, and booleans are 1 or 0.
+var sqliteSchema = sqliteFromPostgres(postgresSchema)
+
+// postgresIndex matches an unnamed index in postgresSchema, which SQLite needs a name for.
+var postgresIndex = regexp.MustCompile(`CREATE INDEX ON authref\.(\w+) \((\w+)\)`)
+
+// sqliteFromPostgres rewrites the statements of postgresSchema for SQLite.
+func sqliteFromPostgres(schema string) string {
+	lines := make([]string, 0)
+
+	// The schema is an attached database instead
+	for _, line := range strings.Split(schema, "\n") {
+		if !strings.HasPrefix(line, "DROP SCHEMA ") && !strings.HasPrefix(line, "CREATE SCHEMA ") {
+			lines = append(lines, line)
+		}
+	}
+
+	schema = strings.Join(lines, "\n")
+	schema = postgresIndex.ReplaceAllString(schema, "CREATE INDEX authref.${1}_$2 ON $1 ($2)")
+
+	// Foreign keys can only refer to tables in their own database, without naming it
+	return strings.NewReplacer("text[]", "text", "REFERENCES authref.", "REFERENCES ").Replace(schema)
+}
+
+// sqliteRowsPerInsert is how many rows go in each INSERT statement of the load script.
+const sqliteRowsPerInsert = 500
+
+// sqliteModes are the output formats of authref sql, as modes of the sqlite3 shell.
+var sqliteModes = map[string]string{
+	"table": "column",
+	"csv":   "csv",
+	"json":  "json",
+}
+
+// sqliteLiteral writes a value from a postgresTable row as an SQLite literal.
+func sqliteLiteral(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	case int:
+		return strconv.Itoa(value)
+	case bool:
+		if value {
+			return "1"
+		}
+
+		return "0"
+	case []string:
+		data, _ := json.Marshal(value)
+		return sqliteLiteral(string(data))
+	default:
+		panic(fmt.Sprintf("unexpected %T in an SQLite row", value))
+	}
+}
+
+// sqliteLoadScript returns the statements that attach a database as "authref", create the
+// tables in it, and load the dataset into them. The database is a file name, or ":memory:".
+func sqliteLoadScript(database string, authRefs []*authref.ServiceAuthorizationReference) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "ATTACH %s AS authref;\n\n", sqliteLiteral(database))
+	buf.WriteString(sqliteSchema)
+	buf.WriteString("BEGIN;\n")
+
+	for _, table := range postgresTables(authRefs) {
+		for i, row := range table.rows {
+			if i%sqliteRowsPerInsert == 0 {
+				if i != 0 {
+					buf.WriteString(";\n")
+				}
+
+				fmt.Fprintf(&buf, "INSERT INTO authref.%s VALUES\n", table.name)
+			} else {
+				buf.WriteString(",\n")
+			}
+
+			buf.WriteByte('(')
+
+			for j, value := range row {
+				if j != 0 {
+					buf.WriteByte(',')
+				}
+
+				buf.WriteString(sqliteLiteral(value))
+			}
+
+			buf.WriteByte(')')
+		}
+
+		if len(table.rows) != 0 {
+			buf.WriteString(";\n")
+		}
+	}
+
+	buf.WriteString("COMMIT;\n")
+	return buf.Bytes()
+}
+
+func runSQL(args []string) error {
+	flags := flag.NewFlagSet("sql", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	format := flags.String("format", "table", "output format: table, csv, or json")
+	noHeader := flags.Bool("no-header", false, "leave out the header line of table and csv output, for scripts")
+	sqlite := flags.String("sqlite3", "sqlite3", "the sqlite3 command-line shell to run the query with")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref sql [flags] \"SELECT ...\"\n\n")
+		fmt.Fprintf(flags.Output(), "Loads the dataset into an in-memory SQLite database, with the tables of the\n")
+		fmt.Fprintf(flags.Output(), "postgres export, and runs a query against it, such as\n")
+		fmt.Fprintf(flags.Output(), "  authref sql \"SELECT s.prefix, count(*) FROM action a JOIN service s ON s.id = a.service_id\n")
+		fmt.Fprintf(flags.Output(), "    WHERE a.access_level = 'Permissions management' GROUP BY s.prefix ORDER BY 2 DESC\"\n")
+		fmt.Fprintf(flags.Output(), "Needs the sqlite3 shell, version 3.33 or later.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	mode, ok := sqliteModes[*format]
+
+	if !ok {
+		return fmt.Errorf("unknown format %#v; use table, csv, or json", *format)
+	}
+
+	sqlitePath, err := exec.LookPath(*sqlite)
+
+	if err != nil {
+		return fmt.Errorf("the sql command needs the sqlite3 shell: %w", err)
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	// The load script is run with -init, so errors in the query are reported against the
+	// query itself rather than a line of the script
+	script, err := os.CreateTemp("", "authref-*.sql")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(script.Name())
+
+	if _, err := script.Write(sqliteLoadScript(":memory:", authRefs)); err != nil {
+		script.Close()
+		return err
+	}
+
+	if err := script.Close(); err != nil {
+		return err
+	}
+
+	headers := "on"
+
+	if *noHeader {
+		headers = "off"
+	}
+
+	cmd := exec.Command(sqlitePath, "-batch", "-bail", "-init", script.Name(),
+		"-cmd", ".headers "+headers, "-cmd", ".mode "+mode, ":memory:", flags.Arg(0))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sqlite3: %w", err)
+	}
+
+	return nil
+}
+
+// exportSQLite writes the tables of the sql command to authref.sqlite, using the sqlite3
+// shell, replacing the file if it exists.
+func exportSQLite(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	sqlitePath, err := exec.LookPath("sqlite3")
+
+	if err != nil {
+		return nil, fmt.Errorf("the sqlite format needs the sqlite3 shell: %w", err)
+	}
+
+	filename := filepath.Join(dir, "authref.sqlite")
+
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(sqlitePath, "-batch", "-bail", ":memory:")
+	cmd.Stdin = bytes.NewReader(sqliteLoadScript(filename, authRefs))
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("sqlite3: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return []string{filename}, nil
+}
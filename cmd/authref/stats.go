@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+type datasetStats struct {
+	Services                     int                         `json:"services"`
+	ServicePrefixes              int                         `json:"servicePrefixes"`
+	Actions                      int                         `json:"actions"`
+	ActionsByAccessLevel         map[authref.AccessLevel]int `json:"actionsByAccessLevel"`
+	PermissionOnlyActions        int                         `json:"permissionOnlyActions"`
+	ResourceTypes                int                         `json:"resourceTypes"`
+	ConditionKeys                int                         `json:"conditionKeys"`
+	ServicesWithoutResourceTypes []string                    `json:"servicesWithoutResourceTypes"`
+	PerService                   []*serviceStats             `json:"perService"`
+}
+
+type serviceStats struct {
+	Name                  string                      `json:"name"`
+	ServicePrefix         string                      `json:"servicePrefix"`
+	Actions               int                         `json:"actions"`
+	ActionsByAccessLevel  map[authref.AccessLevel]int `json:"actionsByAccessLevel"`
+	PermissionOnlyActions int                         `json:"permissionOnlyActions"`
+	ResourceTypes         int                         `json:"resourceTypes"`
+	ConditionKeys         int                         `json:"conditionKeys"`
+	LastUpdated           string                      `json:"lastUpdated,omitempty"`
+}
+
+// ActionsAt returns the number of the service's actions at an access level, for templates,
+// which can't index ActionsByAccessLevel with a plain string.
+func (service *serviceStats) ActionsAt(level authref.AccessLevel) int {
+	return service.ActionsByAccessLevel[level]
+}
+
+func computeStats(authRefs []*authref.ServiceAuthorizationReference) *datasetStats {
+	stats := &datasetStats{
+		ActionsByAccessLevel:         map[authref.AccessLevel]int{},
+		ServicesWithoutResourceTypes: make([]string, 0),
+		PerService:                   make([]*serviceStats, 0, len(authRefs)),
+	}
+	prefixes := map[string]bool{}
+
+	for _, authRef := range authRefs {
+		service := &serviceStats{
+			Name:                 authRef.Name,
+			ServicePrefix:        authRef.ServicePrefix,
+			Actions:              len(authRef.Actions),
+			ActionsByAccessLevel: map[authref.AccessLevel]int{},
+			ResourceTypes:        len(authRef.ResourceTypes),
+			ConditionKeys:        len(authRef.ConditionKeys),
+			LastUpdated:          authRef.LastUpdated,
+		}
+
+		for _, action := range authRef.Actions {
+			service.ActionsByAccessLevel[action.AccessLevel]++
+
+			if action.PermissionOnly {
+				service.PermissionOnlyActions++
+			}
+		}
+
+		stats.Services++
+		prefixes[authRef.ServicePrefix] = true
+		stats.Actions += service.Actions
+		stats.PermissionOnlyActions += service.PermissionOnlyActions
+		stats.ResourceTypes += service.ResourceTypes
+		stats.ConditionKeys += service.ConditionKeys
+
+		for level, count := range service.ActionsByAccessLevel {
+			stats.ActionsByAccessLevel[level] += count
+		}
+
+		if service.ResourceTypes == 0 {
+			stats.ServicesWithoutResourceTypes = append(stats.ServicesWithoutResourceTypes, authRef.Name)
+		}
+
+		stats.PerService = append(stats.PerService, service)
+	}
+
+	stats.ServicePrefixes = len(prefixes)
+	return stats
+}
+
+const defaultServiceColumns = "prefix,actions,list,read,write,perms,tagging,perm-only,res-types,cond-keys,updated,name"
+
+// serviceColumns are the columns stats can print for each service.
+var serviceColumns = []*tableColumn{
+	{name: "prefix", value: func(row interface{}) string { return row.(*serviceStats).ServicePrefix }},
+	{name: "actions", value: func(row interface{}) string { return strconv.Itoa(row.(*serviceStats).Actions) }},
+	{name: "list", value: func(row interface{}) string {
+		return strconv.Itoa(row.(*serviceStats).ActionsAt(authref.AccessLevelList))
+	}},
+	{name: "read", value: func(row interface{}) string {
+		return strconv.Itoa(row.(*serviceStats).ActionsAt(authref.AccessLevelRead))
+	}},
+	{name: "write", value: func(row interface{}) string {
+		return strconv.Itoa(row.(*serviceStats).ActionsAt(authref.AccessLevelWrite))
+	}},
+	{name: "perms", value: func(row interface{}) string {
+		return strconv.Itoa(row.(*serviceStats).ActionsAt(authref.AccessLevelPermissionsManagement))
+	}},
+	{name: "tagging", value: func(row interface{}) string {
+		return strconv.Itoa(row.(*serviceStats).ActionsAt(authref.AccessLevelTagging))
+	}},
+	{name: "perm-only", value: func(row interface{}) string { return strconv.Itoa(row.(*serviceStats).PermissionOnlyActions) }},
+	{name: "res-types", value: func(row interface{}) string { return strconv.Itoa(row.(*serviceStats).ResourceTypes) }},
+	{name: "cond-keys", value: func(row interface{}) string { return strconv.Itoa(row.(*serviceStats).ConditionKeys) }},
+	{name: "updated", value: func(row interface{}) string {
+		if updated := row.(*serviceStats).LastUpdated; updated != "" {
+			return updated
+		}
+
+		return "-"
+	}},
+	{name: "name", value: func(row interface{}) string { return row.(*serviceStats).Name }},
+}
+
+func runStats(args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	jsonOutput := flags.Bool("json", false, "print the statistics as JSON, for comparing releases")
+	templateText := templateFlag(flags)
+	columns := tableFlags(flags, serviceColumns, defaultServiceColumns)
+	flags.Parse(args)
+
+	tmpl, err := parseTemplate(*templateText)
+
+	if err != nil {
+		return err
+	}
+
+	if err := columns.parse(); err != nil {
+		return err
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	stats := computeStats(authRefs)
+	columns.sort(stats.PerService)
+
+	if tmpl != nil {
+		return printTemplate(tmpl, stats.PerService)
+	}
+
+	if *columns.noHeader {
+		return columns.print(stats.PerService)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Services:\t%d (%d service prefixes)\n", stats.Services, stats.ServicePrefixes)
+	fmt.Fprintf(w, "Actions:\t%d\n", stats.Actions)
+
+	for _, level := range authref.AccessLevels {
+		fmt.Fprintf(w, "  %s:\t%d\n", level, stats.ActionsByAccessLevel[level])
+	}
+
+	for level, count := range stats.ActionsByAccessLevel {
+		if !level.Known() {
+			fmt.Fprintf(w, "  %q (unknown):\t%d\n", level, count)
+		}
+	}
+
+	fmt.Fprintf(w, "Permission-only actions:\t%d\n", stats.PermissionOnlyActions)
+	fmt.Fprintf(w, "Resource types:\t%d\n", stats.ResourceTypes)
+	fmt.Fprintf(w, "Condition keys:\t%d\n", stats.ConditionKeys)
+	fmt.Fprintf(w, "Services without resource types:\t%d\n", len(stats.ServicesWithoutResourceTypes))
+
+	for _, name := range stats.ServicesWithoutResourceTypes {
+		fmt.Fprintf(w, "  %s\n", name)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	return columns.print(stats.PerService)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// tableColumn is a column a listing command can print. Its name is what --columns and
+// --sort take, and in upper case, its header.
+type tableColumn struct {
+	name  string
+	value func(row interface{}) string
+
+	// Orders two values for --sort; if nil, values that are both integers are compared
+	// as numbers, and others as text, ignoring case
+	compare func(a, b string) int
+}
+
+// sortKey is a column to sort by, from --sort.
+type sortKey struct {
+	column     *tableColumn
+	descending bool
+}
+
+// table prints the rows of a listing command as aligned columns, chosen with --columns,
+// in the order given with --sort.
+type table struct {
+	available []*tableColumn
+	columns   *string
+	noHeader  *bool
+	sortKeys  *string
+	selected  []*tableColumn
+	sortBy    []*sortKey
+}
+
+// accessLevelOrder ranks access levels from the most sensitive to the least, for sorting.
+var accessLevelOrder = map[string]int{
+	string(authref.AccessLevelPermissionsManagement): 1,
+	string(authref.AccessLevelWrite):                 2,
+	string(authref.AccessLevelTagging):               3,
+	string(authref.AccessLevelRead):                  4,
+	string(authref.AccessLevelList):                  5,
+}
+
+// compareAccessLevels orders access levels from Permissions management down to List.
+// Unknown levels, and the empty ones of condition keys, come last.
+func compareAccessLevels(a, b string) int {
+	rankA, rankB := accessLevelOrder[a], accessLevelOrder[b]
+
+	if rankA == 0 {
+		rankA = len(accessLevelOrder) + 1
+	}
+
+	if rankB == 0 {
+		rankB = len(accessLevelOrder) + 1
+	}
+
+	if rankA != rankB {
+		return rankA - rankB
+	}
+
+	return strings.Compare(a, b)
+}
+
+// compareValues is the order of columns without a compare function of their own.
+func compareValues(a, b string) int {
+	if intA, err := strconv.Atoi(a); err == nil {
+		if intB, err := strconv.Atoi(b); err == nil {
+			return intA - intB
+		}
+	}
+
+	if result := strings.Compare(strings.ToLower(a), strings.ToLower(b)); result != 0 {
+		return result
+	}
+
+	return strings.Compare(a, b)
+}
+
+// tableFlags adds --columns, --no-header, and --sort to a command's flag set. The default columns
+// are given by name, separated by commas.
+func tableFlags(flags *flag.FlagSet, available []*tableColumn, defaults string) *table {
+	names := make([]string, len(available))
+
+	for i, column := range available {
+		names[i] = column.name
+	}
+
+	return &table{
+		available: available,
+		columns:   flags.String("columns", defaults, "comma-separated columns to print, from "+strings.Join(names, ", ")),
+		noHeader:  flags.Bool("no-header", false, "leave out the header line, for scripts"),
+		sortKeys:  flags.String("sort", "", "comma-separated columns to sort by, each optionally followed by \"desc\", as in \"access-level,name desc\""),
+	}
+}
+
+// column finds a column by name, ignoring case and hyphens, so "accessLevel" finds
+// "access-level". It returns nil if there's no such column.
+func (t *table) column(name string) *tableColumn {
+	name = strings.ReplaceAll(name, "-", "")
+
+	for _, column := range t.available {
+		if strings.EqualFold(strings.ReplaceAll(column.name, "-", ""), name) {
+			return column
+		}
+	}
+
+	return nil
+}
+
+// parse checks the values of --columns and --sort. Call it after parsing the flags,
+// before sorting or printing.
+func (t *table) parse() error {
+	t.selected = make([]*tableColumn, 0)
+
+	for _, name := range strings.Split(*t.columns, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		column := t.column(name)
+
+		if column == nil {
+			return fmt.Errorf("unknown column %#v", name)
+		}
+
+		t.selected = append(t.selected, column)
+	}
+
+	if len(t.selected) == 0 && *t.columns != "" {
+		return fmt.Errorf("--columns names no columns")
+	}
+
+	t.sortBy = make([]*sortKey, 0)
+
+	for _, key := range strings.Split(*t.sortKeys, ",") {
+		fields := strings.Fields(key)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		column := t.column(fields[0])
+
+		if column == nil {
+			return fmt.Errorf("unknown column %#v in --sort", fields[0])
+		}
+
+		direction := ""
+
+		if len(fields) > 1 {
+			direction = strings.ToLower(fields[1])
+		}
+
+		if len(fields) > 2 || (direction != "" && direction != "asc" && direction != "desc") {
+			return fmt.Errorf("%#v in --sort should be a column, optionally followed by asc or desc", strings.TrimSpace(key))
+		}
+
+		t.sortBy = append(t.sortBy, &sortKey{column: column, descending: direction == "desc"})
+	}
+
+	return nil
+}
+
+// sort puts the elements of rows, which must be a slice, in the order given by --sort.
+// Rows that tie on every key keep their order.
+func (t *table) sort(rows interface{}) {
+	if len(t.sortBy) == 0 {
+		return
+	}
+
+	type keyedRow struct {
+		row  reflect.Value
+		keys []string
+	}
+
+	list := reflect.ValueOf(rows)
+	keyed := make([]*keyedRow, list.Len())
+
+	for i := range keyed {
+		row := list.Index(i).Interface()
+		keyed[i] = &keyedRow{row: reflect.ValueOf(row), keys: make([]string, len(t.sortBy))}
+
+		for j, key := range t.sortBy {
+			keyed[i].keys[j] = key.column.value(row)
+		}
+	}
+
+	sort.SliceStable(keyed, func(i, j int) bool {
+		for k, key := range t.sortBy {
+			compare := key.column.compare
+
+			if compare == nil {
+				compare = compareValues
+			}
+
+			result := compare(keyed[i].keys[k], keyed[j].keys[k])
+
+			if key.descending {
+				result = -result
+			}
+
+			if result != 0 {
+				return result < 0
+			}
+		}
+
+		return false
+	})
+
+	for i, entry := range keyed {
+		list.Index(i).Set(entry.row)
+	}
+}
+
+// print writes each element of rows, which must be a slice, as a line of the table.
+func (t *table) print(rows interface{}) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	cells := make([]string, len(t.selected))
+
+	if !*t.noHeader {
+		for i, column := range t.selected {
+			cells[i] = strings.ToUpper(column.name)
+		}
+
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+
+	// Tabs and line breaks in a value would break the alignment
+	clean := strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ")
+	list := reflect.ValueOf(rows)
+
+	for i := 0; i < list.Len(); i++ {
+		for j, column := range t.selected {
+			cells[j] = clean.Replace(column.value(list.Index(i).Interface()))
+		}
+
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are the functions available to --template, besides text/template's own.
+var templateFuncs = template.FuncMap{
+	"join":  func(list []string, sep string) string { return strings.Join(list, sep) },
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"replace": func(s, from, to string) string {
+		return strings.ReplaceAll(s, from, to)
+	},
+
+	// json encodes a value, such as a name to put in a policy, as JSON.
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+
+	// csv formats its arguments as one CSV record, quoting them where needed.
+	"csv": func(fields ...interface{}) (string, error) {
+		record := make([]string, len(fields))
+
+		for i, field := range fields {
+			record[i] = fmt.Sprint(field)
+		}
+
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+
+		w.Flush()
+		return strings.TrimSuffix(buf.String(), "\n"), w.Error()
+	},
+
+	// md escapes text for a cell of a Markdown table.
+	"md": func(s string) string {
+		return strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ").Replace(s)
+	},
+}
+
+// templateFlag adds the flag that formats each result of a command with a template.
+func templateFlag(flags *flag.FlagSet) *string {
+	return flags.String("template", "", "print each result with this Go text/template, such as '{{.Name}}', instead of the usual output")
+}
+
+// parseTemplate parses the value of --template, returning nil if it's empty. A newline is
+// added unless it already ends in one, so each result gets its own line.
+func parseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	tmpl, err := template.New("template").Funcs(templateFuncs).Parse(text)
+
+	if err != nil {
+		return nil, fmt.Errorf("parse --template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// printTemplate prints each element of items, which must be a slice, with the template.
+func printTemplate(tmpl *template.Template, items interface{}) error {
+	w := bufio.NewWriter(os.Stdout)
+	list := reflect.ValueOf(items)
+
+	for i := 0; i < list.Len(); i++ {
+		if err := tmpl.Execute(w, list.Index(i).Interface()); err != nil {
+			w.Flush()
+			return err
+		}
+	}
+
+	return w.Flush()
+}
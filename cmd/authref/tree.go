@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Node kinds.
+const (
+	nodeNull = iota
+	nodeBool
+	nodeInt
+	nodeFloat
+	nodeString
+	nodeArray
+	nodeObject
+)
+
+// node is a JSON value that remembers the order of object keys, so that encoders for
+// other formats can write fields in the same order as the JSON.
+type node struct {
+	kind   int
+	bool   bool
+	int    int64
+	float  float64
+	string string
+
+	// Object keys, in order; values holds the matching values, or the array elements
+	keys   []string
+	values []*node
+}
+
+// toTree converts a value to a node tree by way of its JSON encoding.
+func toTree(value interface{}) (*node, error) {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return readNode(decoder)
+}
+
+func readNode(decoder *json.Decoder) (*node, error) {
+	token, err := decoder.Token()
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case nil:
+		return &node{kind: nodeNull}, nil
+	case bool:
+		return &node{kind: nodeBool, bool: token}, nil
+	case string:
+		return &node{kind: nodeString, string: token}, nil
+	case json.Number:
+		if i, err := strconv.ParseInt(string(token), 10, 64); err == nil {
+			return &node{kind: nodeInt, int: i}, nil
+		}
+
+		f, err := token.Float64()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &node{kind: nodeFloat, float: f}, nil
+	case json.Delim:
+		result := &node{kind: nodeArray}
+
+		if token == '{' {
+			result.kind = nodeObject
+		}
+
+		for decoder.More() {
+			if result.kind == nodeObject {
+				key, err := decoder.Token()
+
+				if err != nil {
+					return nil, err
+				}
+
+				result.keys = append(result.keys, key.(string))
+			}
+
+			value, err := readNode(decoder)
+
+			if err != nil {
+				return nil, err
+			}
+
+			result.values = append(result.values, value)
+		}
+
+		// Closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unexpected JSON token %v", token)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// unscopedColumns are the columns unscoped can print for each action.
+var unscopedColumns = []*tableColumn{
+	{name: "action", value: func(row interface{}) string { return row.(*authref.UnscopedAction).Action }},
+	{name: "service", value: func(row interface{}) string { return row.(*authref.UnscopedAction).ServiceName }},
+	{name: "access-level", value: func(row interface{}) string { return string(row.(*authref.UnscopedAction).AccessLevel) }, compare: compareAccessLevels},
+	{name: "permission-only", value: func(row interface{}) string {
+		return strconv.FormatBool(row.(*authref.UnscopedAction).PermissionOnly)
+	}},
+	{name: "description", value: func(row interface{}) string { return row.(*authref.UnscopedAction).Description }},
+	{name: "reference", value: func(row interface{}) string { return row.(*authref.UnscopedAction).ReferenceHref }},
+}
+
+func runUnscoped(args []string) error {
+	flags := flag.NewFlagSet("unscoped", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	jsonOutput := flags.Bool("json", false, "print the actions as JSON, as in unscoped-actions.json")
+	templateText := templateFlag(flags)
+	columns := tableFlags(flags, unscopedColumns, "action,access-level,description")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref unscoped [flags] [service prefix]...\n\n")
+		fmt.Fprintf(flags.Output(), "Lists the Write and Permissions management actions that support no resource\n")
+		fmt.Fprintf(flags.Output(), "types and no condition keys, so a policy that grants them can't limit them at\n")
+		fmt.Fprintf(flags.Output(), "all. Give service prefixes to list only the actions of those services.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	tmpl, err := parseTemplate(*templateText)
+
+	if err != nil {
+		return err
+	}
+
+	if err := columns.parse(); err != nil {
+		return err
+	}
+
+	authRefs, err := loadData(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	prefixes := map[string]bool{}
+
+	for _, prefix := range flags.Args() {
+		prefixes[strings.ToLower(prefix)] = true
+	}
+
+	actions := make([]*authref.UnscopedAction, 0)
+
+	for _, action := range authref.UnscopedActions(authRefs) {
+		prefix := action.Action[:strings.Index(action.Action, ":")]
+
+		if len(prefixes) == 0 || prefixes[strings.ToLower(prefix)] {
+			actions = append(actions, action)
+		}
+	}
+
+	columns.sort(actions)
+
+	switch {
+	case *jsonOutput:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(actions)
+	case tmpl != nil:
+		return printTemplate(tmpl, actions)
+	default:
+		return columns.print(actions)
+	}
+}
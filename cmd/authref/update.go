@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// updateCheck compares a local snapshot with the latest published one.
+type updateCheck struct {
+	File            string `json:"file"`
+	LocalVersion    string `json:"localVersion,omitempty"`
+	LocalGenerated  string `json:"localGeneratedAt,omitempty"`
+	LatestVersion   string `json:"latestVersion"`
+	LatestGenerated string `json:"latestGeneratedAt"`
+
+	// Whether the local dataset is the latest one, going by its checksum
+	UpToDate bool `json:"upToDate"`
+
+	// Days since the local snapshot was generated, or -1 if there's no metadata to say
+	AgeDays int `json:"ageDays"`
+
+	// Whether the local snapshot is out of date and older than the threshold
+	Stale bool `json:"stale"`
+}
+
+// checkUpdate compares the dataset in filename, and the metadata next to it, with the
+// latest metadata. An out-of-date snapshot is stale once it's more than maxAge old, or
+// immediately if there's no local metadata to tell its age.
+func checkUpdate(filename string, latest *authref.Metadata, maxAge time.Duration, now time.Time) (*updateCheck, error) {
+	data, err := os.ReadFile(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(data)
+	local, err := authref.LoadMetadataFile(filepath.Join(filepath.Dir(filename), "metadata.json"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	expected, ok := latest.Checksums[filepath.Base(filename)]
+
+	if !ok {
+		return nil, fmt.Errorf("the latest metadata has no checksum for %s", filepath.Base(filename))
+	}
+
+	result := &updateCheck{
+		File:            filename,
+		LatestVersion:   latest.Version.String(),
+		LatestGenerated: latest.GeneratedAt,
+		UpToDate:        expected == hex.EncodeToString(digest[:]),
+		AgeDays:         -1,
+	}
+
+	var age time.Duration
+
+	if local != nil {
+		result.LocalVersion = local.Version.String()
+		result.LocalGenerated = local.GeneratedAt
+
+		generatedAt, err := time.Parse(time.RFC3339, local.GeneratedAt)
+
+		if err != nil {
+			return nil, fmt.Errorf("metadata.json: generatedAt: %w", err)
+		}
+
+		age = now.Sub(generatedAt)
+		result.AgeDays = int(age.Hours() / 24)
+	}
+
+	result.Stale = !result.UpToDate && (local == nil || age > maxAge)
+	return result, nil
+}
+
+func runCheckUpdate(args []string) error {
+	flags := flag.NewFlagSet("check-update", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	url := flags.String("url", authref.LatestMetadataURL, "URL of the latest published metadata.json")
+	maxAge := flags.Int("max-age", 14, "fail if a newer snapshot exists and the local one is more than this many days old")
+	jsonOutput := flags.Bool("json", false, "print the comparison as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref check-update [flags]\n\n"+
+			"Compares the dataset's checksum with the latest published metadata.json, and exits with\n"+
+			"status 1 if it's out of date and older than --max-age.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 || *maxAge < 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	latest, err := authref.FetchMetadata(&http.Client{Timeout: 30 * time.Second}, *url)
+
+	if err != nil {
+		return err
+	}
+
+	result, err := checkUpdate(*dataFile, latest, time.Duration(*maxAge)*24*time.Hour, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else if result.UpToDate {
+		fmt.Printf("%s is up to date (version %s, generated %s)\n", result.File, result.LatestVersion, result.LatestGenerated)
+	} else {
+		local := "no metadata.json, so its age is unknown"
+
+		if result.LocalVersion != "" {
+			local = fmt.Sprintf("version %s, generated %s, %d day(s) ago", result.LocalVersion, result.LocalGenerated, result.AgeDays)
+		}
+
+		fmt.Printf("%s is out of date (%s); the latest is version %s, generated %s\n", result.File, local, result.LatestVersion, result.LatestGenerated)
+	}
+
+	if result.Stale && result.AgeDays < 0 {
+		return fmt.Errorf("%s is out of date", result.File)
+	} else if result.Stale {
+		return fmt.Errorf("%s is out of date and %d day(s) old, more than --max-age", result.File, result.AgeDays)
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// verifier collects the outcome of each check made by the verify command.
+type verifier struct {
+	failures int
+	warnings int
+}
+
+func (v *verifier) ok(format string, args ...interface{}) {
+	fmt.Printf("ok    "+format+"\n", args...)
+}
+
+func (v *verifier) warn(format string, args ...interface{}) {
+	v.warnings++
+	fmt.Printf("warn  "+format+"\n", args...)
+}
+
+func (v *verifier) fail(format string, args ...interface{}) {
+	v.failures++
+	fmt.Printf("FAIL  "+format+"\n", args...)
+}
+
+func runVerify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	metadataFile := flags.String("metadata", "", "path to metadata.json (default: metadata.json next to the dataset, if present)")
+	publicKeyFile := flags.String("public-key", "", "minisign public key file; if given, "+authref.SignatureFile+" must be present and valid")
+	strict := flags.Bool("strict", false, "treat referential integrity problems as failures")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref verify [flags] [service-auth.json]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	dataFile := "service-auth.json"
+
+	if flags.NArg() == 1 {
+		dataFile = flags.Arg(0)
+	}
+
+	data, err := os.ReadFile(dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	v := &verifier{}
+	dir, base := filepath.Dir(dataFile), filepath.Base(dataFile)
+	digest := sha256.Sum256(data)
+	checksum := hex.EncodeToString(digest[:])
+
+	authRefs, schemaErrs := authref.DecodeStrict(bytes.NewReader(data))
+
+	for _, err := range schemaErrs {
+		v.fail("schema: %v", err)
+	}
+
+	if len(schemaErrs) == 0 {
+		v.ok("schema: %d services", len(authRefs))
+	}
+
+	if authRefs != nil {
+		verifyIntegrity(v, authRefs, *strict)
+	}
+
+	if err := verifyMetadata(v, *metadataFile, filepath.Join(dir, "metadata.json"), base, checksum, authRefs); err != nil {
+		return err
+	}
+
+	if err := verifyChecksums(v, dir, base, checksum, *publicKeyFile); err != nil {
+		return err
+	}
+
+	if v.failures != 0 {
+		return fmt.Errorf("%s: %d checks failed", dataFile, v.failures)
+	}
+
+	return nil
+}
+
+func verifyIntegrity(v *verifier, authRefs []*authref.ServiceAuthorizationReference, strict bool) {
+	report := v.warn
+
+	if strict {
+		report = v.fail
+	}
+
+	count := 0
+
+	for _, authRef := range authRefs {
+		for _, finding := range authref.CheckIntegrity(authRef) {
+			report("integrity: %s: %s (%s)", authRef.ServicePrefix, finding.Message, finding.Code)
+			count++
+		}
+	}
+
+	if count == 0 {
+		v.ok("integrity: all referenced resource types and condition keys exist")
+	}
+}
+
+// verifyMetadata compares the dataset with its metadata. The metadata is optional unless
+// a file was named explicitly.
+func verifyMetadata(v *verifier, metadataFile, defaultFile, base, checksum string, authRefs []*authref.ServiceAuthorizationReference) error {
+	required := metadataFile != ""
+
+	if !required {
+		metadataFile = defaultFile
+	}
+
+	metadata, err := authref.LoadMetadataFile(metadataFile)
+
+	if err != nil {
+		return err
+	}
+
+	if metadata == nil {
+		if required {
+			v.fail("metadata: %s not found", metadataFile)
+		} else {
+			v.warn("metadata: %s not found, skipping metadata checks", metadataFile)
+		}
+
+		return nil
+	}
+
+	if metadata.SchemaVersion != authref.SchemaVersion {
+		v.fail("metadata: schema version %d, but this tool understands version %d", metadata.SchemaVersion, authref.SchemaVersion)
+	}
+
+	if expected, ok := metadata.Checksums[base]; !ok {
+		v.fail("metadata: no checksum for %s", base)
+	} else if expected != checksum {
+		v.fail("metadata: checksum of %s does not match", base)
+	} else {
+		v.ok("metadata: checksum of %s matches version %v", base, metadata.Version)
+	}
+
+	if authRefs == nil {
+		return nil
+	}
+
+	counted := authref.NewMetadata(authRefs, nil, nil, "")
+
+	if counted.Services != metadata.Services || counted.Actions != metadata.Actions ||
+		counted.ResourceTypes != metadata.ResourceTypes || counted.ConditionKeys != metadata.ConditionKeys {
+		v.fail("metadata: counts don't match (dataset has %d services, %d actions, %d resource types, %d condition keys)",
+			counted.Services, counted.Actions, counted.ResourceTypes, counted.ConditionKeys)
+	} else {
+		v.ok("metadata: counts match")
+	}
+
+	return nil
+}
+
+// verifyChecksums checks the dataset against the SHA256SUMS file next to it, and the
+// signature of SHA256SUMS if a public key was given.
+func verifyChecksums(v *verifier, dir, base, checksum, publicKeyFile string) error {
+	sumsFile := filepath.Join(dir, authref.ChecksumsFile)
+	sums, err := os.ReadFile(sumsFile)
+
+	if errors.Is(err, os.ErrNotExist) {
+		if publicKeyFile != "" {
+			v.fail("signature: %s not found", sumsFile)
+		} else {
+			v.warn("checksums: %s not found, skipping checksum checks", sumsFile)
+		}
+
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	checksums, err := authref.ParseChecksums(sums)
+
+	if err != nil {
+		v.fail("checksums: %v", err)
+		return nil
+	}
+
+	if expected, ok := checksums[base]; !ok {
+		v.fail("checksums: %s does not list %s", authref.ChecksumsFile, base)
+	} else if expected != checksum {
+		v.fail("checksums: checksum of %s does not match %s", base, authref.ChecksumsFile)
+	} else {
+		v.ok("checksums: %s matches %s", base, authref.ChecksumsFile)
+	}
+
+	if publicKeyFile == "" {
+		return nil
+	}
+
+	keyText, err := os.ReadFile(publicKeyFile)
+
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := authref.ParsePublicKey(string(keyText))
+
+	if err != nil {
+		return err
+	}
+
+	signature, err := os.ReadFile(filepath.Join(dir, authref.SignatureFile))
+
+	if errors.Is(err, os.ErrNotExist) {
+		v.fail("signature: %s not found", authref.SignatureFile)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := authref.Verify(publicKey, sums, string(signature)); err != nil {
+		v.fail("signature: %v", err)
+	} else {
+		v.ok("signature: %s is signed by the given key", authref.ChecksumsFile)
+	}
+
+	return nil
+}
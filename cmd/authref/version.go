@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// Set by release builds with -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=...".
+// Builds without them fall back to what the Go toolchain records.
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+type versionTool struct {
+	authref.BuildInfo
+	BuildDate     string `json:"buildDate,omitempty"`
+	SchemaVersion int    `json:"schemaVersion"`
+}
+
+// versionDataset fingerprints the dataset file and the metadata published with it.
+type versionDataset struct {
+	File   string `json:"file"`
+	Size   int64  `json:"size,omitempty"`
+	Sha256 string `json:"sha256,omitempty"`
+
+	// Why the file couldn't be read, in place of its size and checksum
+	Error string `json:"error,omitempty"`
+
+	// From metadata.json next to the file, if there is one
+	Metadata *authref.Metadata `json:"metadata,omitempty"`
+
+	// Whether the checksum in the metadata matches the file
+	ChecksumMatches bool `json:"checksumMatches"`
+}
+
+type versionReport struct {
+	Tool    versionTool    `json:"tool"`
+	Dataset versionDataset `json:"dataset"`
+}
+
+// toolVersion describes the build of this program.
+func toolVersion() versionTool {
+	tool := versionTool{BuildInfo: authref.ReadBuildInfo(), BuildDate: buildDate, SchemaVersion: authref.SchemaVersion}
+
+	if version != "" {
+		tool.Version = version
+	}
+
+	if commit != "" {
+		tool.Commit = commit
+	}
+
+	return tool
+}
+
+// fingerprintDataset checksums a dataset file and reads the metadata next to it. Problems
+// reading the dataset are recorded rather than returned, since a report is still useful
+// without it.
+func fingerprintDataset(filename string) (versionDataset, error) {
+	result := versionDataset{File: filename}
+	data, err := os.ReadFile(filename)
+
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	digest := sha256.Sum256(data)
+	result.Size = int64(len(data))
+	result.Sha256 = hex.EncodeToString(digest[:])
+
+	if result.Metadata, err = authref.LoadMetadataFile(filepath.Join(filepath.Dir(filename), "metadata.json")); err != nil {
+		return result, err
+	}
+
+	if result.Metadata != nil {
+		result.ChecksumMatches = result.Metadata.Checksums[filepath.Base(filename)] == result.Sha256
+	}
+
+	return result, nil
+}
+
+func runVersion(args []string) error {
+	flags := flag.NewFlagSet("version", flag.ExitOnError)
+	dataFile := dataFlag(flags)
+	jsonOutput := flags.Bool("json", false, "print the build and dataset information as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref version [flags]\n\nPrints the build of this tool and a fingerprint of the dataset. Include it in bug reports.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	dataset, err := fingerprintDataset(*dataFile)
+
+	if err != nil {
+		return err
+	}
+
+	report := versionReport{Tool: toolVersion(), Dataset: dataset}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	fmt.Printf("authref %s\n", report.Tool.BuildInfo)
+	fmt.Printf("  %s %s, schema version %d\n", report.Tool.GoVersion, report.Tool.Platform, report.Tool.SchemaVersion)
+
+	if report.Tool.BuildDate != "" {
+		fmt.Printf("  built %s\n", report.Tool.BuildDate)
+	}
+
+	fmt.Printf("dataset %s\n", dataset.File)
+
+	if dataset.Error != "" {
+		fmt.Printf("  %s\n", dataset.Error)
+		return nil
+	}
+
+	fmt.Printf("  sha256 %s (%d bytes)\n", dataset.Sha256, dataset.Size)
+
+	if metadata := dataset.Metadata; metadata == nil {
+		fmt.Printf("  no metadata.json alongside it\n")
+	} else {
+		fmt.Printf("  version %s, schema version %d, generated %s\n", metadata.Version, metadata.SchemaVersion, metadata.GeneratedAt)
+
+		if metadata.Generator != nil {
+			fmt.Printf("  scraped by authref scrape %s\n", metadata.Generator)
+		}
+
+		if dataset.ChecksumMatches {
+			fmt.Printf("  checksum matches metadata.json\n")
+		} else {
+			fmt.Printf("  checksum doesn't match metadata.json; the file was changed or comes from another snapshot\n")
+		}
+	}
+
+	return nil
+}
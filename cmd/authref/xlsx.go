@@ -0,0 +1,263 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// sheet is one worksheet of a workbook. Cells are strings or ints.
+type sheet struct {
+	name   string
+	header []string
+	rows   [][]interface{}
+}
+
+// columnName returns the spreadsheet name of a zero-based column, such as "A" or "AB".
+func columnName(column int) string {
+	name := ""
+
+	for column >= 0 {
+		name = string(rune('A'+column%26)) + name
+		column = column/26 - 1
+	}
+
+	return name
+}
+
+func xmlText(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+func (s *sheet) xml() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+
+	// Keep the header row in view
+	buf.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	buf.WriteString(`<sheetData>`)
+
+	writeRow := func(index int, cells []interface{}, style int) {
+		fmt.Fprintf(&buf, `<row r="%d">`, index)
+
+		for column, cell := range cells {
+			ref := fmt.Sprintf("%s%d", columnName(column), index)
+
+			switch cell := cell.(type) {
+			case int:
+				fmt.Fprintf(&buf, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, cell)
+			default:
+				fmt.Fprintf(&buf, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xmlText(fmt.Sprint(cell)))
+			}
+		}
+
+		buf.WriteString(`</row>`)
+	}
+
+	header := make([]interface{}, len(s.header))
+
+	for i, name := range s.header {
+		header[i] = name
+	}
+
+	writeRow(1, header, 1)
+
+	for i, row := range s.rows {
+		writeRow(i+2, row, 0)
+	}
+
+	buf.WriteString(`</sheetData>`)
+	fmt.Fprintf(&buf, `<autoFilter ref="A1:%s%d"/>`, columnName(len(s.header)-1), len(s.rows)+1)
+	buf.WriteString(`</worksheet>`)
+	return buf.Bytes()
+}
+
+// writeWorkbook writes sheets as a minimal Office Open XML workbook. Strings are stored
+// inline rather than in a shared string table, and the only style is a bold header row.
+func writeWorkbook(filename string, sheets []*sheet) error {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	add := func(name, content string) error {
+		w, err := archive.Create(name)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write([]byte(content))
+		return err
+	}
+
+	var contentTypes, workbook, workbookRels strings.Builder
+	contentTypes.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	workbook.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	workbookRels.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+
+	for i, s := range sheets {
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlText(s.name), i+1, i+1)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+
+	fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	workbookRels.WriteString(`</Relationships>`)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", workbookRels.String()},
+		{"xl/styles.xml", xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+			`</styleSheet>`},
+	}
+
+	for _, part := range parts {
+		if err := add(part.name, part.content); err != nil {
+			return err
+		}
+	}
+
+	for i, s := range sheets {
+		if err := add(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), string(s.xml())); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// sdkServiceIds lists the service IDs of a service's SDK clients.
+func sdkServiceIds(authRef *authref.ServiceAuthorizationReference) string {
+	ids := make([]string, len(authRef.SdkServices))
+
+	for i, sdkService := range authRef.SdkServices {
+		ids[i] = sdkService.ServiceId
+	}
+
+	return strings.Join(ids, ", ")
+}
+
+// datasetSheets tabulates the dataset with a sheet for each kind of entity, plus a sheet
+// counting each service's actions by access level.
+func datasetSheets(authRefs []*authref.ServiceAuthorizationReference) []*sheet {
+	services := &sheet{name: "Services", header: []string{"Prefix", "Name", "Actions", "Resource types", "Condition keys", "Service principals", "SDK services", "Last updated", "Reference", "API reference"}}
+	actions := &sheet{name: "Actions", header: []string{"Prefix", "Action", "Access level", "Permission only", "Annotations", "Description", "Resource types", "Condition keys", "Dependent actions", "Reference"}}
+	resourceTypes := &sheet{name: "Resource types", header: []string{"Prefix", "Resource type", "ARN pattern", "Condition keys", "Reference"}}
+	conditionKeys := &sheet{name: "Condition keys", header: []string{"Prefix", "Condition key", "Type", "Description", "Reference"}}
+	pivotHeader := []string{"Prefix"}
+
+	for _, level := range authref.AccessLevels {
+		pivotHeader = append(pivotHeader, string(level))
+	}
+
+	pivot := &sheet{name: "Actions by access level", header: append(pivotHeader, "Total")}
+	pivotRows := map[string][]interface{}{}
+
+	for _, authRef := range authRefs {
+		services.rows = append(services.rows, []interface{}{
+			authRef.ServicePrefix, authRef.Name, len(authRef.Actions), len(authRef.ResourceTypes), len(authRef.ConditionKeys),
+			strings.Join(authRef.ServicePrincipals, ", "), sdkServiceIds(authRef), authRef.LastUpdated, authRef.AuthReferenceHref, authRef.ApiReferenceHref,
+		})
+
+		for _, resourceType := range authRef.ResourceTypes {
+			resourceTypes.rows = append(resourceTypes.rows, []interface{}{
+				authRef.ServicePrefix, resourceType.Name, resourceType.ArnPattern, strings.Join(resourceType.ConditionKeys, ", "), resourceType.ReferenceHref,
+			})
+		}
+
+		for _, conditionKey := range authRef.ConditionKeys {
+			conditionKeys.rows = append(conditionKeys.rows, []interface{}{
+				authRef.ServicePrefix, conditionKey.Name, conditionKey.Type, conditionKey.Description, conditionKey.ReferenceHref,
+			})
+		}
+	}
+
+	for _, action := range authref.AllActions(authRefs) {
+		resources, dependent := make([]string, 0), make([]string, 0)
+
+		for _, resourceType := range action.Action.ResourceTypes {
+			name := resourceType.ResourceType
+
+			if resourceType.Required {
+				name += "*"
+			}
+
+			resources = append(resources, name)
+			dependent = append(dependent, resourceType.DependentActions...)
+		}
+
+		permissionOnly := "no"
+
+		if action.Action.PermissionOnly {
+			permissionOnly = "yes"
+		}
+
+		actions.rows = append(actions.rows, []interface{}{
+			action.Service.ServicePrefix, action.String(), string(action.Action.AccessLevel), permissionOnly,
+			strings.Join(action.Action.Annotations, ", "), action.Action.Description, strings.Join(resources, ", "),
+			strings.Join(action.Action.ConditionKeys, ", "), strings.Join(dependent, ", "), action.Action.ReferenceHref,
+		})
+
+		prefix := action.Service.ServicePrefix
+		row := pivotRows[prefix]
+
+		if row == nil {
+			row = []interface{}{prefix}
+
+			for range pivot.header[1:] {
+				row = append(row, 0)
+			}
+
+			pivotRows[prefix] = row
+			pivot.rows = append(pivot.rows, row)
+		}
+
+		for i, level := range authref.AccessLevels {
+			if action.Action.AccessLevel == level {
+				row[i+1] = row[i+1].(int) + 1
+			}
+		}
+
+		row[len(row)-1] = row[len(row)-1].(int) + 1
+	}
+
+	return []*sheet{services, actions, resourceTypes, conditionKeys, pivot}
+}
+
+// exportXLSX writes the sheets of datasetSheets as a workbook.
+func exportXLSX(dir string, authRefs []*authref.ServiceAuthorizationReference) ([]string, error) {
+	filename := filepath.Join(dir, "service-auth.xlsx")
+
+	if err := writeWorkbook(filename, datasetSheets(authRefs)); err != nil {
+		return nil, err
+	}
+
+	return []string{filename}, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Keys that YAML reads as plain strings without quotes
+var yamlPlainKey = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// yamlScalar returns the YAML for a scalar or an empty array or object. Strings are always
+// double-quoted, so none of them can be mistaken for a number or a boolean; Go's escapes
+// are all valid in YAML's double-quoted style.
+func yamlScalar(n *node) string {
+	switch n.kind {
+	case nodeBool:
+		return strconv.FormatBool(n.bool)
+	case nodeInt:
+		return strconv.FormatInt(n.int, 10)
+	case nodeFloat:
+		return strconv.FormatFloat(n.float, 'g', -1, 64)
+	case nodeString:
+		return strconv.Quote(n.string)
+	case nodeArray:
+		return "[]"
+	case nodeObject:
+		return "{}"
+	default:
+		return "null"
+	}
+}
+
+// yamlIsBlock reports whether a node is written as a block of lines of its own.
+func yamlIsBlock(n *node) bool {
+	return (n.kind == nodeArray || n.kind == nodeObject) && len(n.values) != 0
+}
+
+// encodeYAML appends a YAML document for a node to buf, in block style with object keys in
+// the same order as the JSON.
+func encodeYAML(buf *bytes.Buffer, n *node) {
+	if yamlIsBlock(n) {
+		writeYAMLBlock(buf, n, 0, false)
+	} else {
+		buf.WriteString(yamlScalar(n) + "\n")
+	}
+}
+
+// writeYAMLBlock writes the lines of a nonempty array or object at an indent. If continued,
+// the first line continues one already started, after a "- ".
+func writeYAMLBlock(buf *bytes.Buffer, n *node, indent int, continued bool) {
+	for i, value := range n.values {
+		if i != 0 || !continued {
+			buf.WriteString(strings.Repeat(" ", indent))
+		}
+
+		if n.kind == nodeArray {
+			buf.WriteString("-")
+		} else if yamlPlainKey.MatchString(n.keys[i]) {
+			buf.WriteString(n.keys[i] + ":")
+		} else {
+			buf.WriteString(strconv.Quote(n.keys[i]) + ":")
+		}
+
+		switch {
+		case !yamlIsBlock(value):
+			buf.WriteString(" " + yamlScalar(value) + "\n")
+		case n.kind == nodeArray && value.kind == nodeObject:
+			// The first key of an object in a list goes on the same line as its dash
+			buf.WriteString(" ")
+			writeYAMLBlock(buf, value, indent+2, true)
+		default:
+			buf.WriteString("\n")
+			writeYAMLBlock(buf, value, indent+2, false)
+		}
+	}
+}
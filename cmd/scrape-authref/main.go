@@ -0,0 +1,15 @@
+// Command scrape-authref builds service-auth.json and the files published with it from the
+// AWS Service Authorization Reference. It's the same as "authref scrape", and is kept for
+// scripts that run it by this name, except that it can't write the formats of authref
+// export with --format.
+package main
+
+import (
+	"os"
+
+	"github.com/fluggo/aws-service-auth-reference/internal/scraper"
+)
+
+func main() {
+	scraper.Main(os.Args[1:], nil)
+}
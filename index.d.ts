@@ -23,6 +23,37 @@ export interface ServiceAuthorizationReference {
    */
   apiReferenceHref?: string;
 
+  /**
+   * Service principals this service uses in trust policies, such as "sts.amazonaws.com",
+   * if known. These come from a hand-maintained mapping, not from the reference itself.
+   */
+  servicePrincipals?: string[];
+
+  /**
+   * AWS SDK clients that call this service, if known. These come from a hand-maintained
+   * mapping, not from the reference itself.
+   */
+  sdkServices?: SdkService[];
+
+  /**
+   * Date AWS last changed this service's page, as YYYY-MM-DD, if known.
+   * Absent from releases made before it was recorded.
+   */
+  lastUpdated?: string;
+
+  /**
+   * Partitions this service isn't offered in, such as "aws-cn", if known. These come from
+   * a hand-maintained mapping, not from the reference itself.
+   */
+  unavailablePartitions?: string[];
+
+  /**
+   * Regions this service has endpoints in, across every partition, such as "us-east-1"
+   * and "cn-north-1", sorted. These come from the AWS SDK's endpoints data, not from the
+   * reference itself. Absent for services the SDK doesn't know.
+   */
+  regions?: string[];
+
   /**
    * List of actions that can be specified for this service in IAM action statements.
    */
@@ -41,6 +72,22 @@ export interface ServiceAuthorizationReference {
   conditionKeys: ConditionKey[];
 }
 
+/**
+ * An AWS SDK client for a service, identified as in the SDK's service model.
+ */
+export interface SdkService {
+  /**
+   * Service ID, such as "SFN" or "Elasticsearch Service".
+   */
+  serviceId: string;
+
+  /**
+   * Endpoint prefix, such as "states". This is also the first part of the
+   * event source in CloudTrail, as in "states.amazonaws.com".
+   */
+  endpointPrefix: string;
+}
+
 /**
  * A action that can be allowed or denied via IAM policy.
  */
@@ -55,6 +102,12 @@ export interface Action {
    */
   permissionOnly: boolean;
 
+  /**
+   * Bracketed notes that follow the action name in the reference, such as
+   * `permission only` or `only available in China Regions`.
+   */
+  annotations: string[];
+
   /**
    * URL of the API or user guide reference for this action.
    */
@@ -65,6 +118,13 @@ export interface Action {
    */
   description: string;
 
+  /**
+   * Translations of the description, keyed by documentation locale such as `ja_jp`.
+   *
+   * Only present for the locales the scraper was asked to fetch.
+   */
+  localizedDescriptions?: { [locale: string]: string };
+
   /**
    * The access level classification for this action.
    *
@@ -78,6 +138,13 @@ export interface Action {
    * If empty, you must specify all resources (`"*"`) in the policy when using this action.
    */
   resourceTypes: ActionResourceType[];
+
+  /**
+   * Where this record was scraped from.
+   *
+   * Only present if the scraper was run with `--provenance`.
+   */
+  provenance?: Provenance;
 }
 
 /**
@@ -90,11 +157,18 @@ export interface ActionResourceType {
   resourceType: string;
 
   /**
-   * True if a resource of this type is required in order to execute the action.
-   * That is, if the IAM statement specifies resources, at least one resource of this type is required.
+   * True if the reference marks this resource type as required. When an action has several
+   * required resource types, a request needs a resource of at least one of them, not one of each.
    */
   required: boolean;
 
+  /**
+   * The group of required resource types this one belongs to. A request must name a resource
+   * of at least one type in each group. Absent if the type isn't required, and in releases
+   * made before the field existed, in which case treat every required type as group 1.
+   */
+  requiredGroup?: number;
+
   /**
    * Condition keys that can be specified for this resource type.
    *
@@ -134,6 +208,20 @@ export interface ResourceType {
    * List of condition keys that are valid for this resource type.
    */
   conditionKeys: string[];
+
+  /**
+   * `arnPattern` with its partition filled in, keyed by partition (`aws`, `aws-cn`, and
+   * `aws-us-gov`), leaving out partitions the service isn't offered in.
+   * Absent from releases made before it was recorded.
+   */
+  partitionArnPatterns?: { [partition: string]: string };
+
+  /**
+   * Where this record was scraped from.
+   *
+   * Only present if the scraper was run with `--provenance`.
+   */
+  provenance?: Provenance;
 }
 
 /**
@@ -155,12 +243,46 @@ export interface ConditionKey {
    */
   description: string;
 
+  /**
+   * Translations of the description, keyed by documentation locale such as `ja_jp`.
+   *
+   * Only present for the locales the scraper was asked to fetch.
+   */
+  localizedDescriptions?: { [locale: string]: string };
+
   /**
    * The type of the condition key.
    *
    * This can be a primitive type such as String or a compound type such as ArrayOfString.
    */
   type: string;
+
+  /**
+   * Where this record was scraped from.
+   *
+   * Only present if the scraper was run with `--provenance`.
+   */
+  provenance?: Provenance;
+}
+
+/**
+ * Where a record in the reference was scraped from.
+ */
+export interface Provenance {
+  /**
+   * URL of the page the record was scraped from.
+   */
+  sourceHref: string;
+
+  /**
+   * ID of the heading of the record's table on the page, usable as a URL fragment.
+   */
+  anchor?: string;
+
+  /**
+   * When the page was scraped, as an RFC 3339 timestamp.
+   */
+  scrapedAt: string;
 }
 
 declare const serviceAuth: ServiceAuthorizationReference[];
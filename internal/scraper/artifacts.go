@@ -0,0 +1,303 @@
+package scraper
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+const (
+	byPrefixFile  = "service-auth-by-prefix.json"
+	actionMapFile = "action-map.json"
+
+	// Just the names and access levels of actions, for consumers that don't need the rest
+	actionsOnlyFile = "actions-only.json"
+
+	// Every condition key once, with the services that define it
+	conditionKeysFile = "condition-keys.json"
+
+	// Resource types whose ARNs use a service namespace other than their service prefix
+	arnNamespacesFile = "arn-namespaces.json"
+
+	// Actions that refer to resource types and condition keys their service doesn't define
+	integrityFile = "integrity.json"
+
+	// Every warning raised while making the dataset, from the scrape report
+	warningsFile = "warnings.json"
+
+	// Every permission-only action, with its description
+	permissionOnlyFile = "permission-only-actions.json"
+
+	// Write and Permissions management actions that can't be limited by resource or condition
+	unscopedFile = "unscoped-actions.json"
+
+	// The dataset as an authref.v1.Dataset message; see proto/authref.proto
+	protoFile = "service-auth.pb"
+)
+
+// writeJSONFile writes value as indented JSON, in the same style as the main output file.
+func writeJSONFile(filename string, value interface{}) error {
+	file, err := os.Create(filename)
+
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", filename, err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(value); err != nil {
+		file.Close()
+		os.Remove(filename)
+		return fmt.Errorf("could not write %s: %w", filename, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not close %s: %w", filename, err)
+	}
+
+	return nil
+}
+
+// copyVersioned copies each file into dir with the version added to its name, so that
+// "service-auth.json" becomes "service-auth-1.2.0.json". It returns the paths of the copies.
+func copyVersioned(dir string, version authref.Version, filenames []string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create %s: %w", dir, err)
+	}
+
+	result := make([]string, 0, len(filenames))
+
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", filename, err)
+		}
+
+		ext := filepath.Ext(filename)
+		versionedName := filepath.Join(dir, fmt.Sprintf("%s-%s%s", strings.TrimSuffix(filepath.Base(filename), ext), version, ext))
+
+		if err := os.WriteFile(versionedName, data, 0644); err != nil {
+			return nil, fmt.Errorf("could not write %s: %w", versionedName, err)
+		}
+
+		result = append(result, versionedName)
+	}
+
+	return result, nil
+}
+
+// checksumFiles returns the SHA-256 digest of each file, keyed by its base name.
+func checksumFiles(filenames []string) (map[string]string, error) {
+	result := make(map[string]string, len(filenames))
+
+	for _, filename := range filenames {
+		checksum, err := authref.SHA256File(filename)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not checksum %s: %w", filename, err)
+		}
+
+		result[filepath.Base(filename)] = checksum
+	}
+
+	return result, nil
+}
+
+// writeChecksums writes the checksum list for the files into dir, then signs it if key isn't nil.
+func writeChecksums(dir string, filenames []string, key ed25519.PrivateKey, version authref.Version) error {
+	checksums, err := checksumFiles(filenames)
+
+	if err != nil {
+		return err
+	}
+
+	data := authref.FormatChecksums(checksums)
+
+	if err := os.WriteFile(filepath.Join(dir, authref.ChecksumsFile), data, 0644); err != nil {
+		return fmt.Errorf("could not write checksums: %w", err)
+	}
+
+	if key == nil {
+		return nil
+	}
+
+	signature := authref.Sign(key, data, fmt.Sprintf("aws-service-auth-reference dataset %s", version))
+
+	if err := os.WriteFile(filepath.Join(dir, authref.SignatureFile), []byte(signature), 0644); err != nil {
+		return fmt.Errorf("could not write signature: %w", err)
+	}
+
+	return nil
+}
+
+// keyedService is a service in the by-prefix artifact. Pages that share a service prefix
+// (such as "AWS Elastic Load Balancing" and "AWS Elastic Load Balancing V2") are merged
+// into a single entry, with the pages after the first listed in MergedFrom.
+type keyedService struct {
+	Name              string                           `json:"name"`
+	ServicePrefix     string                           `json:"servicePrefix"`
+	AuthReferenceHref string                           `json:"authReferenceHref"`
+	ApiReferenceHref  string                           `json:"apiReferenceHref,omitempty"`
+	ServicePrincipals []string                         `json:"servicePrincipals,omitempty"`
+	LastUpdated       string                           `json:"lastUpdated,omitempty"`
+	MergedFrom        []*keyedServicePage              `json:"mergedFrom,omitempty"`
+	Actions           map[string]*authref.Action       `json:"actions"`
+	ResourceTypes     map[string]*authref.ResourceType `json:"resourceTypes"`
+	ConditionKeys     map[string]*authref.ConditionKey `json:"conditionKeys"`
+}
+
+type keyedServicePage struct {
+	Name              string `json:"name"`
+	AuthReferenceHref string `json:"authReferenceHref"`
+	ApiReferenceHref  string `json:"apiReferenceHref,omitempty"`
+	LastUpdated       string `json:"lastUpdated,omitempty"`
+}
+
+// keyByPrefix builds the by-prefix artifact. The first page for a prefix wins whenever
+// resource types or condition keys collide; actions are merged as in mergeDuplicateActions.
+func keyByPrefix(authRefs []*authref.ServiceAuthorizationReference) map[string]*keyedService {
+	result := make(map[string]*keyedService, len(authRefs))
+
+	for _, authRef := range authRefs {
+		service := result[authRef.ServicePrefix]
+
+		if service == nil {
+			service = &keyedService{
+				Name:              authRef.Name,
+				ServicePrefix:     authRef.ServicePrefix,
+				AuthReferenceHref: authRef.AuthReferenceHref,
+				ApiReferenceHref:  authRef.ApiReferenceHref,
+				ServicePrincipals: authRef.ServicePrincipals,
+				LastUpdated:       authRef.LastUpdated,
+				Actions:           make(map[string]*authref.Action, len(authRef.Actions)),
+				ResourceTypes:     make(map[string]*authref.ResourceType, len(authRef.ResourceTypes)),
+				ConditionKeys:     make(map[string]*authref.ConditionKey, len(authRef.ConditionKeys)),
+			}
+			result[authRef.ServicePrefix] = service
+		} else {
+			service.MergedFrom = append(service.MergedFrom, &keyedServicePage{
+				Name:              authRef.Name,
+				AuthReferenceHref: authRef.AuthReferenceHref,
+				ApiReferenceHref:  authRef.ApiReferenceHref,
+				LastUpdated:       authRef.LastUpdated,
+			})
+
+			// The service as a whole changed when any of its pages did
+			if authRef.LastUpdated > service.LastUpdated {
+				service.LastUpdated = authRef.LastUpdated
+			}
+		}
+
+		for _, action := range authRef.Actions {
+			if existing := service.Actions[action.Name]; existing != nil {
+				mergeAction(existing, action)
+			} else {
+				// Copy the action, since merging modifies it
+				service.Actions[action.Name] = copyAction(action)
+			}
+		}
+
+		for _, resourceType := range authRef.ResourceTypes {
+			if service.ResourceTypes[resourceType.Name] == nil {
+				service.ResourceTypes[resourceType.Name] = resourceType
+			}
+		}
+
+		for _, conditionKey := range authRef.ConditionKeys {
+			if service.ConditionKeys[conditionKey.Name] == nil {
+				service.ConditionKeys[conditionKey.Name] = conditionKey
+			}
+		}
+	}
+
+	return result
+}
+
+// copyAction returns a copy of action that shares no slices with it.
+func copyAction(action *authref.Action) *authref.Action {
+	result := *action
+	result.Annotations = append([]string{}, action.Annotations...)
+	result.ConditionKeys = append([]string{}, action.ConditionKeys...)
+	result.ResourceTypes = make([]authref.ActionResourceType, len(action.ResourceTypes))
+
+	for i, resourceType := range action.ResourceTypes {
+		resourceType.ConditionKeys = append([]string{}, resourceType.ConditionKeys...)
+		resourceType.DependentActions = append([]string{}, resourceType.DependentActions...)
+		result.ResourceTypes[i] = resourceType
+	}
+
+	return &result
+}
+
+// flatAction is an entry in the action map artifact: an action along with the service it belongs to.
+type flatAction struct {
+	ServicePrefix string `json:"servicePrefix"`
+	ServiceName   string `json:"serviceName"`
+	*authref.Action
+}
+
+// flattenActions builds the action map artifact, keyed by the fully qualified action
+// name as it appears in policies (e.g. "s3:GetObject").
+func flattenActions(services map[string]*keyedService) map[string]*flatAction {
+	result := make(map[string]*flatAction)
+
+	for prefix, service := range services {
+		for name, action := range service.Actions {
+			result[prefix+":"+name] = &flatAction{ServicePrefix: prefix, ServiceName: service.Name, Action: action}
+		}
+	}
+
+	return result
+}
+
+// actionsOnlyEntry is an entry in the actions-only artifact.
+type actionsOnlyEntry struct {
+	ServicePrefix  string              `json:"servicePrefix"`
+	Name           string              `json:"name"`
+	AccessLevel    authref.AccessLevel `json:"accessLevel"`
+	PermissionOnly bool                `json:"permissionOnly"`
+}
+
+// writeActionsOnly writes every action, sorted as in authref.AllActions, as an array with
+// one compact object per line. That keeps the file a fraction of the size of the others
+// while still diffing cleanly from one snapshot to the next.
+func writeActionsOnly(filename string, authRefs []*authref.ServiceAuthorizationReference) error {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+
+	for i, action := range authref.AllActions(authRefs) {
+		data, err := json.Marshal(&actionsOnlyEntry{
+			ServicePrefix:  action.Service.ServicePrefix,
+			Name:           action.Action.Name,
+			AccessLevel:    action.Action.AccessLevel,
+			PermissionOnly: action.Action.PermissionOnly,
+		})
+
+		if err != nil {
+			return fmt.Errorf("could not encode %s: %w", filename, err)
+		}
+
+		if i != 0 {
+			buf.WriteString(",")
+		}
+
+		buf.WriteString("\n  ")
+		buf.Write(data)
+	}
+
+	buf.WriteString("\n]\n")
+
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", filename, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// notableAccessLevels ranks access levels by how much a new action at that level is worth
+// pointing out; one that manages permissions matters more to a reviewer than a new List action.
+var notableAccessLevels = []authref.AccessLevel{
+	authref.AccessLevelPermissionsManagement,
+	authref.AccessLevelWrite,
+	authref.AccessLevelTagging,
+	authref.AccessLevelRead,
+	authref.AccessLevelList,
+}
+
+// plural formats a count with a noun, adding an "s" unless the count is one.
+func plural(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// notableAction picks the added action to name in a commit subject: the first one at the
+// highest-ranked access level, outside of services that are new altogether.
+func notableAction(changes *authref.Changes) string {
+	for _, level := range notableAccessLevels {
+		for _, service := range changes.Services {
+			if service.Added {
+				continue
+			}
+
+			for _, action := range service.AddedActions {
+				if action.AccessLevel == level {
+					return service.ServicePrefix + ":" + action.Name
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// commitMessage describes the changes from the previous snapshot as a commit message, such
+// as "Add 12 actions across 5 services (notably bedrock:CreateGuardrail)", followed by a line
+// for each service that changed.
+func commitMessage(changes *authref.Changes, metadata *authref.Metadata) string {
+	var addedServices, removedServices []string
+	added, removed, changed := 0, 0, 0
+	addedIn, removedIn, changedIn := 0, 0, 0
+
+	for _, service := range changes.Services {
+		// The actions of a new or removed service are summed up by the service
+		if service.Added {
+			addedServices = append(addedServices, service.ServicePrefix)
+			continue
+		} else if service.Removed {
+			removedServices = append(removedServices, service.ServicePrefix)
+			continue
+		}
+
+		if len(service.AddedActions) != 0 {
+			added += len(service.AddedActions)
+			addedIn++
+		}
+
+		if len(service.RemovedActions) != 0 {
+			removed += len(service.RemovedActions)
+			removedIn++
+		}
+
+		if len(service.ChangedActions) != 0 {
+			changed += len(service.ChangedActions)
+			changedIn++
+		}
+	}
+
+	parts := make([]string, 0)
+
+	if len(addedServices) != 0 {
+		parts = append(parts, fmt.Sprintf("add %s (%s)", plural(len(addedServices), "service"), strings.Join(addedServices, ", ")))
+	}
+
+	if added != 0 {
+		part := fmt.Sprintf("add %s across %s", plural(added, "action"), plural(addedIn, "service"))
+
+		if addedIn == 1 {
+			part = fmt.Sprintf("add %s", plural(added, "action"))
+		}
+
+		if notable := notableAction(changes); notable != "" {
+			part += fmt.Sprintf(" (notably %s)", notable)
+		}
+
+		parts = append(parts, part)
+	}
+
+	if len(removedServices) != 0 {
+		parts = append(parts, fmt.Sprintf("remove %s (%s)", plural(len(removedServices), "service"), strings.Join(removedServices, ", ")))
+	}
+
+	if removed != 0 {
+		parts = append(parts, fmt.Sprintf("remove %s across %s", plural(removed, "action"), plural(removedIn, "service")))
+	}
+
+	// Changed actions are only worth the subject line when nothing was added or removed
+	if len(parts) == 0 && changed != 0 {
+		parts = append(parts, fmt.Sprintf("update %s across %s", plural(changed, "action"), plural(changedIn, "service")))
+	}
+
+	if len(parts) == 0 && !changes.Empty() {
+		parts = append(parts, fmt.Sprintf("update resource types and condition keys in %s", plural(len(changes.Services), "service")))
+	}
+
+	if len(parts) == 0 {
+		parts = append(parts, "update service details")
+	}
+
+	subject := strings.Join(parts, "; ")
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(subject[:1]) + subject[1:] + "\n")
+
+	if len(changes.Services) != 0 {
+		b.WriteString("\n")
+	}
+
+	for _, service := range changes.Services {
+		counts := make([]string, 0)
+		count := func(sign string, n int, noun string) {
+			if n != 0 {
+				counts = append(counts, sign+plural(n, noun))
+			}
+		}
+
+		count("+", len(service.AddedActions), "action")
+		count("-", len(service.RemovedActions), "action")
+		count("~", len(service.ChangedActions), "action")
+		count("+", len(service.AddedResourceTypes), "resource type")
+		count("-", len(service.RemovedResourceTypes), "resource type")
+		count("~", len(service.ChangedResourceTypes), "resource type")
+		count("+", len(service.AddedConditionKeys), "condition key")
+		count("-", len(service.RemovedConditionKeys), "condition key")
+		count("~", len(service.ChangedConditionKeys), "condition key")
+
+		switch {
+		case service.Added:
+			counts = append([]string{"new service"}, counts...)
+		case service.Removed:
+			counts = append([]string{"service removed"}, counts...)
+		}
+
+		fmt.Fprintf(&b, "%s: %s\n", service.ServicePrefix, strings.Join(counts, ", "))
+	}
+
+	fmt.Fprintf(&b, "\nDataset version %s (%s change from %s)\n", metadata.Version, metadata.Bump, metadata.PreviousVersion)
+	return b.String()
+}
+
+// git runs a git command, passing its output through.
+func git(args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return nil
+}
+
+// datasetChanged reports whether git sees changes to the dataset. Only then are the
+// published files worth committing; the others change on every run.
+func datasetChanged() (bool, error) {
+	err := exec.Command("git", "diff", "--quiet", "--", outputFile).Run()
+
+	if err == nil {
+		return false, nil
+	} else if _, ok := err.(*exec.ExitError); ok {
+		return true, nil
+	}
+
+	return false, fmt.Errorf("git diff: %w", err)
+}
+
+// commitArtifacts commits the published files with a message describing the changes.
+func commitArtifacts(filenames []string, changes *authref.Changes, metadata *authref.Metadata) error {
+	if err := git(append([]string{"add", "--"}, filenames...)...); err != nil {
+		return err
+	}
+
+	return git("commit", "--quiet", "--message", commitMessage(changes, metadata))
+}
@@ -0,0 +1,123 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/fluggo/aws-service-auth-reference/authref"
+	"golang.org/x/net/html"
+)
+
+// Coverage statuses for a table that parsed as empty.
+const (
+	// The page says the service has none of these.
+	coverageNone = "none"
+	// The section is there and doesn't say the service has none, so the table selector
+	// probably failed to match.
+	coverageSelectorFailed = "selector-failed"
+	// The section heading couldn't be found at all.
+	coverageSectionMissing = "section-missing"
+)
+
+// coverageEntry records why one table on a service page came back empty.
+type coverageEntry struct {
+	Name          string `json:"name"`
+	ServicePrefix string `json:"servicePrefix,omitempty"`
+	Table         string `json:"table"`
+	Status        string `json:"status"`
+}
+
+// emptySection describes how to confirm that an empty table is genuinely empty.
+type emptySection struct {
+	table      string
+	heading    cascadia.SelectorGroup
+	nonePhrase string
+}
+
+var emptySections = []emptySection{
+	{
+		table:      "actions",
+		heading:    mustParseSelector(`h2:containsOwn("Actions defined by")`),
+		nonePhrase: "",
+	},
+	{
+		table:      "resourceTypes",
+		heading:    mustParseSelector(`h2:containsOwn("Resource types defined by")`),
+		nonePhrase: "does not support specifying a resource ARN",
+	},
+	{
+		table:      "conditionKeys",
+		heading:    mustParseSelector(`h2:containsOwn("Condition keys for")`),
+		nonePhrase: "has no service-specific context keys",
+	},
+}
+
+// sectionText gathers the text between a heading and the next heading of the same kind.
+func sectionText(heading *html.Node) string {
+	var builder strings.Builder
+
+	for node := heading.NextSibling; node != nil; node = node.NextSibling {
+		if node.Type == html.ElementNode && node.Data == heading.Data {
+			break
+		}
+
+		if node.Type == html.TextNode {
+			builder.WriteString(node.Data)
+		} else {
+			builder.WriteString(gatherText(node, true))
+		}
+
+		builder.WriteString(" ")
+	}
+
+	return spaceReplacer.ReplaceAllLiteralString(builder.String(), " ")
+}
+
+// checkCoverage explains each table of authRef that came back empty, using the text of
+// the page to tell a service that has none apart from a selector that missed.
+func checkCoverage(page *html.Node, authRef *authref.ServiceAuthorizationReference) []*coverageEntry {
+	counts := map[string]int{
+		"actions":       len(authRef.Actions),
+		"resourceTypes": len(authRef.ResourceTypes),
+		"conditionKeys": len(authRef.ConditionKeys),
+	}
+	result := make([]*coverageEntry, 0)
+
+	for _, section := range emptySections {
+		if counts[section.table] != 0 {
+			continue
+		}
+
+		entry := &coverageEntry{Name: authRef.Name, ServicePrefix: authRef.ServicePrefix, Table: section.table}
+		heading := cascadia.Query(page, section.heading)
+
+		if heading == nil {
+			entry.Status = coverageSectionMissing
+		} else if section.nonePhrase != "" && strings.Contains(strings.ToLower(sectionText(heading)), strings.ToLower(section.nonePhrase)) {
+			entry.Status = coverageNone
+		} else {
+			entry.Status = coverageSelectorFailed
+		}
+
+		result = append(result, entry)
+	}
+
+	return result
+}
+
+// coverageWarnings turns the coverage entries that indicate a parsing problem into warnings.
+func coverageWarnings(entries []*coverageEntry) []*warning {
+	warnings := make([]*warning, 0)
+
+	for _, entry := range entries {
+		switch entry.Status {
+		case coverageSelectorFailed:
+			warnings = append(warnings, &warning{Code: warnEmptyTable, Message: fmt.Sprintf("%s table is empty, but the page doesn't say there are none", entry.Table)})
+		case coverageSectionMissing:
+			warnings = append(warnings, &warning{Code: warnEmptyTable, Message: fmt.Sprintf("%s section not found", entry.Table)})
+		}
+	}
+
+	return warnings
+}
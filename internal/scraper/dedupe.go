@@ -0,0 +1,138 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+const warnDuplicateAction = "duplicate-action"
+
+// mergeDuplicateActions combines actions that appear more than once in the list, keeping
+// the position of the first occurrence. Fields that disagree between occurrences are
+// resolved deterministically and reported as warnings:
+//
+//   - Description, access level, and reference link come from the first occurrence
+//     that has them.
+//   - The action is permission-only only if every occurrence is marked that way.
+//   - Annotations, resource types, and condition keys are the union of all occurrences, in order
+//     of first appearance. A resource type is required if any occurrence requires it.
+func mergeDuplicateActions(actions []*authref.Action) ([]*authref.Action, []*warning) {
+	result := make([]*authref.Action, 0, len(actions))
+	byName := make(map[string]*authref.Action, len(actions))
+	warnings := make([]*warning, 0)
+
+	for _, action := range actions {
+		existing := byName[action.Name]
+
+		if existing == nil {
+			byName[action.Name] = action
+			result = append(result, action)
+			continue
+		}
+
+		conflicts := mergeAction(existing, action)
+
+		if len(conflicts) == 0 {
+			warnings = append(warnings, &warning{Code: warnDuplicateAction, Action: action.Name, Message: fmt.Sprintf("action %s is listed more than once; merged", action.Name)})
+		}
+
+		for _, conflict := range conflicts {
+			warnings = append(warnings, &warning{Code: warnDuplicateAction, Action: action.Name, Message: fmt.Sprintf("action %s is listed more than once with different %s; merged", action.Name, conflict)})
+		}
+	}
+
+	return result, warnings
+}
+
+// mergeAction folds other into action and returns the names of the fields that disagreed.
+func mergeAction(action, other *authref.Action) []string {
+	conflicts := make([]string, 0)
+
+	mergeString := func(field string, value *string, otherValue string) {
+		if *value == "" {
+			*value = otherValue
+		} else if otherValue != "" && otherValue != *value {
+			conflicts = append(conflicts, field)
+		}
+	}
+
+	mergeString("description", &action.Description, other.Description)
+	accessLevel := string(action.AccessLevel)
+	mergeString("access level", &accessLevel, string(other.AccessLevel))
+	action.AccessLevel = authref.AccessLevel(accessLevel)
+	mergeString("reference link", &action.ReferenceHref, other.ReferenceHref)
+
+	action.Annotations = appendMissing(action.Annotations, other.Annotations)
+
+	if action.PermissionOnly != other.PermissionOnly {
+		conflicts = append(conflicts, "permission-only flags")
+		action.PermissionOnly = false
+
+		annotations := make([]string, 0, len(action.Annotations))
+
+		for _, annotation := range action.Annotations {
+			if !strings.EqualFold(annotation, permissionOnlyAnnotation) {
+				annotations = append(annotations, annotation)
+			}
+		}
+
+		action.Annotations = annotations
+	}
+
+	for _, otherResourceType := range other.ResourceTypes {
+		found := false
+
+		for i := range action.ResourceTypes {
+			resourceType := &action.ResourceTypes[i]
+
+			if resourceType.ResourceType != otherResourceType.ResourceType {
+				continue
+			}
+
+			found = true
+
+			if resourceType.Required != otherResourceType.Required {
+				conflicts = append(conflicts, "required flags for resource type "+resourceType.ResourceType)
+				resourceType.Required = resourceType.Required || otherResourceType.Required
+			}
+
+			if resourceType.RequiredGroup == 0 {
+				resourceType.RequiredGroup = otherResourceType.RequiredGroup
+			}
+
+			resourceType.ConditionKeys = appendMissing(resourceType.ConditionKeys, otherResourceType.ConditionKeys)
+			resourceType.DependentActions = appendMissing(resourceType.DependentActions, otherResourceType.DependentActions)
+			break
+		}
+
+		if !found {
+			action.ResourceTypes = append(action.ResourceTypes, otherResourceType)
+		}
+	}
+
+	action.ConditionKeys = appendMissing(action.ConditionKeys, other.ConditionKeys)
+
+	return conflicts
+}
+
+// appendMissing appends the values from other that aren't already in list.
+func appendMissing(list, other []string) []string {
+	for _, value := range other {
+		found := false
+
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			list = append(list, value)
+		}
+	}
+
+	return list
+}
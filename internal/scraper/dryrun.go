@@ -0,0 +1,67 @@
+package scraper
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// printDryRun describes what a run would have published: the new dataset version, the
+// changes from the previous snapshot, and a summary of the warnings, with the pages that
+// would have opened an anomaly issue listed in full.
+func printDryRun(w io.Writer, changes *authref.Changes, metadata *authref.Metadata, report *scrapeReport) {
+	fmt.Fprintf(w, "Dry run; nothing was written.\n\n")
+
+	if changes.Empty() {
+		fmt.Fprintf(w, "No changes to the dataset (would be version %s).\n", metadata.Version)
+	} else {
+		fmt.Fprint(w, commitMessage(changes, metadata))
+		fmt.Fprintf(w, "\nChangelog:\n%s", changelog(changes))
+	}
+
+	counts := map[string]int{}
+	services := 0
+
+	for _, service := range report.Services {
+		if len(service.Warnings) != 0 {
+			services++
+		}
+
+		for _, warn := range service.Warnings {
+			counts[warn.Code]++
+		}
+	}
+
+	codes := make([]string, 0, len(counts))
+	total := 0
+
+	for code, count := range counts {
+		codes = append(codes, code)
+		total += count
+	}
+
+	sort.Strings(codes)
+	fmt.Fprintf(w, "\nData quality: %s in %s\n", plural(total, "warning"), plural(services, "service"))
+
+	for _, code := range codes {
+		fmt.Fprintf(w, "  %-30s %d\n", code, counts[code])
+	}
+
+	anomalies := findAnomalies(report)
+
+	if len(anomalies) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nPages that may have been misread:\n")
+
+	for _, found := range anomalies {
+		fmt.Fprintf(w, "  %s (%s)\n", found.name, found.href)
+
+		for _, warn := range found.warnings {
+			fmt.Fprintf(w, "    %s: %s\n", warn.Code, warn.Message)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// The files of the authrefdata package that are regenerated with each snapshot
+const (
+	embedDataFile    = "service-auth.pb"
+	embedVersionFile = "version.go"
+)
+
+// writeEmbedPackage updates the authrefdata package in dir to embed the snapshot, so
+// the package's version always matches the dataset it carries.
+func writeEmbedPackage(dir string, authRefs []*authref.ServiceAuthorizationReference, metadata *authref.Metadata) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("could not create %s: %w", dir, err)
+	}
+
+	dataFile := filepath.Join(dir, embedDataFile)
+
+	if err := os.WriteFile(dataFile, authref.MarshalProto(authRefs), 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", dataFile, err)
+	}
+
+	source := fmt.Sprintf(`// Code generated by authref scrape. DO NOT EDIT.
+
+package authrefdata
+
+// Version is the version of the embedded dataset, as published in metadata.json.
+const Version = %q
+
+// GeneratedAt is when the embedded dataset was scraped, in RFC 3339 format.
+const GeneratedAt = %q
+`, metadata.Version, metadata.GeneratedAt)
+
+	versionFile := filepath.Join(dir, embedVersionFile)
+
+	if err := os.WriteFile(versionFile, []byte(source), 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", versionFile, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package scraper
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+const (
+	feedFile       = "atom.xml"
+	feedMaxEntries = 200
+	feedID         = "https://github.com/fluggo/aws-service-auth-reference"
+	feedSelfHref   = "https://raw.githubusercontent.com/fluggo/aws-service-auth-reference/master/atom.xml"
+)
+
+type atomFeed struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string       `xml:"title"`
+	ID      string       `xml:"id"`
+	Updated string       `xml:"updated"`
+	Author  atomAuthor   `xml:"author"`
+	Links   []atomLink   `xml:"link"`
+	Entries []*atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func readFeed(filename string) (*atomFeed, error) {
+	data, err := os.ReadFile(filename)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return &atomFeed{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read feed: %w", err)
+	}
+
+	feed := &atomFeed{}
+
+	if err := xml.Unmarshal(data, feed); err != nil {
+		return nil, fmt.Errorf("parse feed %s: %w", filename, err)
+	}
+
+	return feed, nil
+}
+
+// addChanges adds an entry to the feed for each service that gained or lost actions,
+// resource types, or condition keys. Changes to existing items aren't included. The
+// newest entries come first, and only the newest feedMaxEntries are kept.
+func (feed *atomFeed) addChanges(changes *authref.Changes, authRefs []*authref.ServiceAuthorizationReference, now time.Time) {
+	updated := now.UTC().Format(time.RFC3339)
+	hrefs := map[string]string{}
+
+	for _, authRef := range authRefs {
+		if hrefs[authRef.ServicePrefix] == "" {
+			hrefs[authRef.ServicePrefix] = authRef.AuthReferenceHref
+		}
+	}
+
+	entries := make([]*atomEntry, 0)
+
+	for _, service := range changes.Services {
+		body := feedEntryBody(service)
+
+		if body == "" {
+			continue
+		}
+
+		entry := &atomEntry{
+			Title:   fmt.Sprintf("%s (%s)", service.Name, service.ServicePrefix),
+			ID:      fmt.Sprintf("tag:github.com,%s:fluggo/aws-service-auth-reference/%s", now.UTC().Format(authref.HistoryDateFormat), service.ServicePrefix),
+			Updated: updated,
+			Content: atomContent{Type: "html", Body: body},
+		}
+
+		if href := hrefs[service.ServicePrefix]; href != "" {
+			entry.Links = []atomLink{{Rel: "alternate", Href: href}}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	feed.Title = "AWS service authorization reference changes"
+	feed.ID = feedID
+	feed.Author = atomAuthor{Name: "aws-service-auth-reference"}
+	feed.Links = []atomLink{{Rel: "self", Href: feedSelfHref}, {Rel: "alternate", Href: feedID}}
+
+	if feed.Updated == "" || len(entries) != 0 {
+		feed.Updated = updated
+	}
+
+	feed.Entries = append(entries, feed.Entries...)
+
+	if len(feed.Entries) > feedMaxEntries {
+		feed.Entries = feed.Entries[:feedMaxEntries]
+	}
+}
+
+// feedEntryBody describes the additions and removals for one service as HTML,
+// or returns "" if there are none.
+func feedEntryBody(service *authref.ServiceChanges) string {
+	var builder strings.Builder
+
+	list := func(title string, names []string) {
+		if len(names) == 0 {
+			return
+		}
+
+		fmt.Fprintf(&builder, "<p>%s:</p><ul>", html.EscapeString(title))
+
+		for _, name := range names {
+			fmt.Fprintf(&builder, "<li><code>%s</code></li>", html.EscapeString(name))
+		}
+
+		builder.WriteString("</ul>")
+	}
+
+	if service.Added {
+		builder.WriteString("<p>New service.</p>")
+	} else if service.Removed {
+		builder.WriteString("<p>Service removed.</p>")
+	}
+
+	addedActions := make([]string, len(service.AddedActions))
+	removedActions := make([]string, len(service.RemovedActions))
+	addedResourceTypes := make([]string, len(service.AddedResourceTypes))
+	addedConditionKeys := make([]string, len(service.AddedConditionKeys))
+
+	for i, action := range service.AddedActions {
+		addedActions[i] = fmt.Sprintf("%s:%s (%s)", service.ServicePrefix, action.Name, action.AccessLevel)
+	}
+
+	for i, action := range service.RemovedActions {
+		removedActions[i] = service.ServicePrefix + ":" + action.Name
+	}
+
+	for i, resourceType := range service.AddedResourceTypes {
+		addedResourceTypes[i] = resourceType.Name
+	}
+
+	for i, conditionKey := range service.AddedConditionKeys {
+		addedConditionKeys[i] = conditionKey.Name
+	}
+
+	list("New actions", addedActions)
+	list("Removed actions", removedActions)
+	list("New resource types", addedResourceTypes)
+	list("New condition keys", addedConditionKeys)
+
+	return builder.String()
+}
+
+func writeFeed(filename string, feed *atomFeed) error {
+	data, err := xml.MarshalIndent(feed, "", "  ")
+
+	if err != nil {
+		return fmt.Errorf("could not encode feed: %w", err)
+	}
+
+	data = append([]byte(xml.Header), data...)
+	data = append(data, '\n')
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("could not write feed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const githubApiUrl = "https://api.github.com"
+
+// GitHub refuses issue and pull request bodies longer than this
+const maxGithubBody = 65536
+
+// truncateBody cuts a body down to the size GitHub accepts, ending it with a note.
+func truncateBody(body, note string) string {
+	if len(body) <= maxGithubBody {
+		return body
+	}
+
+	truncated := "\n\n(Truncated; " + note + ".)\n"
+	return body[:maxGithubBody-len(truncated)] + truncated
+}
+
+// githubClient makes GitHub API requests about one repository.
+type githubClient struct {
+	// The repository, as owner/name
+	repo  string
+	token string
+}
+
+// request makes a GitHub API request, decoding the response into result if it isn't nil.
+func (c *githubClient) request(method, path string, body, result interface{}) error {
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, githubApiUrl+path, reader)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: status code %v", method, path, resp.StatusCode)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
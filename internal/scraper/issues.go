@@ -0,0 +1,143 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+const (
+	// The label that marks the issue the scraper keeps up to date, so that it finds the
+	// same issue again on the next run
+	anomalyLabel = "scraper-anomaly"
+
+	anomalyIssueTitle = "Scraper found parse anomalies"
+)
+
+// anomalyCodes are the warnings that suggest the scraper misread a page, usually because
+// the page layout changed, as opposed to problems in AWS's own data that the scraper read
+// correctly.
+var anomalyCodes = map[string]bool{
+	warnEmptyTable:                      true,
+	authref.FindingUnknownAccessLevel:   true,
+	authref.FindingMissingServicePrefix: true,
+}
+
+// issueFiler opens or updates a GitHub issue describing parse anomalies.
+type issueFiler struct {
+	github *githubClient
+}
+
+// anomaly is a page the scraper had trouble with, and what it found there.
+type anomaly struct {
+	name     string
+	href     string
+	failure  string
+	warnings []*warning
+}
+
+// findAnomalies picks out the pages in the report that failed or have anomaly warnings.
+func findAnomalies(report *scrapeReport) []*anomaly {
+	result := make([]*anomaly, 0)
+
+	for _, service := range report.Services {
+		found := &anomaly{name: service.Name, href: service.AuthReferenceHref}
+
+		if service.Status == statusFailed {
+			found.failure = service.Reason
+		}
+
+		for _, w := range service.Warnings {
+			if anomalyCodes[w.Code] {
+				found.warnings = append(found.warnings, w)
+			}
+		}
+
+		if found.failure != "" || len(found.warnings) != 0 {
+			result = append(result, found)
+		}
+	}
+
+	return result
+}
+
+// issueBody describes the anomalies in Markdown. A warning about an action comes with the
+// row as the scraper parsed it, to show what went wrong without reproducing the run.
+func issueBody(anomalies []*anomaly, authRefs []*authref.ServiceAuthorizationReference, report *scrapeReport) string {
+	byHref := make(map[string]*authref.ServiceAuthorizationReference, len(authRefs))
+
+	for _, authRef := range authRefs {
+		byHref[authRef.AuthReferenceHref] = authRef
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "The scraper run started at %s found %d pages it may have misread. This issue is updated by each run that finds problems; close it once they're fixed.\n", report.StartedAt.Format("2006-01-02 15:04 UTC"), len(anomalies))
+
+	for _, found := range anomalies {
+		fmt.Fprintf(&b, "\n### [%s](%s)\n\n", found.name, found.href)
+
+		if found.failure != "" {
+			fmt.Fprintf(&b, "* Failed: %s\n", found.failure)
+		}
+
+		for _, w := range found.warnings {
+			fmt.Fprintf(&b, "* `%s`: %s\n", w.Code, w.Message)
+
+			if w.Action == "" || byHref[found.href] == nil {
+				continue
+			}
+
+			for _, action := range byHref[found.href].Actions {
+				if action.Name == w.Action {
+					row, _ := json.MarshalIndent(action, "", "  ")
+					fmt.Fprintf(&b, "\n  <details><summary>Parsed row</summary>\n\n  ```json\n  %s\n  ```\n  </details>\n\n", strings.ReplaceAll(string(row), "\n", "\n  "))
+					break
+				}
+			}
+		}
+	}
+
+	return truncateBody(b.String(), "see scrape-report.json for the rest")
+}
+
+// file opens an issue about the anomalies in the report, or updates the one a previous run
+// opened if it's still open. It does nothing if there are no anomalies.
+func (f *issueFiler) file(report *scrapeReport, authRefs []*authref.ServiceAuthorizationReference) error {
+	anomalies := findAnomalies(report)
+
+	if len(anomalies) == 0 {
+		return nil
+	}
+
+	issue := map[string]interface{}{
+		"title": fmt.Sprintf("%s (%d pages)", anomalyIssueTitle, len(anomalies)),
+		"body":  issueBody(anomalies, authRefs, report),
+	}
+
+	var open []struct {
+		Number int `json:"number"`
+	}
+
+	if err := f.github.request("GET", fmt.Sprintf("/repos/%s/issues?state=open&labels=%s", f.github.repo, url.QueryEscape(anomalyLabel)), nil, &open); err != nil {
+		return fmt.Errorf("github issue: %w", err)
+	}
+
+	if len(open) != 0 {
+		if err := f.github.request("PATCH", fmt.Sprintf("/repos/%s/issues/%d", f.github.repo, open[0].Number), issue, nil); err != nil {
+			return fmt.Errorf("github issue: %w", err)
+		}
+
+		return nil
+	}
+
+	issue["labels"] = []string{anomalyLabel}
+
+	if err := f.github.request("POST", fmt.Sprintf("/repos/%s/issues", f.github.repo), issue, nil); err != nil {
+		return fmt.Errorf("github issue: %w", err)
+	}
+
+	return nil
+}
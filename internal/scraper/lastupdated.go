@@ -0,0 +1,66 @@
+package scraper
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Layout of the dates recorded in lastUpdated
+const lastUpdatedLayout = "2006-01-02"
+
+// Meta tags the documentation site has used for a page's revision date
+var lastUpdatedMetaSelector = mustParseSelector(`meta[name="date"], meta[name="last-modified"], meta[name="document-revision-date"], meta[property="article:modified_time"]`)
+
+// Elements holding a "Last updated" line, such as "Last updated: March 4, 2025"
+var lastUpdatedTextSelector = mustParseSelector(`:matchesOwn("(?i)last\\s+updated")`)
+
+var lastUpdatedTextMatcher = regexp.MustCompile(`(?i)last\s+updated:?\s+([A-Za-z]+\.?\s+\d{1,2},\s+\d{4}|\d{4}-\d{2}-\d{2})`)
+
+// parseDate reads a date in any of the forms the documentation uses and returns it in
+// lastUpdatedLayout, or "" if it can't be read.
+func parseDate(value string) string {
+	value = strings.TrimSpace(value)
+
+	for _, layout := range []string{time.RFC3339, lastUpdatedLayout, "January 2, 2006", "Jan 2, 2006", "Jan. 2, 2006", http.TimeFormat} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format(lastUpdatedLayout)
+		}
+	}
+
+	// Meta tags sometimes carry a bare timestamp without a zone
+	if len(value) > len(lastUpdatedLayout) {
+		if t, err := time.Parse(lastUpdatedLayout, value[:len(lastUpdatedLayout)]); err == nil {
+			return t.Format(lastUpdatedLayout)
+		}
+	}
+
+	return ""
+}
+
+// parseLastUpdated finds the date AWS last changed a service's page. It prefers a date in
+// the page itself, from a meta tag or a "Last updated" line, and otherwise falls back to
+// the Last-Modified header of the response. It returns "" if neither is there.
+func parseLastUpdated(page *html.Node, header http.Header, raw *rawRecorder) string {
+	for _, node := range cascadia.QueryAll(page, lastUpdatedMetaSelector) {
+		if date := parseDate(getAttrValue(node, "content")); date != "" {
+			raw.cell("lastUpdated", node)
+			return date
+		}
+	}
+
+	for _, node := range cascadia.QueryAll(page, lastUpdatedTextSelector) {
+		if match := lastUpdatedTextMatcher.FindStringSubmatch(gatherText(node, true)); match != nil {
+			if date := parseDate(match[1]); date != "" {
+				raw.cell("lastUpdated", node)
+				return date
+			}
+		}
+	}
+
+	return parseDate(header.Get("Last-Modified"))
+}
@@ -0,0 +1,125 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+const warnLocalizedPage = "localized-page"
+
+// Translated pages have the same layout as the English ones, but the headings the main
+// selectors look for are translated too. The ids of the sections aren't.
+var (
+	localizedActionTableSelector       = mustParseSelector(`h2[id$="-actions-as-permissions"] ~ div[class*="table-container"] table`)
+	localizedConditionKeyTableSelector = mustParseSelector(`h2[id$="-policy-keys"] ~ div[class*="table-container"] table`)
+)
+
+// parseLocales reads a comma-separated list of documentation locales, such as
+// "ja_jp,de_de".
+func parseLocales(list string) ([]string, error) {
+	result := make([]string, 0)
+
+	for _, locale := range strings.Split(list, ",") {
+		if locale = strings.ToLower(strings.TrimSpace(locale)); locale == "" {
+			continue
+		}
+
+		if !authref.IsLocale(locale) {
+			return nil, fmt.Errorf("%#v isn't a documentation locale like \"ja_jp\"", locale)
+		}
+
+		result = append(result, locale)
+	}
+
+	return result, nil
+}
+
+// localizedUrl returns the address of a reference page in another locale, which the docs
+// put before the path, as in https://docs.aws.amazon.com/ja_jp/service-authorization/...
+func localizedUrl(pageUrl *url.URL, locale string) *url.URL {
+	result := *pageUrl
+	result.Path = "/" + locale + pageUrl.Path
+	return &result
+}
+
+// addLocalizedDescriptions fetches a topic's page in another locale and copies the
+// descriptions of its actions and condition keys into authRef, matching them by name.
+// Problems come back as warnings rather than errors, since the English data is complete
+// without them; translations often lag behind the English pages.
+func addLocalizedDescriptions(topic topic, authRef *authref.ServiceAuthorizationReference, locale string) []*warning {
+	warnf := func(format string, args ...interface{}) []*warning {
+		return []*warning{{Code: warnLocalizedPage, Message: locale + ": " + fmt.Sprintf(format, args...)}}
+	}
+
+	page, _, err := fetchHtml(localizedUrl(topic.url, locale).String())
+
+	if err != nil {
+		return warnf("%v", err)
+	}
+
+	descriptions := map[string]string{}
+
+	if tableNode := cascadia.Query(page, localizedActionTableSelector); tableNode != nil {
+		actions, err := parseActionRows(tableNode, nil)
+
+		if err != nil {
+			return warnf("actions table: %v", err)
+		}
+
+		for _, action := range actions {
+			if descriptions[action.Name] == "" {
+				descriptions[action.Name] = action.Description
+			}
+		}
+	}
+
+	keyDescriptions := map[string]string{}
+
+	if tableNode := cascadia.Query(page, localizedConditionKeyTableSelector); tableNode != nil {
+		conditionKeys, err := parseConditionKeyRows(tableNode, nil)
+
+		if err != nil {
+			return warnf("condition keys table: %v", err)
+		}
+
+		for _, conditionKey := range conditionKeys {
+			keyDescriptions[conditionKey.Name] = conditionKey.Description
+		}
+	}
+
+	missing := 0
+
+	for _, action := range authRef.Actions {
+		if description := descriptions[action.Name]; description != "" {
+			if action.LocalizedDescriptions == nil {
+				action.LocalizedDescriptions = map[string]string{}
+			}
+
+			action.LocalizedDescriptions[locale] = description
+		} else if action.Description != "" {
+			missing++
+		}
+	}
+
+	for _, conditionKey := range authRef.ConditionKeys {
+		if description := keyDescriptions[conditionKey.Name]; description != "" {
+			if conditionKey.LocalizedDescriptions == nil {
+				conditionKey.LocalizedDescriptions = map[string]string{}
+			}
+
+			conditionKey.LocalizedDescriptions[locale] = description
+		} else if conditionKey.Description != "" {
+			missing++
+		}
+	}
+
+	if missing != 0 {
+		return warnf("%d action(s) and condition key(s) have no translated description", missing)
+	}
+
+	return nil
+}
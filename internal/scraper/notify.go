@@ -0,0 +1,196 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// Webhook payload formats.
+const (
+	webhookSlack = "slack"
+	webhookTeams = "teams"
+	webhookJSON  = "json"
+)
+
+// webhookEnv names the environment variable that supplies the webhook URL when --webhook
+// isn't given, so that the URL can be kept in a secret.
+const webhookEnv = "AUTHREF_WEBHOOK_URL"
+
+// notifier posts a summary of dataset changes to a webhook.
+type notifier struct {
+	url    string
+	format string
+
+	// If not empty, only these service prefixes are included
+	services map[string]bool
+
+	// If not empty, only added or removed actions with these access levels are included
+	accessLevels map[authref.AccessLevel]bool
+}
+
+func newNotifier(url, format, services, accessLevels string) (*notifier, error) {
+	switch format {
+	case webhookSlack, webhookTeams, webhookJSON:
+	default:
+		return nil, fmt.Errorf("unknown webhook format %#v (expected %s, %s, or %s)", format, webhookSlack, webhookTeams, webhookJSON)
+	}
+
+	n := &notifier{url: url, format: format, services: map[string]bool{}, accessLevels: map[authref.AccessLevel]bool{}}
+
+	for _, service := range strings.Split(services, ",") {
+		if service = strings.TrimSpace(service); service != "" {
+			n.services[service] = true
+		}
+	}
+
+	for _, level := range strings.Split(accessLevels, ",") {
+		if level = strings.TrimSpace(level); level != "" {
+			accessLevel := authref.AccessLevel(level)
+
+			if !accessLevel.Known() {
+				return nil, fmt.Errorf("unknown access level %#v", level)
+			}
+
+			n.accessLevels[accessLevel] = true
+		}
+	}
+
+	return n, nil
+}
+
+// notifyChange is the part of a service's changes that the notifier reports.
+type notifyChange struct {
+	ServicePrefix  string            `json:"servicePrefix"`
+	Name           string            `json:"name"`
+	Added          bool              `json:"added"`
+	Removed        bool              `json:"removed"`
+	AddedActions   []*authref.Action `json:"addedActions"`
+	RemovedActions []*authref.Action `json:"removedActions"`
+}
+
+// filter picks out the added and removed actions the notifier is interested in.
+func (n *notifier) filter(changes *authref.Changes) []*notifyChange {
+	result := make([]*notifyChange, 0)
+
+	filterActions := func(actions []*authref.Action) []*authref.Action {
+		filtered := make([]*authref.Action, 0, len(actions))
+
+		for _, action := range actions {
+			if len(n.accessLevels) == 0 || n.accessLevels[action.AccessLevel] {
+				filtered = append(filtered, action)
+			}
+		}
+
+		return filtered
+	}
+
+	for _, service := range changes.Services {
+		if len(n.services) != 0 && !n.services[service.ServicePrefix] {
+			continue
+		}
+
+		change := &notifyChange{
+			ServicePrefix:  service.ServicePrefix,
+			Name:           service.Name,
+			Added:          service.Added,
+			Removed:        service.Removed,
+			AddedActions:   filterActions(service.AddedActions),
+			RemovedActions: filterActions(service.RemovedActions),
+		}
+
+		if len(change.AddedActions) != 0 || len(change.RemovedActions) != 0 {
+			result = append(result, change)
+		}
+	}
+
+	return result
+}
+
+// summarize returns a one-line title for the changes and a longer description, formatted
+// so that both Slack and Teams can show it.
+func summarize(changes []*notifyChange) (string, string) {
+	added, removed := 0, 0
+
+	for _, change := range changes {
+		added += len(change.AddedActions)
+		removed += len(change.RemovedActions)
+	}
+
+	title := fmt.Sprintf("AWS service authorization reference: %d new and %d removed actions across %d services", added, removed, len(changes))
+
+	var builder strings.Builder
+	builder.WriteString(title)
+
+	for _, change := range changes {
+		builder.WriteString("\n\n")
+		fmt.Fprintf(&builder, "*%s* (`%s`)", change.Name, change.ServicePrefix)
+
+		if change.Added {
+			builder.WriteString(" (new service)")
+		} else if change.Removed {
+			builder.WriteString(" (service removed)")
+		}
+
+		for _, action := range change.AddedActions {
+			fmt.Fprintf(&builder, "\n+ `%s:%s` (%s)", change.ServicePrefix, action.Name, action.AccessLevel)
+		}
+
+		for _, action := range change.RemovedActions {
+			fmt.Fprintf(&builder, "\n- `%s:%s` (%s)", change.ServicePrefix, action.Name, action.AccessLevel)
+		}
+	}
+
+	return title, builder.String()
+}
+
+// notify posts the changes to the webhook. It doesn't post anything if none of the
+// changes pass the notifier's filters.
+func (n *notifier) notify(changes *authref.Changes) error {
+	filtered := n.filter(changes)
+
+	if len(filtered) == 0 {
+		return nil
+	}
+
+	title, text := summarize(filtered)
+	var payload interface{}
+
+	switch n.format {
+	case webhookSlack:
+		payload = map[string]interface{}{"text": text}
+	case webhookTeams:
+		payload = map[string]interface{}{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  title,
+			"text":     strings.ReplaceAll(text, "\n", "\n\n"),
+		}
+	default:
+		payload = map[string]interface{}{"summary": title, "services": filtered}
+	}
+
+	body, err := json.Marshal(payload)
+
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+
+	resp, err := http.Post(n.url, "application/json", bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: status code %v", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// Saved reference pages for the largest services, gzipped to keep the repository small.
+// See testdata/README.md for how to refresh them.
+var benchmarkPages = []struct {
+	name string
+	file string
+}{
+	{name: "EC2", file: "list_amazonec2.html.gz"},
+	{name: "IAM", file: "list_awsidentityandaccessmanagementiam.html.gz"},
+	{name: "S3", file: "list_amazons3.html.gz"},
+}
+
+func readBenchmarkPage(b *testing.B, file string) []byte {
+	b.Helper()
+	f, err := os.Open(filepath.Join("testdata", file))
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+
+	if err != nil {
+		b.Fatalf("%s: %v", file, err)
+	}
+
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		b.Fatalf("%s: %v", file, err)
+	}
+
+	return data
+}
+
+// BenchmarkParseHTML measures turning a page into a DOM, which the scraper can't speed up.
+func BenchmarkParseHTML(b *testing.B) {
+	for _, page := range benchmarkPages {
+		data := readBenchmarkPage(b, page.file)
+
+		b.Run(page.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := html.Parse(bytes.NewReader(data)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParsePage measures the scraper's own work on a parsed page: the tables, the
+// page details, and the coverage checks.
+func BenchmarkParsePage(b *testing.B) {
+	for _, page := range benchmarkPages {
+		doc, err := html.Parse(bytes.NewReader(readBenchmarkPage(b, page.file)))
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		pageUrl, err := url.Parse("https://docs.aws.amazon.com/service-authorization/latest/reference/" + page.file[:len(page.file)-len(".gz")])
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(page.name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				result, err := parsePage(topic{name: page.name, url: pageUrl}, doc, nil, nil)
+
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				if len(result.authRef.Actions) == 0 {
+					b.Fatal("no actions parsed")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+//go:build go1.18
+// +build go1.18
+
+package scraper
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// fuzzPage is a small reference page with each of the tables, in the same layout as the
+// real ones. The seeds below break it in the ways AWS's pages have broken before.
+const fuzzPage = `<html><body><div id="main-col-body">
+<p>Example Service (service prefix: <code class="code">example</code>) provides the following.</p>
+<h2>Actions defined by Example Service</h2>
+<p>You can specify the following actions.</p>
+<div class="table-container"><table>
+<thead><tr><th>Actions</th><th>Description</th><th>Access level</th><th>Resource types (*required)</th><th>Condition keys</th><th>Dependent actions</th></tr></thead>
+<tr><td rowspan="3"><a id="example-CreateWidget"></a><a href="https://example.com/CreateWidget">CreateWidget</a> [permission only]</td>
+<td rowspan="2">Grants permission to create a widget</td><td rowspan="2">Write</td>
+<td><p><a href="#example-widget">widget*</a></p></td><td><p>example:Color</p></td><td><p>example:TagResource</p></td></tr>
+<tr><td></td><td><p>aws:RequestTag/${TagKey}</p><p>aws:TagKeys</p></td><td></td></tr>
+<tr><td>SCENARIO: something else</td><td>Write</td><td><p>gadget</p></td><td></td><td></td></tr>
+<tr><td>ListWidgets</td><td>Grants permission to list widgets</td><td>List</td><td></td><td></td><td></td></tr>
+</table></div>
+<h2>Resource types defined by Example Service</h2>
+<p>The following resource types are defined.</p>
+<div class="table-container"><table>
+<thead><tr><th>Resource types</th><th>ARN</th><th>Condition keys</th></tr></thead>
+<tr><td><a href="https://example.com/widget">widget</a></td><td><code>arn:${Partition}:example:${Region}:${Account}:widget/${WidgetId}</code></td><td><p>aws:ResourceTag/${TagKey}</p></td></tr>
+</table></div>
+<h2>Condition keys for Example Service</h2>
+<p>Example Service defines the following condition keys.</p>
+<p>To view the global condition keys, see elsewhere.</p>
+<div class="table-container"><table>
+<thead><tr><th>Condition keys</th><th>Description</th><th>Type</th></tr></thead>
+<tr><td><a href="https://example.com/keys">example:Color</a></td><td>Filters access by color</td><td>String</td></tr>
+</table></div>
+</div></body></html>`
+
+// FuzzParsePage checks that no markup, however malformed, makes the parsers panic or hang;
+// they should return an error or an incomplete result instead. Run it with:
+//
+//	go test -run '^$' -fuzz FuzzParsePage ./internal/scraper
+func FuzzParsePage(f *testing.F) {
+	f.Add(fuzzPage)
+	f.Add(strings.Replace(fuzzPage, `rowspan="3"`, `rowspan="0"`, 1))
+	f.Add(strings.Replace(fuzzPage, `rowspan="3"`, `rowspan="-2"`, 1))
+	f.Add(strings.Replace(fuzzPage, `rowspan="2"`, `rowspan="9"`, 1))
+	f.Add(strings.Replace(fuzzPage, `<td></td><td><p>aws:RequestTag`, `<td><p>aws:RequestTag`, 1))
+	f.Add(strings.Replace(fuzzPage, `<td></td><td><p>aws:RequestTag`, `<td colspan="2"><p>aws:RequestTag`, 1))
+	f.Add(strings.Replace(fuzzPage, `<td><p><a href="#example-widget">widget*</a></p></td>`, `<td rowspan="2"><p><a href="#example-widget">widget*</a></p></td>`, 1))
+	f.Add(strings.Replace(fuzzPage, `<td rowspan="2">Grants permission to create a widget</td><td rowspan="2">Write</td>`, `<td rowspan="2" colspan="2">Grants permission to create a widget</td>`, 1))
+	f.Add(strings.Replace(fuzzPage, `<td><code>arn:`, `<th><code>arn:`, 1))
+	f.Add(strings.Replace(fuzzPage, `<td>String</td>`, ``, 1))
+	f.Add(strings.Replace(fuzzPage, `<code class="code">example</code>`, `<code class="code"></code>`, 1))
+	f.Add(strings.Replace(fuzzPage, `<thead>`, `<table><tr><td>`, 1))
+	f.Add(`<html><body><div id="main-col-body"><h2>Actions defined by nothing</h2></div></body></html>`)
+
+	pageUrl, err := url.Parse("https://docs.aws.amazon.com/service-authorization/latest/reference/list_example.html")
+
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		page, err := html.Parse(strings.NewReader(data))
+
+		if err != nil {
+			return
+		}
+
+		exampleTopic := topic{name: "Example Service", url: pageUrl}
+		raw := newRawRecorder(exampleTopic)
+
+		// Each table separately, since parsePage stops at the first one that fails
+		parseActionsTable(page, raw)
+		parseResourceTypesTable(page, raw)
+		parseConditionKeyTable(page, raw)
+		parsePage(exampleTopic, page, nil, nil)
+	})
+}
@@ -0,0 +1,78 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// servicePartitions maps service prefixes to the partitions (such as "aws-cn") the service
+// isn't offered in. The reference only covers the aws partition, so the mapping is
+// maintained by hand in service-partitions.json.
+type servicePartitions map[string][]string
+
+// readServicePartitions loads the partition mapping. A missing file is treated as an empty
+// mapping.
+func readServicePartitions(filename string) (servicePartitions, error) {
+	data, err := os.ReadFile(filename)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return servicePartitions{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read service partitions: %w", err)
+	}
+
+	var result servicePartitions
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse service partitions %s: %w", filename, err)
+	}
+
+	for prefix, partitions := range result {
+		for _, partition := range partitions {
+			known := false
+
+			for _, arnPartition := range authref.ArnPartitions {
+				known = known || partition == arnPartition
+			}
+
+			if !known || partition == "aws" {
+				return nil, fmt.Errorf("parse service partitions %s: %s: %#v isn't aws-cn or aws-us-gov", filename, prefix, partition)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// apply sets the unavailable partitions of the service from the mapping, then fills in the
+// partition variants of its ARN patterns.
+func (partitions servicePartitions) apply(authRef *authref.ServiceAuthorizationReference) {
+	authRef.UnavailablePartitions = partitions[authRef.ServicePrefix]
+	authRef.SetPartitionArnPatterns()
+}
+
+// unused returns the prefixes in the mapping that don't match any service, which usually
+// means the mapping is out of date.
+func (partitions servicePartitions) unused(authRefs []*authref.ServiceAuthorizationReference) []string {
+	used := map[string]bool{}
+
+	for _, authRef := range authRefs {
+		used[authRef.ServicePrefix] = true
+	}
+
+	unused := make([]string, 0)
+
+	for prefix := range partitions {
+		if !used[prefix] {
+			unused = append(unused, prefix)
+		}
+	}
+
+	sort.Strings(unused)
+	return unused
+}
@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// servicePrincipals maps service prefixes to the service principals (such as
+// "lambda.amazonaws.com") that the service uses in trust policies. The reference pages
+// don't list these, so the mapping is maintained by hand in service-principals.json.
+type servicePrincipals map[string][]string
+
+// readServicePrincipals loads the principal mapping. A missing file is treated as an
+// empty mapping.
+func readServicePrincipals(filename string) (servicePrincipals, error) {
+	data, err := os.ReadFile(filename)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return servicePrincipals{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read service principals: %w", err)
+	}
+
+	var result servicePrincipals
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse service principals %s: %w", filename, err)
+	}
+
+	return result, nil
+}
+
+// apply sets the service principals of the service from the mapping, replacing whatever
+// it had before.
+func (principals servicePrincipals) apply(authRef *authref.ServiceAuthorizationReference) {
+	authRef.ServicePrincipals = principals[authRef.ServicePrefix]
+}
+
+// unused returns the prefixes in the mapping that don't match any service, which usually
+// means the mapping is out of date.
+func (principals servicePrincipals) unused(authRefs []*authref.ServiceAuthorizationReference) []string {
+	used := map[string]bool{}
+
+	for _, authRef := range authRefs {
+		used[authRef.ServicePrefix] = true
+	}
+
+	unused := make([]string, 0)
+
+	for prefix := range principals {
+		if !used[prefix] {
+			unused = append(unused, prefix)
+		}
+	}
+
+	sort.Strings(unused)
+	return unused
+}
@@ -0,0 +1,49 @@
+package scraper
+
+import (
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/fluggo/aws-service-auth-reference/authref"
+	"golang.org/x/net/html"
+)
+
+// tableAnchors returns the ids of the headings of a page's tables, keyed by the table names
+// in emptySections. Tables whose heading is missing or has no id are left out.
+func tableAnchors(page *html.Node) map[string]string {
+	result := map[string]string{}
+
+	for _, section := range emptySections {
+		if heading := cascadia.Query(page, section.heading); heading != nil {
+			if id := getAttrValue(heading, "id"); id != "" {
+				result[section.table] = id
+			}
+		}
+	}
+
+	return result
+}
+
+// addProvenance records on each action, resource type, and condition key of a result the
+// page and table it was parsed from, and when.
+func addProvenance(topic topic, result *scrapeResult, scrapedAt time.Time) {
+	newProvenance := func(table string) *authref.Provenance {
+		return &authref.Provenance{
+			SourceHref: topic.url.String(),
+			Anchor:     result.anchors[table],
+			ScrapedAt:  scrapedAt.UTC().Format(time.RFC3339),
+		}
+	}
+
+	for _, action := range result.authRef.Actions {
+		action.Provenance = newProvenance("actions")
+	}
+
+	for _, resourceType := range result.authRef.ResourceTypes {
+		resourceType.Provenance = newProvenance("resourceTypes")
+	}
+
+	for _, conditionKey := range result.authRef.ConditionKeys {
+		conditionKey.Provenance = newProvenance("conditionKeys")
+	}
+}
@@ -0,0 +1,116 @@
+package scraper
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// changelog lists the names of everything added, removed, or changed in each service, in
+// Markdown, for reviewers who want more than the counts in the commit message.
+func changelog(changes *authref.Changes) string {
+	var b strings.Builder
+
+	for _, service := range changes.Services {
+		fmt.Fprintf(&b, "\n### %s (`%s`)\n\n", service.Name, service.ServicePrefix)
+
+		switch {
+		case service.Added:
+			b.WriteString("New service.\n\n")
+		case service.Removed:
+			b.WriteString("Service removed.\n\n")
+		}
+
+		for _, action := range service.AddedActions {
+			fmt.Fprintf(&b, "* Added `%s:%s` (%s)\n", service.ServicePrefix, action.Name, action.AccessLevel)
+		}
+
+		for _, action := range service.RemovedActions {
+			fmt.Fprintf(&b, "* Removed `%s:%s` (%s)\n", service.ServicePrefix, action.Name, action.AccessLevel)
+		}
+
+		for _, change := range service.ChangedActions {
+			fmt.Fprintf(&b, "* Changed `%s:%s`: %s\n", service.ServicePrefix, change.New.Name, strings.Join(change.Fields, ", "))
+		}
+
+		for _, resourceType := range service.AddedResourceTypes {
+			fmt.Fprintf(&b, "* Added resource type `%s`\n", resourceType.Name)
+		}
+
+		for _, resourceType := range service.RemovedResourceTypes {
+			fmt.Fprintf(&b, "* Removed resource type `%s`\n", resourceType.Name)
+		}
+
+		for _, change := range service.ChangedResourceTypes {
+			fmt.Fprintf(&b, "* Changed resource type `%s`: %s\n", change.New.Name, strings.Join(change.Fields, ", "))
+		}
+
+		for _, conditionKey := range service.AddedConditionKeys {
+			fmt.Fprintf(&b, "* Added condition key `%s`\n", conditionKey.Name)
+		}
+
+		for _, conditionKey := range service.RemovedConditionKeys {
+			fmt.Fprintf(&b, "* Removed condition key `%s`\n", conditionKey.Name)
+		}
+
+		for _, change := range service.ChangedConditionKeys {
+			fmt.Fprintf(&b, "* Changed condition key `%s`: %s\n", change.New.Name, strings.Join(change.Fields, ", "))
+		}
+	}
+
+	return b.String()
+}
+
+// openPullRequest commits the published files to a new branch named for the dataset
+// version, pushes it, and opens a pull request against the branch that was checked out,
+// with the commit message and the changelog as its description.
+func openPullRequest(github *githubClient, filenames []string, changes *authref.Changes, metadata *authref.Metadata) error {
+	output, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output()
+
+	if err != nil {
+		return fmt.Errorf("git rev-parse: %w", err)
+	}
+
+	base := strings.TrimSpace(string(output))
+	branch := fmt.Sprintf("authref-update/%s", metadata.Version)
+
+	if err := git("checkout", "--quiet", "-b", branch); err != nil {
+		return err
+	}
+
+	if err := commitArtifacts(filenames, changes, metadata); err != nil {
+		return err
+	}
+
+	if err := git("push", "--quiet", "origin", branch); err != nil {
+		return err
+	}
+
+	message := commitMessage(changes, metadata)
+	title, description := message, ""
+
+	if i := strings.IndexByte(message, '\n'); i != -1 {
+		title, description = message[:i], strings.TrimSpace(message[i:])
+	}
+
+	body := description + "\n\n## Changelog\n" + changelog(changes)
+	pull := map[string]interface{}{
+		"title": title,
+		"head":  branch,
+		"base":  base,
+		"body":  truncateBody(body, "see the pull request's changes for the rest"),
+	}
+
+	var created struct {
+		HtmlUrl string `json:"html_url"`
+	}
+
+	if err := github.request("POST", fmt.Sprintf("/repos/%s/pulls", github.repo), pull, &created); err != nil {
+		return fmt.Errorf("github pull request: %w", err)
+	}
+
+	fmt.Printf("opened %s\n", created.HtmlUrl)
+	return nil
+}
@@ -0,0 +1,80 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"golang.org/x/net/html"
+)
+
+const rawFile = "service-auth.raw.json"
+
+// rawService holds the original markup behind one service's parsed fields, for --debug-raw.
+type rawService struct {
+	Name              string       `json:"name"`
+	AuthReferenceHref string       `json:"authReferenceHref"`
+	Records           []*rawRecord `json:"records"`
+}
+
+// rawRecord is the markup for one parsed entry, such as an action or a condition key.
+// Cells maps a field name to the HTML of each cell that contributed to it; actions
+// that span several rows have one cell per row.
+type rawRecord struct {
+	Table string              `json:"table"`
+	Key   string              `json:"key"`
+	Cells map[string][]string `json:"cells"`
+}
+
+// rawRecorder collects markup while a page is parsed. A nil recorder records nothing,
+// so the parsers can call it unconditionally.
+type rawRecorder struct {
+	service *rawService
+	current *rawRecord
+}
+
+func newRawRecorder(t topic) *rawRecorder {
+	return &rawRecorder{service: &rawService{Name: t.name, AuthReferenceHref: t.url.String(), Records: make([]*rawRecord, 0)}}
+}
+
+// begin starts a new record; subsequent calls to cell add to it.
+func (raw *rawRecorder) begin(table, key string) {
+	if raw == nil {
+		return
+	}
+
+	raw.current = &rawRecord{Table: table, Key: key, Cells: map[string][]string{}}
+	raw.service.Records = append(raw.service.Records, raw.current)
+}
+
+// cell records the markup of node as contributing to field of the current record.
+func (raw *rawRecorder) cell(field string, node *html.Node) {
+	if raw == nil || raw.current == nil || node == nil {
+		return
+	}
+
+	raw.current.Cells[field] = append(raw.current.Cells[field], renderToString(node))
+}
+
+func writeRawFile(services []*rawService) error {
+	file, err := os.Create(rawFile)
+
+	if err != nil {
+		return fmt.Errorf("could not open raw HTML file: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(services); err != nil {
+		file.Close()
+		return fmt.Errorf("could not write raw HTML file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not close raw HTML file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// defaultEndpointsSource is the endpoints data the AWS SDK for Python ships, which lists the
+// regions each service's endpoints are in for every partition.
+const defaultEndpointsSource = "https://raw.githubusercontent.com/boto/botocore/develop/botocore/data/endpoints.json"
+
+// endpointsFile is the part of the SDK's endpoints.json the scraper reads.
+type endpointsFile struct {
+	Partitions []struct {
+		Partition string `json:"partition"`
+
+		// Real regions, as opposed to the FIPS and global pseudo-regions in Endpoints
+		Regions map[string]json.RawMessage `json:"regions"`
+
+		// Keyed by endpoint prefix, such as "states"
+		Services map[string]struct {
+			Endpoints map[string]struct {
+				Deprecated bool `json:"deprecated"`
+
+				// The region a pseudo-region such as "aws-global" signs requests for
+				CredentialScope struct {
+					Region string `json:"region"`
+				} `json:"credentialScope"`
+			} `json:"endpoints"`
+		} `json:"services"`
+	} `json:"partitions"`
+}
+
+// serviceRegions maps endpoint prefixes to the regions they have endpoints in.
+type serviceRegions map[string][]string
+
+// readServiceRegions loads the SDK's endpoints data from a URL or a file. An empty source
+// means no data, and every service's regions are left empty.
+func readServiceRegions(source string) (serviceRegions, error) {
+	if source == "" {
+		return serviceRegions{}, nil
+	}
+
+	var data []byte
+	var err error
+
+	if strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://") {
+		data, err = fetchEndpoints(source)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read endpoints %s: %w", source, err)
+	}
+
+	var file endpointsFile
+
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse endpoints %s: %w", source, err)
+	}
+
+	result := serviceRegions{}
+
+	for _, partition := range file.Partitions {
+		for prefix, service := range partition.Services {
+			seen := map[string]bool{}
+
+			for region, endpoint := range service.Endpoints {
+				if endpoint.Deprecated {
+					continue
+				}
+
+				// A global service, such as IAM, only has an endpoint in a pseudo-region
+				if _, ok := partition.Regions[region]; !ok {
+					region = endpoint.CredentialScope.Region
+				}
+
+				if _, ok := partition.Regions[region]; ok && !seen[region] {
+					seen[region] = true
+					result[prefix] = append(result[prefix], region)
+				}
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("parse endpoints %s: no services found", source)
+	}
+
+	return result, nil
+}
+
+func fetchEndpoints(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+
+	if err != nil {
+		return nil, fmt.Errorf("HTTP GET: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("HTTP GET: status code %v", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// apply sets the regions of the service. The endpoints data is keyed by endpoint prefix,
+// so the service's SDK services are looked up if it has any, and otherwise its service
+// prefix, which is usually the same.
+func (regions serviceRegions) apply(authRef *authref.ServiceAuthorizationReference) {
+	prefixes := []string{authRef.ServicePrefix}
+
+	if len(authRef.SdkServices) != 0 {
+		prefixes = prefixes[:0]
+
+		for _, sdkService := range authRef.SdkServices {
+			prefixes = append(prefixes, sdkService.EndpointPrefix)
+		}
+	}
+
+	seen := map[string]bool{}
+	authRef.Regions = nil
+
+	for _, prefix := range prefixes {
+		for _, region := range regions[prefix] {
+			if !seen[region] {
+				seen[region] = true
+				authRef.Regions = append(authRef.Regions, region)
+			}
+		}
+	}
+
+	sort.Strings(authRef.Regions)
+}
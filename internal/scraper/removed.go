@@ -0,0 +1,86 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+const removedActionsFile = "removed-actions.json"
+
+// removedAction is an action that used to be in the dataset, along with its last known details.
+type removedAction struct {
+	ServicePrefix string `json:"servicePrefix"`
+
+	// The date of the last scrape that included the action, if known
+	LastSeen string `json:"lastSeen,omitempty"`
+
+	// The date of the first scrape that didn't include the action
+	Removed string `json:"removed"`
+
+	*authref.Action
+}
+
+func readRemovedActions(filename string) ([]*removedAction, error) {
+	data, err := os.ReadFile(filename)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return make([]*removedAction, 0), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read removed actions: %w", err)
+	}
+
+	var result []*removedAction
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse removed actions %s: %w", filename, err)
+	}
+
+	return result, nil
+}
+
+// updateRemovedActions adds the actions removed in changes to the list and drops any
+// that have come back in authRefs. The result is sorted by service prefix and name.
+func updateRemovedActions(removed []*removedAction, changes *authref.Changes, authRefs []*authref.ServiceAuthorizationReference, history *authref.History, date string) []*removedAction {
+	current := map[string]bool{}
+
+	for _, authRef := range authRefs {
+		for _, action := range authRef.Actions {
+			current[authRef.ServicePrefix+":"+action.Name] = true
+		}
+	}
+
+	result := make([]*removedAction, 0, len(removed))
+
+	for _, entry := range removed {
+		if !current[entry.ServicePrefix+":"+entry.Name] {
+			result = append(result, entry)
+		}
+	}
+
+	for _, service := range changes.Services {
+		for _, action := range service.RemovedActions {
+			entry := &removedAction{ServicePrefix: service.ServicePrefix, Removed: date, Action: action}
+
+			if historyEntry := history.Actions[service.ServicePrefix][action.Name]; historyEntry != nil {
+				entry.LastSeen = historyEntry.LastSeen
+			}
+
+			result = append(result, entry)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].ServicePrefix != result[j].ServicePrefix {
+			return result[i].ServicePrefix < result[j].ServicePrefix
+		}
+
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
@@ -0,0 +1,192 @@
+package scraper
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+const reportFile = "scrape-report.json"
+
+// Service statuses recorded in the scrape report.
+const (
+	statusOK      = "ok"
+	statusSkipped = "skipped"
+	statusFailed  = "failed"
+)
+
+// scrapeReport is the machine-readable summary of a scraper run, written to reportFile.
+type scrapeReport struct {
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	DurationMs int64            `json:"durationMs"`
+	Success    bool             `json:"success"`
+	Error      string           `json:"error,omitempty"`
+	HTTP       *httpStats       `json:"http"`
+	Services   []*serviceReport `json:"services"`
+
+	// Tables that came back empty, and whether the page confirms there are none
+	Coverage []*coverageEntry `json:"coverage"`
+}
+
+type serviceReport struct {
+	Name              string     `json:"name"`
+	ServicePrefix     string     `json:"servicePrefix,omitempty"`
+	AuthReferenceHref string     `json:"authReferenceHref"`
+	Status            string     `json:"status"`
+	Reason            string     `json:"reason,omitempty"`
+	DurationMs        int64      `json:"durationMs"`
+	Warnings          []*warning `json:"warnings"`
+}
+
+type warning struct {
+	Code string `json:"code"`
+
+	// The action the warning is about, if any
+	Action string `json:"action,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// Warning codes, in addition to the authref.Finding codes.
+const (
+	warnEmptyTable     = "empty-table"
+	warnSkippedService = "skipped-service"
+)
+
+// httpStats counts the requests made by fetchHtml.
+type httpStats struct {
+	mu           sync.Mutex
+	Requests     int            `json:"requests"`
+	Failures     int            `json:"failures"`
+	Bytes        int64          `json:"bytes"`
+	TotalTimeMs  int64          `json:"totalTimeMs"`
+	StatusCounts map[string]int `json:"statusCounts"`
+}
+
+var fetchStats = &httpStats{StatusCounts: map[string]int{}}
+
+func (stats *httpStats) record(statusCode int, bytes int64, elapsed time.Duration, failed bool) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	stats.Requests++
+	stats.Bytes += bytes
+	stats.TotalTimeMs += elapsed.Milliseconds()
+
+	if failed {
+		stats.Failures++
+	}
+
+	if statusCode != 0 {
+		stats.StatusCounts[fmt.Sprint(statusCode)]++
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func newScrapeReport() *scrapeReport {
+	return &scrapeReport{StartedAt: time.Now().UTC(), HTTP: fetchStats, Services: make([]*serviceReport, 0), Coverage: make([]*coverageEntry, 0)}
+}
+
+// addService records the outcome of scraping one topic, along with any warnings raised
+// while parsing it. authRef may be nil if the topic failed.
+func (report *scrapeReport) addService(t topic, authRef *authref.ServiceAuthorizationReference, status, reason string, elapsed time.Duration, warnings []*warning) {
+	service := &serviceReport{
+		Name:              t.name,
+		AuthReferenceHref: t.url.String(),
+		Status:            status,
+		Reason:            reason,
+		DurationMs:        elapsed.Milliseconds(),
+		Warnings:          make([]*warning, 0),
+	}
+
+	if authRef != nil {
+		service.ServicePrefix = authRef.ServicePrefix
+
+		if status == statusOK {
+			service.Warnings = append(warnings, checkService(authRef)...)
+		}
+	}
+
+	// Data kept from an earlier run wasn't checked against the current page
+	if status == statusSkipped {
+		message := fmt.Sprintf("page skipped (%s); data kept from an earlier snapshot", reason)
+
+		if authRef == nil {
+			message = fmt.Sprintf("page skipped (%s); not in the dataset", reason)
+		}
+
+		service.Warnings = append(service.Warnings, &warning{Code: warnSkippedService, Message: message})
+	}
+
+	report.Services = append(report.Services, service)
+}
+
+// snapshotWarning is an entry in warningsFile, a warning about one service as it appears in
+// the published dataset.
+type snapshotWarning struct {
+	Service           string `json:"service"`
+	ServicePrefix     string `json:"servicePrefix,omitempty"`
+	AuthReferenceHref string `json:"authReferenceHref"`
+	Code              string `json:"code"`
+	Message           string `json:"message"`
+}
+
+// snapshotWarnings lists the warnings of every service in the report, in order, for
+// publishing with the dataset.
+func (report *scrapeReport) snapshotWarnings() []*snapshotWarning {
+	result := make([]*snapshotWarning, 0)
+
+	for _, service := range report.Services {
+		for _, w := range service.Warnings {
+			result = append(result, &snapshotWarning{
+				Service:           service.Name,
+				ServicePrefix:     service.ServicePrefix,
+				AuthReferenceHref: service.AuthReferenceHref,
+				Code:              w.Code,
+				Message:           w.Message,
+			})
+		}
+	}
+
+	return result
+}
+
+// checkService looks for signs that a page parsed incorrectly.
+func checkService(authRef *authref.ServiceAuthorizationReference) []*warning {
+	findings := authref.CheckIntegrity(authRef)
+	warnings := make([]*warning, len(findings))
+
+	for i, finding := range findings {
+		warnings[i] = &warning{Code: finding.Code, Action: finding.Action, Message: finding.Message}
+	}
+
+	return warnings
+}
+
+// finish stamps the end of the run and writes the report to reportFile.
+func (report *scrapeReport) finish(runErr error) error {
+	report.FinishedAt = time.Now().UTC()
+	report.DurationMs = report.FinishedAt.Sub(report.StartedAt).Milliseconds()
+	report.Success = runErr == nil
+
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+
+	return writeJSONFile(reportFile, report)
+}
@@ -0,0 +1,1095 @@
+package scraper
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/fluggo/aws-service-auth-reference/authref"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	startPage       = "https://docs.aws.amazon.com/service-authorization/latest/reference/reference_policies_actions-resources-contextkeys.html"
+	testActionsPage = "https://docs.aws.amazon.com/service-authorization/latest/reference/list_amazonec2.html"
+	outputFile      = "service-auth.json"
+	metadataFile    = "metadata.json"
+)
+
+const permissionOnlyAnnotation = "permission only"
+
+var (
+	spaceReplacer     = regexp.MustCompile(`\s{2,}`)
+	annotationMatcher = regexp.MustCompile(`\[([^\[\]]+)\]`)
+)
+
+// Selectors are compiled once rather than on every page
+var (
+	topicsListSelector        = mustParseSelector(`h6:matchesOwn(^\s*Topics\s*$) + ul`)
+	topicsSelector            = mustParseSelector(`li > a`)
+	apiReferenceLinkSelector  = mustParseSelector(`#main-col-body a[href]:containsOwn("API operations available for")`)
+	servicePrefixSelector     = mustParseSelector(`#main-col-body > p:containsOwn("service prefix:") > code[class*="code"]`)
+	actionTableSelector       = mustParseSelector(`h2:containsOwn("Actions defined by") ~ div[class*="table-container"] table`)
+	resourceTypeTableSelector = mustParseSelector(`h2:containsOwn("Resource types defined by") + p + div[class*="table-container"] table, h2:containsOwn("Resource types defined by") + p + div + div[class*="table-container"] table`)
+	conditionKeyTableSelector = mustParseSelector(`h2:containsOwn("Condition keys for") + p + p + div[class*="table-container"] table`)
+	aHrefSelector             = mustParseSelector(`a[href]`)
+)
+
+// textBuffers holds scratch space for gatherText, which runs on every cell of every table.
+var textBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+func mustParseSelector(sel string) cascadia.SelectorGroup {
+	result, err := cascadia.ParseGroup(sel)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+// gatherText returns the text in node, trimmed and with runs of whitespace collapsed. A
+// nil node, such as a missing table cell, has no text.
+func gatherText(node *html.Node, recursive bool) string {
+	if node == nil {
+		return ""
+	}
+
+	buf := textBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	appendText(buf, node, recursive)
+	result := collapseSpaces(bytes.TrimSpace(buf.Bytes()))
+	textBuffers.Put(buf)
+	return result
+}
+
+func appendText(buf *bytes.Buffer, node *html.Node, recursive bool) {
+	for childNode := node.FirstChild; childNode != nil; childNode = childNode.NextSibling {
+		if childNode.Type == html.TextNode {
+			buf.WriteString(childNode.Data)
+		} else if recursive {
+			appendText(buf, childNode, true)
+		}
+	}
+}
+
+// collapseSpaces applies spaceReplacer, skipping the regexp for the common case of text
+// with no runs of whitespace.
+func collapseSpaces(text []byte) string {
+	for i := 1; i < len(text); i++ {
+		if isSpace(text[i-1]) && isSpace(text[i]) {
+			return spaceReplacer.ReplaceAllLiteralString(string(text), " ")
+		}
+	}
+
+	return string(text)
+}
+
+// isSpace matches the characters of \s in regexp.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// appendElements appends the elements below node with the given tag, without looking
+// inside the ones it finds. Walking the tree directly is much cheaper than a selector
+// for the rows and cells of large tables.
+func appendElements(result []*html.Node, node *html.Node, tag atom.Atom) []*html.Node {
+	for childNode := node.FirstChild; childNode != nil; childNode = childNode.NextSibling {
+		if childNode.Type != html.ElementNode {
+			continue
+		}
+
+		if childNode.DataAtom == tag {
+			result = append(result, childNode)
+		} else {
+			result = appendElements(result, childNode, tag)
+		}
+	}
+
+	return result
+}
+
+// paragraphText returns the text of each paragraph in a cell, such as its condition keys.
+func paragraphText(cellNode *html.Node) []string {
+	if cellNode == nil {
+		return make([]string, 0)
+	}
+
+	paragraphNodes := appendElements(nil, cellNode, atom.P)
+	result := make([]string, len(paragraphNodes))
+
+	for i, paragraphNode := range paragraphNodes {
+		result[i] = gatherText(paragraphNode, true)
+	}
+
+	return result
+}
+
+func renderToString(node *html.Node) string {
+	if node == nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	html.Render(&buf, node)
+	return buf.String()
+}
+
+// fetchHtml fetches and parses a page, returning its response headers as well.
+func fetchHtml(url string) (*html.Node, http.Header, error) {
+	start := time.Now()
+	resp, err := http.Get(url)
+
+	if err != nil {
+		fetchStats.record(0, 0, time.Since(start), true)
+		return nil, nil, fmt.Errorf("HTTP GET: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		fetchStats.record(resp.StatusCode, 0, time.Since(start), true)
+		return nil, nil, fmt.Errorf("HTTP GET: status code %v", resp.StatusCode)
+	}
+
+	body := &countingReader{r: resp.Body}
+	node, err := html.Parse(body)
+	fetchStats.record(resp.StatusCode, body.n, time.Since(start), err != nil)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse HTML: %w", err)
+	}
+
+	return node, resp.Header, nil
+}
+
+type topic struct {
+	name string
+	url  *url.URL
+}
+
+func getAttrValue(node *html.Node, name string) string {
+	for _, v := range node.Attr {
+		if v.Key == name {
+			return v.Val
+		}
+	}
+
+	return ""
+}
+
+func parseTopics() ([]topic, error) {
+	node, _, err := fetchHtml(startPage)
+
+	if err != nil {
+		return nil, fmt.Errorf("parseTopics: %w", err)
+	}
+
+	// Not fully documented in cascadia, but it has these additional text selectors:
+	//
+	//	:contains("str")		Selects nodes that contain the given text when all descendant text nodes are combined
+	//	:containsOwn("str")		Selects nodes that contain the given text when all child text nodes are combined
+	//	:matches(^[a-z]$)		Selects nodes that match the given regex when all descendant text nodes are combined
+	//	:matchesOwn(^[a-z]$)	Selects nodes that match the given regex when all child text nodes are combined
+	//	:has(selector)			Selects nodes that contain descendant nodes that match the given selector
+	//	:haschild(selector)		Selects nodes that contain child nodes that match the given selector
+	//	:input					Selects any input element (input, select, textarea, or button)
+	//	[attr#=(^[a-z]$)]		Selects elements with attributes that match the given regex
+	//
+	// Additionally, it implements all the tree-structural pseudo-classes found here:
+	//	https://developer.mozilla.org/en-US/docs/Web/CSS/Pseudo-classes#tree-structural_pseudo-classes
+
+	topicsListNode := cascadia.Query(node, topicsListSelector)
+
+	if topicsListNode == nil {
+		return nil, fmt.Errorf("get topics: could not find topics")
+	}
+
+	result := make([]topic, 0, 20)
+	baseUrl, err := url.Parse(startPage)
+
+	if err != nil {
+		panic(err)
+	}
+
+	topicsNodes := cascadia.QueryAll(topicsListNode, topicsSelector)
+
+	for _, aNode := range topicsNodes {
+		partialHref := getAttrValue(aNode, "href")
+		title := aNode.FirstChild.Data
+
+		if partialHref == "" {
+			return nil, fmt.Errorf("get topics: could not find topic <a> href")
+		}
+
+		newUrl, err := baseUrl.Parse(partialHref)
+
+		if err != nil {
+			return nil, fmt.Errorf("get topics: parse URL %s: %w", partialHref, err)
+		}
+
+		result = append(result, topic{name: title, url: newUrl})
+	}
+
+	return result, nil
+}
+
+func parseAPIReferenceHref(page *html.Node, raw *rawRecorder) string {
+	if apiReferenceNode := cascadia.Query(page, apiReferenceLinkSelector); apiReferenceNode != nil {
+		raw.cell("apiReferenceHref", apiReferenceNode)
+		return getAttrValue(apiReferenceNode, "href")
+	} else {
+		return ""
+	}
+}
+
+func parseServicePrefix(page *html.Node, raw *rawRecorder) (string, error) {
+	servicePrefixNode := cascadia.Query(page, servicePrefixSelector)
+
+	if servicePrefixNode == nil {
+		return "", fmt.Errorf("could not find service prefix")
+	}
+
+	raw.cell("servicePrefix", servicePrefixNode)
+
+	if prefix := gatherText(servicePrefixNode, true); prefix != "" {
+		return prefix, nil
+	}
+
+	return "", fmt.Errorf("service prefix is empty: %#v", renderToString(servicePrefixNode))
+}
+
+// Columns of the actions table
+const (
+	actionNameColumn = iota
+	descriptionColumn
+	accessLevelColumn
+	resourceTypesColumn
+	conditionKeysColumn
+	dependentActionsColumn
+	actionColumns
+)
+
+// parseActionName starts an action from the cell holding its name and annotations.
+func parseActionName(cellNode *html.Node, raw *rawRecorder) *authref.Action {
+	action := &authref.Action{}
+	actionNameRaw := gatherText(cellNode, true)
+	actionNameSubstrings := strings.SplitN(actionNameRaw, " ", 2)
+
+	if actionNameNode := cascadia.Query(cellNode, aHrefSelector); actionNameNode != nil {
+		action.Name = gatherText(actionNameNode, true)
+		action.ReferenceHref = getAttrValue(actionNameNode, "href")
+	} else {
+		action.Name = actionNameSubstrings[0]
+	}
+
+	raw.begin("actions", action.Name)
+	raw.cell("name", cellNode)
+
+	// Bracketed notes follow the name, such as "[permission only]" or "[only available in China Regions]"
+	action.Annotations = make([]string, 0)
+
+	for _, match := range annotationMatcher.FindAllStringSubmatch(actionNameRaw, -1) {
+		annotation := strings.TrimSpace(match[1])
+		action.Annotations = append(action.Annotations, annotation)
+
+		if strings.EqualFold(annotation, permissionOnlyAnnotation) {
+			action.PermissionOnly = true
+		}
+	}
+
+	action.ResourceTypes = make([]authref.ActionResourceType, 0)
+	action.ConditionKeys = make([]string, 0)
+	return action
+}
+
+func parseActionsTable(page *html.Node, raw *rawRecorder) ([]*authref.Action, error) {
+	actionTableNode := cascadia.Query(page, actionTableSelector)
+
+	// Left for checkCoverage to report
+	if actionTableNode == nil {
+		return make([]*authref.Action, 0), nil
+	}
+
+	return parseActionRows(actionTableNode, raw)
+}
+
+// parseActionRows reads the actions from an actions table.
+func parseActionRows(actionTableNode *html.Node, raw *rawRecorder) ([]*authref.Action, error) {
+	grid, err := newTableGrid(actionTableNode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]*authref.Action, 0, len(grid))
+	var action *authref.Action
+	skipping := false
+
+	for row := 1; row < len(grid); row++ {
+		if len(grid[row]) != actionColumns {
+			return nil, fmt.Errorf("row %d has %d columns (expected %d): %#v", row, len(grid[row]), actionColumns, grid.rowHTML(row))
+		}
+
+		// An action runs until the next name cell starts
+		if nameCellNode := grid.own(row, actionNameColumn); nameCellNode != nil {
+			action = parseActionName(nameCellNode, raw)
+			actions = append(actions, action)
+			skipping = false
+		} else if action == nil {
+			return nil, fmt.Errorf("row %d doesn't start with an action name: %#v", row, grid.rowHTML(row))
+		}
+
+		if skipping {
+			continue
+		}
+
+		if descriptionCellNode := grid.own(row, descriptionColumn); descriptionCellNode != nil {
+			// For now, we only take the first description we find; the "SCENARIO" blocks in the EC2 documentation aren't interesting to us
+			if action.Description != "" {
+				skipping = true
+				continue
+			}
+
+			action.Description = gatherText(descriptionCellNode, true)
+
+			accessLevelNode := grid.own(row, accessLevelColumn)
+			action.AccessLevel = authref.AccessLevel(gatherText(accessLevelNode, true))
+
+			raw.cell("description", descriptionCellNode)
+			raw.cell("accessLevel", accessLevelNode)
+		} else if action.Description == "" {
+			return nil, fmt.Errorf("row %d has no description for action %#v: %#v", row, action.Name, grid.rowHTML(row))
+		}
+
+		// Cells carried down from above were already read with the row they started in
+		resourceTypeNode := grid.own(row, resourceTypesColumn)
+		conditionKeysNode := grid.own(row, conditionKeysColumn)
+		dependentActionsNode := grid.own(row, dependentActionsColumn)
+
+		if resourceTypeNode == nil && conditionKeysNode == nil && dependentActionsNode == nil {
+			continue
+		}
+
+		raw.cell("resourceTypes", resourceTypeNode)
+		raw.cell("conditionKeys", conditionKeysNode)
+		raw.cell("dependentActions", dependentActionsNode)
+
+		conditionKeys := paragraphText(conditionKeysNode)
+
+		resourceTypeField := gatherText(resourceTypeNode, true)
+		if resourceTypeField == "" {
+			action.ConditionKeys = conditionKeys
+			continue
+		}
+
+		resourceType := authref.ActionResourceType{}
+		resourceType.ResourceType = strings.TrimSuffix(resourceTypeField, "*")
+		resourceType.Required = strings.HasSuffix(resourceTypeField, "*")
+
+		// Starred resource types are alternatives; the page has no way to mark more than
+		// one group of them
+		if resourceType.Required {
+			resourceType.RequiredGroup = 1
+		}
+
+		resourceType.ConditionKeys = conditionKeys
+
+		resourceType.DependentActions = paragraphText(dependentActionsNode)
+		action.ResourceTypes = append(action.ResourceTypes, resourceType)
+	}
+
+	return actions, nil
+}
+
+func parseResourceTypesTable(page *html.Node, raw *rawRecorder) ([]*authref.ResourceType, error) {
+	rtTableNode := cascadia.Query(page, resourceTypeTableSelector)
+
+	if rtTableNode == nil {
+		return make([]*authref.ResourceType, 0), nil
+	}
+
+	grid, err := newTableGrid(rtTableNode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resourceTypes := make([]*authref.ResourceType, 0, len(grid))
+	var resourceType *authref.ResourceType
+
+	for row := 1; row < len(grid); row++ {
+		nameCellNode := grid.own(row, 0)
+
+		if len(grid[row]) != 3 || nameCellNode == nil {
+			return nil, fmt.Errorf("row %d has %d columns (expected 3, starting with a name): %#v", row, len(grid[row]), grid.rowHTML(row))
+		}
+
+		resourceType = &authref.ResourceType{}
+		resourceTypes = append(resourceTypes, resourceType)
+
+		resourceType.Name = gatherText(nameCellNode, true)
+		raw.begin("resourceTypes", resourceType.Name)
+		raw.cell("name", nameCellNode)
+		raw.cell("arnPattern", grid.at(row, 1))
+		raw.cell("conditionKeys", grid.at(row, 2))
+
+		if resourceTypeRefLink := cascadia.Query(nameCellNode, aHrefSelector); resourceTypeRefLink != nil {
+			resourceType.ReferenceHref = getAttrValue(resourceTypeRefLink, "href")
+		}
+
+		resourceType.ArnPattern = gatherText(grid.at(row, 1), true)
+
+		resourceType.ConditionKeys = paragraphText(grid.at(row, 2))
+	}
+
+	return resourceTypes, nil
+}
+
+func parseConditionKeyTable(page *html.Node, raw *rawRecorder) ([]*authref.ConditionKey, error) {
+	ckTableNode := cascadia.Query(page, conditionKeyTableSelector)
+
+	if ckTableNode == nil {
+		return make([]*authref.ConditionKey, 0), nil
+	}
+
+	return parseConditionKeyRows(ckTableNode, raw)
+}
+
+// parseConditionKeyRows reads the condition keys from a condition keys table.
+func parseConditionKeyRows(ckTableNode *html.Node, raw *rawRecorder) ([]*authref.ConditionKey, error) {
+	grid, err := newTableGrid(ckTableNode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	conditionKeys := make([]*authref.ConditionKey, 0, len(grid))
+	var conditionKey *authref.ConditionKey
+
+	for row := 1; row < len(grid); row++ {
+		nameCellNode := grid.own(row, 0)
+
+		if len(grid[row]) != 3 || nameCellNode == nil {
+			return nil, fmt.Errorf("row %d has %d columns (expected 3, starting with a name): %#v", row, len(grid[row]), grid.rowHTML(row))
+		}
+
+		conditionKey = &authref.ConditionKey{}
+		conditionKeys = append(conditionKeys, conditionKey)
+
+		conditionKey.Name = gatherText(nameCellNode, true)
+		raw.begin("conditionKeys", conditionKey.Name)
+		raw.cell("name", nameCellNode)
+		raw.cell("description", grid.at(row, 1))
+		raw.cell("type", grid.at(row, 2))
+
+		if refLink := cascadia.Query(nameCellNode, aHrefSelector); refLink != nil {
+			conditionKey.ReferenceHref = getAttrValue(refLink, "href")
+		}
+
+		conditionKey.Description = gatherText(grid.at(row, 1), true)
+		conditionKey.Type = gatherText(grid.at(row, 2), true)
+	}
+
+	return conditionKeys, nil
+}
+
+// scrapeResult is the outcome of scraping one topic.
+type scrapeResult struct {
+	authRef *authref.ServiceAuthorizationReference
+
+	// Problems the scraper corrected or noticed while parsing
+	warnings []*warning
+
+	// Explanations for any tables that came back empty
+	coverage []*coverageEntry
+
+	// Ids of the headings of the page's tables, from tableAnchors
+	anchors map[string]string
+}
+
+// scrapeTopic fetches and parses the service authorization reference page for a topic.
+// If raw is not nil, it receives the markup behind each parsed field.
+func scrapeTopic(topic topic, raw *rawRecorder) (*scrapeResult, error) {
+	page, header, err := fetchHtml(topic.url.String())
+
+	if err != nil {
+		return nil, fmt.Errorf("topic %#v: %w", topic.name, err)
+	}
+
+	return parsePage(topic, page, header, raw)
+}
+
+// parsePage parses a topic's reference page once it's been fetched. The header is the
+// HTTP response's, and may be nil.
+func parsePage(topic topic, page *html.Node, header http.Header, raw *rawRecorder) (*scrapeResult, error) {
+	authRef := &authref.ServiceAuthorizationReference{Name: topic.name, AuthReferenceHref: topic.url.String()}
+	result := &scrapeResult{authRef: authRef}
+
+	actions, err := parseActionsTable(page, raw)
+
+	if err != nil {
+		return nil, fmt.Errorf("topic %#v: actions table: %w", topic.name, err)
+	}
+
+	authRef.Actions, result.warnings = mergeDuplicateActions(actions)
+
+	if authRef.ConditionKeys, err = parseConditionKeyTable(page, raw); err != nil {
+		return nil, fmt.Errorf("topic %#v: condition keys table: %w", topic.name, err)
+	}
+
+	if authRef.ResourceTypes, err = parseResourceTypesTable(page, raw); err != nil {
+		return nil, fmt.Errorf("topic %#v: resource types table: %w", topic.name, err)
+	}
+
+	raw.begin("page", topic.name)
+	authRef.ApiReferenceHref = parseAPIReferenceHref(page, raw)
+
+	if authRef.ServicePrefix, err = parseServicePrefix(page, raw); err != nil {
+		return nil, fmt.Errorf("topic %#v: %w", topic.name, err)
+	}
+
+	authRef.LastUpdated = parseLastUpdated(page, header, raw)
+
+	result.coverage = checkCoverage(page, authRef)
+	result.anchors = tableAnchors(page)
+	result.warnings = append(result.warnings, coverageWarnings(result.coverage)...)
+
+	return result, nil
+}
+
+// Exporter writes the dataset in the formats of "authref export", for --format. Those
+// formats are defined by the authref command, which passes them to Main.
+type Exporter interface {
+	// CheckFormats returns an error if a comma-separated list of formats names one that
+	// doesn't exist.
+	CheckFormats(list string) error
+
+	// Export writes the dataset to dir in each format of the list.
+	Export(list, dir string, authRefs []*authref.ServiceAuthorizationReference) error
+}
+
+// Main runs the scraper with command-line arguments, not including the program name. It
+// exits the process when it's done or if it fails. The exporter provides the formats of
+// --format; without one, --format is an error.
+func Main(args []string, exporter Exporter) {
+	flags := flag.NewFlagSet("scrape", flag.ExitOnError)
+	skipListFile := flags.String("skip-list", "skip-list.json", "JSON file of services to skip, with the reason for each")
+	exclude := flags.String("exclude", "", "comma-separated list of additional services to skip in this run")
+	principalsFile := flags.String("service-principals", "service-principals.json", "JSON file mapping service prefixes to their service principals")
+	sdkServicesFile := flags.String("sdk-services", "sdk-services.json", "JSON file mapping service prefixes to the AWS SDK services that call them")
+	partitionsFile := flags.String("service-partitions", "service-partitions.json", "JSON file mapping service prefixes to the partitions they aren't offered in")
+	endpointsSource := flags.String("endpoints", defaultEndpointsSource, "URL or file of the AWS SDK's endpoints.json, for the regions of each service; regions are left out if empty")
+	historyFile := flags.String("history", "history.json", "JSON file recording when each action, resource type, and condition key was first and last seen")
+	embedDir := flags.String("embed-dir", "authrefdata", "directory of the Go package that embeds the dataset; not updated if empty")
+	versionedDir := flags.String("versioned-dir", "", "if set, also copy the published artifacts to this directory with the dataset version in their names")
+	signingKeyEnv := flags.String("signing-key-env", "AUTHREF_SIGNING_KEY", "environment variable holding a base64 Ed25519 key to sign "+authref.ChecksumsFile+" with; unsigned if empty")
+	printPublicKey := flags.Bool("print-public-key", false, "print the minisign public key for the signing key and exit")
+	localeList := flags.String("locales", "", "comma-separated documentation locales, such as ja_jp,de_de, to also scrape translated descriptions from")
+	provenance := flags.Bool("provenance", false, "record the page, table, and time each action, resource type, and condition key was scraped from")
+	debugRaw := flags.Bool("debug-raw", false, "also write the original HTML of each parsed cell to "+rawFile)
+	smokeTest := flags.Bool("smoke-test", false, "scrape only the EC2 page, check it against expected minimums, and exit")
+	dryRun := flags.Bool("dry-run", false, "scrape every page and print the changes and warnings, but write nothing")
+	webhookUrl := flags.String("webhook", "", "URL to post a summary of new and removed actions to (default from $"+webhookEnv+")")
+	webhookFormat := flags.String("webhook-format", webhookSlack, "payload format for --webhook: slack, teams, or json")
+	webhookServices := flags.String("webhook-services", "", "comma-separated service prefixes to notify about (default all)")
+	webhookAccessLevels := flags.String("webhook-access-levels", "", "comma-separated access levels to notify about (default all)")
+	commit := flags.Bool("commit", false, "if the dataset changed, commit the published files with a message summarizing the changes")
+	pullRequest := flags.Bool("pull-request", false, "like --commit, but commit to a new branch and open a pull request for it")
+	pullRequestTokenEnv := flags.String("pull-request-token-env", "AUTHREF_PR_TOKEN", "environment variable holding a GitHub token to open pull requests with")
+	issueTokenEnv := flags.String("issue-token-env", "AUTHREF_ISSUE_TOKEN", "environment variable holding a GitHub token to open an issue about parse anomalies with; no issue is opened if empty")
+	formats := flags.String("format", "", "comma-separated formats of authref export, such as yaml,csv,sqlite, to also write the dataset in")
+	formatDir := flags.String("format-dir", ".", "directory to write the files of --format to")
+	githubRepo := flags.String("github-repo", "", "GitHub repository, as owner/name, for issues and pull requests (default from $GITHUB_REPOSITORY)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: authref scrape [flags]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *smokeTest {
+		if err := runSmokeTest(); err != nil {
+			fmt.Fprintf(os.Stderr, "smoke test failed: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("smoke test passed")
+		return
+	}
+
+	var signingKey ed25519.PrivateKey
+
+	if keyText := os.Getenv(*signingKeyEnv); keyText != "" {
+		var err error
+
+		if signingKey, err = authref.ParseSigningKey(keyText); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *signingKeyEnv, err)
+			os.Exit(2)
+		}
+	}
+
+	if *printPublicKey {
+		if signingKey == nil {
+			fmt.Fprintf(os.Stderr, "no signing key in $%s\n", *signingKeyEnv)
+			os.Exit(2)
+		}
+
+		fmt.Print(authref.FormatPublicKey(signingKey))
+		return
+	}
+
+	var notify *notifier
+
+	if *webhookUrl == "" {
+		*webhookUrl = os.Getenv(webhookEnv)
+	}
+
+	if *webhookUrl != "" {
+		var err error
+
+		if notify, err = newNotifier(*webhookUrl, *webhookFormat, *webhookServices, *webhookAccessLevels); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if *githubRepo == "" {
+		*githubRepo = os.Getenv("GITHUB_REPOSITORY")
+	}
+
+	var issues *issueFiler
+
+	if token := os.Getenv(*issueTokenEnv); token != "" {
+		if *githubRepo == "" {
+			fmt.Fprintf(os.Stderr, "$%s is set, but there's no --github-repo to open issues in\n", *issueTokenEnv)
+			os.Exit(2)
+		}
+
+		issues = &issueFiler{github: &githubClient{repo: *githubRepo, token: token}}
+	}
+
+	var pulls *githubClient
+
+	if *pullRequest {
+		token := os.Getenv(*pullRequestTokenEnv)
+
+		switch {
+		case *commit:
+			fmt.Fprintf(os.Stderr, "--commit and --pull-request can't be used together\n")
+			os.Exit(2)
+		case token == "":
+			fmt.Fprintf(os.Stderr, "--pull-request needs a GitHub token in $%s\n", *pullRequestTokenEnv)
+			os.Exit(2)
+		case *githubRepo == "":
+			fmt.Fprintf(os.Stderr, "--pull-request needs --github-repo\n")
+			os.Exit(2)
+		}
+
+		pulls = &githubClient{repo: *githubRepo, token: token}
+	}
+
+	// Check the formats before scraping, so a typo doesn't waste a run
+	if *formats != "" {
+		if exporter == nil {
+			fmt.Fprintf(os.Stderr, "--format is only available from authref scrape\n")
+			os.Exit(2)
+		}
+
+		if err := exporter.CheckFormats(*formats); err != nil {
+			fmt.Fprintf(os.Stderr, "--format: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	locales, err := parseLocales(*localeList)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--locales: %v\n", err)
+		os.Exit(2)
+	}
+
+	skips, err := readSkipList(*skipListFile)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	skips = skips.addExcludes(*exclude)
+	principals, err := readServicePrincipals(*principalsFile)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	sdks, err := readSdkServices(*sdkServicesFile)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	partitions, err := readServicePartitions(*partitionsFile)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	regions, err := readServiceRegions(*endpointsSource)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	previousAuthRefs, err := readPreviousReferences(outputFile)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	previousRefs := make(map[string]*authref.ServiceAuthorizationReference, len(previousAuthRefs))
+
+	for _, authRef := range previousAuthRefs {
+		previousRefs[authRef.AuthReferenceHref] = authRef
+	}
+
+	report := newScrapeReport()
+	authRefs := make([]*authref.ServiceAuthorizationReference, 0)
+	var output *jsonArrayWriter
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+
+		if output != nil {
+			output.abort()
+		}
+
+		if *dryRun {
+			os.Exit(1)
+		}
+
+		if reportErr := report.finish(err); reportErr != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", reportErr)
+		}
+
+		if issues != nil {
+			if issueErr := issues.file(report, authRefs); issueErr != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", issueErr)
+			}
+		}
+
+		os.Exit(1)
+	}
+
+	topics, err := parseTopics()
+
+	if err != nil {
+		fail(fmt.Errorf("failed to parse topics page: %w", err))
+	}
+
+	if !*dryRun {
+		if output, err = newJSONArrayWriter(outputFile); err != nil {
+			fail(err)
+		}
+	}
+
+	rawServices := make([]*rawService, 0)
+
+	// Write each service as soon as it's parsed, rather than encoding them all at the end
+	addService := func(authRef *authref.ServiceAuthorizationReference) {
+		principals.apply(authRef)
+		sdks.apply(authRef)
+		partitions.apply(authRef)
+		regions.apply(authRef)
+		authRefs = append(authRefs, authRef)
+
+		if output == nil {
+			return
+		}
+
+		if err := output.write(authRef); err != nil {
+			fail(err)
+		}
+	}
+
+	for _, topic := range topics {
+		if skip := skips.find(topic); skip != nil {
+			// Keep the last known data so the service doesn't vanish from the dataset
+			previous := previousRefs[topic.url.String()]
+
+			if previous != nil {
+				addService(previous)
+				fmt.Fprintf(os.Stderr, "topic %#v: skipped (%s), keeping previous data\n", topic.name, skip.Reason)
+			} else {
+				fmt.Fprintf(os.Stderr, "topic %#v: skipped (%s), no previous data\n", topic.name, skip.Reason)
+			}
+
+			report.addService(topic, previous, statusSkipped, skip.Reason, 0, nil)
+			continue
+		}
+
+		var raw *rawRecorder
+
+		if *debugRaw {
+			raw = newRawRecorder(topic)
+			rawServices = append(rawServices, raw.service)
+		}
+
+		start := time.Now()
+		result, err := scrapeTopic(topic, raw)
+
+		if err != nil {
+			report.addService(topic, nil, statusFailed, err.Error(), time.Since(start), nil)
+			fail(err)
+		}
+
+		if *provenance {
+			addProvenance(topic, result, start)
+		}
+
+		for _, locale := range locales {
+			result.warnings = append(result.warnings, addLocalizedDescriptions(topic, result.authRef, locale)...)
+		}
+
+		report.addService(topic, result.authRef, statusOK, "", time.Since(start), result.warnings)
+		report.Coverage = append(report.Coverage, result.coverage...)
+		addService(result.authRef)
+	}
+
+	for _, prefix := range principals.unused(authRefs) {
+		fmt.Fprintf(os.Stderr, "%s: service prefix %#v not found in the reference\n", *principalsFile, prefix)
+	}
+
+	for _, prefix := range sdks.unused(authRefs) {
+		fmt.Fprintf(os.Stderr, "%s: service prefix %#v not found in the reference\n", *sdkServicesFile, prefix)
+	}
+
+	for _, prefix := range partitions.unused(authRefs) {
+		fmt.Fprintf(os.Stderr, "%s: service prefix %#v not found in the reference\n", *partitionsFile, prefix)
+	}
+
+	if *dryRun {
+		previousMetadata, err := authref.LoadMetadataFile(metadataFile)
+
+		if err != nil {
+			fail(fmt.Errorf("could not read metadata: %w", err))
+		}
+
+		changes := authref.Diff(previousAuthRefs, authRefs)
+		printDryRun(os.Stdout, changes, authref.NewMetadata(authRefs, previousMetadata, changes, report.StartedAt.Format(time.RFC3339)), report)
+		return
+	}
+
+	if err := output.close(); err != nil {
+		fail(err)
+	}
+
+	// The dataset is in place; later failures shouldn't touch it
+	output = nil
+
+	if err := os.WriteFile(protoFile, authref.MarshalProto(authRefs), 0644); err != nil {
+		fail(fmt.Errorf("could not write %s: %w", protoFile, err))
+	}
+
+	searchIndex, err := json.Marshal(authref.BuildSearchIndex(authRefs))
+
+	if err != nil {
+		fail(fmt.Errorf("could not encode search index: %w", err))
+	}
+
+	if err := os.WriteFile(authref.SearchIndexFile, searchIndex, 0644); err != nil {
+		fail(fmt.Errorf("could not write %s: %w", authref.SearchIndexFile, err))
+	}
+
+	byPrefix := keyByPrefix(authRefs)
+
+	if err := writeJSONFile(byPrefixFile, byPrefix); err != nil {
+		fail(err)
+	}
+
+	if err := writeJSONFile(actionMapFile, flattenActions(byPrefix)); err != nil {
+		fail(err)
+	}
+
+	if err := writeActionsOnly(actionsOnlyFile, authRefs); err != nil {
+		fail(err)
+	}
+
+	if err := writeJSONFile(conditionKeysFile, authref.SummarizeConditionKeys(authRefs)); err != nil {
+		fail(err)
+	}
+
+	if err := writeJSONFile(arnNamespacesFile, authref.ArnNamespaceMismatches(authRefs)); err != nil {
+		fail(err)
+	}
+
+	if err := writeJSONFile(integrityFile, authref.CheckDatasetIntegrity(authRefs)); err != nil {
+		fail(err)
+	}
+
+	if err := writeJSONFile(warningsFile, report.snapshotWarnings()); err != nil {
+		fail(err)
+	}
+
+	if err := writeJSONFile(permissionOnlyFile, authref.PermissionOnlyActions(authRefs)); err != nil {
+		fail(err)
+	}
+
+	if err := writeJSONFile(unscopedFile, authref.UnscopedActions(authRefs)); err != nil {
+		fail(err)
+	}
+
+	history, err := authref.LoadHistoryFile(*historyFile)
+
+	if err != nil {
+		fail(fmt.Errorf("could not read history: %w", err))
+	}
+
+	today := report.StartedAt.Format(authref.HistoryDateFormat)
+	history.Update(authRefs, today)
+
+	if err := writeJSONFile(*historyFile, history); err != nil {
+		fail(err)
+	}
+
+	changes := authref.Diff(previousAuthRefs, authRefs)
+	removed, err := readRemovedActions(removedActionsFile)
+
+	if err != nil {
+		fail(err)
+	}
+
+	if err := writeJSONFile(removedActionsFile, updateRemovedActions(removed, changes, authRefs, history, today)); err != nil {
+		fail(err)
+	}
+
+	feed, err := readFeed(feedFile)
+
+	if err != nil {
+		fail(err)
+	}
+
+	feed.addChanges(changes, authRefs, report.StartedAt)
+
+	if err := writeFeed(feedFile, feed); err != nil {
+		fail(err)
+	}
+
+	previousMetadata, err := authref.LoadMetadataFile(metadataFile)
+
+	if err != nil {
+		fail(fmt.Errorf("could not read metadata: %w", err))
+	}
+
+	metadata := authref.NewMetadata(authRefs, previousMetadata, changes, report.StartedAt.Format(time.RFC3339))
+	generator := authref.ReadBuildInfo()
+	metadata.Generator = &generator
+	artifacts := []string{outputFile, protoFile, authref.SearchIndexFile, byPrefixFile, actionMapFile, actionsOnlyFile, conditionKeysFile, arnNamespacesFile, integrityFile, warningsFile, permissionOnlyFile, unscopedFile, removedActionsFile, *historyFile, feedFile}
+
+	if metadata.Checksums, err = checksumFiles(artifacts); err != nil {
+		fail(err)
+	}
+
+	if err := writeJSONFile(metadataFile, metadata); err != nil {
+		fail(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "dataset version %s (%s change from %s)\n", metadata.Version, metadata.Bump, metadata.PreviousVersion)
+	artifacts = append(artifacts, metadataFile)
+
+	if err := writeChecksums(".", artifacts, signingKey, metadata.Version); err != nil {
+		fail(err)
+	}
+
+	if *embedDir != "" {
+		if err := writeEmbedPackage(*embedDir, authRefs, metadata); err != nil {
+			fail(err)
+		}
+	}
+
+	if *versionedDir != "" {
+		versioned, err := copyVersioned(*versionedDir, metadata.Version, artifacts)
+
+		if err != nil {
+			fail(err)
+		}
+
+		if err := writeChecksums(*versionedDir, versioned, signingKey, metadata.Version); err != nil {
+			fail(err)
+		}
+	}
+
+	if *debugRaw {
+		if err := writeRawFile(rawServices); err != nil {
+			fail(err)
+		}
+	}
+
+	// The other formats come from the dataset just scraped, without reading it back
+	if *formats != "" {
+		if err := exporter.Export(*formats, *formatDir, authRefs); err != nil {
+			fail(fmt.Errorf("--format: %w", err))
+		}
+	}
+
+	if *commit || *pullRequest {
+		committed := append([]string{authref.ChecksumsFile}, artifacts...)
+
+		if signingKey != nil {
+			committed = append(committed, authref.SignatureFile)
+		}
+
+		if *embedDir != "" {
+			committed = append(committed, *embedDir)
+		}
+
+		changed, err := datasetChanged()
+
+		switch {
+		case err != nil:
+			fail(err)
+		case !changed:
+			fmt.Fprintf(os.Stderr, "%s is unchanged; nothing committed\n", outputFile)
+		case *pullRequest:
+			if err := openPullRequest(pulls, committed, changes, metadata); err != nil {
+				fail(err)
+			}
+		default:
+			if err := commitArtifacts(committed, changes, metadata); err != nil {
+				fail(err)
+			}
+		}
+	}
+
+	// The dataset is already written, so a failed notification shouldn't fail the run
+	if notify != nil {
+		if err := notify.notify(changes); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+
+	if issues != nil {
+		if err := issues.file(report, authRefs); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+
+	if err := report.finish(nil); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,70 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// sdkServices maps service prefixes to the AWS SDK clients that call the service. The
+// reference pages don't name the SDK clients, so the mapping is maintained by hand in
+// sdk-services.json.
+type sdkServices map[string][]*authref.SdkService
+
+// readSdkServices loads the SDK mapping. A missing file is treated as an empty mapping.
+func readSdkServices(filename string) (sdkServices, error) {
+	data, err := os.ReadFile(filename)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return sdkServices{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read SDK services: %w", err)
+	}
+
+	var result sdkServices
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse SDK services %s: %w", filename, err)
+	}
+
+	for prefix, services := range result {
+		for _, service := range services {
+			if service == nil || service.ServiceId == "" || service.EndpointPrefix == "" {
+				return nil, fmt.Errorf("parse SDK services %s: %s: each entry needs a serviceId and endpointPrefix", filename, prefix)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// apply sets the SDK services of the service from the mapping, replacing whatever it had
+// before.
+func (services sdkServices) apply(authRef *authref.ServiceAuthorizationReference) {
+	authRef.SdkServices = services[authRef.ServicePrefix]
+}
+
+// unused returns the prefixes in the mapping that don't match any service, which usually
+// means the mapping is out of date.
+func (services sdkServices) unused(authRefs []*authref.ServiceAuthorizationReference) []string {
+	used := map[string]bool{}
+
+	for _, authRef := range authRefs {
+		used[authRef.ServicePrefix] = true
+	}
+
+	unused := make([]string, 0)
+
+	for prefix := range services {
+		if !used[prefix] {
+			unused = append(unused, prefix)
+		}
+	}
+
+	sort.Strings(unused)
+	return unused
+}
@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/fluggo/aws-service-auth-reference/authref"
+)
+
+// skipEntry names a service whose reference page should not be parsed, along with
+// the reason it was excluded. Service is matched against either the topic name
+// (e.g. "Amazon EC2") or the reference page name (e.g. "list_amazonec2.html").
+type skipEntry struct {
+	Service string `json:"service"`
+	Reason  string `json:"reason"`
+}
+
+type skipList []skipEntry
+
+// readSkipList loads the persisted skip list. A missing file is treated as an empty list.
+func readSkipList(filename string) (skipList, error) {
+	data, err := os.ReadFile(filename)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read skip list: %w", err)
+	}
+
+	var result skipList
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse skip list %s: %w", filename, err)
+	}
+
+	for i, entry := range result {
+		if entry.Service == "" {
+			return nil, fmt.Errorf("parse skip list %s: entry %d has no service", filename, i)
+		}
+	}
+
+	return result, nil
+}
+
+// addExcludes appends the comma-separated services given on the command line.
+func (list skipList) addExcludes(exclude string) skipList {
+	for _, service := range strings.Split(exclude, ",") {
+		if service = strings.TrimSpace(service); service != "" {
+			list = append(list, skipEntry{Service: service, Reason: "excluded on command line"})
+		}
+	}
+
+	return list
+}
+
+// find returns the entry matching the topic, or nil if the topic should be scraped.
+func (list skipList) find(t topic) *skipEntry {
+	pageName := path.Base(t.url.Path)
+
+	for i := range list {
+		service := list[i].Service
+
+		if strings.EqualFold(service, t.name) || strings.EqualFold(service, pageName) ||
+			strings.EqualFold(service, strings.TrimSuffix(pageName, ".html")) {
+			return &list[i]
+		}
+	}
+
+	return nil
+}
+
+// readPreviousReferences loads the existing output file, which is the previous snapshot
+// of the dataset. A missing file yields an empty snapshot.
+func readPreviousReferences(filename string) ([]*authref.ServiceAuthorizationReference, error) {
+	authRefs, err := authref.LoadFile(filename)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return make([]*authref.ServiceAuthorizationReference, 0), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read previous references: %w", err)
+	}
+
+	return authRefs, nil
+}
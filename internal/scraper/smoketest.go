@@ -0,0 +1,70 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Minimum counts expected from the EC2 page. These are well below the real numbers
+// so that normal churn in the docs doesn't trip them; a broken selector will.
+const (
+	smokeMinActions       = 500
+	smokeMinResourceTypes = 50
+	smokeMinConditionKeys = 50
+)
+
+// runSmokeTest scrapes testActionsPage alone and checks that every table parsed
+// into something plausible. It's a quick check of selector health before a full run.
+func runSmokeTest() error {
+	pageUrl, err := url.Parse(testActionsPage)
+
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := scrapeTopic(topic{name: "Amazon EC2", url: pageUrl}, nil)
+
+	if err != nil {
+		return err
+	}
+
+	authRef := result.authRef
+
+	if authRef.ServicePrefix != "ec2" {
+		return fmt.Errorf("service prefix is %#v (expected \"ec2\")", authRef.ServicePrefix)
+	}
+
+	if len(authRef.Actions) < smokeMinActions {
+		return fmt.Errorf("found %d actions (expected at least %d)", len(authRef.Actions), smokeMinActions)
+	}
+
+	if len(authRef.ResourceTypes) < smokeMinResourceTypes {
+		return fmt.Errorf("found %d resource types (expected at least %d)", len(authRef.ResourceTypes), smokeMinResourceTypes)
+	}
+
+	if len(authRef.ConditionKeys) < smokeMinConditionKeys {
+		return fmt.Errorf("found %d condition keys (expected at least %d)", len(authRef.ConditionKeys), smokeMinConditionKeys)
+	}
+
+	foundRunInstances := false
+
+	for _, action := range authRef.Actions {
+		if action.Name == "" || action.Description == "" || action.AccessLevel == "" {
+			return fmt.Errorf("action %#v is missing its name, description, or access level", action.Name)
+		}
+
+		if action.Name == "RunInstances" {
+			foundRunInstances = true
+
+			if len(action.ResourceTypes) == 0 {
+				return fmt.Errorf("action RunInstances has no resource types")
+			}
+		}
+	}
+
+	if !foundRunInstances {
+		return fmt.Errorf("action RunInstances not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// jsonArrayWriter writes a JSON array one element at a time, in the same style as
+// writeJSONFile, so the scraper doesn't have to hold the encoded output of every service
+// at once. It writes to a temporary file that replaces the real one only when the array is
+// complete, so a failed run never leaves a partial file behind.
+type jsonArrayWriter struct {
+	filename string
+	file     *os.File
+	buf      *bufio.Writer
+	count    int
+}
+
+func newJSONArrayWriter(filename string) (*jsonArrayWriter, error) {
+	file, err := os.Create(filename + ".partial")
+
+	if err != nil {
+		return nil, fmt.Errorf("could not open output file: %w", err)
+	}
+
+	return &jsonArrayWriter{filename: filename, file: file, buf: bufio.NewWriter(file)}, nil
+}
+
+// write encodes the next element of the array and flushes it to the file.
+func (w *jsonArrayWriter) write(value interface{}) error {
+	data, err := json.MarshalIndent(value, "  ", "  ")
+
+	if err != nil {
+		return fmt.Errorf("could not encode %s: %w", w.filename, err)
+	}
+
+	separator := ",\n  "
+
+	if w.count == 0 {
+		separator = "[\n  "
+	}
+
+	w.count++
+	w.buf.WriteString(separator)
+	w.buf.Write(data)
+
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("could not write %s: %w", w.filename, err)
+	}
+
+	return nil
+}
+
+// close ends the array and moves the file into place.
+func (w *jsonArrayWriter) close() error {
+	end := "\n]\n"
+
+	if w.count == 0 {
+		end = "[]\n"
+	}
+
+	w.buf.WriteString(end)
+
+	if err := w.buf.Flush(); err != nil {
+		w.abort()
+		return fmt.Errorf("could not write %s: %w", w.filename, err)
+	}
+
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return fmt.Errorf("could not close output file: %w", err)
+	}
+
+	if err := os.Rename(w.file.Name(), w.filename); err != nil {
+		os.Remove(w.file.Name())
+		return fmt.Errorf("could not replace %s: %w", w.filename, err)
+	}
+
+	return nil
+}
+
+// abort discards what was written, leaving any existing file untouched.
+func (w *jsonArrayWriter) abort() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
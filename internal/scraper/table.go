@@ -0,0 +1,171 @@
+package scraper
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxRowspan is the largest rowspan HTML allows; browsers clamp larger values to it.
+const maxRowspan = 65534
+
+// maxTableColumns is wider than any table in the reference. It keeps a page full of
+// colspans from expanding into an enormous grid.
+const maxTableColumns = 64
+
+// tableCell is a cell of a normalized table. A cell that spans several rows or columns
+// appears at every position it covers.
+type tableCell struct {
+	node *html.Node
+
+	// Where the cell starts
+	row, col int
+}
+
+// tableGrid is a table with its rowspans and colspans expanded into a dense grid of rows
+// and columns, laid out the way a browser would. The parsers interpret the grid by
+// column instead of working out which cell of a row is which from the spans above it.
+type tableGrid [][]*tableCell
+
+// rowspan returns the number of rows a cell spans, treating a missing or nonsensical value
+// as 1.
+func rowspan(cellNode *html.Node) int {
+	return spanValue(cellNode, "rowspan", maxRowspan)
+}
+
+// colspan returns the number of columns a cell spans, treating a missing or nonsensical
+// value as 1.
+func colspan(cellNode *html.Node) int {
+	return spanValue(cellNode, "colspan", maxTableColumns)
+}
+
+func spanValue(cellNode *html.Node, attr string, max int) int {
+	value := getAttrValue(cellNode, attr)
+
+	// Most cells don't span anything, and a failed Atoi allocates an error
+	if value == "" {
+		return 1
+	}
+
+	v, err := strconv.Atoi(value)
+
+	if err != nil || v < 1 {
+		return 1
+	} else if v > max {
+		return max
+	}
+
+	return v
+}
+
+// newTableGrid lays out the rows of a table, header rows included. Rows can come out
+// with different widths, and can have holes where a page leaves cells out; cell returns
+// nil for those.
+func newTableGrid(tableNode *html.Node) (tableGrid, error) {
+	rowNodes := appendElements(nil, tableNode, atom.Tr)
+	grid := make(tableGrid, len(rowNodes))
+	cellCount := 0
+
+	for _, rowNode := range rowNodes {
+		for cellNode := rowNode.FirstChild; cellNode != nil; cellNode = cellNode.NextSibling {
+			if isTableCell(cellNode) {
+				cellCount++
+			}
+		}
+	}
+
+	// Allocated together, since the actions table of a big service has thousands of cells
+	cells := make([]tableCell, 0, cellCount)
+
+	for r, rowNode := range rowNodes {
+		col := 0
+
+		if grid[r] == nil && r > 0 {
+			grid[r] = make([]*tableCell, 0, len(grid[r-1]))
+		}
+
+		for cellNode := rowNode.FirstChild; cellNode != nil; cellNode = cellNode.NextSibling {
+			if !isTableCell(cellNode) {
+				continue
+			}
+
+			// Skip the positions taken by cells from the rows above
+			for col < len(grid[r]) && grid[r][col] != nil {
+				col++
+			}
+
+			cells = append(cells, tableCell{node: cellNode, row: r, col: col})
+			cell := &cells[len(cells)-1]
+			rows, cols := rowspan(cellNode), colspan(cellNode)
+
+			if r+rows > len(grid) {
+				rows = len(grid) - r
+			}
+
+			if col+cols > maxTableColumns {
+				return nil, fmt.Errorf("table is more than %d columns wide", maxTableColumns)
+			}
+
+			for i := r; i < r+rows; i++ {
+				for len(grid[i]) < col+cols {
+					grid[i] = append(grid[i], nil)
+				}
+
+				for j := col; j < col+cols; j++ {
+					grid[i][j] = cell
+				}
+			}
+
+			col += cols
+		}
+	}
+
+	return grid, nil
+}
+
+func isTableCell(node *html.Node) bool {
+	return node.Type == html.ElementNode && (node.DataAtom == atom.Td || node.DataAtom == atom.Th)
+}
+
+// cell returns the cell covering a position, or nil if there isn't one.
+func (grid tableGrid) cell(row, col int) *tableCell {
+	if row < len(grid) && col < len(grid[row]) {
+		return grid[row][col]
+	}
+
+	return nil
+}
+
+// at returns the node covering a position, wherever its cell starts, or nil.
+func (grid tableGrid) at(row, col int) *html.Node {
+	if cell := grid.cell(row, col); cell != nil {
+		return cell.node
+	}
+
+	return nil
+}
+
+// own returns the node of the cell that starts at a position, or nil if the position is
+// empty or covered by a cell from an earlier row or column.
+func (grid tableGrid) own(row, col int) *html.Node {
+	if cell := grid.cell(row, col); cell != nil && cell.row == row && cell.col == col {
+		return cell.node
+	}
+
+	return nil
+}
+
+// rowHTML renders the cells that start in a row, for error messages.
+func (grid tableGrid) rowHTML(row int) string {
+	result := ""
+
+	for col, cell := range grid[row] {
+		if cell != nil && cell.row == row && cell.col == col {
+			result += renderToString(cell.node)
+		}
+	}
+
+	return result
+}